@@ -8,6 +8,8 @@
 // TODO: 投稿の基本型定義
 export interface Post {
   id: string
+  author_id?: string  // 投稿者のユーザーID（認証導入前の投稿にはない）
+  author?: string     // 投稿者のユーザー名
   content: string
   created_at: string  // ISO8601形式の日時文字列
   updated_at: string  // ISO8601形式の日時文字列
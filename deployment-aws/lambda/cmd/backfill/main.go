@@ -0,0 +1,33 @@
+// 既存の投稿にインデックス用の属性を追加するコマンド
+//
+// 🎯 学習ポイント:
+// - Lambdaと同じ設定（環境変数）とDynamoDBクライアントを再利用する
+// - デプロイでインデックスを追加した後に一度だけ実行する
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"simple-crud-board-lambda/internal/config"
+	"simple-crud-board-lambda/internal/database"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	dbClient, err := database.NewClient(cfg.DynamoDBTableName, cfg.DynamoDBAuthorIndex, cfg.DynamoDBCreatedAtIndex)
+	if err != nil {
+		log.Fatalf("Failed to initialize database client: %v", err)
+	}
+
+	updated, err := dbClient.Backfill(context.Background())
+	if err != nil {
+		log.Fatalf("Backfill failed after updating %d posts: %v", updated, err)
+	}
+	log.Printf("Backfill completed: %d posts updated", updated)
+}
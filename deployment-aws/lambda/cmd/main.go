@@ -18,9 +18,11 @@ import (
 	"github.com/gin-contrib/cors"
 	ginFramework "github.com/gin-gonic/gin"
 
+	"simple-crud-board-lambda/internal/auth"
 	"simple-crud-board-lambda/internal/config"
 	"simple-crud-board-lambda/internal/database"
 	"simple-crud-board-lambda/internal/handlers"
+	"simple-crud-board-lambda/internal/repository"
 )
 
 var ginLambda *ginadapter.GinLambda
@@ -39,22 +41,58 @@ func init() {
 
 	// TODO: DynamoDBクライアントの初期化
 	// ヒント: database.NewClient()を実装してDynamoDBクライアントを作成
-	dbClient, err := database.NewClient(cfg.DynamoDBTableName)
+	dbClient, err := database.NewClient(cfg.DynamoDBTableName, cfg.DynamoDBAuthorIndex, cfg.DynamoDBCreatedAtIndex)
 	if err != nil {
 		log.Fatalf("Failed to initialize database client: %v", err)
 	}
 
 	// TODO: Ginルーターの設定
 	// ヒント: setupRouter()関数を実装してルーターを設定
-	router := setupRouter(dbClient)
+	// 認証の設定（AUTH_MODE=jwtの場合のみ）
+	authenticator, err := newAuthenticator(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize authentication: %v", err)
+	}
+
+	router := setupRouter(dbClient, authenticator)
 
 	// TODO: Gin Lambda アダプターの初期化
 	// ヒント: ginadapter.New(router)でアダプターを作成
 	ginLambda = ginadapter.New(router)
 }
 
+// newAuthenticator は設定に応じた認証方式を返す
+// AUTH_MODE=noneの場合はnil（認証なし）
+// API_TOKEN_INTROSPECTION_URLが設定されていれば、JWTに加えて個人APIトークンも受け付ける
+func newAuthenticator(cfg *config.Config) (auth.Authenticator, error) {
+	if cfg.AuthMode != "jwt" {
+		return nil, nil
+	}
+
+	jwtAuthenticator, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+		Algorithm: cfg.JWTAlgorithm,
+		Keys:      cfg.JWTKeys,
+		Issuer:    cfg.JWTIssuer,
+		Audience:  cfg.JWTAudience,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.APITokenIntrospectionURL == "" {
+		return jwtAuthenticator, nil
+	}
+
+	return auth.NewAPITokenAuthenticator(auth.IntrospectionConfig{
+		URL:      cfg.APITokenIntrospectionURL,
+		Token:    cfg.APITokenIntrospectionToken,
+		CacheTTL: cfg.APITokenCacheTTL,
+	}, jwtAuthenticator)
+}
+
 // setupRouter はGinルーターを設定する
-func setupRouter(dbClient *database.Client) *ginFramework.Engine {
+// database.Clientはrepository.PostRepositoryのDynamoDB実装として渡される
+// authenticatorがnilでなければ、投稿の作成・更新・削除に認証を要求する
+func setupRouter(postRepo repository.PostRepository, authenticator auth.Authenticator) *ginFramework.Engine {
 	// TODO: Ginモードの設定
 	// ヒント: 本番環境ではgin.SetMode(gin.ReleaseMode)
 	if os.Getenv("GIN_MODE") == "" {
@@ -77,21 +115,39 @@ func setupRouter(dbClient *database.Client) *ginFramework.Engine {
 
 	// TODO: ハンドラーの初期化
 	// ヒント: handlers.NewPostHandler()を実装
-	postHandler := handlers.NewPostHandler(dbClient)
+	postHandler := handlers.NewPostHandler(postRepo)
 
 	// TODO: ルートの設定
 	// ヒント: /api/posts のエンドポイントを設定
 	api := r.Group("/api")
+	if authenticator != nil {
+		api.Use(auth.Middleware(authenticator))
+	}
+
+	// 書き込み系と自分の投稿のルート（認証が有効な場合はログイン必須）
+	// 個人APIトークンはスコープで許された操作だけができる
+	writes := api.Group("")
+	if authenticator != nil {
+		writes.Use(auth.RequireAuth())
+	}
+	requireWrite := auth.RequireScope(auth.ScopePostsWrite)
+
 	{
 		// TODO: 投稿関連のルートを設定
 		// GET /api/posts - 全投稿取得
+		// GET /api/posts/search - 投稿検索
 		// POST /api/posts - 投稿作成
-		// PUT /api/posts/:id - 投稿更新
-		// DELETE /api/posts/:id - 投稿削除
+		// GET /api/posts/my - 自分の投稿取得（認証が有効な場合のみ）
+		// PUT /api/posts/:id - 投稿更新（投稿者または管理者のみ）
+		// DELETE /api/posts/:id - 投稿削除（投稿者または管理者のみ）
 		api.GET("/posts", postHandler.GetPosts)
-		api.POST("/posts", postHandler.CreatePost)
-		api.PUT("/posts/:id", postHandler.UpdatePost)
-		api.DELETE("/posts/:id", postHandler.DeletePost)
+		api.GET("/posts/search", postHandler.SearchPosts)
+		if authenticator != nil {
+			writes.GET("/posts/my", auth.RequireScope(auth.ScopePostsRead), postHandler.GetMyPosts)
+		}
+		writes.POST("/posts", requireWrite, postHandler.CreatePost)
+		writes.PUT("/posts/:id", requireWrite, postHandler.UpdatePost)
+		writes.DELETE("/posts/:id", requireWrite, postHandler.DeletePost)
 	}
 
 	// TODO: ヘルスチェックエンドポイント
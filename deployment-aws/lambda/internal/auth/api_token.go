@@ -0,0 +1,193 @@
+// 個人APIトークンの検証
+//
+// 🎯 学習ポイント:
+// - 個人APIトークンはuser-authenticationのデータベースにハッシュだけが保存されている
+// - LambdaはMySQLに接続できないため、user-authenticationのイントロスペクションAPI（RFC 7662）に問い合わせて検証する
+// - 問い合わせ結果を短時間キャッシュして、リクエストごとのHTTP呼び出しを減らす（失効の反映はキャッシュ期間だけ遅れる）
+// - "pat_" で始まるトークンだけを扱い、それ以外はJWTの検証に任せる
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APITokenPrefix は個人APIトークンの先頭の文字列（user-authenticationのservices.APITokenPrefixと同じ）
+const APITokenPrefix = "pat_"
+
+// 個人APIトークンのスコープ（user-authenticationのmodels.Scopesと同じ）
+const (
+	// ScopePostsRead は自分の投稿の取得を許可する
+	ScopePostsRead = "posts:read"
+	// ScopePostsWrite は投稿の作成・更新・削除を許可する
+	ScopePostsWrite = "posts:write"
+)
+
+const (
+	// introspectionTimeout はイントロスペクションAPIの応答を待つ時間
+	introspectionTimeout = 5 * time.Second
+	// maxCachedTokens はキャッシュするトークン数の上限（超えたらキャッシュを空にする）
+	maxCachedTokens = 1000
+)
+
+// IntrospectionConfig はトークンイントロスペクションの設定
+type IntrospectionConfig struct {
+	// URL はuser-authenticationの POST /api/auth/tokens/introspect のURL
+	URL string
+	// Token はイントロスペクションAPIに送るBearerトークン
+	// （user-authenticationのAPI_TOKEN_INTROSPECTION_TOKENSのいずれか）
+	Token string
+	// CacheTTL は問い合わせ結果をキャッシュする時間（0ならキャッシュしない）
+	CacheTTL time.Duration
+}
+
+// introspectionResponse はイントロスペクションAPIのレスポンス
+type introspectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope"`
+	Subject   string   `json:"sub"`
+	Username  string   `json:"username"`
+	Roles     []string `json:"roles"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// cachedIdentity はキャッシュされた問い合わせ結果
+type cachedIdentity struct {
+	// identity は無効なトークンの場合nil
+	identity  *Identity
+	expiresAt time.Time
+}
+
+// APITokenAuthenticator は "Authorization: Bearer pat_..." の個人APIトークンを検証する
+// それ以外のリクエストはnext（JWTAuthenticator）に任せる
+type APITokenAuthenticator struct {
+	config IntrospectionConfig
+	next   Authenticator
+	client *http.Client
+	now    func() time.Time
+
+	mu sync.Mutex
+	// cache はトークンのSHA-256ごとの問い合わせ結果（トークンそのものはメモリにも残さない）
+	cache map[string]cachedIdentity
+}
+
+var _ Authenticator = (*APITokenAuthenticator)(nil)
+
+// NewAPITokenAuthenticator は設定を検証してAPITokenAuthenticatorを作成する
+func NewAPITokenAuthenticator(config IntrospectionConfig, next Authenticator) (*APITokenAuthenticator, error) {
+	if config.URL == "" || config.Token == "" {
+		return nil, errors.New("API_TOKEN_INTROSPECTION_URL and API_TOKEN_INTROSPECTION_TOKEN are required for API tokens")
+	}
+
+	return &APITokenAuthenticator{
+		config: config,
+		next:   next,
+		client: &http.Client{Timeout: introspectionTimeout},
+		now:    time.Now,
+		cache:  make(map[string]cachedIdentity),
+	}, nil
+}
+
+// Authenticate は個人APIトークンを検証してユーザーを返す
+func (a *APITokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || !strings.HasPrefix(token, APITokenPrefix) {
+		return a.next.Authenticate(r)
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[key]
+	a.mu.Unlock()
+	if !ok || !now.Before(cached.expiresAt) {
+		identity, expiresAt, err := a.introspect(r.Context(), token)
+		if err != nil {
+			// 障害時はキャッシュせず、次のリクエストで再び問い合わせる
+			return nil, fmt.Errorf("%w: %v", ErrAuthUnavailable, err)
+		}
+		cached = cachedIdentity{identity: identity, expiresAt: now.Add(a.config.CacheTTL)}
+		// 期限切れのトークンをキャッシュから受け付けない
+		if !expiresAt.IsZero() && expiresAt.Before(cached.expiresAt) {
+			cached.expiresAt = expiresAt
+		}
+		a.store(key, cached)
+	}
+
+	if cached.identity == nil {
+		return nil, ErrUnauthenticated
+	}
+	return cached.identity, nil
+}
+
+// introspect はイントロスペクションAPIにトークンを問い合わせる
+// 無効なトークンの場合はnilを返す。expiresAtは有効期限のないトークンではゼロ値
+func (a *APITokenAuthenticator) introspect(ctx context.Context, token string) (*Identity, time.Time, error) {
+	body, err := json.Marshal(map[string]string{"token": token})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.config.Token)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("token introspection failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("token introspection returned status %d", resp.StatusCode)
+	}
+
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid token introspection response: %w", err)
+	}
+	if !result.Active || result.Subject == "" {
+		return nil, time.Time{}, nil
+	}
+
+	var expiresAt time.Time
+	if result.ExpiresAt != 0 {
+		expiresAt = time.Unix(result.ExpiresAt, 0)
+	}
+	return &Identity{
+		UserID:   result.Subject,
+		Username: result.Username,
+		Roles:    result.Roles,
+		APIToken: true,
+		Scopes:   strings.Fields(result.Scope),
+	}, expiresAt, nil
+}
+
+// store は問い合わせ結果をキャッシュする
+func (a *APITokenAuthenticator) store(key string, cached cachedIdentity) {
+	if a.config.CacheTTL <= 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.cache) >= maxCachedTokens {
+		a.cache = make(map[string]cachedIdentity)
+	}
+	a.cache[key] = cached
+}
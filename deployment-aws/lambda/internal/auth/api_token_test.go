@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// テスト用の個人APIトークンとイントロスペクションAPIのトークン
+const (
+	testAPIToken           = "pat_alice"
+	testIntrospectionToken = "lambda-secret"
+)
+
+// fakeIntrospection はuser-authenticationのイントロスペクションAPIの代わり
+type fakeIntrospection struct {
+	mu       sync.Mutex
+	status   int
+	response map[string]interface{}
+	calls    int
+}
+
+func (f *fakeIntrospection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+
+	if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer "+testIntrospectionToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Token != testAPIToken {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if f.status != 0 && f.status != http.StatusOK {
+		w.WriteHeader(f.status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f.response)
+}
+
+// set はイントロスペクションAPIの応答を変更する
+func (f *fakeIntrospection) set(status int, response map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status, f.response = status, response
+}
+
+func (f *fakeIntrospection) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// jwtStub はpat_以外のトークンを受け取るnextの代わり
+type jwtStub struct{}
+
+func (jwtStub) Authenticate(r *http.Request) (*Identity, error) {
+	if r.Header.Get("Authorization") == "Bearer jwt-token" {
+		return &Identity{UserID: "7", Username: "bob"}, nil
+	}
+	return nil, ErrUnauthenticated
+}
+
+// activeResponse は有効なトークンのイントロスペクション結果を返す
+func activeResponse(exp time.Time) map[string]interface{} {
+	response := map[string]interface{}{
+		"active":   true,
+		"scope":    "posts:read posts:write",
+		"sub":      "42",
+		"username": "alice",
+		"roles":    []string{"moderator"},
+	}
+	if !exp.IsZero() {
+		response["exp"] = exp.Unix()
+	}
+	return response
+}
+
+// setupAPITokenAuthenticator はfakeIntrospectionに問い合わせるAPITokenAuthenticatorを返す
+// 時計はclockを進めて操作する
+func setupAPITokenAuthenticator(t *testing.T, cacheTTL time.Duration) (*APITokenAuthenticator, *fakeIntrospection, *time.Time) {
+	t.Helper()
+	fake := &fakeIntrospection{response: activeResponse(time.Time{})}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	a, err := NewAPITokenAuthenticator(IntrospectionConfig{URL: server.URL, Token: testIntrospectionToken, CacheTTL: cacheTTL}, jwtStub{})
+	if err != nil {
+		t.Fatalf("NewAPITokenAuthenticator failed: %v", err)
+	}
+	clock := time.Now()
+	a.now = func() time.Time { return clock }
+	return a, fake, &clock
+}
+
+func TestAPITokenAuthenticator_Active(t *testing.T) {
+	a, fake, _ := setupAPITokenAuthenticator(t, time.Minute)
+
+	identity, err := authenticate(a, testAPIToken)
+	if err != nil {
+		t.Fatalf("Expected an active token to be accepted, got %v", err)
+	}
+	if identity.UserID != "42" || identity.Username != "alice" || !identity.HasRole("moderator") {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+	if !identity.APIToken || !identity.HasScope(ScopePostsWrite) || identity.HasScope("posts:admin") {
+		t.Errorf("Expected an API token identity with the introspected scopes, got %+v", identity)
+	}
+	if fake.callCount() != 1 {
+		t.Errorf("Expected 1 introspection call, got %d", fake.callCount())
+	}
+}
+
+func TestAPITokenAuthenticator_Inactive(t *testing.T) {
+	a, fake, _ := setupAPITokenAuthenticator(t, time.Minute)
+	fake.set(http.StatusOK, map[string]interface{}{"active": false})
+
+	if _, err := authenticate(a, testAPIToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for an inactive token, got %v", err)
+	}
+	// 無効という結果もキャッシュする
+	if _, err := authenticate(a, testAPIToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated from the cache, got %v", err)
+	}
+	if fake.callCount() != 1 {
+		t.Errorf("Expected the inactive result to be cached, got %d calls", fake.callCount())
+	}
+}
+
+func TestAPITokenAuthenticator_Cache(t *testing.T) {
+	a, fake, clock := setupAPITokenAuthenticator(t, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := authenticate(a, testAPIToken); err != nil {
+			t.Fatalf("Expected the token to be accepted, got %v", err)
+		}
+	}
+	if fake.callCount() != 1 {
+		t.Errorf("Expected cache hits after the first call, got %d calls", fake.callCount())
+	}
+
+	// 失効はキャッシュ期間が過ぎると反映される
+	fake.set(http.StatusOK, map[string]interface{}{"active": false})
+	*clock = clock.Add(time.Minute)
+	if _, err := authenticate(a, testAPIToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected a revoked token to be rejected after the cache TTL, got %v", err)
+	}
+	if fake.callCount() != 2 {
+		t.Errorf("Expected a new introspection call after the cache TTL, got %d calls", fake.callCount())
+	}
+}
+
+func TestAPITokenAuthenticator_CacheStopsAtTokenExpiry(t *testing.T) {
+	a, fake, clock := setupAPITokenAuthenticator(t, time.Hour)
+	fake.set(http.StatusOK, activeResponse(clock.Add(10*time.Second)))
+
+	if _, err := authenticate(a, testAPIToken); err != nil {
+		t.Fatalf("Expected the token to be accepted, got %v", err)
+	}
+
+	// トークンの有効期限を過ぎたらキャッシュ期間内でも問い合わせ直す
+	fake.set(http.StatusOK, map[string]interface{}{"active": false})
+	*clock = clock.Add(10 * time.Second)
+	if _, err := authenticate(a, testAPIToken); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected an expired token not to be served from the cache, got %v", err)
+	}
+	if fake.callCount() != 2 {
+		t.Errorf("Expected a new introspection call at the token's exp, got %d calls", fake.callCount())
+	}
+}
+
+func TestAPITokenAuthenticator_NoCache(t *testing.T) {
+	a, fake, _ := setupAPITokenAuthenticator(t, 0)
+
+	authenticate(a, testAPIToken)
+	authenticate(a, testAPIToken)
+	if fake.callCount() != 2 {
+		t.Errorf("Expected every request to be introspected without a cache TTL, got %d calls", fake.callCount())
+	}
+}
+
+func TestAPITokenAuthenticator_IntrospectionErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response map[string]interface{}
+	}{
+		{"server error", http.StatusInternalServerError, nil},
+		{"rejected introspection token", http.StatusUnauthorized, nil},
+	}
+
+	for _, tt := range tests {
+		a, fake, _ := setupAPITokenAuthenticator(t, time.Minute)
+		fake.set(tt.status, tt.response)
+
+		_, err := authenticate(a, testAPIToken)
+		if !errors.Is(err, ErrAuthUnavailable) {
+			t.Errorf("%s: expected ErrAuthUnavailable, got %v", tt.name, err)
+		}
+
+		// 障害はキャッシュしない
+		fake.set(http.StatusOK, activeResponse(time.Time{}))
+		if _, err := authenticate(a, testAPIToken); err != nil {
+			t.Errorf("%s: expected the token to be accepted once introspection recovers, got %v", tt.name, err)
+		}
+	}
+
+	// 接続できない場合
+	a, err := NewAPITokenAuthenticator(IntrospectionConfig{URL: "http://127.0.0.1:1/introspect", Token: testIntrospectionToken}, jwtStub{})
+	if err != nil {
+		t.Fatalf("NewAPITokenAuthenticator failed: %v", err)
+	}
+	if _, err := authenticate(a, testAPIToken); !errors.Is(err, ErrAuthUnavailable) {
+		t.Errorf("Expected ErrAuthUnavailable for an unreachable server, got %v", err)
+	}
+}
+
+func TestAPITokenAuthenticator_Middleware(t *testing.T) {
+	a, fake, _ := setupAPITokenAuthenticator(t, 0)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(a))
+	r.POST("/api/posts", RequireAuth(), RequireScope(ScopePostsWrite), func(c *gin.Context) {
+		identity, _ := FromContext(c)
+		c.String(http.StatusCreated, identity.UserID)
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		status   int
+		response map[string]interface{}
+		want     int
+	}{
+		{"active token", testAPIToken, http.StatusOK, activeResponse(time.Time{}), http.StatusCreated},
+		{"missing scope", testAPIToken, http.StatusOK, map[string]interface{}{"active": true, "sub": "42", "scope": ScopePostsRead}, http.StatusForbidden},
+		{"inactive token", testAPIToken, http.StatusOK, map[string]interface{}{"active": false}, http.StatusUnauthorized},
+		{"introspection down", testAPIToken, http.StatusBadGateway, nil, http.StatusServiceUnavailable},
+		{"JWT", "jwt-token", http.StatusOK, nil, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		fake.set(tt.status, tt.response)
+		req := httptest.NewRequest(http.MethodPost, "/api/posts", strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAPITokenAuthenticator_DelegatesOtherTokens(t *testing.T) {
+	a, fake, _ := setupAPITokenAuthenticator(t, time.Minute)
+
+	identity, err := authenticate(a, "jwt-token")
+	if err != nil || identity.UserID != "7" || identity.APIToken {
+		t.Errorf("Expected the JWT identity from next, got %+v, %v", identity, err)
+	}
+	if _, err := authenticate(a, ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated without a token, got %v", err)
+	}
+	if fake.callCount() != 0 {
+		t.Errorf("Expected no introspection for other tokens, got %d calls", fake.callCount())
+	}
+}
+
+func TestNewAPITokenAuthenticator_RequiresConfig(t *testing.T) {
+	for _, config := range []IntrospectionConfig{{URL: "http://auth/introspect"}, {Token: testIntrospectionToken}} {
+		if _, err := NewAPITokenAuthenticator(config, jwtStub{}); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// 認証の抽象化とGinミドルウェア
+//
+// 🎯 学習ポイント:
+// - 認証方式（JWTなど）をインターフェースで差し替え可能にする
+// - Lambdaはステートレスなので、サーバー側セッションではなくトークン検証で認証する
+// - 認証結果はgin.Contextに保存し、ハンドラーから取り出して使う
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrUnauthenticated は認証情報がない、または無効な場合に返される
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ErrAuthUnavailable は認証情報を検証できなかった場合（イントロスペクションAPIの障害など）に返される
+// 認証情報が正しいかどうか分からないため、匿名として通さずに503で拒否する
+var ErrAuthUnavailable = errors.New("authentication unavailable")
+
+// IdentityContextKey は認証済みユーザーをgin.Contextに保存するキー
+const IdentityContextKey = "identity"
+
+// Identity はリクエストを送ったユーザー
+type Identity struct {
+	// UserID はuser-authenticationのusers.id（JWTのsubクレーム）
+	UserID string
+	// Username はユーザー名
+	Username string
+	// Roles はユーザーのロール（JWTのrolesクレーム）
+	Roles []string
+	// APIToken は個人APIトークンで認証された場合にtrue
+	APIToken bool
+	// Scopes は個人APIトークンのスコープ（JWTで認証された場合は使わない）
+	Scopes []string
+}
+
+// AdminRole はすべての投稿を編集・削除できるロール
+const AdminRole = "admin"
+
+// HasRole はユーザーがroleを持っているかどうかを判定する
+func (i *Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope はリクエストがscopeの操作を許されているかどうかを判定する
+// JWTで認証されたユーザーはスコープで制限しない
+func (i *Identity) HasScope(scope string) bool {
+	if !i.APIToken {
+		return true
+	}
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator はリクエストからユーザーを特定する
+// user-authenticationのmiddleware.Authenticatorに対応するが、Lambdaではトークンの検証のみを行い、発行はしない
+type Authenticator interface {
+	// Authenticate は認証情報からユーザーを返す。認証情報がなければErrUnauthenticatedを返す
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Middleware は認証済みユーザーをIdentityContextKeyに保存する
+// 認証情報のないリクエストは匿名のまま次に進む（拒否する場合はRequireAuthを使う）
+func Middleware(a Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, err := a.Authenticate(c.Request)
+		if errors.Is(err, ErrUnauthenticated) {
+			c.Next()
+			return
+		}
+		if errors.Is(err, ErrAuthUnavailable) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Authentication unavailable",
+				"message": "Could not verify the credentials, please retry later",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to authenticate request",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		c.Set(IdentityContextKey, identity)
+		c.Next()
+	}
+}
+
+// RequireAuth は認証されていないリクエストを401で拒否する
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := FromContext(c); !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope は個人APIトークンで認証されたリクエストのうち、scopeを持たないものを403で拒否する
+// RequireAuthの後に使う
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identity, ok := FromContext(c); ok && !identity.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "API token lacks the " + scope + " scope",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// FromContext はMiddlewareが保存した認証済みユーザーを返す
+func FromContext(c *gin.Context) (*Identity, bool) {
+	value, ok := c.Get(IdentityContextKey)
+	if !ok {
+		return nil, false
+	}
+	identity, ok := value.(*Identity)
+	return identity, ok
+}
@@ -0,0 +1,241 @@
+// JWTアクセストークンの検証
+//
+// 🎯 学習ポイント:
+// - JWTは「ヘッダー.クレーム.署名」をbase64urlでつないだ文字列
+// - HS256（共有鍵）、RS256・EdDSA（公開鍵）の署名を標準ライブラリだけで検証する
+// - アルゴリズムはトークンではなく設定で固定する（alg=noneなどの攻撃を防ぐ）
+// - kid（鍵ID）で複数の鍵を持ち、鍵のローテーション中も古いトークンを受け付ける
+// - トークンはuser-authenticationのservices.JWTServiceが発行する
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 対応する署名アルゴリズム
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// accessTokenType はアクセストークンのtypクレーム
+const accessTokenType = "access"
+
+// JWTConfig はJWT検証の設定
+type JWTConfig struct {
+	// Algorithm は署名アルゴリズム（HS256、RS256、EdDSA）
+	Algorithm string
+	// Keys は "kid:鍵" のカンマ区切り
+	// HS256は共有鍵、RS256・EdDSAはPEM形式の公開鍵（文字列またはファイルパス）
+	Keys string
+	// Issuer はissクレームに期待する値
+	Issuer string
+	// Audience はaudクレームに期待する値（空文字ならaudなし）
+	Audience string
+}
+
+// accessClaims はアクセストークンのクレーム
+type accessClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  string   `json:"aud,omitempty"`
+	Username  string   `json:"username"`
+	Roles     []string `json:"roles,omitempty"`
+	Type      string   `json:"typ"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// jwtHeader はトークンのヘッダー
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// JWTAuthenticator は "Authorization: Bearer <token>" のアクセストークンを検証する
+type JWTAuthenticator struct {
+	config JWTConfig
+	// keys はkidごとの検証鍵（HS256は[]byte、RS256は*rsa.PublicKey、EdDSAはed25519.PublicKey）
+	keys map[string]interface{}
+	now  func() time.Time
+}
+
+var _ Authenticator = (*JWTAuthenticator)(nil)
+
+// NewJWTAuthenticator は設定を検証してJWTAuthenticatorを作成する
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{config: config, keys: make(map[string]interface{}), now: time.Now}
+
+	for _, entry := range strings.Split(config.Keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, material, found := strings.Cut(entry, ":")
+		if !found || id == "" || material == "" {
+			return nil, fmt.Errorf("JWT key %q must have the form kid:key", entry)
+		}
+		if _, exists := a.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate JWT key ID %q", id)
+		}
+
+		key, err := parseVerificationKey(config.Algorithm, material)
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", id, err)
+		}
+		a.keys[id] = key
+	}
+
+	if len(a.keys) == 0 {
+		return nil, errors.New("JWT_KEYS is required when AUTH_MODE=jwt")
+	}
+	return a, nil
+}
+
+// parseVerificationKey はアルゴリズムに応じた検証鍵を読み込む
+func parseVerificationKey(algorithm, material string) (interface{}, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		if len(material) < sha256.Size {
+			return nil, fmt.Errorf("HS256 key must be at least %d bytes", sha256.Size)
+		}
+		return []byte(material), nil
+	case AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+
+	// PEM文字列でなければファイルパスとして読み込む
+	pemBytes := []byte(material)
+	if !strings.HasPrefix(strings.TrimSpace(material), "-----BEGIN") {
+		data, err := os.ReadFile(material)
+		if err != nil {
+			return nil, err
+		}
+		pemBytes = data
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("key must be a PEM encoded public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if algorithm == AlgorithmRS256 {
+			return k, nil
+		}
+	case ed25519.PublicKey:
+		if algorithm == AlgorithmEdDSA {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%T cannot be used with %s", key, algorithm)
+}
+
+// Authenticate はBearerトークンを検証してユーザーを返す
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	claims, err := a.verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	return &Identity{UserID: claims.Subject, Username: claims.Username, Roles: claims.Roles}, nil
+}
+
+// verify はトークンの署名とクレームを検証する
+func (a *JWTAuthenticator) verify(token string) (*accessClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	// アルゴリズムはトークンに決めさせない
+	if header.Algorithm != a.config.Algorithm {
+		return nil, fmt.Errorf("unexpected algorithm %q", header.Algorithm)
+	}
+	key, ok := a.keys[header.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", header.KeyID)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	if !verifySignature(key, parts[0]+"."+parts[1], signature) {
+		return nil, errors.New("bad signature")
+	}
+
+	var claims accessClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case claims.Type != accessTokenType:
+		return nil, errors.New("not an access token")
+	case claims.Issuer != a.config.Issuer:
+		return nil, errors.New("unexpected issuer")
+	case claims.Audience != a.config.Audience:
+		return nil, errors.New("unexpected audience")
+	case a.now().Unix() >= claims.ExpiresAt:
+		return nil, errors.New("token expired")
+	case claims.Subject == "":
+		return nil, errors.New("missing subject")
+	}
+	return &claims, nil
+}
+
+// verifySignature は鍵の種類に応じて署名を検証する
+func verifySignature(key interface{}, signingInput string, signature []byte) bool {
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case *rsa.PublicKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, []byte(signingInput), signature)
+	}
+	return false
+}
+
+// decodeSegment はbase64urlのJSONをデコードする
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// テスト用のHS256共有鍵（32バイト以上）
+const testHSKey = "0123456789abcdef0123456789abcdef"
+
+// testClaims はuser-authenticationが発行するアクセストークンと同じクレームを返す
+func testClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":      "42",
+		"iss":      "user-authentication",
+		"aud":      "board",
+		"username": "alice",
+		"roles":    []string{"admin"},
+		"typ":      "access",
+		"exp":      time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+// signToken はheaderとclaimsからトークンを作り、signで署名する
+func signToken(t *testing.T, alg, kid string, claims map[string]interface{}, sign func(signingInput []byte) []byte) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	if err != nil {
+		t.Fatalf("Failed to encode header: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode claims: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signingInput)))
+}
+
+// hs256 はHS256で署名する関数を返す
+func hs256(key string) func([]byte) []byte {
+	return func(signingInput []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(signingInput)
+		return mac.Sum(nil)
+	}
+}
+
+// publicKeyPEM は公開鍵をPEM文字列にする
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func newTestJWTAuthenticator(t *testing.T, config JWTConfig) *JWTAuthenticator {
+	t.Helper()
+	if config.Issuer == "" {
+		config.Issuer = "user-authentication"
+		config.Audience = "board"
+	}
+	a, err := NewJWTAuthenticator(config)
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator failed: %v", err)
+	}
+	return a
+}
+
+// authenticate はBearerトークン付きのリクエストを認証する
+func authenticate(a Authenticator, token string) (*Identity, error) {
+	req := httptest.NewRequest("GET", "/api/posts", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return a.Authenticate(req)
+}
+
+func TestJWTAuthenticator_HS256(t *testing.T) {
+	a := newTestJWTAuthenticator(t, JWTConfig{Algorithm: AlgorithmHS256, Keys: "k1:" + testHSKey})
+
+	identity, err := authenticate(a, signToken(t, AlgorithmHS256, "k1", testClaims(), hs256(testHSKey)))
+	if err != nil {
+		t.Fatalf("Expected a valid token, got %v", err)
+	}
+	if identity.UserID != "42" || identity.Username != "alice" || !identity.HasRole(AdminRole) {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+	if identity.APIToken {
+		t.Error("Expected a JWT identity not to be marked as an API token")
+	}
+}
+
+// rs256 はRS256で署名する関数を返す
+func rs256(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(signingInput []byte) []byte {
+		digest := sha256.Sum256(signingInput)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		return signature
+	}
+}
+
+// eddsa はEdDSAで署名する関数を返す
+func eddsa(key ed25519.PrivateKey) func([]byte) []byte {
+	return func(signingInput []byte) []byte {
+		return ed25519.Sign(key, signingInput)
+	}
+}
+
+func TestJWTAuthenticator_RS256AndEdDSA(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	_, otherEdPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	// 公開鍵はファイルパスでも指定できる
+	edPath := filepath.Join(t.TempDir(), "ed25519.pem")
+	if err := os.WriteFile(edPath, []byte(publicKeyPEM(t, edPublic)), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		alg   string
+		keys  string
+		sign  func([]byte) []byte
+		forge func([]byte) []byte
+	}{
+		{AlgorithmRS256, "k1:" + publicKeyPEM(t, &rsaKey.PublicKey), rs256(t, rsaKey), rs256(t, otherRSAKey)},
+		{AlgorithmEdDSA, "k1:" + edPath, eddsa(edPrivate), eddsa(otherEdPrivate)},
+	}
+
+	for _, tt := range tests {
+		a := newTestJWTAuthenticator(t, JWTConfig{Algorithm: tt.alg, Keys: tt.keys})
+
+		if _, err := authenticate(a, signToken(t, tt.alg, "k1", testClaims(), tt.sign)); err != nil {
+			t.Errorf("%s: expected a valid token, got %v", tt.alg, err)
+		}
+		// 別の秘密鍵で署名されたトークンは拒否する
+		if _, err := authenticate(a, signToken(t, tt.alg, "k1", testClaims(), tt.forge)); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%s: expected a signature by another key to be rejected, got %v", tt.alg, err)
+		}
+	}
+}
+
+func TestJWTAuthenticator_Rejects(t *testing.T) {
+	a := newTestJWTAuthenticator(t, JWTConfig{Algorithm: AlgorithmHS256, Keys: "k1:" + testHSKey})
+
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		claims := testClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	valid := signToken(t, AlgorithmHS256, "k1", testClaims(), hs256(testHSKey))
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"bad signature", signToken(t, AlgorithmHS256, "k1", testClaims(), hs256(strings.Repeat("x", 32)))},
+		{"tampered claims", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1","iss":"user-authentication","aud":"board","typ":"access","exp":9999999999}`)) + "." + parts[2]},
+		{"alg none", signToken(t, "none", "k1", testClaims(), func([]byte) []byte { return nil })},
+		{"wrong alg", signToken(t, AlgorithmRS256, "k1", testClaims(), hs256(testHSKey))},
+		{"unknown kid", signToken(t, AlgorithmHS256, "k2", testClaims(), hs256(testHSKey))},
+		{"expired", signToken(t, AlgorithmHS256, "k1", withClaim("exp", time.Now().Add(-time.Second).Unix()), hs256(testHSKey))},
+		{"wrong issuer", signToken(t, AlgorithmHS256, "k1", withClaim("iss", "someone-else"), hs256(testHSKey))},
+		{"wrong audience", signToken(t, AlgorithmHS256, "k1", withClaim("aud", "other-service"), hs256(testHSKey))},
+		{"missing audience", signToken(t, AlgorithmHS256, "k1", withClaim("aud", nil), hs256(testHSKey))},
+		{"refresh token", signToken(t, AlgorithmHS256, "k1", withClaim("typ", "refresh"), hs256(testHSKey))},
+		{"missing subject", signToken(t, AlgorithmHS256, "k1", withClaim("sub", nil), hs256(testHSKey))},
+		{"malformed", "not.a-token"},
+	}
+
+	for _, tt := range tests {
+		if _, err := authenticate(a, tt.token); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%s: expected ErrUnauthenticated, got %v", tt.name, err)
+		}
+	}
+
+	if _, err := authenticate(a, ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated without a token, got %v", err)
+	}
+}
+
+func TestJWTAuthenticator_ExpiryUsesClock(t *testing.T) {
+	a := newTestJWTAuthenticator(t, JWTConfig{Algorithm: AlgorithmHS256, Keys: "k1:" + testHSKey})
+	token := signToken(t, AlgorithmHS256, "k1", testClaims(), hs256(testHSKey))
+
+	a.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if _, err := authenticate(a, token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected a token to be rejected at its exp, got %v", err)
+	}
+}
+
+func TestJWTAuthenticator_KeyRotation(t *testing.T) {
+	oldKey, newKey := testHSKey, strings.Repeat("n", 32)
+	a := newTestJWTAuthenticator(t, JWTConfig{Algorithm: AlgorithmHS256, Keys: "old:" + oldKey + ",new:" + newKey})
+
+	for kid, key := range map[string]string{"old": oldKey, "new": newKey} {
+		if _, err := authenticate(a, signToken(t, AlgorithmHS256, kid, testClaims(), hs256(key))); err != nil {
+			t.Errorf("Expected a token signed with %q to be accepted, got %v", kid, err)
+		}
+	}
+
+	// kidと違う鍵で署名されたトークンは拒否する
+	if _, err := authenticate(a, signToken(t, AlgorithmHS256, "new", testClaims(), hs256(oldKey))); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected a token signed with another key than its kid to be rejected, got %v", err)
+	}
+}
+
+func TestNewJWTAuthenticator_InvalidConfig(t *testing.T) {
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		config JWTConfig
+	}{
+		{"no keys", JWTConfig{Algorithm: AlgorithmHS256}},
+		{"short HS256 key", JWTConfig{Algorithm: AlgorithmHS256, Keys: "k1:short"}},
+		{"missing kid", JWTConfig{Algorithm: AlgorithmHS256, Keys: testHSKey}},
+		{"duplicate kid", JWTConfig{Algorithm: AlgorithmHS256, Keys: "k1:" + testHSKey + ",k1:" + testHSKey}},
+		{"unsupported algorithm", JWTConfig{Algorithm: "HS512", Keys: "k1:" + testHSKey}},
+		{"key for another algorithm", JWTConfig{Algorithm: AlgorithmRS256, Keys: "k1:" + publicKeyPEM(t, edPublic)}},
+		{"missing key file", JWTConfig{Algorithm: AlgorithmEdDSA, Keys: "k1:" + filepath.Join(t.TempDir(), "missing.pem")}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewJWTAuthenticator(tt.config); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -10,12 +10,19 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 // Config はアプリケーションの設定を保持する構造体
 type Config struct {
 	// DynamoDBテーブル名
 	DynamoDBTableName string
+
+	// 投稿者ごとの投稿を取得するGSI名
+	DynamoDBAuthorIndex string
+
+	// 全投稿を作成日時の順に取得するGSI名
+	DynamoDBCreatedAtIndex string
 	
 	// AWSリージョン
 	AWSRegion string
@@ -25,6 +32,22 @@ type Config struct {
 	
 	// CORS設定
 	AllowedOrigins []string
+
+	// 認証方式（"none" または "jwt"）
+	AuthMode string
+
+	// JWT設定（AuthModeが"jwt"の場合のみ使用）
+	// user-authenticationと同じ値を設定する
+	JWTAlgorithm string
+	JWTKeys      string
+	JWTIssuer    string
+	JWTAudience  string
+
+	// 個人APIトークンの設定（AuthModeが"jwt"でURLが設定されている場合のみ使用）
+	// user-authenticationのイントロスペクションAPIでトークンを検証する
+	APITokenIntrospectionURL   string
+	APITokenIntrospectionToken string
+	APITokenCacheTTL           time.Duration
 }
 
 // Load は環境変数から設定を読み込む
@@ -38,6 +61,9 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("DYNAMODB_TABLE_NAME environment variable is required")
 	}
 
+	config.DynamoDBAuthorIndex = getEnv("DYNAMODB_AUTHOR_INDEX", "AuthorIndex")
+	config.DynamoDBCreatedAtIndex = getEnv("DYNAMODB_CREATED_AT_INDEX", "CreatedAtIndex")
+
 	// TODO: AWSリージョンの読み込み
 	// ヒント: AWS_REGIONまたはAWS_DEFAULT_REGION
 	config.AWSRegion = os.Getenv("TODO: 環境変数名を設定")
@@ -65,6 +91,34 @@ func Load() (*Config, error) {
 		config.AllowedOrigins = []string{"TODO: デフォルト許可オリジンを設定"}
 	}
 
+	// 認証方式の読み込み
+	// Lambdaはリクエストごとに状態を持たないため、サーバー側セッションは使わずJWTで認証する
+	config.AuthMode = getEnv("AUTH_MODE", "none")
+	switch config.AuthMode {
+	case "none", "jwt":
+	case "session":
+		return nil, fmt.Errorf("AUTH_MODE=session is not supported on Lambda; use jwt")
+	default:
+		return nil, fmt.Errorf("unknown AUTH_MODE %q (use none or jwt)", config.AuthMode)
+	}
+
+	config.JWTAlgorithm = getEnv("JWT_ALGORITHM", "HS256")
+	config.JWTKeys = os.Getenv("JWT_KEYS")
+	config.JWTIssuer = getEnv("JWT_ISSUER", "user-authentication")
+	config.JWTAudience = os.Getenv("JWT_AUDIENCE")
+
+	// 個人APIトークンの読み込み
+	config.APITokenIntrospectionURL = os.Getenv("API_TOKEN_INTROSPECTION_URL")
+	config.APITokenIntrospectionToken = os.Getenv("API_TOKEN_INTROSPECTION_TOKEN")
+	if config.APITokenIntrospectionURL != "" && config.AuthMode != "jwt" {
+		return nil, fmt.Errorf("API_TOKEN_INTROSPECTION_URL requires AUTH_MODE=jwt")
+	}
+	cacheTTL, err := time.ParseDuration(getEnv("API_TOKEN_CACHE_TTL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_TOKEN_CACHE_TTL: %w", err)
+	}
+	config.APITokenCacheTTL = cacheTTL
+
 	return config, nil
 }
 
@@ -103,4 +157,12 @@ func (c *Config) GetLogLevel() string {
 // GetAllowedOrigins は許可されたオリジンのリストを返す
 func (c *Config) GetAllowedOrigins() []string {
 	return c.AllowedOrigins
+}
+
+// getEnv は環境変数を読み込み、未設定の場合はデフォルト値を返す
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
\ No newline at end of file
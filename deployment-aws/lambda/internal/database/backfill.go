@@ -0,0 +1,75 @@
+// 既存の投稿へのインデックス用属性の追加
+//
+// 🎯 学習ポイント:
+// - GSIのキー属性を持たないアイテムはインデックスに含まれない（スパースインデックス）
+// - 属性を後から導入した場合は、既存のアイテムに書き足す必要がある
+// - 検索用の小文字の本文（content_lc）も、導入前の投稿には書き足す
+// - 条件付き更新で、走査中に削除・編集された投稿を上書きしない
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Backfill はインデックス用の属性がない投稿（属性の導入前に作成された投稿）に属性を追加する
+// 更新した件数を返す。追加済みの投稿は変更しないため、何度実行してもよい
+func (c *Client) Backfill(ctx context.Context) (int, error) {
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(c.tableName),
+		FilterExpression:     aws.String("attribute_not_exists(feed) OR attribute_not_exists(content_lc)"),
+		ProjectionExpression: aws.String("id, content"),
+	}
+
+	updated := 0
+	paginator := dynamodb.NewScanPaginator(c.dynamodb, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return updated, c.handleDynamoDBError(err, "scan posts for backfill")
+		}
+
+		for _, item := range page.Items {
+			id, ok := item["id"].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			content, ok := item["content"].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+
+			_, err := c.dynamodb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName: aws.String(c.tableName),
+				Key: map[string]types.AttributeValue{
+					"id": id,
+				},
+				UpdateExpression: aws.String("SET feed = :feed, content_lc = :content_lc"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":feed":       &types.AttributeValueMemberS{Value: feedPartition},
+					":content_lc": &types.AttributeValueMemberS{Value: strings.ToLower(content.Value)},
+					":content":    content,
+				},
+				// 走査後に削除された投稿は作り直さず、編集された投稿は古い本文で上書きしない（次回の実行で更新する）
+				ConditionExpression: aws.String("content = :content"),
+			})
+			if err != nil {
+				if isConditionalCheckFailed(err) {
+					continue
+				}
+				return updated, fmt.Errorf("failed to backfill post %s: %w", id.Value, c.handleDynamoDBError(err, "backfill post"))
+			}
+			updated++
+		}
+	}
+
+	log.Printf("Backfilled %d posts", updated)
+	return updated, nil
+}
@@ -0,0 +1,61 @@
+// ページネーションカーソルのエンコード/デコード
+//
+// 🎯 学習ポイント:
+// - DynamoDBのLastEvaluatedKey/ExclusiveStartKeyによるページ分割
+// - 内部のキー構造をクライアントに見せない不透明なカーソル
+// - base64（URLセーフ）でクエリパラメータに載せられる形にする
+
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"simple-crud-board-lambda/internal/repository"
+)
+
+// encodeCursor はLastEvaluatedKeyを不透明なカーソル文字列に変換する
+// テーブルとGSIのキーは文字列属性（id、feed、author_id、created_at）のみなので、文字列のマップとして保存する
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	var plain map[string]string
+	if err := attributevalue.UnmarshalMap(key, &plain); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// nextCursor はQueryのLastEvaluatedKeyから次ページのカーソルを作る
+// 最後のページ（LastEvaluatedKeyがない）では空文字を返す
+func nextCursor(lastKey map[string]types.AttributeValue) (string, error) {
+	if len(lastKey) == 0 {
+		return "", nil
+	}
+	return encodeCursor(lastKey)
+}
+
+// decodeCursor はカーソル文字列をExclusiveStartKeyに戻す
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+
+	var plain map[string]string
+	if err := json.Unmarshal(data, &plain); err != nil || plain["id"] == "" {
+		return nil, repository.ErrInvalidCursor
+	}
+
+	key, err := attributevalue.MarshalMap(plain)
+	if err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+	return key, nil
+}
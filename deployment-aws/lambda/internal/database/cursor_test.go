@@ -0,0 +1,68 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"simple-crud-board-lambda/internal/repository"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"id":         &types.AttributeValueMemberS{Value: "11111111-1111-4111-8111-111111111111"},
+		"feed":       &types.AttributeValueMemberS{Value: feedPartition},
+		"created_at": &types.AttributeValueMemberS{Value: "2024-01-02T03:04:05Z"},
+	}
+
+	cursor, err := nextCursor(key)
+	if err != nil {
+		t.Fatalf("nextCursor failed: %v", err)
+	}
+	if cursor == "" {
+		t.Fatal("Expected a cursor for a non-empty LastEvaluatedKey")
+	}
+
+	decoded, err := decodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("decodeCursor failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, key) {
+		t.Errorf("Expected %v after the round trip, got %v", key, decoded)
+	}
+}
+
+func TestNextCursor_LastPage(t *testing.T) {
+	for _, key := range []map[string]types.AttributeValue{nil, {}} {
+		cursor, err := nextCursor(key)
+		if err != nil || cursor != "" {
+			t.Errorf("Expected an empty cursor on the last page, got %q (err: %v)", cursor, err)
+		}
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "malformed base64", cursor: "!!!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"id":"x"}`))},
+		{name: "not JSON", cursor: encode("not json")},
+		{name: "non-string values", cursor: encode(`{"id":1}`)},
+		{name: "missing id", cursor: encode(`{"created_at":"2024-01-02T03:04:05Z"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); !errors.Is(err, repository.ErrInvalidCursor) {
+				t.Errorf("Expected ErrInvalidCursor, got %v", err)
+			}
+		})
+	}
+}
@@ -2,15 +2,19 @@
 //
 // 🎯 学習ポイント:
 // - AWS SDK for Go v2の使用方法
-// - DynamoDB操作（PutItem, GetItem, UpdateItem, DeleteItem, Scan）
+// - DynamoDB操作（PutItem, GetItem, UpdateItem, DeleteItem, Scan, Query）
+// - GSI（グローバルセカンダリインデックス）で全投稿・投稿者ごとの投稿を新しい順に取得する
 // - エラーハンドリングとAWS固有のエラー処理
 
 package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -19,16 +23,34 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"simple-crud-board-lambda/internal/models"
+	"simple-crud-board-lambda/internal/repository"
 )
 
 // Client はDynamoDBクライアントを管理する構造体
+// repository.PostRepositoryのDynamoDB実装でもある
 type Client struct {
 	dynamodb  *dynamodb.Client
 	tableName string
+	// authorIndex はauthor_id（パーティションキー）とcreated_at（ソートキー）のGSI名
+	authorIndex string
+	// createdAtIndex はfeed（パーティションキー）とcreated_at（ソートキー）のGSI名
+	createdAtIndex string
 }
 
+// contentLowerAttribute は本文を小文字化して保存する属性
+// DynamoDBのcontainsは大文字小文字を区別するため、検索はこの属性を小文字のクエリで絞り込む
+const contentLowerAttribute = "content_lc"
+
+// feedAttribute はすべての投稿に同じ値（feedPartition）を保存する属性
+// createdAtIndexのパーティションキーにして、全投稿を作成日時の順にQueryできるようにする
+// （1つのパーティションに書き込みが集中するが、掲示板の規模では問題にならない）
+const (
+	feedAttribute = "feed"
+	feedPartition = "posts"
+)
+
 // NewClient は新しいDynamoDBクライアントを作成する
-func NewClient(tableName string) (*Client, error) {
+func NewClient(tableName, authorIndex, createdAtIndex string) (*Client, error) {
 	// TODO: AWS設定の読み込み
 	// ヒント: config.LoadDefaultConfig()を使用
 	cfg, err := config.LoadDefaultConfig(context.TODO())
@@ -41,16 +63,29 @@ func NewClient(tableName string) (*Client, error) {
 	client := dynamodb.NewFromConfig(cfg)
 
 	return &Client{
-		dynamodb:  client,
-		tableName: tableName,
+		dynamodb:       client,
+		tableName:      tableName,
+		authorIndex:    authorIndex,
+		createdAtIndex: createdAtIndex,
 	}, nil
 }
 
-// CreatePost は新しい投稿をDynamoDBに作成する
-func (c *Client) CreatePost(ctx context.Context, post *models.Post) error {
+// postItem は投稿をDynamoDBのアイテムに変換し、インデックス用の属性を追加する
+func postItem(post *models.Post) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(post)
+	if err != nil {
+		return nil, err
+	}
+	item[feedAttribute] = &types.AttributeValueMemberS{Value: feedPartition}
+	item[contentLowerAttribute] = &types.AttributeValueMemberS{Value: strings.ToLower(post.Content)}
+	return item, nil
+}
+
+// Create は新しい投稿をDynamoDBに作成する
+func (c *Client) Create(ctx context.Context, post *models.Post) error {
 	// TODO: 投稿データをDynamoDB属性値に変換
 	// ヒント: attributevalue.MarshalMap()を使用
-	item, err := attributevalue.MarshalMap(post)
+	item, err := postItem(post)
 	if err != nil {
 		return fmt.Errorf("failed to marshal post: %w", err)
 	}
@@ -76,8 +111,8 @@ func (c *Client) CreatePost(ctx context.Context, post *models.Post) error {
 	return nil
 }
 
-// GetPost はIDで指定された投稿を取得する
-func (c *Client) GetPost(ctx context.Context, id string) (*models.Post, error) {
+// Get はIDで指定された投稿を取得する
+func (c *Client) Get(ctx context.Context, id string) (*models.Post, error) {
 	// TODO: GetItem操作の入力を作成
 	// ヒント: パーティションキーでアイテムを取得
 	input := &dynamodb.GetItemInput{
@@ -96,7 +131,7 @@ func (c *Client) GetPost(ctx context.Context, id string) (*models.Post, error) {
 
 	// TODO: アイテムが見つからない場合の処理
 	if result.Item == nil {
-		return nil, fmt.Errorf("post with ID %s not found", id)
+		return nil, repository.ErrPostNotFound
 	}
 
 	// TODO: DynamoDB属性値を投稿構造体に変換
@@ -110,24 +145,37 @@ func (c *Client) GetPost(ctx context.Context, id string) (*models.Post, error) {
 	return &post, nil
 }
 
-// GetAllPosts はすべての投稿を取得する（作成日時の降順）
-func (c *Client) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
-	// TODO: Scan操作の入力を作成
-	// ヒント: 全件取得にはScanを使用（小規模なテーブル用）
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(c.tableName),
-		// TODO: 投影式を追加（必要な属性のみ取得）
-		// ProjectionExpression: aws.String("id, content, created_at, updated_at"),
+// List は投稿を作成日時の降順に1ページ分取得する
+// createdAtIndexをQueryするため、ページをまたいでも新しい順になる
+func (c *Client) List(ctx context.Context, opts repository.ListOptions) ([]*models.Post, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(c.tableName),
+		IndexName:              aws.String(c.createdAtIndex),
+		KeyConditionExpression: aws.String("feed = :feed"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":feed": &types.AttributeValueMemberS{Value: feedPartition},
+		},
+		// ソートキー（created_at）の降順 = 新しい順
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(opts.PageSize())),
 	}
 
-	// TODO: Scan操作を実行
-	result, err := c.dynamodb.Scan(ctx, input)
+	// カーソルにはLastEvaluatedKey（id・feed・created_at）が入っているので、続きから読み取る
+	if opts.Cursor != "" {
+		startKey, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	result, err := c.dynamodb.Query(ctx, input)
 	if err != nil {
-		return nil, c.handleDynamoDBError(err, "scan posts")
+		return nil, "", c.handleDynamoDBError(err, "query posts")
 	}
 
-	// TODO: 結果をPost構造体のスライスに変換
-	var posts []*models.Post
+	// 結果をPost構造体のスライスに変換
+	posts := []*models.Post{}
 	for _, item := range result.Items {
 		var post models.Post
 		err := attributevalue.UnmarshalMap(item, &post)
@@ -138,18 +186,65 @@ func (c *Client) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
 		posts = append(posts, &post)
 	}
 
-	// TODO: 作成日時で降順ソート
-	// ヒント: sort.Slice()を使用してCreatedAtでソート
-	// sort.Slice(posts, func(i, j int) bool {
-	//     return posts[i].CreatedAt.After(posts[j].CreatedAt)
-	// })
+	next, err := nextCursor(result.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
 
 	log.Printf("Retrieved %d posts", len(posts))
-	return posts, nil
+	return posts, next, nil
 }
 
-// UpdatePost は既存の投稿を更新する
-func (c *Client) UpdatePost(ctx context.Context, id string, content string) (*models.Post, error) {
+// ListByAuthor は投稿者の投稿を1ページ分取得する
+// GSIをQueryするため、Scanと違いテーブル全体を読まずに作成日時の降順で取得できる
+func (c *Client) ListByAuthor(ctx context.Context, authorID string, opts repository.ListOptions) ([]*models.Post, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(c.tableName),
+		IndexName:              aws.String(c.authorIndex),
+		KeyConditionExpression: aws.String("author_id = :author_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":author_id": &types.AttributeValueMemberS{Value: authorID},
+		},
+		// ソートキー（created_at）の降順 = 新しい順
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(opts.PageSize())),
+	}
+
+	// GSIのLastEvaluatedKeyにはid・author_id・created_atが含まれる
+	if opts.Cursor != "" {
+		startKey, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	result, err := c.dynamodb.Query(ctx, input)
+	if err != nil {
+		return nil, "", c.handleDynamoDBError(err, "query posts by author")
+	}
+
+	posts := []*models.Post{}
+	for _, item := range result.Items {
+		var post models.Post
+		if err := attributevalue.UnmarshalMap(item, &post); err != nil {
+			log.Printf("Failed to unmarshal post: %v", err)
+			continue
+		}
+		posts = append(posts, &post)
+	}
+
+	next, err := nextCursor(result.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	log.Printf("Retrieved %d posts by author %s", len(posts), authorID)
+	return posts, next, nil
+}
+
+// Update は既存の投稿を更新する
+func (c *Client) Update(ctx context.Context, id string, content string) (*models.Post, error) {
 	// TODO: UpdateItem操作の入力を作成
 	// ヒント: UpdateExpressionで特定の属性のみ更新
 	input := &dynamodb.UpdateItemInput{
@@ -158,11 +253,13 @@ func (c *Client) UpdatePost(ctx context.Context, id string, content string) (*mo
 			"id": &types.AttributeValueMemberS{Value: id},
 		},
 		// TODO: 更新式を設定
-		UpdateExpression: aws.String("SET content = :content, updated_at = :updated_at"),
+		// 検索用の小文字の本文も同時に更新する
+		UpdateExpression: aws.String("SET content = :content, content_lc = :content_lc, updated_at = :updated_at"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":content": &types.AttributeValueMemberS{Value: content},
-			// TODO: 現在時刻をISO8601形式で設定
-			":updated_at": &types.AttributeValueMemberS{Value: "TODO: 現在時刻を設定"},
+			":content":    &types.AttributeValueMemberS{Value: content},
+			":content_lc": &types.AttributeValueMemberS{Value: strings.ToLower(content)},
+			// 現在時刻をISO8601形式で設定
+			":updated_at": &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
 		},
 		// TODO: 条件式を追加（投稿が存在する場合のみ更新）
 		ConditionExpression: aws.String("attribute_exists(id)"),
@@ -173,6 +270,9 @@ func (c *Client) UpdatePost(ctx context.Context, id string, content string) (*mo
 	// TODO: UpdateItem操作を実行
 	result, err := c.dynamodb.UpdateItem(ctx, input)
 	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, repository.ErrPostNotFound
+		}
 		return nil, c.handleDynamoDBError(err, "update post")
 	}
 
@@ -187,8 +287,8 @@ func (c *Client) UpdatePost(ctx context.Context, id string, content string) (*mo
 	return &post, nil
 }
 
-// DeletePost は指定されたIDの投稿を削除する
-func (c *Client) DeletePost(ctx context.Context, id string) error {
+// Delete は指定されたIDの投稿を削除する
+func (c *Client) Delete(ctx context.Context, id string) error {
 	// TODO: DeleteItem操作の入力を作成
 	input := &dynamodb.DeleteItemInput{
 		TableName: aws.String(c.tableName),
@@ -202,6 +302,9 @@ func (c *Client) DeletePost(ctx context.Context, id string) error {
 	// TODO: DeleteItem操作を実行
 	_, err := c.dynamodb.DeleteItem(ctx, input)
 	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return repository.ErrPostNotFound
+		}
 		return c.handleDynamoDBError(err, "delete post")
 	}
 
@@ -227,11 +330,25 @@ func (c *Client) handleDynamoDBError(err error, operation string) error {
 	}
 
 	// ValidationException: バリデーションエラー
-	var validationException *types.ValidationException
-	if errors.As(err, &validationException) {
-		return fmt.Errorf("validation error for %s: %s", operation, *validationException.Message)
+	// typesパッケージに専用の型はないため、APIエラーのコードで判定する
+	var apiErr interface {
+		ErrorCode() string
+		ErrorMessage() string
+	}
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationException" {
+		return fmt.Errorf("validation error for %s: %s", operation, apiErr.ErrorMessage())
 	}
 
 	// その他のエラー
 	return fmt.Errorf("DynamoDB error for %s: %w", operation, err)
-}
\ No newline at end of file
+}
+
+// isConditionalCheckFailed は存在チェックの条件式で失敗したかどうかを判定する
+// attribute_exists(id) を条件にしているため、対象の投稿が存在しないことを意味する
+func isConditionalCheckFailed(err error) bool {
+	var conditionalCheckFailed *types.ConditionalCheckFailedException
+	return errors.As(err, &conditionalCheckFailed)
+}
+
+// Clientがrepository.PostRepositoryを満たすことをコンパイル時に確認する
+var _ repository.PostRepository = (*Client)(nil)
\ No newline at end of file
@@ -0,0 +1,143 @@
+// 投稿の全文検索（フィルターベース）
+//
+// 🎯 学習ポイント:
+// - DynamoDBには全文検索インデックスがないため、Scan + FilterExpressionで絞り込む
+// - ScanPaginatorでテーブル全体を走査する（件数に比例してコストがかかる）
+// - 出現回数による簡易的なランキングとスニペット生成
+
+package database
+
+import (
+	"context"
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"simple-crud-board-lambda/internal/models"
+	"simple-crud-board-lambda/internal/repository"
+)
+
+// snippetRadius はスニペットで最初の一致箇所の前後に残す文字数
+const snippetRadius = 40
+
+// Search はqueryを含む投稿を出現回数の多い順に最大limit件返す
+// 小文字化した本文（content_lc）を小文字化したqueryで絞り込むため、大文字小文字を区別しない
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]*models.PostSearchResult, error) {
+	limit = repository.ListOptions{Limit: limit}.PageSize()
+
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(c.tableName),
+		FilterExpression: aws.String("contains(content_lc, :q)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":q": &types.AttributeValueMemberS{Value: strings.ToLower(query)},
+		},
+	}
+
+	// フィルターは読み取り後に適用されるため、全ページを走査する
+	results := []*models.PostSearchResult{}
+	paginator := dynamodb.NewScanPaginator(c.dynamodb, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, c.handleDynamoDBError(err, "search posts")
+		}
+
+		for _, item := range page.Items {
+			var post models.Post
+			if err := attributevalue.UnmarshalMap(item, &post); err != nil {
+				log.Printf("Failed to unmarshal post: %v", err)
+				continue
+			}
+			snippet, occurrences := buildSnippet(post.Content, query)
+			results = append(results, &models.PostSearchResult{
+				Post:    post,
+				Snippet: snippet,
+				Score:   float64(occurrences),
+			})
+		}
+	}
+
+	// 出現回数の多い順、同数なら新しい順
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	log.Printf("Found %d posts for search", len(results))
+	return results, nil
+}
+
+// buildSnippet は最初の一致箇所の周辺を切り出し、一致箇所を<mark>タグで囲む
+// 抜粋はHTMLエスケープ済み。あわせて本文全体での出現回数を返す
+func buildSnippet(content, query string) (string, int) {
+	text := []rune(content)
+	haystack := lowerRunes(text)
+	needle := lowerRunes([]rune(query))
+
+	var matches []int
+	if len(needle) > 0 {
+		for i := 0; i+len(needle) <= len(haystack); {
+			if string(haystack[i:i+len(needle)]) == string(needle) {
+				matches = append(matches, i)
+				i += len(needle)
+				continue
+			}
+			i++
+		}
+	}
+
+	start, end := 0, 2*snippetRadius
+	if len(matches) > 0 {
+		start = matches[0] - snippetRadius
+		end = matches[0] + len(needle) + snippetRadius
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m < start || m+len(needle) > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(text[pos:m])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(text[m : m+len(needle)])))
+		b.WriteString("</mark>")
+		pos = m + len(needle)
+	}
+	b.WriteString(html.EscapeString(string(text[pos:end])))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String(), len(matches)
+}
+
+// lowerRunes は1文字ずつ小文字化する（元の文字列と位置がずれないようにする）
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildSnippet(t *testing.T) {
+	long := strings.Repeat("a", 100) + "needle" + strings.Repeat("b", 100)
+
+	tests := []struct {
+		name        string
+		content     string
+		query       string
+		snippet     string
+		occurrences int
+	}{
+		{
+			name:        "case-insensitive matches keep their case",
+			content:     "Go and go and GO",
+			query:       "go",
+			snippet:     "<mark>Go</mark> and <mark>go</mark> and <mark>GO</mark>",
+			occurrences: 3,
+		},
+		{
+			name:        "HTML is escaped around and inside matches",
+			content:     `<b>Go</b> & "go"`,
+			query:       "go",
+			snippet:     "&lt;b&gt;<mark>Go</mark>&lt;/b&gt; &amp; &#34;<mark>go</mark>&#34;",
+			occurrences: 2,
+		},
+		{
+			name:        "query with HTML characters",
+			content:     "a<b",
+			query:       "<",
+			snippet:     "a<mark>&lt;</mark>b",
+			occurrences: 1,
+		},
+		{
+			name:        "multibyte text",
+			content:     "日本語の投稿です",
+			query:       "投稿",
+			snippet:     "日本語の<mark>投稿</mark>です",
+			occurrences: 1,
+		},
+		{
+			name:        "truncated on both sides of the first match",
+			content:     long,
+			query:       "needle",
+			snippet:     "…" + strings.Repeat("a", snippetRadius) + "<mark>needle</mark>" + strings.Repeat("b", snippetRadius) + "…",
+			occurrences: 1,
+		},
+		{
+			name:        "later matches outside the snippet are counted",
+			content:     "needle" + strings.Repeat("x", 100) + "needle",
+			query:       "needle",
+			snippet:     "<mark>needle</mark>" + strings.Repeat("x", snippetRadius) + "…",
+			occurrences: 2,
+		},
+		{
+			name:        "no match shows the beginning",
+			content:     long,
+			query:       "missing",
+			snippet:     strings.Repeat("a", 2*snippetRadius) + "…",
+			occurrences: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet, occurrences := buildSnippet(tt.content, tt.query)
+			if snippet != tt.snippet {
+				t.Errorf("Expected snippet %q, got %q", tt.snippet, snippet)
+			}
+			if occurrences != tt.occurrences {
+				t.Errorf("Expected %d occurrences, got %d", tt.occurrences, occurrences)
+			}
+		})
+	}
+}
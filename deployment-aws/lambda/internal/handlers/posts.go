@@ -5,37 +5,97 @@
 // - DynamoDBクライアントを使用したCRUD操作
 // - エラーハンドリングとHTTPステータスコード
 // - JSONレスポンスの生成
+// - 認可: 投稿を変更できるのは投稿者と管理者だけ（認証済みでも他人の投稿は403）
 
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
-	"simple-crud-board-lambda/internal/database"
+	"simple-crud-board-lambda/internal/auth"
 	"simple-crud-board-lambda/internal/models"
+	"simple-crud-board-lambda/internal/repository"
 )
 
 // PostHandler は投稿関連のHTTPリクエストを処理する
 type PostHandler struct {
-	db *database.Client
+	repo repository.PostRepository
 }
 
 // NewPostHandler は新しいPostHandlerを作成する
-func NewPostHandler(db *database.Client) *PostHandler {
-	return &PostHandler{db: db}
+// ストレージはrepository.PostRepositoryを満たしていれば差し替え可能
+func NewPostHandler(repo repository.PostRepository) *PostHandler {
+	return &PostHandler{repo: repo}
 }
 
-// GetPosts はすべての投稿を取得する (GET /api/posts)
+// GetPosts は投稿を1ページ分取得する (GET /api/posts?limit=&cursor=)
 func (h *PostHandler) GetPosts(c *gin.Context) {
-	// TODO: DynamoDBからすべての投稿を取得
-	// ヒント: h.db.GetAllPosts(c.Request.Context())を使用
-	posts, err := h.db.GetAllPosts(c.Request.Context())
+	opts, ok := parseListOptions(c)
+	if !ok {
+		return
+	}
+
+	// DynamoDBから投稿を1ページ分取得
+	posts, nextCursor, err := h.repo.List(c.Request.Context(), opts)
+	respondPostPage(c, posts, nextCursor, err)
+}
+
+// GetMyPosts はログイン中のユーザーの投稿を1ページ分取得する (GET /api/posts/my?limit=&cursor=)
+func (h *PostHandler) GetMyPosts(c *gin.Context) {
+	identity, ok := auth.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	opts, ok := parseListOptions(c)
+	if !ok {
+		return
+	}
+
+	posts, nextCursor, err := h.repo.ListByAuthor(c.Request.Context(), identity.UserID, opts)
+	respondPostPage(c, posts, nextCursor, err)
+}
+
+// parseListOptions はクエリパラメータからページ指定を読み取る
+// limitが不正な場合は400を返してfalseを返す
+func parseListOptions(c *gin.Context) (repository.ListOptions, bool) {
+	opts := repository.ListOptions{Cursor: c.Query("cursor")}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"message": "limit must be a positive integer",
+			})
+			return opts, false
+		}
+		opts.Limit = n
+	}
+	return opts, true
+}
+
+// respondPostPage はListまたはListByAuthorの結果をレスポンスとして返す
+func respondPostPage(c *gin.Context, posts []*models.Post, nextCursor string, err error) {
 	if err != nil {
-		// TODO: エラーレスポンスを返す
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid cursor",
+				"message": "cursor must be a value returned as next_cursor",
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to retrieve posts",
 			"message": err.Error(),
@@ -43,15 +103,60 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 		return
 	}
 
-	// TODO: 投稿が0件の場合は空配列を返す
+	// 投稿が0件の場合は空配列を返す
 	if posts == nil {
 		posts = []*models.Post{}
 	}
 
-	// TODO: 成功レスポンスを返す
-	c.JSON(http.StatusOK, gin.H{
+	// 成功レスポンスを返す（SQLite版と同じく、最後のページではnext_cursorを省略する）
+	response := gin.H{
 		"posts": posts,
 		"count": len(posts),
+	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchPosts は投稿内容を検索する (GET /api/posts/search?q=&limit=)
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	// 検索語のバリデーション（SQLite版と同じく3〜100文字）
+	query := strings.TrimSpace(c.Query("q"))
+	if n := utf8.RuneCountInString(query); n < 3 || n > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid search query",
+			"message": "q must be between 3 and 100 characters",
+		})
+		return
+	}
+
+	limit := 0
+	if l := c.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"message": "limit must be a positive integer",
+			})
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.repo.Search(c.Request.Context(), query, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search posts",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   query,
+		"results": results,
+		"count":   len(results),
 	})
 }
 
@@ -84,8 +189,14 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	// ヒント: uuid.New().String()
 	post.ID = uuid.New().String()
 
+	// 認証が有効な場合はログイン中のユーザーを投稿者にする
+	if identity, ok := auth.FromContext(c); ok {
+		post.AuthorID = identity.UserID
+		post.Author = identity.Username
+	}
+
 	// TODO: DynamoDBに投稿を保存
-	err := h.db.CreatePost(c.Request.Context(), post)
+	err := h.repo.Create(c.Request.Context(), post)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create post",
@@ -140,11 +251,16 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
+	// 投稿者または管理者であることを確認
+	if !h.authorizeModify(c, id) {
+		return
+	}
+
 	// TODO: DynamoDBで投稿を更新
-	updatedPost, err := h.db.UpdatePost(c.Request.Context(), id, req.Content)
+	updatedPost, err := h.repo.Update(c.Request.Context(), id, req.Content)
 	if err != nil {
-		// TODO: エラーの種類に応じて適切なHTTPステータスを返す
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "conditional check failed") {
+		// エラーの種類に応じて適切なHTTPステータスを返す
+		if errors.Is(err, repository.ErrPostNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "Post not found",
 				"message": "The specified post does not exist",
@@ -186,11 +302,16 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
+	// 投稿者または管理者であることを確認
+	if !h.authorizeModify(c, id) {
+		return
+	}
+
 	// TODO: DynamoDBから投稿を削除
-	err := h.db.DeletePost(c.Request.Context(), id)
+	err := h.repo.Delete(c.Request.Context(), id)
 	if err != nil {
-		// TODO: エラーの種類に応じて適切なHTTPステータスを返す
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "conditional check failed") {
+		// エラーの種類に応じて適切なHTTPステータスを返す
+		if errors.Is(err, repository.ErrPostNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "Post not found",
 				"message": "The specified post does not exist",
@@ -212,6 +333,43 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 	})
 }
 
+// authorizeModify はログイン中のユーザーが投稿を変更できるかを確認する
+// 変更できない場合は404または403を返してfalseを返す
+// 認証が無効（AUTH_MODE=none）の場合は従来どおり誰でも変更できる
+func (h *PostHandler) authorizeModify(c *gin.Context, id string) bool {
+	identity, ok := auth.FromContext(c)
+	if !ok {
+		return true
+	}
+
+	post, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Post not found",
+				"message": "The specified post does not exist",
+			})
+			return false
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve post",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	// 投稿者のない古い投稿は管理者だけが変更できる
+	if !post.IsOwnedBy(identity.UserID) && !identity.HasRole(auth.AdminRole) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "You can only modify your own posts",
+		})
+		return false
+	}
+	return true
+}
+
 // GetPost は指定されたIDの投稿を取得する (GET /api/posts/:id) - オプション
 func (h *PostHandler) GetPost(c *gin.Context) {
 	// TODO: URLパラメータからIDを取得
@@ -233,9 +391,9 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 	}
 
 	// TODO: DynamoDBから投稿を取得
-	post, err := h.db.GetPost(c.Request.Context(), id)
+	post, err := h.repo.Get(c.Request.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, repository.ErrPostNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "Post not found",
 				"message": "The specified post does not exist",
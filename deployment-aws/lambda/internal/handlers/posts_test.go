@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"simple-crud-board-lambda/internal/auth"
+	"simple-crud-board-lambda/internal/models"
+	"simple-crud-board-lambda/internal/repository"
+)
+
+// テスト用の投稿ID（UUID形式）
+const (
+	existingID = "11111111-1111-4111-8111-111111111111"
+	missingID  = "22222222-2222-4222-8222-222222222222"
+)
+
+// errStorage はストレージ障害を表す
+var errStorage = errors.New("storage unavailable")
+
+// fakePostRepository はrepository.PostRepositoryのインメモリ実装
+// カーソルは次のページの先頭位置を10進数で表す
+type fakePostRepository struct {
+	mu    sync.Mutex
+	posts map[string]*models.Post
+	// err が設定されている場合、すべての操作がerrを返す
+	err error
+}
+
+func newFakePostRepository(posts ...*models.Post) *fakePostRepository {
+	repo := &fakePostRepository{posts: map[string]*models.Post{}}
+	for _, post := range posts {
+		repo.posts[post.ID] = post
+	}
+	return repo
+}
+
+func (r *fakePostRepository) List(ctx context.Context, opts repository.ListOptions) ([]*models.Post, string, error) {
+	return r.list(func(*models.Post) bool { return true }, opts)
+}
+
+func (r *fakePostRepository) ListByAuthor(ctx context.Context, authorID string, opts repository.ListOptions) ([]*models.Post, string, error) {
+	return r.list(func(post *models.Post) bool { return post.AuthorID == authorID }, opts)
+}
+
+// list はmatchに一致する投稿を新しい順に1ページ分返す
+func (r *fakePostRepository) list(match func(*models.Post) bool, opts repository.ListOptions) ([]*models.Post, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, "", r.err
+	}
+
+	start := 0
+	if opts.Cursor != "" {
+		n, err := strconv.Atoi(opts.Cursor)
+		if err != nil || n < 0 {
+			return nil, "", repository.ErrInvalidCursor
+		}
+		start = n
+	}
+
+	var posts []*models.Post
+	for _, post := range r.posts {
+		if match(post) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].CreatedAt.After(posts[j].CreatedAt) })
+
+	if start >= len(posts) {
+		return nil, "", nil
+	}
+	end := start + opts.PageSize()
+	if end >= len(posts) {
+		return posts[start:], "", nil
+	}
+	return posts[start:end], strconv.Itoa(end), nil
+}
+
+func (r *fakePostRepository) Search(ctx context.Context, query string, limit int) ([]*models.PostSearchResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakePostRepository) Get(ctx context.Context, id string) (*models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, repository.ErrPostNotFound
+	}
+	copied := *post
+	return &copied, nil
+}
+
+func (r *fakePostRepository) Create(ctx context.Context, post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+
+	copied := *post
+	r.posts[post.ID] = &copied
+	return nil
+}
+
+func (r *fakePostRepository) Update(ctx context.Context, id string, content string) (*models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, repository.ErrPostNotFound
+	}
+	post.UpdateContent(content)
+	copied := *post
+	return &copied, nil
+}
+
+func (r *fakePostRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+
+	if _, ok := r.posts[id]; !ok {
+		return repository.ErrPostNotFound
+	}
+	delete(r.posts, id)
+	return nil
+}
+
+// testPost はhoursAgo時間前に作成された投稿を返す
+func testPost(id string, hoursAgo int) *models.Post {
+	createdAt := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC).Add(-time.Duration(hoursAgo) * time.Hour)
+	return &models.Post{ID: id, Content: "Post " + id, CreatedAt: createdAt, UpdatedAt: createdAt}
+}
+
+// setupRouter はcmd/main.goと同じパスでハンドラーを登録する（認証ミドルウェアは除く）
+// identityがnilでない場合は認証済みユーザーとしてリクエストを処理する（nilは認証無効と同じ）
+func setupRouter(repo repository.PostRepository, identity *auth.Identity) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if identity != nil {
+		r.Use(func(c *gin.Context) {
+			c.Set(auth.IdentityContextKey, identity)
+			c.Next()
+		})
+	}
+
+	h := NewPostHandler(repo)
+	api := r.Group("/api")
+	api.GET("/posts", h.GetPosts)
+	api.GET("/posts/my", h.GetMyPosts)
+	api.GET("/posts/:id", h.GetPost)
+	api.POST("/posts", h.CreatePost)
+	api.PUT("/posts/:id", h.UpdatePost)
+	api.DELETE("/posts/:id", h.DeletePost)
+	return r
+}
+
+func doRequest(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeBody はレスポンスボディをJSONとして読み取る
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+// handlerTest はリクエスト1件とその期待結果
+type handlerTest struct {
+	name   string
+	path   string
+	body   string
+	err    error
+	status int
+}
+
+// runHandlerTests はテストごとに投稿existingIDだけを持つリポジトリでリクエストを送る
+func runHandlerTests(t *testing.T, method string, tests []handlerTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakePostRepository(testPost(existingID, 0))
+			repo.err = tt.err
+
+			w := doRequest(setupRouter(repo, nil), method, tt.path, tt.body)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetPosts(t *testing.T) {
+	repo := newFakePostRepository(testPost("a", 2), testPost("b", 1), testPost("c", 0))
+	r := setupRouter(repo, nil)
+
+	w := doRequest(r, http.MethodGet, "/api/posts?limit=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := decodeBody(t, w)
+	posts := body["posts"].([]interface{})
+	if len(posts) != 2 || posts[0].(map[string]interface{})["id"] != "c" || body["count"] != float64(2) {
+		t.Errorf("Expected the 2 newest posts, got %v", body)
+	}
+	cursor, ok := body["next_cursor"].(string)
+	if !ok || cursor == "" {
+		t.Fatalf("Expected next_cursor on the first page, got %v", body)
+	}
+
+	// 最後のページにはnext_cursorが含まれない
+	w = doRequest(r, http.MethodGet, "/api/posts?limit=2&cursor="+cursor, "")
+	body = decodeBody(t, w)
+	if posts := body["posts"].([]interface{}); len(posts) != 1 || posts[0].(map[string]interface{})["id"] != "a" {
+		t.Errorf("Expected the oldest post on the last page, got %v", body)
+	}
+	if _, ok := body["next_cursor"]; ok {
+		t.Errorf("Expected no next_cursor on the last page, got %v", body)
+	}
+
+	// 投稿が0件でもpostsはnullではなく空配列
+	w = doRequest(setupRouter(newFakePostRepository(), nil), http.MethodGet, "/api/posts", "")
+	if !strings.Contains(w.Body.String(), `"posts":[]`) {
+		t.Errorf("Expected an empty posts array, got %s", w.Body.String())
+	}
+}
+
+func TestGetPosts_Errors(t *testing.T) {
+	runHandlerTests(t, http.MethodGet, []handlerTest{
+		{name: "non-numeric limit", path: "/api/posts?limit=abc", status: http.StatusBadRequest},
+		{name: "zero limit", path: "/api/posts?limit=0", status: http.StatusBadRequest},
+		{name: "invalid cursor", path: "/api/posts?cursor=not-a-cursor", status: http.StatusBadRequest},
+		{name: "storage error", path: "/api/posts", err: errStorage, status: http.StatusInternalServerError},
+	})
+}
+
+func TestGetPost(t *testing.T) {
+	runHandlerTests(t, http.MethodGet, []handlerTest{
+		{name: "found", path: "/api/posts/" + existingID, status: http.StatusOK},
+		{name: "invalid id", path: "/api/posts/not-a-uuid", status: http.StatusBadRequest},
+		{name: "not found", path: "/api/posts/" + missingID, status: http.StatusNotFound},
+		{name: "storage error", path: "/api/posts/" + existingID, err: errStorage, status: http.StatusInternalServerError},
+	})
+
+	w := doRequest(setupRouter(newFakePostRepository(testPost(existingID, 0)), nil), http.MethodGet, "/api/posts/"+existingID, "")
+	post := decodeBody(t, w)["post"].(map[string]interface{})
+	if post["id"] != existingID || post["content"] != "Post "+existingID {
+		t.Errorf("Expected the stored post, got %v", post)
+	}
+}
+
+func TestCreatePost(t *testing.T) {
+	runHandlerTests(t, http.MethodPost, []handlerTest{
+		{name: "created", path: "/api/posts", body: `{"content":"Hello"}`, status: http.StatusCreated},
+		{name: "invalid JSON", path: "/api/posts", body: `{"content":`, status: http.StatusBadRequest},
+		{name: "missing content", path: "/api/posts", body: `{}`, status: http.StatusBadRequest},
+		{name: "too long", path: "/api/posts", body: `{"content":"` + strings.Repeat("a", 1001) + `"}`, status: http.StatusBadRequest},
+		{name: "storage error", path: "/api/posts", body: `{"content":"Hello"}`, err: errStorage, status: http.StatusInternalServerError},
+	})
+
+	repo := newFakePostRepository()
+	w := doRequest(setupRouter(repo, nil), http.MethodPost, "/api/posts", `{"content":"Hello"}`)
+	post := decodeBody(t, w)["post"].(map[string]interface{})
+	id, _ := post["id"].(string)
+	stored, err := repo.Get(context.Background(), id)
+	if err != nil || stored.Content != "Hello" {
+		t.Errorf("Expected the post to be stored under a new UUID, got %v (err: %v)", post, err)
+	}
+	if stored != nil && stored.AuthorID != "" {
+		t.Errorf("Expected no author when auth is disabled, got %q", stored.AuthorID)
+	}
+}
+
+func TestUpdatePost(t *testing.T) {
+	runHandlerTests(t, http.MethodPut, []handlerTest{
+		{name: "updated", path: "/api/posts/" + existingID, body: `{"content":"Edited"}`, status: http.StatusOK},
+		{name: "invalid id", path: "/api/posts/not-a-uuid", body: `{"content":"Edited"}`, status: http.StatusBadRequest},
+		{name: "invalid JSON", path: "/api/posts/" + existingID, body: `{"content":`, status: http.StatusBadRequest},
+		{name: "missing content", path: "/api/posts/" + existingID, body: `{}`, status: http.StatusBadRequest},
+		{name: "not found", path: "/api/posts/" + missingID, body: `{"content":"Edited"}`, status: http.StatusNotFound},
+		{name: "storage error", path: "/api/posts/" + existingID, body: `{"content":"Edited"}`, err: errStorage, status: http.StatusInternalServerError},
+	})
+
+	repo := newFakePostRepository(testPost(existingID, 1))
+	w := doRequest(setupRouter(repo, nil), http.MethodPut, "/api/posts/"+existingID, `{"content":"Edited"}`)
+	if post := decodeBody(t, w)["post"].(map[string]interface{}); post["content"] != "Edited" {
+		t.Errorf("Expected the updated post, got %v", post)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	runHandlerTests(t, http.MethodDelete, []handlerTest{
+		{name: "deleted", path: "/api/posts/" + existingID, status: http.StatusOK},
+		{name: "invalid id", path: "/api/posts/not-a-uuid", status: http.StatusBadRequest},
+		{name: "not found", path: "/api/posts/" + missingID, status: http.StatusNotFound},
+		{name: "storage error", path: "/api/posts/" + existingID, err: errStorage, status: http.StatusInternalServerError},
+	})
+
+	repo := newFakePostRepository(testPost(existingID, 0))
+	doRequest(setupRouter(repo, nil), http.MethodDelete, "/api/posts/"+existingID, "")
+	if _, err := repo.Get(context.Background(), existingID); !errors.Is(err, repository.ErrPostNotFound) {
+		t.Errorf("Expected the post to be deleted, got %v", err)
+	}
+}
+
+// テスト用のユーザー
+var (
+	alice = &auth.Identity{UserID: "1", Username: "alice"}
+	bob   = &auth.Identity{UserID: "2", Username: "bob"}
+	admin = &auth.Identity{UserID: "3", Username: "admin", Roles: []string{auth.AdminRole}}
+)
+
+func TestModifyPost_Authorization(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity *auth.Identity
+		authorID string
+		status   int
+	}{
+		{name: "owner", identity: alice, authorID: alice.UserID, status: http.StatusOK},
+		{name: "other user", identity: bob, authorID: alice.UserID, status: http.StatusForbidden},
+		{name: "admin on another user's post", identity: admin, authorID: alice.UserID, status: http.StatusOK},
+		{name: "user on post without author", identity: alice, authorID: "", status: http.StatusForbidden},
+		{name: "admin on post without author", identity: admin, authorID: "", status: http.StatusOK},
+		{name: "auth disabled", identity: nil, authorID: alice.UserID, status: http.StatusOK},
+	}
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		for _, tt := range tests {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				post := testPost(existingID, 0)
+				post.AuthorID = tt.authorID
+				repo := newFakePostRepository(post)
+
+				w := doRequest(setupRouter(repo, tt.identity), method, "/api/posts/"+existingID, `{"content":"Edited"}`)
+				if w.Code != tt.status {
+					t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+				}
+
+				// 拒否された場合は投稿が変更されていない
+				stored, err := repo.Get(context.Background(), existingID)
+				if tt.status == http.StatusForbidden && (err != nil || stored.Content != post.Content) {
+					t.Errorf("Expected a forbidden request to leave the post unchanged, got %+v (err: %v)", stored, err)
+				}
+			})
+		}
+	}
+}
+
+func TestModifyPost_AuthorizationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		err    error
+		status int
+	}{
+		{name: "not found", id: missingID, status: http.StatusNotFound},
+		{name: "storage error", id: existingID, err: errStorage, status: http.StatusInternalServerError},
+	}
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		for _, tt := range tests {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				repo := newFakePostRepository(testPost(existingID, 0))
+				repo.err = tt.err
+
+				w := doRequest(setupRouter(repo, alice), method, "/api/posts/"+tt.id, `{"content":"Edited"}`)
+				if w.Code != tt.status {
+					t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestCreatePost_SetsAuthor(t *testing.T) {
+	repo := newFakePostRepository()
+
+	w := doRequest(setupRouter(repo, alice), http.MethodPost, "/api/posts", `{"content":"Hello"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	id, _ := decodeBody(t, w)["post"].(map[string]interface{})["id"].(string)
+	stored, err := repo.Get(context.Background(), id)
+	if err != nil || stored.AuthorID != alice.UserID || stored.Author != alice.Username {
+		t.Errorf("Expected alice as the author, got %+v (err: %v)", stored, err)
+	}
+}
+
+func TestGetMyPosts(t *testing.T) {
+	mine, theirs, orphan := testPost("mine", 1), testPost("theirs", 0), testPost("orphan", 2)
+	mine.AuthorID, theirs.AuthorID = alice.UserID, bob.UserID
+	repo := newFakePostRepository(mine, theirs, orphan)
+
+	w := doRequest(setupRouter(repo, nil), http.MethodGet, "/api/posts/my", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without an identity, got %d", w.Code)
+	}
+
+	w = doRequest(setupRouter(repo, alice), http.MethodGet, "/api/posts/my", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	posts := decodeBody(t, w)["posts"].([]interface{})
+	if len(posts) != 1 || posts[0].(map[string]interface{})["id"] != "mine" {
+		t.Errorf("Expected only alice's post, got %v", posts)
+	}
+
+	w = doRequest(setupRouter(repo, alice), http.MethodGet, "/api/posts/my?limit=-1", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid limit, got %d", w.Code)
+	}
+
+	w = doRequest(setupRouter(repo, alice), http.MethodGet, "/api/posts/my?cursor=not-a-cursor", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid cursor, got %d", w.Code)
+	}
+}
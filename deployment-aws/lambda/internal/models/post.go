@@ -8,6 +8,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -17,6 +18,13 @@ type Post struct {
 	// ヒント: UUIDを使用してユニークなIDを生成
 	ID string `json:"id" dynamodbav:"id"`
 
+	// 投稿者のユーザーID（user-authenticationのusers.id）
+	// 認証導入前の投稿には存在しないためomitemptyにする（GSIのキーに空文字は使えない）
+	AuthorID string `json:"author_id,omitempty" dynamodbav:"author_id,omitempty"`
+
+	// 投稿者のユーザー名（表示用）
+	Author string `json:"author,omitempty" dynamodbav:"author,omitempty"`
+
 	// TODO: 投稿内容
 	Content string `json:"content" dynamodbav:"content"`
 
@@ -28,6 +36,15 @@ type Post struct {
 	UpdatedAt time.Time `json:"updated_at" dynamodbav:"updated_at"`
 }
 
+// PostSearchResult は全文検索でヒットした投稿
+type PostSearchResult struct {
+	Post
+	// Snippet はHTMLエスケープ済みの抜粋。一致箇所は<mark>タグで囲まれる
+	Snippet string `json:"snippet"`
+	// Score は関連度（大きいほど上位）
+	Score float64 `json:"score"`
+}
+
 // CreatePostRequest は投稿作成リクエストの構造体
 type CreatePostRequest struct {
 	// TODO: 投稿内容（必須）
@@ -105,6 +122,12 @@ func (p *Post) IsEmpty() bool {
 	return p.Content == ""
 }
 
+// IsOwnedBy は投稿がuserIDのユーザーのものかどうかを判定する
+// 投稿者のない古い投稿は誰のものでもない
+func (p *Post) IsOwnedBy(userID string) bool {
+	return p.AuthorID != "" && p.AuthorID == userID
+}
+
 // Age は投稿の経過時間を返す
 func (p *Post) Age() time.Duration {
 	return time.Since(p.CreatedAt)
@@ -0,0 +1,64 @@
+// 投稿ストレージの抽象化
+//
+// 🎯 学習ポイント:
+// - インターフェースでハンドラーとストレージ実装を疎結合にする
+// - DynamoDB以外の実装（テスト用のインメモリ実装など）に差し替え可能にする
+// - 共通のエラー値で「見つからない」を表現する
+
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"simple-crud-board-lambda/internal/models"
+)
+
+// ErrPostNotFound は指定されたIDの投稿が存在しない場合に返される
+var ErrPostNotFound = errors.New("post not found")
+
+// ErrInvalidCursor はページネーションカーソルを解釈できない場合に返される
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Listで取得する1ページあたりの件数
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ListOptions はListで取得するページを指定する
+type ListOptions struct {
+	// Limit は1ページの件数。0の場合はDefaultPageSize、MaxPageSizeを超える場合は切り詰める
+	Limit int
+	// Cursor は前回のListが返した次ページ用カーソル。最初のページでは空文字
+	Cursor string
+}
+
+// PageSize は実際に使用するページサイズを返す
+func (o ListOptions) PageSize() int {
+	if o.Limit <= 0 {
+		return DefaultPageSize
+	}
+	if o.Limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return o.Limit
+}
+
+// PostRepository は投稿ハンドラーが使用するストレージ操作を定義する
+type PostRepository interface {
+	// List は投稿を1ページ分返す。次ページのカーソルは最後のページでは空文字
+	List(ctx context.Context, opts ListOptions) ([]*models.Post, string, error)
+	// ListByAuthor はauthorIDのユーザーの投稿を新しい順に1ページ分返す
+	ListByAuthor(ctx context.Context, authorID string, opts ListOptions) ([]*models.Post, string, error)
+	// Search はqueryを含む投稿を関連度の高い順に最大limit件返す
+	Search(ctx context.Context, query string, limit int) ([]*models.PostSearchResult, error)
+	// Get は投稿を1件返す。存在しない場合はErrPostNotFound
+	Get(ctx context.Context, id string) (*models.Post, error)
+	// Create は新しい投稿を保存する
+	Create(ctx context.Context, post *models.Post) error
+	// Update は投稿内容を更新し、保存後の投稿を返す
+	Update(ctx context.Context, id string, content string) (*models.Post, error)
+	// Delete は投稿を削除する。存在しない場合はErrPostNotFound
+	Delete(ctx context.Context, id string) error
+}
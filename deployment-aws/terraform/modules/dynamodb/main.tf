@@ -25,12 +25,42 @@ resource "aws_dynamodb_table" "posts" {
     type = "TODO: 属性タイプを設定" # S=String, N=Number, B=Binary
   }
 
-  # TODO: 追加の属性が必要な場合は定義
-  # 例: GSI（Global Secondary Index）用の属性
-  # attribute {
-  #   name = "created_at"
-  #   type = "S"
-  # }
+  # GSI（Global Secondary Index）用の属性
+  # author_idのない投稿（認証導入前の投稿）はインデックスに含まれない
+  attribute {
+    name = "author_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "created_at"
+    type = "S"
+  }
+
+  # 全投稿で共通の値（"posts"）を持つ属性。全投稿を1つのパーティションにまとめる
+  # feedのない投稿（インデックス導入前の投稿）はbackfillコマンドで追加する
+  attribute {
+    name = "feed"
+    type = "S"
+  }
+
+  # 投稿者ごとの投稿を新しい順に取得するためのインデックス（GET /api/posts/my）
+  # Lambdaの環境変数DYNAMODB_AUTHOR_INDEXと同じ名前にする
+  global_secondary_index {
+    name            = "AuthorIndex"
+    hash_key        = "author_id"
+    range_key       = "created_at"
+    projection_type = "ALL"
+  }
+
+  # 全投稿を新しい順に取得するためのインデックス（GET /api/posts）
+  # Lambdaの環境変数DYNAMODB_CREATED_AT_INDEXと同じ名前にする
+  global_secondary_index {
+    name            = "CreatedAtIndex"
+    hash_key        = "feed"
+    range_key       = "created_at"
+    projection_type = "ALL"
+  }
 
   # TODO: タグを設定
   tags = "TODO: タグを設定"
@@ -46,6 +46,7 @@ resource "aws_iam_policy" "lambda_dynamodb_access" {
     # Version: "2012-10-17"
     # Statement: DynamoDB の GetItem, PutItem, UpdateItem, DeleteItem, Query, Scan を許可
     # Resource: 特定のテーブルARNを指定
+    #           GSI（AuthorIndex、CreatedAtIndex）をQueryするため "<テーブルARN>/index/*" も含める
   })
 
   # TODO: タグを設定
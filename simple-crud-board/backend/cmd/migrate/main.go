@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"simple-crud-board/database"
+)
+
+func main() {
+	dbOptions := database.GetDefaultOptions()
+	dbOptions.RegisterFlags(flag.CommandLine)
+
+	var (
+		action  = flag.String("action", "up", "Migration action: up, down, goto, redo, reset, status, or validate")
+		version = flag.Int("version", -1, "Target version for -action=goto (0 rolls back everything)")
+		help    = flag.Bool("help", false, "Show help")
+	)
+	flag.Parse()
+
+	if *help {
+		printHelp()
+		return
+	}
+
+	// Open the database without applying migrations
+	db, err := database.Open(dbOptions)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Initialize migration manager with all registered migrations
+	migrationManager := database.NewMigrationManager(db)
+
+	// Initialize migration table
+	if err := migrationManager.InitializeMigrationTable(); err != nil {
+		log.Fatalf("Failed to initialize migration table: %v", err)
+	}
+
+	// Execute the requested action
+	switch *action {
+	case "up":
+		if err := database.Migrate(db); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		log.Println("Migrations completed successfully")
+
+	case "down":
+		if err := migrationManager.Down(); err != nil {
+			log.Fatalf("Failed to rollback migration: %v", err)
+		}
+		log.Println("Migration rollback completed successfully")
+
+	case "goto":
+		if *version < 0 {
+			log.Fatal("-action=goto requires -version=N")
+		}
+		if err := migrationManager.DownTo(*version); err != nil {
+			log.Fatalf("Failed to rollback migrations: %v", err)
+		}
+		if err := migrationManager.UpTo(*version); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		log.Printf("Database is now at version %d", *version)
+
+	case "redo":
+		if err := migrationManager.Redo(); err != nil {
+			log.Fatalf("Failed to redo migration: %v", err)
+		}
+		log.Println("Migration redo completed successfully")
+
+	case "reset":
+		if err := migrationManager.Reset(); err != nil {
+			log.Fatalf("Failed to reset migrations: %v", err)
+		}
+		log.Println("All migrations rolled back successfully")
+
+	case "status":
+		status, err := migrationManager.Status()
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+
+		log.Println("Migration Status:")
+		log.Println("================")
+		for _, s := range status {
+			appliedStatus := "Not Applied"
+			if s.Applied {
+				appliedStatus = "Applied"
+			}
+			if s.Modified {
+				appliedStatus += " (MODIFIED)"
+			}
+			if s.Unknown {
+				appliedStatus += " (UNKNOWN)"
+			}
+			log.Printf("Version %d: %s - %s", s.Version, s.Description, appliedStatus)
+		}
+
+	case "validate":
+		if err := migrationManager.Validate(); err != nil {
+			log.Fatalf("Validation failed: %v", err)
+		}
+		log.Println("Applied migrations match the registered migrations")
+
+	default:
+		log.Fatalf("Unknown action: %s. Use 'up', 'down', 'goto', 'redo', 'reset', 'status', or 'validate'", *action)
+	}
+}
+
+func printHelp() {
+	log.Println("Migration Tool")
+	log.Println("==============")
+	log.Println("Usage: go run cmd/migrate/main.go [options]")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  -action string")
+	log.Println("        Migration action: up, down, goto, redo, reset, status, or validate (default \"up\")")
+	log.Println("  -version int")
+	log.Println("        Target version for -action=goto (0 rolls back everything)")
+	log.Println("  -help")
+	log.Println("        Show this help message")
+	log.Println("")
+	log.Println("The -db-* flags and DB_* environment variables accepted by the server")
+	log.Println("select the database file (see README).")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  go run cmd/migrate/main.go -action=up      # Run all pending migrations")
+	log.Println("  go run cmd/migrate/main.go -action=down    # Rollback last migration")
+	log.Println("  go run cmd/migrate/main.go -action=goto -version=1 # Migrate up or down to version 1")
+	log.Println("  go run cmd/migrate/main.go -action=redo    # Rollback and re-apply the last migration")
+	log.Println("  go run cmd/migrate/main.go -action=reset   # Rollback all migrations")
+	log.Println("  go run cmd/migrate/main.go -action=status  # Show migration status")
+	log.Println("  go run cmd/migrate/main.go -action=validate # Fail if applied migrations were edited or removed")
+}
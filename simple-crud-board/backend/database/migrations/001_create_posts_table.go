@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"simple-crud-board/services"
+)
+
+// CreatePostsTableMigration creates the posts table migration for the given
+// database/sql driver name ("sqlite3" or "mysql")
+func CreatePostsTableMigration(driver string) services.Migration {
+	up, upSQL := createPostsTableUpSQLite, createPostsTableSQLite
+	if driver == "mysql" {
+		up, upSQL = createPostsTableUpMySQL, createPostsTableMySQL
+	}
+
+	return services.Migration{
+		Version:     1,
+		Description: "Create posts table",
+		Up:          up,
+		Down:        createPostsTableDown,
+		Checksum:    services.Checksum(upSQL, dropPostsTableSQL),
+	}
+}
+
+const createPostsTableSQLite = `
+		CREATE TABLE IF NOT EXISTS posts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			content TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+
+const createPostsTableMySQL = `
+		CREATE TABLE IF NOT EXISTS posts (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			content TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_posts_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`
+
+const dropPostsTableSQL = "DROP TABLE IF EXISTS posts"
+
+// createPostsTableUpSQLite uses IF NOT EXISTS so that posts.db files created
+// before migrations existed are adopted as version 1 instead of failing
+func createPostsTableUpSQLite(exec services.Executor) error {
+	_, err := exec.Exec(createPostsTableSQLite)
+	return err
+}
+
+func createPostsTableUpMySQL(exec services.Executor) error {
+	_, err := exec.Exec(createPostsTableMySQL)
+	return err
+}
+
+func createPostsTableDown(exec services.Executor) error {
+	_, err := exec.Exec(dropPostsTableSQL)
+	return err
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCreatePostsTableMigration(t *testing.T) {
+	migration := CreatePostsTableMigration("sqlite3")
+
+	if migration.Version != 1 {
+		t.Errorf("Expected migration version 1, got %d", migration.Version)
+	}
+
+	if migration.Description != "Create posts table" {
+		t.Errorf("Expected migration description 'Create posts table', got '%s'", migration.Description)
+	}
+
+	if migration.Up == nil || migration.Down == nil {
+		t.Error("Migration Up and Down functions should not be nil")
+	}
+}
+
+func TestCreatePostsTableMigration_SQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// A database created before migrations existed already has the table
+	if _, err := db.Exec("CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, content TEXT NOT NULL, created_at DATETIME, updated_at DATETIME)"); err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+
+	migration := CreatePostsTableMigration("sqlite3")
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if err := migration.Up(tx); err != nil {
+		t.Fatalf("Up should adopt an existing posts table, got %v", err)
+	}
+	if err := migration.Down(tx); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'posts'").Scan(&count)
+	if count != 0 {
+		t.Error("Expected posts table to be dropped by Down")
+	}
+}
@@ -0,0 +1,116 @@
+package migrations
+
+import (
+	"log"
+	"simple-crud-board/services"
+)
+
+// CreatePostsSearchIndexMigration creates the full-text search index for the
+// given database/sql driver name ("sqlite3" or "mysql")
+func CreatePostsSearchIndexMigration(driver string) services.Migration {
+	up, down := createPostsSearchIndexUpSQLite, createPostsSearchIndexDownSQLite
+	checksum := services.Checksum(createPostsSearchIndexSQL, dropPostsSearchIndexSQL)
+	if driver == "mysql" {
+		// MySQLPostRepository.Search scans posts with LIKE, so there is no index
+		up, down, checksum = noop, noop, ""
+	}
+
+	return services.Migration{
+		Version:     2,
+		Description: "Create posts full-text search index",
+		Up:          up,
+		Down:        down,
+		Checksum:    checksum,
+	}
+}
+
+const createPostsSearchIndexSQL = `
+		CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+			content,
+			content='posts',
+			content_rowid='id',
+			tokenize='trigram'
+		);
+		CREATE TRIGGER IF NOT EXISTS posts_fts_insert AFTER INSERT ON posts BEGIN
+			INSERT INTO posts_fts(rowid, content) VALUES (new.id, new.content);
+		END;
+		CREATE TRIGGER IF NOT EXISTS posts_fts_delete AFTER DELETE ON posts BEGIN
+			INSERT INTO posts_fts(posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		END;
+		CREATE TRIGGER IF NOT EXISTS posts_fts_update AFTER UPDATE OF content ON posts BEGIN
+			INSERT INTO posts_fts(posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+			INSERT INTO posts_fts(rowid, content) VALUES (new.id, new.content);
+		END;
+		INSERT INTO posts_fts(posts_fts) VALUES ('rebuild');
+	`
+
+const dropPostsSearchIndexSQL = `
+		DROP TRIGGER IF EXISTS posts_fts_insert;
+		DROP TRIGGER IF EXISTS posts_fts_delete;
+		DROP TRIGGER IF EXISTS posts_fts_update;
+		DROP TABLE IF EXISTS posts_fts;
+	`
+
+// fts5Available reports whether go-sqlite3 was built with FTS5 (the
+// sqlite_fts5 build tag). Tests replace it to simulate either build.
+var fts5Available = func(exec services.Executor) (bool, error) {
+	var fts5 bool
+	err := exec.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5)
+	return fts5, err
+}
+
+// createPostsSearchIndexUpSQLite creates the posts_fts FTS5 table and the
+// triggers that keep it in sync with posts. The trigram tokenizer is used so
+// that substring search also works for text without spaces, such as Japanese.
+//
+// Without FTS5 the migration is recorded without creating anything and search
+// requests report that search is unavailable; EnsurePostsSearchIndex creates
+// the index once the server runs on a build with FTS5. IF NOT EXISTS adopts
+// indexes created before this migration existed.
+func createPostsSearchIndexUpSQLite(exec services.Executor) error {
+	fts5, err := fts5Available(exec)
+	if err != nil {
+		return err
+	}
+	if !fts5 {
+		log.Println("FTS5 is not available (build with -tags sqlite_fts5); skipping the full-text search index")
+		return nil
+	}
+
+	_, err = exec.Exec(createPostsSearchIndexSQL)
+	return err
+}
+
+// EnsurePostsSearchIndex creates the search index when migration 2 was applied
+// by a build without FTS5 and this build has it. It does nothing while
+// migration 2 is rolled back, so that the index stays dropped.
+func EnsurePostsSearchIndex(exec services.Executor) error {
+	var applied, existing int
+	err := exec.QueryRow("SELECT COUNT(*) FROM migrations WHERE version = 2").Scan(&applied)
+	if err != nil || applied == 0 {
+		return err
+	}
+
+	err = exec.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'posts_fts'").Scan(&existing)
+	if err != nil || existing > 0 {
+		return err
+	}
+
+	// Still no FTS5: migration 2 already logged that the index was skipped
+	if fts5, err := fts5Available(exec); err != nil || !fts5 {
+		return err
+	}
+
+	log.Println("Creating the full-text search index skipped by an earlier build without FTS5")
+	return createPostsSearchIndexUpSQLite(exec)
+}
+
+// createPostsSearchIndexDownSQLite also works when Up skipped the index
+func createPostsSearchIndexDownSQLite(exec services.Executor) error {
+	_, err := exec.Exec(dropPostsSearchIndexSQL)
+	return err
+}
+
+func noop(exec services.Executor) error {
+	return nil
+}
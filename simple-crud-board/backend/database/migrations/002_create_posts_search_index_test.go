@@ -0,0 +1,160 @@
+package migrations
+
+import (
+	"database/sql"
+	"simple-crud-board/services"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var realFTS5Available = fts5Available
+
+func TestCreatePostsSearchIndexMigration(t *testing.T) {
+	migration := CreatePostsSearchIndexMigration("sqlite3")
+
+	if migration.Version != 2 {
+		t.Errorf("Expected migration version 2, got %d", migration.Version)
+	}
+
+	if migration.Up == nil || migration.Down == nil {
+		t.Error("Migration Up and Down functions should not be nil")
+	}
+}
+
+// newPostsMigrator opens an in-memory database with every migration
+// registered and reports whether this build has FTS5
+func newPostsMigrator(t *testing.T) (*sql.DB, *services.MigrationManager, bool) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	fts5, err := fts5Available(db)
+	if err != nil {
+		t.Fatalf("Failed to check for FTS5: %v", err)
+	}
+
+	manager := services.NewMigrationManager(db)
+	for _, migration := range All("sqlite3") {
+		manager.AddMigration(migration)
+	}
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("Failed to initialize migration table: %v", err)
+	}
+	return db, manager, fts5
+}
+
+func TestCreatePostsSearchIndexMigration_SQLite(t *testing.T) {
+	db, manager, fts5 := newPostsMigrator(t)
+
+	// Posts written before the index exists are indexed by Up
+	if err := manager.UpTo(1); err != nil {
+		t.Fatalf("UpTo(1) failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO posts (content) VALUES ('written before the index')"); err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+	if err := manager.UpTo(2); err != nil {
+		t.Fatalf("Up should succeed with or without FTS5, got %v", err)
+	}
+
+	// Without FTS5 the migration is recorded but creates nothing
+	want := 0
+	if fts5 {
+		want = 4
+	}
+	if got := countSearchObjects(t, db); got != want {
+		t.Errorf("Expected %d search objects (FTS5: %v), got %d", want, fts5, got)
+	}
+	if fts5 {
+		var matches int
+		if err := db.QueryRow("SELECT COUNT(*) FROM posts_fts WHERE posts_fts MATCH 'before'").Scan(&matches); err != nil {
+			t.Fatalf("Failed to query posts_fts: %v", err)
+		}
+		if matches != 1 {
+			t.Errorf("Expected the existing post to be indexed, got %d matches", matches)
+		}
+	}
+
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if got := countSearchObjects(t, db); got != 0 {
+		t.Errorf("Expected Down to drop the index and its triggers, %d remain", got)
+	}
+	if _, err := db.Exec("INSERT INTO posts (content) VALUES ('written after Down')"); err != nil {
+		t.Errorf("Expected posts to be writable without the index, got %v", err)
+	}
+}
+
+// TestEnsurePostsSearchIndex_AfterBuildWithoutFTS5 applies migration 2 on a
+// build without FTS5, then starts a build with it
+func TestEnsurePostsSearchIndex_AfterBuildWithoutFTS5(t *testing.T) {
+	db, manager, fts5 := newPostsMigrator(t)
+	if !fts5 {
+		t.Skip("FTS5 not compiled in; run with -tags sqlite_fts5")
+	}
+
+	fts5Available = func(services.Executor) (bool, error) { return false, nil }
+	defer func() { fts5Available = realFTS5Available }()
+
+	if err := manager.UpTo(1); err != nil {
+		t.Fatalf("UpTo(1) failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO posts (content) VALUES ('written before the index')"); err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+	if err := manager.UpTo(2); err != nil {
+		t.Fatalf("UpTo(2) without FTS5 failed: %v", err)
+	}
+	if err := EnsurePostsSearchIndex(db); err != nil {
+		t.Fatalf("EnsurePostsSearchIndex without FTS5 failed: %v", err)
+	}
+	if got := countSearchObjects(t, db); got != 0 {
+		t.Fatalf("Expected no search objects without FTS5, got %d", got)
+	}
+
+	fts5Available = realFTS5Available
+	if err := EnsurePostsSearchIndex(db); err != nil {
+		t.Fatalf("EnsurePostsSearchIndex with FTS5 failed: %v", err)
+	}
+	if got := countSearchObjects(t, db); got != 4 {
+		t.Fatalf("Expected the index and its 3 triggers, got %d objects", got)
+	}
+	var matches int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts_fts WHERE posts_fts MATCH 'before'").Scan(&matches); err != nil {
+		t.Fatalf("Failed to query posts_fts: %v", err)
+	}
+	if matches != 1 {
+		t.Errorf("Expected the existing post to be backfilled, got %d matches", matches)
+	}
+
+	// Once migration 2 is rolled back the index must not come back
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if err := EnsurePostsSearchIndex(db); err != nil {
+		t.Fatalf("EnsurePostsSearchIndex after Down failed: %v", err)
+	}
+	if got := countSearchObjects(t, db); got != 0 {
+		t.Errorf("Expected no search objects after Down, got %d", got)
+	}
+}
+
+// countSearchObjects counts posts_fts and its triggers, leaving out the
+// shadow tables FTS5 creates next to it
+func countSearchObjects(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('posts_fts', 'posts_fts_insert', 'posts_fts_delete', 'posts_fts_update')").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	return count
+}
@@ -0,0 +1,12 @@
+package migrations
+
+import "simple-crud-board/services"
+
+// All returns every migration for the given database/sql driver name, in
+// version order. Register new migrations here.
+func All(driver string) []services.Migration {
+	return []services.Migration{
+		CreatePostsTableMigration(driver),
+		CreatePostsSearchIndexMigration(driver),
+	}
+}
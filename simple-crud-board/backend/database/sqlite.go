@@ -2,38 +2,202 @@ package database
 
 import (
 	"database/sql"
+	"flag"
+	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"simple-crud-board/database/migrations"
+	"simple-crud-board/services"
+	"strconv"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitDB initializes the SQLite database and creates tables
-func InitDB() (*sql.DB, error) {
-	// Create or open database file
-	db, err := sql.Open("sqlite3", "./posts.db")
+// memoryPath is the SQLite file name for a private in-memory database
+const memoryPath = ":memory:"
+
+// Options holds the SQLite connection settings
+type Options struct {
+	// Path is the database file; ":memory:" is the same as setting InMemory
+	Path string
+	// InMemory opens a throwaway in-memory database instead of Path
+	InMemory bool
+	// WAL switches the journal to write-ahead logging so readers do not block writers
+	WAL bool
+	// BusyTimeout is how long a connection waits for a lock before failing with SQLITE_BUSY
+	BusyTimeout time.Duration
+	// ForeignKeys enables foreign key enforcement
+	ForeignKeys bool
+	// MaxOpenConns limits the connection pool; zero means unlimited
+	MaxOpenConns int
+}
+
+// GetDefaultOptions returns SQLite options read from environment variables
+func GetDefaultOptions() *Options {
+	return &Options{
+		Path:         getEnv("DB_PATH", "./posts.db"),
+		InMemory:     getEnvBool("DB_IN_MEMORY", false),
+		WAL:          getEnvBool("DB_WAL", false),
+		BusyTimeout:  getEnvDuration("DB_BUSY_TIMEOUT", 5*time.Second),
+		ForeignKeys:  getEnvBool("DB_FOREIGN_KEYS", true),
+		MaxOpenConns: getEnvInt("DB_MAX_OPEN_CONNS", 0),
+	}
+}
+
+// RegisterFlags adds command-line flags for the options, using the current
+// values (normally loaded from the environment) as defaults
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Path, "db-path", o.Path, "SQLite database file (env DB_PATH)")
+	fs.BoolVar(&o.InMemory, "db-in-memory", o.InMemory, "Use an in-memory database (env DB_IN_MEMORY)")
+	fs.BoolVar(&o.WAL, "db-wal", o.WAL, "Use WAL journal mode (env DB_WAL)")
+	fs.DurationVar(&o.BusyTimeout, "db-busy-timeout", o.BusyTimeout, "How long to wait for a locked database (env DB_BUSY_TIMEOUT)")
+	fs.BoolVar(&o.ForeignKeys, "db-foreign-keys", o.ForeignKeys, "Enforce foreign keys (env DB_FOREIGN_KEYS)")
+	fs.IntVar(&o.MaxOpenConns, "db-max-open-conns", o.MaxOpenConns, "Maximum open connections, 0 for unlimited (env DB_MAX_OPEN_CONNS)")
+}
+
+// isMemory reports whether the options select an in-memory database
+func (o *Options) isMemory() bool {
+	return o.InMemory || o.Path == memoryPath
+}
+
+// DSN builds the go-sqlite3 data source name for the options
+func (o *Options) DSN() string {
+	params := url.Values{}
+	if o.WAL && !o.isMemory() {
+		params.Set("_journal_mode", "WAL")
+	}
+	if o.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10))
+	}
+	if o.ForeignKeys {
+		params.Set("_foreign_keys", "on")
+	}
+
+	path := o.Path
+	if o.isMemory() {
+		path = memoryPath
+	}
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + params.Encode()
+}
+
+// InitDB opens the SQLite database and applies pending migrations
+func InitDB(opts *Options) (*sql.DB, error) {
+	db, err := Open(opts)
 	if err != nil {
 		return nil, err
 	}
 
+	if err = Migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if opts.isMemory() {
+		log.Println("Database initialized successfully (in-memory)")
+	} else {
+		log.Printf("Database initialized successfully: %s", opts.Path)
+	}
+	return db, nil
+}
+
+// Open opens the SQLite database without touching its schema
+func Open(opts *Options) (*sql.DB, error) {
+	// Create or open database file
+	db, err := sql.Open("sqlite3", opts.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Every connection to :memory: gets its own empty database, so keep
+	// exactly one connection open for the lifetime of the pool
+	if opts.isMemory() {
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	} else if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+
 	// Test connection
 	if err = db.Ping(); err != nil {
-		return nil, err
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Create posts table
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS posts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		content TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
+	return db, nil
+}
+
+// NewMigrationManager returns a migration manager with every posts schema
+// migration registered for SQLite
+func NewMigrationManager(db *sql.DB) *services.MigrationManager {
+	manager := services.NewMigrationManager(db)
+	for _, migration := range migrations.All("sqlite3") {
+		manager.AddMigration(migration)
+	}
+	return manager
+}
+
+// Migrate brings the schema up to date by applying pending versioned
+// migrations. It also creates the search index if migration 2 ran on a build
+// without FTS5 and this one has it.
+func Migrate(db *sql.DB) error {
+	manager := NewMigrationManager(db)
+	if err := manager.InitializeMigrationTable(); err != nil {
+		return err
+	}
+
+	if err := manager.Up(); err != nil {
+		return err
+	}
 
-	_, err = db.Exec(createTableSQL)
+	return migrations.EnsurePostsSearchIndex(db)
+}
+
+// HasSearchIndex reports whether the posts_fts full-text index exists. It is
+// missing when the migrations ran without FTS5 (the sqlite_fts5 build tag), so
+// check it once at startup and pass the result to the repository.
+func HasSearchIndex(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'posts_fts'").Scan(&count)
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("failed to check for the search index: %w", err)
 	}
+	return count > 0, nil
+}
 
-	log.Println("Database initialized successfully")
-	return db, nil
+// getEnv gets environment variable with fallback
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvBool gets a boolean environment variable with fallback
+func getEnvBool(key string, fallback bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt gets an integer environment variable with fallback
+func getEnvInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "5s") with fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
 }
\ No newline at end of file
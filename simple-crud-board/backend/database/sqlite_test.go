@@ -0,0 +1,185 @@
+package database
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetDefaultOptions(t *testing.T) {
+	options := GetDefaultOptions()
+
+	if options.Path != "./posts.db" {
+		t.Errorf("Expected path to be './posts.db', got '%s'", options.Path)
+	}
+
+	if options.InMemory {
+		t.Error("Expected in-memory mode to be off")
+	}
+
+	if options.BusyTimeout != 5*time.Second {
+		t.Errorf("Expected busy timeout to be 5s, got %s", options.BusyTimeout)
+	}
+
+	if !options.ForeignKeys {
+		t.Error("Expected foreign keys to be enabled")
+	}
+}
+
+func TestGetDefaultOptions_Env(t *testing.T) {
+	t.Setenv("DB_PATH", "/tmp/board.db")
+	t.Setenv("DB_WAL", "true")
+	t.Setenv("DB_BUSY_TIMEOUT", "250ms")
+	t.Setenv("DB_MAX_OPEN_CONNS", "4")
+
+	options := GetDefaultOptions()
+
+	if options.Path != "/tmp/board.db" {
+		t.Errorf("Expected path to be '/tmp/board.db', got '%s'", options.Path)
+	}
+
+	if !options.WAL {
+		t.Error("Expected WAL to be enabled")
+	}
+
+	if options.BusyTimeout != 250*time.Millisecond {
+		t.Errorf("Expected busy timeout to be 250ms, got %s", options.BusyTimeout)
+	}
+
+	if options.MaxOpenConns != 4 {
+		t.Errorf("Expected max open conns to be 4, got %d", options.MaxOpenConns)
+	}
+}
+
+func TestOptionsFlags(t *testing.T) {
+	options := GetDefaultOptions()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	options.RegisterFlags(fs)
+
+	if err := fs.Parse([]string{"-db-path=other.db", "-db-wal"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if options.Path != "other.db" {
+		t.Errorf("Expected path to be 'other.db', got '%s'", options.Path)
+	}
+
+	if !options.WAL {
+		t.Error("Expected WAL to be enabled")
+	}
+}
+
+func TestOptionsDSN(t *testing.T) {
+	options := &Options{Path: "posts.db", WAL: true, BusyTimeout: time.Second, ForeignKeys: true}
+	expected := "posts.db?_busy_timeout=1000&_foreign_keys=on&_journal_mode=WAL"
+	if dsn := options.DSN(); dsn != expected {
+		t.Errorf("Expected DSN '%s', got '%s'", expected, dsn)
+	}
+
+	// WAL does not apply to in-memory databases
+	options = &Options{Path: "posts.db", InMemory: true, WAL: true}
+	if dsn := options.DSN(); dsn != ":memory:" {
+		t.Errorf("Expected DSN ':memory:', got '%s'", dsn)
+	}
+}
+
+func TestInitDB_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+	db, err := InitDB(&Options{Path: path, WAL: true, BusyTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("Failed to read journal mode: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Errorf("Expected journal mode 'wal', got '%s'", journalMode)
+	}
+
+	if _, err := db.Exec("INSERT INTO posts (content) VALUES (?)", "Hello World!"); err != nil {
+		t.Errorf("Expected posts table to exist: %v", err)
+	}
+}
+
+func TestInitDB_InMemory(t *testing.T) {
+	db, err := InitDB(&Options{InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Both statements must reach the same in-memory database
+	if _, err := db.Exec("INSERT INTO posts (content) VALUES (?)", "Hello World!"); err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts").Scan(&count); err != nil {
+		t.Fatalf("Failed to count posts: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 post, got %d", count)
+	}
+}
+
+func TestInitDB_RecordsMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+
+	// Opening the same file twice must not re-apply migration 1
+	for i := 0; i < 2; i++ {
+		db, err := InitDB(&Options{Path: path})
+		if err != nil {
+			t.Fatalf("Failed to initialize database (run %d): %v", i+1, err)
+		}
+		db.Close()
+	}
+
+	db, err := Open(&Options{Path: path})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	status, err := NewMigrationManager(db).Status()
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	for _, s := range status {
+		if !s.Applied {
+			t.Errorf("Expected migration %d to be applied", s.Version)
+		}
+	}
+}
+
+func TestHasSearchIndex(t *testing.T) {
+	db, err := InitDB(&Options{InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var fts5 bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		t.Fatalf("Failed to check for FTS5: %v", err)
+	}
+
+	// The index is created by migration 2 whenever FTS5 is compiled in
+	found, err := HasSearchIndex(db)
+	if err != nil {
+		t.Fatalf("HasSearchIndex failed: %v", err)
+	}
+	if found != fts5 {
+		t.Errorf("Expected HasSearchIndex to be %v, got %v", fts5, found)
+	}
+
+	// Rolling migration 2 back removes it
+	if err := NewMigrationManager(db).Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if found, err = HasSearchIndex(db); err != nil || found {
+		t.Errorf("Expected no search index after Down, got %v (err: %v)", found, err)
+	}
+}
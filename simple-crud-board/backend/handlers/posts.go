@@ -1,125 +1,212 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
 	"simple-crud-board/models"
+	"simple-crud-board/repository"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
 // PostHandler handles post-related HTTP requests
 type PostHandler struct {
-	db *sql.DB
+	repo repository.PostRepository
 }
 
 // NewPostHandler creates a new PostHandler
-func NewPostHandler(db *sql.DB) *PostHandler {
-	return &PostHandler{db: db}
+func NewPostHandler(repo repository.PostRepository) *PostHandler {
+	return &PostHandler{repo: repo}
 }
 
-// GetPosts handles GET /api/posts
+// GetPosts handles GET /api/posts?limit=&cursor=
 func (h *PostHandler) GetPosts(c *gin.Context) {
-	// TODO: Implement get all posts
-	// This should:
-	// 1. Query all posts from the database with SQL: SELECT id, content, created_at, updated_at FROM posts ORDER BY created_at DESC
-	// 2. Scan results into Post structs
-	// 3. Return posts as JSON array (empty array [] if no posts)
-	
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Not implemented - Please implement this handler",
-		"hint": "Use SQL: SELECT id, content, created_at, updated_at FROM posts ORDER BY created_at DESC",
-		"expected_return": "Array of Post objects or empty array []",
-	})
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+	opts := repository.ListOptions{Limit: limit, Cursor: c.Query("cursor")}
+
+	posts, next, err := h.repo.List(c.Request.Context(), opts)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts"})
+		return
+	}
+
+	// Always return an array, even when there are no posts
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+
+	c.JSON(http.StatusOK, models.PostListResponse{Posts: posts, NextCursor: next})
+}
+
+// SearchPosts handles GET /api/posts/search?q=&limit=
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	// The trigram index cannot match anything shorter than three characters
+	if utf8.RuneCountInString(query) < 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query must be at least 3 characters long"})
+		return
+	}
+
+	if utf8.RuneCountInString(query) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query cannot exceed 100 characters"})
+		return
+	}
+
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.repo.Search(c.Request.Context(), query, limit)
+	if errors.Is(err, repository.ErrSearchUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Full-text search is not available"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PostSearchResponse{Query: query, Results: results, Count: len(results)})
+}
+
+// GetPost handles GET /api/posts/:id
+func (h *PostHandler) GetPost(c *gin.Context) {
+	id, ok := parsePostID(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.repo.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
 }
 
 // CreatePost handles POST /api/posts
 func (h *PostHandler) CreatePost(c *gin.Context) {
-	// TODO: Implement create post
-	// This should:
-	// 1. Bind JSON request to CreatePostRequest struct
-	// 2. Validate the content (not empty, length limits)
-	// 3. Insert new post into database with SQL: INSERT INTO posts (content) VALUES (?)
-	// 4. Get the inserted post ID and timestamps
-	// 5. Return created post with ID and timestamps
-	
 	var req models.CreatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
 		return
 	}
 
-	// Server-side validation (students should implement this)
-	if len(req.Content) < 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content must be at least 3 characters long"})
+	if !validateContent(c, req.Content) {
 		return
 	}
-	
-	if len(req.Content) > 1000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content cannot exceed 1000 characters"})
+
+	post := &models.Post{Content: req.Content}
+	if err := h.repo.Create(c.Request.Context(), post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Not implemented - Please implement this handler",
-		"hint": "Use SQL: INSERT INTO posts (content) VALUES (?) and return the created post",
-		"received_content": req.Content,
-		"validation": "passed",
-	})
+	c.JSON(http.StatusCreated, post)
 }
 
 // UpdatePost handles PUT /api/posts/:id
 func (h *PostHandler) UpdatePost(c *gin.Context) {
-	// TODO: Implement update post
-	// This should:
-	// 1. Get post ID from URL parameter and validate it's a number
-	// 2. Bind JSON request to UpdatePostRequest struct
-	// 3. Validate the content (not empty, length limits)
-	// 4. Check if post exists with SQL: SELECT id FROM posts WHERE id = ?
-	// 5. Update post content and updated_at timestamp with SQL: UPDATE posts SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
-	// 6. Return updated post
-	
-	id := c.Param("id")
+	id, ok := parsePostID(c)
+	if !ok {
+		return
+	}
+
 	var req models.UpdatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
 		return
 	}
 
-	// Server-side validation (students should implement this)
-	if len(req.Content) < 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content must be at least 3 characters long"})
+	if !validateContent(c, req.Content) {
 		return
 	}
-	
-	if len(req.Content) > 1000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content cannot exceed 1000 characters"})
+
+	post, err := h.repo.Update(c.Request.Context(), id, req.Content)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Not implemented - Please implement this handler",
-		"hint": "Use SQL: UPDATE posts SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
-		"post_id": id,
-		"new_content": req.Content,
-		"validation": "passed",
-	})
+	c.JSON(http.StatusOK, post)
 }
 
 // DeletePost handles DELETE /api/posts/:id
 func (h *PostHandler) DeletePost(c *gin.Context) {
-	// TODO: Implement delete post
-	// This should:
-	// 1. Get post ID from URL parameter and validate it's a number
-	// 2. Check if post exists with SQL: SELECT id FROM posts WHERE id = ?
-	// 3. Delete post from database with SQL: DELETE FROM posts WHERE id = ?
-	// 4. Return success response (204 No Content)
-	
-	id := c.Param("id")
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Not implemented - Please implement this handler",
-		"hint": "Use SQL: DELETE FROM posts WHERE id = ? and return 204 status",
-		"post_id": id,
-	})
-}
\ No newline at end of file
+	id, ok := parsePostID(c)
+	if !ok {
+		return
+	}
+
+	err := h.repo.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseLimit reads the optional limit query parameter and writes a 400 response if it is not a positive number
+func parseLimit(c *gin.Context) (int, bool) {
+	limit := c.Query("limit")
+	if limit == "" {
+		return 0, true
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return 0, false
+	}
+	return n, true
+}
+
+// parsePostID reads the :id URL parameter and writes a 400 response if it is not a positive number
+func parsePostID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// validateContent applies the server-side content rules and writes a 400 response on failure
+func validateContent(c *gin.Context, content string) bool {
+	if len(content) < 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content must be at least 3 characters long"})
+		return false
+	}
+
+	if len(content) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content cannot exceed 1000 characters"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"simple-crud-board/database"
+	"simple-crud-board/models"
+	"simple-crud-board/repository"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakePostRepository is an in-memory PostRepository for handler tests
+type fakePostRepository struct {
+	posts  map[int]*models.Post
+	nextID int
+}
+
+func newFakePostRepository() *fakePostRepository {
+	return &fakePostRepository{posts: make(map[int]*models.Post), nextID: 1}
+}
+
+func (r *fakePostRepository) List(ctx context.Context, opts repository.ListOptions) ([]*models.Post, string, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = repository.DefaultPageSize
+	}
+
+	// The fake's cursor is simply the ID of the last post on the previous page
+	start := r.nextID - 1
+	if opts.Cursor != "" {
+		id, err := strconv.Atoi(opts.Cursor)
+		if err != nil {
+			return nil, "", repository.ErrInvalidCursor
+		}
+		start = id - 1
+	}
+
+	posts := []*models.Post{}
+	for id := start; id > 0; id-- {
+		post, ok := r.posts[id]
+		if !ok {
+			continue
+		}
+		if len(posts) == limit {
+			return posts, strconv.Itoa(posts[len(posts)-1].ID), nil
+		}
+		posts = append(posts, post)
+	}
+	return posts, "", nil
+}
+
+func (r *fakePostRepository) Search(ctx context.Context, query string, limit int) ([]*models.PostSearchResult, error) {
+	results := []*models.PostSearchResult{}
+	for id := r.nextID - 1; id > 0; id-- {
+		post, ok := r.posts[id]
+		if ok && strings.Contains(post.Content, query) {
+			results = append(results, &models.PostSearchResult{Post: *post, Snippet: post.Content, Score: 1})
+		}
+	}
+	return results, nil
+}
+
+func (r *fakePostRepository) Get(ctx context.Context, id int) (*models.Post, error) {
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, repository.ErrPostNotFound
+	}
+	return post, nil
+}
+
+func (r *fakePostRepository) Create(ctx context.Context, post *models.Post) error {
+	now := time.Now()
+	post.ID = r.nextID
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	r.posts[post.ID] = post
+	r.nextID++
+	return nil
+}
+
+func (r *fakePostRepository) Update(ctx context.Context, id int, content string) (*models.Post, error) {
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, repository.ErrPostNotFound
+	}
+	post.Content = content
+	post.UpdatedAt = time.Now()
+	return post, nil
+}
+
+func (r *fakePostRepository) Delete(ctx context.Context, id int) error {
+	if _, ok := r.posts[id]; !ok {
+		return repository.ErrPostNotFound
+	}
+	delete(r.posts, id)
+	return nil
+}
+
+func setupRouter(repo repository.PostRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := NewPostHandler(repo)
+	r.GET("/api/posts", h.GetPosts)
+	r.GET("/api/posts/search", h.SearchPosts)
+	r.GET("/api/posts/:id", h.GetPost)
+	r.POST("/api/posts", h.CreatePost)
+	r.PUT("/api/posts/:id", h.UpdatePost)
+	r.DELETE("/api/posts/:id", h.DeletePost)
+	return r
+}
+
+func doRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetPosts_Empty(t *testing.T) {
+	r := setupRouter(newFakePostRepository())
+
+	w := doRequest(r, http.MethodGet, "/api/posts", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if strings.TrimSpace(w.Body.String()) != `{"posts":[]}` {
+		t.Errorf("Expected empty posts array, got %s", w.Body.String())
+	}
+}
+
+func TestGetPosts_Pagination(t *testing.T) {
+	repo := newFakePostRepository()
+	for i := 0; i < 3; i++ {
+		repo.Create(context.Background(), &models.Post{Content: "Post " + strconv.Itoa(i)})
+	}
+	r := setupRouter(repo)
+
+	w := doRequest(r, http.MethodGet, "/api/posts?limit=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var page models.PostListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(page.Posts) != 2 {
+		t.Fatalf("Expected 2 posts on first page, got %d", len(page.Posts))
+	}
+	if page.NextCursor == "" {
+		t.Fatal("Expected next_cursor on first page")
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/posts?limit=2&cursor="+page.NextCursor, "")
+	page = models.PostListResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != 1 {
+		t.Errorf("Expected only post 1 on second page, got %+v", page.Posts)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("Expected no next_cursor on last page, got '%s'", page.NextCursor)
+	}
+}
+
+func TestGetPosts_InvalidParams(t *testing.T) {
+	r := setupRouter(newFakePostRepository())
+
+	w := doRequest(r, http.MethodGet, "/api/posts?limit=abc", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid limit, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/posts?cursor=not-a-cursor", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid cursor, got %d", w.Code)
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	repo := newFakePostRepository()
+	repo.Create(context.Background(), &models.Post{Content: "Hello World!"})
+	repo.Create(context.Background(), &models.Post{Content: "Goodbye"})
+	r := setupRouter(repo)
+
+	w := doRequest(r, http.MethodGet, "/api/posts/search?q=World", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp models.PostSearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Count != 1 || resp.Results[0].ID != 1 {
+		t.Errorf("Expected post 1 as the only result, got %+v", resp.Results)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/posts/search?q=ab", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for short query, got %d", w.Code)
+	}
+}
+
+func TestCreatePost(t *testing.T) {
+	repo := newFakePostRepository()
+	r := setupRouter(repo)
+
+	w := doRequest(r, http.MethodPost, "/api/posts", `{"content":"Hello World!"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	var post models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if post.ID != 1 {
+		t.Errorf("Expected post ID 1, got %d", post.ID)
+	}
+	if post.Content != "Hello World!" {
+		t.Errorf("Expected content 'Hello World!', got '%s'", post.Content)
+	}
+	if len(repo.posts) != 1 {
+		t.Errorf("Expected 1 stored post, got %d", len(repo.posts))
+	}
+}
+
+func TestCreatePost_Validation(t *testing.T) {
+	r := setupRouter(newFakePostRepository())
+
+	w := doRequest(r, http.MethodPost, "/api/posts", `{"content":"hi"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for short content, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodPost, "/api/posts", `{"content":"`+strings.Repeat("a", 1001)+`"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for long content, got %d", w.Code)
+	}
+}
+
+func TestCreatePost_InvalidJSON(t *testing.T) {
+	r := setupRouter(newFakePostRepository())
+
+	w := doRequest(r, http.MethodPost, "/api/posts", `{"content":`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for malformed JSON, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodPost, "/api/posts", `{}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for missing content, got %d", w.Code)
+	}
+}
+
+func TestGetPost(t *testing.T) {
+	repo := newFakePostRepository()
+	repo.Create(context.Background(), &models.Post{Content: "First post"})
+	r := setupRouter(repo)
+
+	w := doRequest(r, http.MethodGet, "/api/posts/1", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/posts/99", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/posts/abc", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestUpdatePost(t *testing.T) {
+	repo := newFakePostRepository()
+	repo.Create(context.Background(), &models.Post{Content: "First post"})
+	r := setupRouter(repo)
+
+	w := doRequest(r, http.MethodPut, "/api/posts/1", `{"content":"Edited post"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if repo.posts[1].Content != "Edited post" {
+		t.Errorf("Expected content 'Edited post', got '%s'", repo.posts[1].Content)
+	}
+
+	w = doRequest(r, http.MethodPut, "/api/posts/99", `{"content":"Edited post"}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	repo := newFakePostRepository()
+	repo.Create(context.Background(), &models.Post{Content: "First post"})
+	r := setupRouter(repo)
+
+	w := doRequest(r, http.MethodDelete, "/api/posts/1", "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodDelete, "/api/posts/1", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdatePost_Validation(t *testing.T) {
+	repo := newFakePostRepository()
+	repo.Create(context.Background(), &models.Post{Content: "First post"})
+	r := setupRouter(repo)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"invalid ID", "/api/posts/abc", `{"content":"Edited post"}`},
+		{"malformed JSON", "/api/posts/1", `{"content":`},
+		{"short content", "/api/posts/1", `{"content":"hi"}`},
+		{"long content", "/api/posts/1", `{"content":"` + strings.Repeat("a", 1001) + `"}`},
+	}
+
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodPut, tt.path, tt.body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.name, w.Code)
+		}
+	}
+	if repo.posts[1].Content != "First post" {
+		t.Errorf("Expected rejected updates to leave the post alone, got '%s'", repo.posts[1].Content)
+	}
+}
+
+func TestDeletePost_InvalidID(t *testing.T) {
+	r := setupRouter(newFakePostRepository())
+
+	w := doRequest(r, http.MethodDelete, "/api/posts/abc", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+// TestPostHandler_SQLite runs create, get, update and delete against the
+// SQLite repository the server uses
+func TestPostHandler_SQLite(t *testing.T) {
+	db, err := database.InitDB(&database.Options{InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	r := setupRouter(repository.NewSQLitePostRepository(db, false))
+
+	w := doRequest(r, http.MethodPost, "/api/posts", `{"content":"Hello World!"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	path := "/api/posts/" + strconv.Itoa(created.ID)
+
+	w = doRequest(r, http.MethodGet, path, "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Hello World!") {
+		t.Errorf("Expected the created post, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(r, http.MethodPut, path, `{"content":"Edited post"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var updated models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if updated.Content != "Edited post" || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Expected edited content and the original created_at, got %+v", updated)
+	}
+
+	w = doRequest(r, http.MethodPut, "/api/posts/99", `{"content":"Edited post"}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 when updating a missing post, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodDelete, path, "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodGet, path, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", w.Code)
+	}
+
+	w = doRequest(r, http.MethodDelete, path, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 when deleting twice, got %d", w.Code)
+	}
+}
@@ -1,22 +1,38 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"simple-crud-board/database"
 	"simple-crud-board/handlers"
+	"simple-crud-board/repository"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Load database options from the environment, then let flags override them
+	dbOptions := database.GetDefaultOptions()
+	dbOptions.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
 	// Initialize database
-	db, err := database.InitDB()
+	db, err := database.InitDB(dbOptions)
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 	defer db.Close()
 
+	// Search needs the FTS5 index; check for it once instead of on every request
+	searchIndex, err := database.HasSearchIndex(db)
+	if err != nil {
+		log.Fatal("Failed to check the search index:", err)
+	}
+	if !searchIndex {
+		log.Println("Full-text search index not found; search is disabled (build with -tags sqlite_fts5, see README)")
+	}
+
 	// Initialize Gin router
 	r := gin.Default()
 
@@ -28,12 +44,15 @@ func main() {
 	r.Use(cors.New(config))
 
 	// Initialize handlers
-	postHandler := handlers.NewPostHandler(db)
+	postRepo := repository.NewSQLitePostRepository(db, searchIndex)
+	postHandler := handlers.NewPostHandler(postRepo)
 
 	// API routes
 	api := r.Group("/api")
 	{
 		api.GET("/posts", postHandler.GetPosts)
+		api.GET("/posts/search", postHandler.SearchPosts)
+		api.GET("/posts/:id", postHandler.GetPost)
 		api.POST("/posts", postHandler.CreatePost)
 		api.PUT("/posts/:id", postHandler.UpdatePost)
 		api.DELETE("/posts/:id", postHandler.DeletePost)
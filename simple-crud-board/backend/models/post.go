@@ -18,4 +18,26 @@ type CreatePostRequest struct {
 // UpdatePostRequest represents the request body for updating a post
 type UpdatePostRequest struct {
 	Content string `json:"content" binding:"required"`
+}
+
+// PostListResponse represents the response body for listing posts
+type PostListResponse struct {
+	Posts      []*Post `json:"posts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// PostSearchResult is a post matched by a full-text search
+type PostSearchResult struct {
+	Post
+	// Snippet is an HTML-escaped excerpt with matches wrapped in <mark> tags
+	Snippet string `json:"snippet"`
+	// Score orders results by relevance; higher is better
+	Score float64 `json:"score"`
+}
+
+// PostSearchResponse represents the response body for searching posts
+type PostSearchResponse struct {
+	Query   string              `json:"query"`
+	Results []*PostSearchResult `json:"results"`
+	Count   int                 `json:"count"`
 }
\ No newline at end of file
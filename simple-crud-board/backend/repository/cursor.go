@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"simple-crud-board/models"
+	"time"
+)
+
+// postCursor is the keyset position encoded in an opaque pagination cursor.
+// Posts are ordered by (created_at DESC, id DESC), so the last post of a page
+// identifies where the next page starts.
+type postCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+}
+
+// encodeCursor builds the cursor pointing just past the given post
+func encodeCursor(post *models.Post) string {
+	data, _ := json.Marshal(postCursor{CreatedAt: post.CreatedAt, ID: post.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(cursor string) (*postCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c postCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// collectPage trims the limit+1 rows fetched by List down to one page and
+// returns the next cursor when more rows remain
+func collectPage(posts []*models.Post, limit int) ([]*models.Post, string) {
+	if len(posts) <= limit {
+		return posts, ""
+	}
+	posts = posts[:limit]
+	return posts, encodeCursor(posts[len(posts)-1])
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simple-crud-board/models"
+	"sort"
+	"strings"
+)
+
+// MySQLPostRepository stores posts in a MySQL posts table with the same columns
+// as the SQLite schema. The connection must be opened with parseTime=true so
+// that DATETIME/TIMESTAMP columns scan into time.Time.
+type MySQLPostRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLPostRepository creates a new MySQLPostRepository
+func NewMySQLPostRepository(db *sql.DB) *MySQLPostRepository {
+	return &MySQLPostRepository{db: db}
+}
+
+// List returns one page of posts ordered by creation date (newest first)
+func (r *MySQLPostRepository) List(ctx context.Context, opts ListOptions) ([]*models.Post, string, error) {
+	limit := opts.pageSize()
+
+	query := "SELECT " + postColumns + " FROM posts"
+	args := []interface{}{}
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += " WHERE created_at < ? OR (created_at = ? AND id < ?)"
+		args = append(args, cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+	// Fetch one extra row to find out whether another page follows
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query posts: %w", err)
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to iterate posts: %w", err)
+	}
+
+	posts, next := collectPage(posts, limit)
+	return posts, next, nil
+}
+
+// Search returns up to limit posts containing query. MySQL has no full-text
+// index on posts, so this is a LIKE filter over the newest matches, ranked by
+// how often the query occurs.
+func (r *MySQLPostRepository) Search(ctx context.Context, query string, limit int) ([]*models.PostSearchResult, error) {
+	limit = ListOptions{Limit: limit}.pageSize()
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+postColumns+" FROM posts WHERE content LIKE ? ORDER BY created_at DESC, id DESC LIMIT ?",
+		"%"+escapeLike(query)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*models.PostSearchResult{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		snippet, occurrences := buildSnippet(post.Content, query)
+		results = append(results, &models.PostSearchResult{Post: *post, Snippet: snippet, Score: float64(occurrences)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search results: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
+}
+
+// Get returns a single post or ErrPostNotFound
+func (r *MySQLPostRepository) Get(ctx context.Context, id int) (*models.Post, error) {
+	post, err := scanPost(r.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return post, nil
+}
+
+// Create stores a new post and fills in its ID and timestamps
+func (r *MySQLPostRepository) Create(ctx context.Context, post *models.Post) error {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO posts (content) VALUES (?)", post.Content)
+	if err != nil {
+		return fmt.Errorf("failed to create post: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	created, err := r.Get(ctx, int(id))
+	if err != nil {
+		return err
+	}
+	*post = *created
+	return nil
+}
+
+// Update replaces the content of a post and returns the stored result
+func (r *MySQLPostRepository) Update(ctx context.Context, id int, content string) (*models.Post, error) {
+	// MySQL reports zero affected rows when the new content equals the old one,
+	// so existence is decided by re-reading the row instead of RowsAffected.
+	_, err := r.db.ExecContext(ctx, "UPDATE posts SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", content, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	return r.Get(ctx, id)
+}
+
+// Delete removes a post or returns ErrPostNotFound
+func (r *MySQLPostRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrPostNotFound
+	}
+
+	return nil
+}
+
+// escapeLike escapes the LIKE wildcards in user input using MySQL's default
+// backslash escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"simple-crud-board/models"
+)
+
+// ErrPostNotFound is returned when a post with the requested ID does not exist
+var ErrPostNotFound = errors.New("post not found")
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrSearchUnavailable is returned by Search when the storage has no full-text index
+var ErrSearchUnavailable = errors.New("full-text search is not available")
+
+// Page size limits for List
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ListOptions controls which page of posts List returns
+type ListOptions struct {
+	// Limit is the page size; zero means DefaultPageSize and values above MaxPageSize are clamped
+	Limit int
+	// Cursor is the opaque next cursor returned by a previous List call; empty for the first page
+	Cursor string
+}
+
+// pageSize returns the effective page size for the options
+func (o ListOptions) pageSize() int {
+	if o.Limit <= 0 {
+		return DefaultPageSize
+	}
+	if o.Limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return o.Limit
+}
+
+// PostRepository defines the storage operations used by the post handlers
+type PostRepository interface {
+	// List returns one page of posts ordered by creation date (newest first)
+	// and the cursor for the following page, which is empty on the last page
+	List(ctx context.Context, opts ListOptions) ([]*models.Post, string, error)
+	// Search returns up to limit posts matching query, most relevant first
+	Search(ctx context.Context, query string, limit int) ([]*models.PostSearchResult, error)
+	// Get returns a single post or ErrPostNotFound
+	Get(ctx context.Context, id int) (*models.Post, error)
+	// Create stores a new post and fills in its ID and timestamps
+	Create(ctx context.Context, post *models.Post) error
+	// Update replaces the content of a post and returns the stored result
+	Update(ctx context.Context, id int, content string) (*models.Post, error)
+	// Delete removes a post or returns ErrPostNotFound
+	Delete(ctx context.Context, id int) error
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost reads the columns selected by postColumns into a Post
+func scanPost(row rowScanner) (*models.Post, error) {
+	var post models.Post
+	if err := row.Scan(&post.ID, &post.Content, &post.CreatedAt, &post.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// postColumns is the column list every post query selects
+const postColumns = "id, content, created_at, updated_at"
+
+// Compile-time checks that the implementations satisfy PostRepository
+var (
+	_ PostRepository = (*SQLitePostRepository)(nil)
+	_ PostRepository = (*MySQLPostRepository)(nil)
+)
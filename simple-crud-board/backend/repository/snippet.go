@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Markers wrapped around matches before the snippet is HTML-escaped. They are
+// control characters so they survive escaping and never collide with content.
+const (
+	markOpen  = "\x02"
+	markClose = "\x03"
+)
+
+// snippetRadius is the number of characters kept on each side of the first match
+const snippetRadius = 40
+
+// highlightSnippet HTML-escapes a marked snippet and turns the markers into <mark> tags
+func highlightSnippet(marked string) string {
+	escaped := html.EscapeString(marked)
+	escaped = strings.ReplaceAll(escaped, markOpen, "<mark>")
+	return strings.ReplaceAll(escaped, markClose, "</mark>")
+}
+
+// buildSnippet cuts an excerpt of content around the first case-insensitive
+// occurrence of query and highlights every occurrence inside it. It also
+// returns the total number of occurrences, which filter-based searches use as
+// their relevance score.
+func buildSnippet(content, query string) (string, int) {
+	text := []rune(content)
+	haystack := lowerRunes(text)
+	needle := lowerRunes([]rune(query))
+
+	var matches []int
+	if len(needle) > 0 {
+		for i := 0; i+len(needle) <= len(haystack); {
+			if runesEqual(haystack[i:i+len(needle)], needle) {
+				matches = append(matches, i)
+				i += len(needle)
+				continue
+			}
+			i++
+		}
+	}
+
+	start, end := 0, len(text)
+	if len(matches) > 0 {
+		start = matches[0] - snippetRadius
+		end = matches[0] + len(needle) + snippetRadius
+	} else {
+		end = 2 * snippetRadius
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m < start || m+len(needle) > end {
+			continue
+		}
+		b.WriteString(string(text[pos:m]))
+		b.WriteString(markOpen)
+		b.WriteString(string(text[m : m+len(needle)]))
+		b.WriteString(markClose)
+		pos = m + len(needle)
+	}
+	b.WriteString(string(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return highlightSnippet(b.String()), len(matches)
+}
+
+// lowerRunes lower-cases rune by rune so that indexes line up with the original text
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package repository
+
+import "testing"
+
+func TestBuildSnippet(t *testing.T) {
+	snippet, count := buildSnippet("Hello <World>, hello again", "hello")
+	if count != 2 {
+		t.Errorf("Expected 2 occurrences, got %d", count)
+	}
+	expected := "<mark>Hello</mark> &lt;World&gt;, <mark>hello</mark> again"
+	if snippet != expected {
+		t.Errorf("Expected snippet '%s', got '%s'", expected, snippet)
+	}
+}
+
+func TestBuildSnippet_Truncates(t *testing.T) {
+	content := ""
+	for i := 0; i < 100; i++ {
+		content += "あ"
+	}
+	content += "検索"
+	for i := 0; i < 100; i++ {
+		content += "い"
+	}
+
+	snippet, count := buildSnippet(content, "検索")
+	if count != 1 {
+		t.Errorf("Expected 1 occurrence, got %d", count)
+	}
+	runes := []rune(snippet)
+	if runes[0] != '…' || runes[len(runes)-1] != '…' {
+		t.Errorf("Expected ellipses on both sides, got '%s'", snippet)
+	}
+}
+
+func TestFtsPhrase(t *testing.T) {
+	if got := ftsPhrase(`say "hi" OR *`); got != `"say ""hi"" OR *"` {
+		t.Errorf("Unexpected phrase: %s", got)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got := escapeLike(`100%_\`); got != `100\%\_\\` {
+		t.Errorf("Unexpected escape: %s", got)
+	}
+}
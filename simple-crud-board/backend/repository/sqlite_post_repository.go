@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simple-crud-board/models"
+	"strings"
+	"time"
+)
+
+// SQLitePostRepository stores posts in the SQLite table created by database.InitDB
+type SQLitePostRepository struct {
+	db     *sql.DB
+	search bool
+}
+
+// NewSQLitePostRepository creates a new SQLitePostRepository. search tells
+// whether the posts_fts index exists (see database.HasSearchIndex); without
+// it Search returns ErrSearchUnavailable.
+func NewSQLitePostRepository(db *sql.DB, search bool) *SQLitePostRepository {
+	return &SQLitePostRepository{db: db, search: search}
+}
+
+// List returns one page of posts ordered by creation date (newest first)
+func (r *SQLitePostRepository) List(ctx context.Context, opts ListOptions) ([]*models.Post, string, error) {
+	limit := opts.pageSize()
+
+	query := "SELECT " + postColumns + " FROM posts"
+	args := []interface{}{}
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += " WHERE created_at < ? OR (created_at = ? AND id < ?)"
+		args = append(args, sqliteTime(cursor.CreatedAt), sqliteTime(cursor.CreatedAt), cursor.ID)
+	}
+	// Fetch one extra row to find out whether another page follows
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query posts: %w", err)
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to iterate posts: %w", err)
+	}
+
+	posts, next := collectPage(posts, limit)
+	return posts, next, nil
+}
+
+// Search returns up to limit posts matching query, ranked by FTS5's bm25
+func (r *SQLitePostRepository) Search(ctx context.Context, query string, limit int) ([]*models.PostSearchResult, error) {
+	if !r.search {
+		return nil, ErrSearchUnavailable
+	}
+	limit = ListOptions{Limit: limit}.pageSize()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.id, p.content, p.created_at, p.updated_at,
+			snippet(posts_fts, 0, char(2), char(3), '…', 32),
+			bm25(posts_fts)
+		FROM posts_fts
+		JOIN posts p ON p.id = posts_fts.rowid
+		WHERE posts_fts MATCH ?
+		ORDER BY bm25(posts_fts), p.id DESC
+		LIMIT ?`, ftsPhrase(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*models.PostSearchResult{}
+	for rows.Next() {
+		var result models.PostSearchResult
+		var rank float64
+		err := rows.Scan(&result.ID, &result.Content, &result.CreatedAt, &result.UpdatedAt, &result.Snippet, &rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		result.Snippet = highlightSnippet(result.Snippet)
+		// bm25 is lower for better matches; flip it so higher scores rank first
+		result.Score = -rank
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search results: %w", err)
+	}
+
+	return results, nil
+}
+
+// Get returns a single post or ErrPostNotFound
+func (r *SQLitePostRepository) Get(ctx context.Context, id int) (*models.Post, error) {
+	post, err := scanPost(r.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return post, nil
+}
+
+// Create stores a new post and fills in its ID and timestamps
+func (r *SQLitePostRepository) Create(ctx context.Context, post *models.Post) error {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO posts (content) VALUES (?)", post.Content)
+	if err != nil {
+		return fmt.Errorf("failed to create post: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	// Re-read the row so the post carries the database-assigned timestamps
+	created, err := r.Get(ctx, int(id))
+	if err != nil {
+		return err
+	}
+	*post = *created
+	return nil
+}
+
+// Update replaces the content of a post and returns the stored result
+func (r *SQLitePostRepository) Update(ctx context.Context, id int, content string) (*models.Post, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE posts SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", content, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return nil, ErrPostNotFound
+	}
+
+	return r.Get(ctx, id)
+}
+
+// Delete removes a post or returns ErrPostNotFound
+func (r *SQLitePostRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrPostNotFound
+	}
+
+	return nil
+}
+
+// sqliteTime formats a timestamp the way SQLite's CURRENT_TIMESTAMP stores it,
+// so that cursor comparisons against the TEXT created_at column line up
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.999999999")
+}
+
+// ftsPhrase quotes user input as a single FTS5 phrase so that operators such
+// as AND, OR, NEAR or * in the query are matched literally
+func ftsPhrase(query string) string {
+	return `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+}
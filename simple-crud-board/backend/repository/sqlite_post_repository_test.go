@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"simple-crud-board/database"
+	"simple-crud-board/models"
+	"strings"
+	"testing"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := database.InitDB(&database.Options{InMemory: true})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLitePostRepository_CRUD(t *testing.T) {
+	repo := NewSQLitePostRepository(newTestDB(t), false)
+	ctx := context.Background()
+
+	post := &models.Post{Content: "Hello World!"}
+	if err := repo.Create(ctx, post); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if post.ID != 1 {
+		t.Errorf("Expected post ID 1, got %d", post.ID)
+	}
+	if post.CreatedAt.IsZero() {
+		t.Error("Expected created_at to be set")
+	}
+
+	updated, err := repo.Update(ctx, post.ID, "Edited")
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Content != "Edited" {
+		t.Errorf("Expected content 'Edited', got '%s'", updated.Content)
+	}
+
+	if err := repo.Delete(ctx, post.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := repo.Get(ctx, post.ID); err != ErrPostNotFound {
+		t.Errorf("Expected ErrPostNotFound after delete, got %v", err)
+	}
+	if _, err := repo.Update(ctx, post.ID, "Edited"); err != ErrPostNotFound {
+		t.Errorf("Expected ErrPostNotFound on update, got %v", err)
+	}
+	if err := repo.Delete(ctx, post.ID); err != ErrPostNotFound {
+		t.Errorf("Expected ErrPostNotFound on delete, got %v", err)
+	}
+}
+
+func TestSQLitePostRepository_ListPagination(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewSQLitePostRepository(db, false)
+	ctx := context.Background()
+
+	// Two posts share a timestamp so the id tie-breaker is exercised
+	timestamps := []string{
+		"2024-01-01 10:00:00",
+		"2024-01-02 10:00:00",
+		"2024-01-02 10:00:00",
+		"2024-01-03 10:00:00",
+		"2024-01-04 10:00:00",
+	}
+	for _, ts := range timestamps {
+		if _, err := db.Exec("INSERT INTO posts (content, created_at, updated_at) VALUES (?, ?, ?)", "post at "+ts, ts, ts); err != nil {
+			t.Fatalf("Failed to insert post: %v", err)
+		}
+	}
+
+	var ids []int
+	cursor := ""
+	pages := 0
+	for {
+		posts, next, err := repo.List(ctx, ListOptions{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	expected := []int{5, 4, 3, 2, 1}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected %d posts, got %d (%v)", len(expected), len(ids), ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected post %d at position %d, got %d", expected[i], i, ids[i])
+		}
+	}
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+}
+
+func TestSQLitePostRepository_InvalidCursor(t *testing.T) {
+	repo := NewSQLitePostRepository(newTestDB(t), false)
+
+	if _, _, err := repo.List(context.Background(), ListOptions{Cursor: "%%%"}); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestSQLitePostRepository_Search(t *testing.T) {
+	db := newTestDB(t)
+	searchIndex, err := database.HasSearchIndex(db)
+	if err != nil {
+		t.Fatalf("HasSearchIndex failed: %v", err)
+	}
+	if !searchIndex {
+		t.Skip("FTS5 not compiled in; run with -tags sqlite_fts5")
+	}
+	repo := NewSQLitePostRepository(db, true)
+	ctx := context.Background()
+
+	for _, content := range []string{"今日は東京タワーに行きました", "Go and <b>SQLite</b> full-text search", "Nothing to see here"} {
+		if err := repo.Create(ctx, &models.Post{Content: content}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	results, err := repo.Search(ctx, "sqlite", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 2 {
+		t.Fatalf("Expected post 2 as the only result, got %+v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "&lt;b&gt;<mark>SQLite</mark>&lt;/b&gt;") {
+		t.Errorf("Expected escaped and highlighted snippet, got '%s'", results[0].Snippet)
+	}
+
+	results, err = repo.Search(ctx, "東京タワー", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 1 {
+		t.Errorf("Expected post 1 for Japanese query, got %+v", results)
+	}
+
+	// The index follows updates and deletes through triggers
+	if _, err := repo.Update(ctx, 1, "Updated without the keyword"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for _, q := range []string{"東京タワー", "sqlite"} {
+		results, err = repo.Search(ctx, q, 10)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 0 {
+			t.Errorf("Expected no results for '%s' after update/delete, got %+v", q, results)
+		}
+	}
+}
+
+func TestSQLitePostRepository_SearchUnavailable(t *testing.T) {
+	repo := NewSQLitePostRepository(newTestDB(t), false)
+
+	if _, err := repo.Search(context.Background(), "sqlite", 10); err != ErrSearchUnavailable {
+		t.Errorf("Expected ErrSearchUnavailable without a search index, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultLockTimeout is how long Up and Down wait for another runner's lock
+const DefaultLockTimeout = 30 * time.Second
+
+// lockPollInterval is how often a waiting runner retries the lock
+const lockPollInterval = 100 * time.Millisecond
+
+// ErrLockTimeout is returned when the migration lock could not be acquired
+// within the manager's lock timeout
+var ErrLockTimeout = errors.New("timed out waiting for migration lock")
+
+// SetLockTimeout sets how long Up, Down and the other migrating methods wait
+// for the migration lock. Zero or less disables waiting: the lock is tried
+// once.
+func (m *MigrationManager) SetLockTimeout(timeout time.Duration) {
+	m.lockTimeout = timeout
+}
+
+// withLock runs fn while holding the migration lock, so that the server and
+// the migrate tool never migrate the same database file at the same time
+func (m *MigrationManager) withLock(fn func() error) error {
+	release, err := m.acquireLock(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn()
+	if err := release(); err != nil && fnErr == nil {
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+	return fnErr
+}
+
+// acquireLock inserts the single row of the migration_lock table. SQLite has
+// no advisory locks, so the lock survives a crashed runner; delete the row by
+// hand once no runner is active.
+func (m *MigrationManager) acquireLock(ctx context.Context) (func() error, error) {
+	_, err := m.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS migration_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			locked_at DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration_lock table: %w", err)
+	}
+
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		result, err := m.db.ExecContext(ctx, "INSERT OR IGNORE INTO migration_lock (id, locked_at) VALUES (1, ?)", time.Now().UTC())
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if inserted == 1 {
+			break
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w after %s: another migration runner holds the lock (if no runner is active, clear it with DELETE FROM migration_lock)", ErrLockTimeout, m.lockTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	return func() error {
+		_, err := m.db.ExecContext(context.Background(), "DELETE FROM migration_lock WHERE id = 1")
+		return err
+	}, nil
+}
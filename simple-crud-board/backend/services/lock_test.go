@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMigrationManager_LockBlocksConcurrentRunner(t *testing.T) {
+	db, manager := newVersionedManager(t)
+	manager.SetLockTimeout(300 * time.Millisecond)
+
+	// Another runner holds the lock
+	other := NewMigrationManager(db)
+	other.SetLockTimeout(0)
+	release, err := other.acquireLock(context.Background())
+	if err != nil {
+		t.Fatalf("acquireLock failed: %v", err)
+	}
+
+	start := time.Now()
+	err = manager.Up()
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Expected ErrLockTimeout, got %v", err)
+	}
+	if time.Since(start) < 300*time.Millisecond {
+		t.Error("Expected Up to wait for the lock timeout")
+	}
+	if got := appliedVersions(t, manager); len(got) != 0 {
+		t.Errorf("Expected no migrations to run without the lock, got %v", got)
+	}
+
+	if err := release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed after the lock was released: %v", err)
+	}
+	if got := appliedVersions(t, manager); len(got) != 3 {
+		t.Errorf("Expected all migrations to be applied, got %v", got)
+	}
+
+	// Up must release the lock when it is done
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM migration_lock").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected lock row to be removed, got %d (err: %v)", count, err)
+	}
+}
+
+func TestMigrationManager_LockReleasedOnFailure(t *testing.T) {
+	db := newTestDB(t)
+	manager := NewMigrationManager(db)
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Broken migration",
+		Up:          func(exec Executor) error { return errors.New("boom") },
+		Down:        func(exec Executor) error { return nil },
+	})
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	if err := manager.Up(); err == nil {
+		t.Fatal("Expected Up to fail")
+	}
+
+	manager.SetLockTimeout(0)
+	release, err := manager.acquireLock(context.Background())
+	if err != nil {
+		t.Fatalf("Expected lock to be free after a failed Up, got %v", err)
+	}
+	release()
+}
+
+func TestMigrationManager_ResetTakesLockOnce(t *testing.T) {
+	_, manager := newVersionedManager(t)
+	manager.SetLockTimeout(0)
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// DownTo rolls back each migration while already holding the lock, so
+	// taking it again for every step would time out immediately
+	if err := manager.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if got := appliedVersions(t, manager); len(got) != 0 {
+		t.Errorf("Expected no applied migrations after Reset, got %v", got)
+	}
+}
@@ -0,0 +1,540 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Executor is the subset of *sql.DB and *sql.Tx that migrations use
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Migration represents a database migration.
+//
+// Up and Down receive the transaction that also records the migration, so a
+// failing migration leaves neither schema changes nor a bookkeeping row
+// behind (on MySQL, DDL statements still commit implicitly).
+//
+// Set NoTransaction for statements that cannot run inside a transaction.
+// Up and Down then receive the *sql.DB and the migration is recorded only
+// after they succeed.
+//
+// Checksum identifies the migration's contents; it is stored when the
+// migration is applied so that later edits can be detected (see Status).
+// Migrations without a checksum are not checked for drift.
+type Migration struct {
+	Version       int
+	Description   string
+	Up            func(Executor) error
+	Down          func(Executor) error
+	NoTransaction bool
+	Checksum      string
+}
+
+// ErrMigrationDrift is returned by Validate when applied migrations no longer
+// match the registered ones
+var ErrMigrationDrift = errors.New("migration drift detected")
+
+// Checksum returns the hex SHA-256 of the given migration sources
+func Checksum(sources ...string) string {
+	h := sha256.New()
+	for _, source := range sources {
+		h.Write([]byte(source))
+		// Separate the parts so that moving text between them changes the sum
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MigrationManager manages database migrations.
+//
+// It is a deliberate fork of the MigrationManager in user-authentication: the
+// two backends are separate Go modules, each built on its own (the Docker
+// build context is the backend directory), so they cannot import a shared
+// package. This one only runs against SQLite, so it leaves out SQL dialects
+// and dry runs but keeps the migration lock and checksums. Keep the method
+// names and behaviour in step with that version so both migrate tools work
+// alike.
+type MigrationManager struct {
+	db          *sql.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+// NewMigrationManager creates a new migration manager
+func NewMigrationManager(db *sql.DB) *MigrationManager {
+	return &MigrationManager{
+		db:          db,
+		migrations:  make([]Migration, 0),
+		lockTimeout: DefaultLockTimeout,
+	}
+}
+
+// AddMigration adds a migration to the manager
+func (m *MigrationManager) AddMigration(migration Migration) {
+	m.migrations = append(m.migrations, migration)
+}
+
+// GetMigrations returns all registered migrations
+func (m *MigrationManager) GetMigrations() []Migration {
+	return m.migrations
+}
+
+// InitializeMigrationTable creates the migrations table if it doesn't exist.
+// The DDL sticks to types and defaults that SQLite and MySQL both accept.
+func (m *MigrationManager) InitializeMigrationTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS migrations (
+			version INTEGER PRIMARY KEY,
+			description VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL DEFAULT '',
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+
+	_, err := m.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	// Tables created before checksums were introduced lack the column
+	if _, err := m.db.Exec("SELECT checksum FROM migrations WHERE 1 = 0"); err != nil {
+		_, err = m.db.Exec("ALTER TABLE migrations ADD COLUMN checksum VARCHAR(64) NOT NULL DEFAULT ''")
+		if err != nil {
+			return fmt.Errorf("failed to add checksum column to migrations table: %w", err)
+		}
+	}
+
+	log.Println("Migration table initialized successfully")
+	return nil
+}
+
+// GetAppliedMigrations returns a list of applied migration versions
+func (m *MigrationManager) GetAppliedMigrations() ([]int, error) {
+	query := "SELECT version FROM migrations ORDER BY version"
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// IsMigrationApplied checks if a specific migration version is applied
+func (m *MigrationManager) IsMigrationApplied(version int) (bool, error) {
+	query := "SELECT COUNT(*) FROM migrations WHERE version = ?"
+	var count int
+	err := m.db.QueryRow(query, version).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration status: %w", err)
+	}
+	return count > 0, nil
+}
+
+// recordMigration records a migration as applied
+func recordMigration(exec Executor, version int, description, checksum string) error {
+	query := "INSERT INTO migrations (version, description, checksum, applied_at) VALUES (?, ?, ?, ?)"
+	_, err := exec.Exec(query, version, description, checksum, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return nil
+}
+
+// removeMigrationRecord removes a migration record
+func removeMigrationRecord(exec Executor, version int) error {
+	query := "DELETE FROM migrations WHERE version = ?"
+	_, err := exec.Exec(query, version)
+	if err != nil {
+		return fmt.Errorf("failed to remove migration record: %w", err)
+	}
+	return nil
+}
+
+// Up runs all pending migrations while holding the migration lock
+func (m *MigrationManager) Up() error {
+	return m.withLock(m.up)
+}
+
+func (m *MigrationManager) up() error {
+	// Sort migrations by version
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+
+	appliedMigrations, err := m.GetAppliedMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	appliedMap := make(map[int]bool)
+	for _, version := range appliedMigrations {
+		appliedMap[version] = true
+	}
+
+	for _, migration := range m.migrations {
+		if appliedMap[migration.Version] {
+			continue
+		}
+
+		log.Printf("Applying migration %d: %s", migration.Version, migration.Description)
+
+		if err := m.applyMigration(migration); err != nil {
+			return err
+		}
+
+		log.Printf("Successfully applied migration %d: %s", migration.Version, migration.Description)
+	}
+
+	return nil
+}
+
+// Down rolls back the last applied migration while holding the migration lock
+func (m *MigrationManager) Down() error {
+	return m.withLock(m.down)
+}
+
+func (m *MigrationManager) down() error {
+	appliedMigrations, err := m.GetAppliedMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	if len(appliedMigrations) == 0 {
+		log.Println("No migrations to rollback")
+		return nil
+	}
+
+	// Get the last applied migration
+	lastVersion := appliedMigrations[len(appliedMigrations)-1]
+
+	// Find the migration to rollback
+	var migrationToRollback *Migration
+	for i := range m.migrations {
+		if m.migrations[i].Version == lastVersion {
+			migrationToRollback = &m.migrations[i]
+			break
+		}
+	}
+
+	if migrationToRollback == nil {
+		return fmt.Errorf("migration %d not found in registered migrations", lastVersion)
+	}
+
+	log.Printf("Rolling back migration %d: %s", migrationToRollback.Version, migrationToRollback.Description)
+
+	if err := m.rollbackMigration(*migrationToRollback); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully rolled back migration %d: %s", migrationToRollback.Version, migrationToRollback.Description)
+	return nil
+}
+
+// UpTo applies pending migrations up to and including version
+func (m *MigrationManager) UpTo(version int) error {
+	return m.withLock(func() error { return m.upTo(version) })
+}
+
+func (m *MigrationManager) upTo(version int) error {
+	if err := m.checkTargetVersion(version); err != nil {
+		return err
+	}
+
+	appliedMigrations, err := m.GetAppliedMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	appliedMap := make(map[int]bool)
+	for _, v := range appliedMigrations {
+		appliedMap[v] = true
+	}
+
+	for _, migration := range m.sortedMigrations() {
+		if migration.Version > version || appliedMap[migration.Version] {
+			continue
+		}
+
+		log.Printf("Applying migration %d: %s", migration.Version, migration.Description)
+
+		if err := m.applyMigration(migration); err != nil {
+			return err
+		}
+
+		log.Printf("Successfully applied migration %d: %s", migration.Version, migration.Description)
+	}
+
+	return nil
+}
+
+// DownTo rolls back applied migrations, newest first, until version is the
+// latest applied one. DownTo(0) rolls back everything.
+func (m *MigrationManager) DownTo(version int) error {
+	return m.withLock(func() error { return m.downTo(version) })
+}
+
+func (m *MigrationManager) downTo(version int) error {
+	if err := m.checkTargetVersion(version); err != nil {
+		return err
+	}
+
+	for {
+		appliedMigrations, err := m.GetAppliedMigrations()
+		if err != nil {
+			return fmt.Errorf("failed to get applied migrations: %w", err)
+		}
+		if len(appliedMigrations) == 0 || appliedMigrations[len(appliedMigrations)-1] <= version {
+			return nil
+		}
+
+		if err := m.down(); err != nil {
+			return err
+		}
+	}
+}
+
+// Redo rolls back the last applied migration and applies it again
+func (m *MigrationManager) Redo() error {
+	return m.withLock(m.redo)
+}
+
+func (m *MigrationManager) redo() error {
+	appliedMigrations, err := m.GetAppliedMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	if len(appliedMigrations) == 0 {
+		log.Println("No migrations to redo")
+		return nil
+	}
+
+	lastVersion := appliedMigrations[len(appliedMigrations)-1]
+	var migrationToRedo *Migration
+	for i := range m.migrations {
+		if m.migrations[i].Version == lastVersion {
+			migrationToRedo = &m.migrations[i]
+			break
+		}
+	}
+
+	if migrationToRedo == nil {
+		return fmt.Errorf("migration %d not found in registered migrations", lastVersion)
+	}
+
+	log.Printf("Redoing migration %d: %s", migrationToRedo.Version, migrationToRedo.Description)
+
+	if err := m.rollbackMigration(*migrationToRedo); err != nil {
+		return err
+	}
+	if err := m.applyMigration(*migrationToRedo); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully redid migration %d: %s", migrationToRedo.Version, migrationToRedo.Description)
+	return nil
+}
+
+// Reset rolls back every applied migration
+func (m *MigrationManager) Reset() error {
+	return m.DownTo(0)
+}
+
+// checkTargetVersion accepts 0 (no migrations) and registered versions
+func (m *MigrationManager) checkTargetVersion(version int) error {
+	if version == 0 {
+		return nil
+	}
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("migration %d not found in registered migrations", version)
+}
+
+// sortedMigrations returns the registered migrations ordered by version
+func (m *MigrationManager) sortedMigrations() []Migration {
+	migrations := append([]Migration{}, m.migrations...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// applyMigration runs migration.Up and records it in the same transaction
+func (m *MigrationManager) applyMigration(migration Migration) error {
+	return m.inTransaction(migration, func(exec Executor) error {
+		if err := migration.Up(exec); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+		}
+
+		if err := recordMigration(exec, migration.Version, migration.Description, migration.Checksum); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		return nil
+	})
+}
+
+// rollbackMigration runs migration.Down and removes its record in the same transaction
+func (m *MigrationManager) rollbackMigration(migration Migration) error {
+	return m.inTransaction(migration, func(exec Executor) error {
+		if err := migration.Down(exec); err != nil {
+			return fmt.Errorf("failed to rollback migration %d: %w", migration.Version, err)
+		}
+
+		if err := removeMigrationRecord(exec, migration.Version); err != nil {
+			return fmt.Errorf("failed to remove migration record %d: %w", migration.Version, err)
+		}
+		return nil
+	})
+}
+
+// inTransaction calls fn with a transaction that is committed if fn succeeds
+// and rolled back otherwise. Migrations marked NoTransaction get the *sql.DB.
+func (m *MigrationManager) inTransaction(migration Migration, fn func(Executor) error) error {
+	if migration.NoTransaction {
+		return fn(m.db)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction for migration %d: %w", migration.Version, err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// appliedMigration is a row of the migrations table
+type appliedMigration struct {
+	Version     int
+	Description string
+	Checksum    string
+}
+
+// getAppliedRecords returns the rows of the migrations table keyed by version
+func (m *MigrationManager) getAppliedRecords() (map[int]appliedMigration, error) {
+	rows, err := m.db.Query("SELECT version, description, checksum FROM migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	records := make(map[int]appliedMigration)
+	for rows.Next() {
+		var record appliedMigration
+		if err := rows.Scan(&record.Version, &record.Description, &record.Checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		records[record.Version] = record
+	}
+
+	return records, rows.Err()
+}
+
+// Status returns the current migration status.
+//
+// An applied migration is flagged Modified when its stored checksum differs
+// from the registered one, and applied versions that are not registered at
+// all are included with Unknown set. Both count as drift.
+func (m *MigrationManager) Status() ([]MigrationStatus, error) {
+	applied, err := m.getAppliedRecords()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	registered := make(map[int]bool)
+	var status []MigrationStatus
+	for _, migration := range m.migrations {
+		registered[migration.Version] = true
+
+		record, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Applied:     ok,
+			Modified:    ok && record.Checksum != "" && migration.Checksum != "" && record.Checksum != migration.Checksum,
+		})
+	}
+
+	for version, record := range applied {
+		if registered[version] {
+			continue
+		}
+		status = append(status, MigrationStatus{
+			Version:     version,
+			Description: record.Description,
+			Applied:     true,
+			Unknown:     true,
+		})
+	}
+
+	// Sort by version
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+
+	return status, nil
+}
+
+// Validate returns an error wrapping ErrMigrationDrift when Status reports
+// modified or unknown migrations
+func (m *MigrationManager) Validate() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, s := range status {
+		if s.Modified {
+			problems = append(problems, fmt.Sprintf("migration %d (%s) was modified after it was applied", s.Version, s.Description))
+		}
+		if s.Unknown {
+			problems = append(problems, fmt.Sprintf("migration %d (%s) is applied but not registered", s.Version, s.Description))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrMigrationDrift, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// MigrationStatus represents the status of a migration
+type MigrationStatus struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Applied     bool   `json:"applied"`
+	Modified    bool   `json:"modified"`
+	Unknown     bool   `json:"unknown"`
+}
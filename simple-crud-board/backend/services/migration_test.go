@@ -0,0 +1,390 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func widgetsMigration() Migration {
+	return Migration{
+		Version:     1,
+		Description: "Create widgets table",
+		Up: func(exec Executor) error {
+			_, err := exec.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY)")
+			return err
+		},
+		Down: func(exec Executor) error {
+			_, err := exec.Exec("DROP TABLE widgets")
+			return err
+		},
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	return count > 0
+}
+
+func TestNewMigrationManager(t *testing.T) {
+	manager := NewMigrationManager(nil)
+
+	if manager == nil {
+		t.Fatal("Expected migration manager to be created")
+	}
+
+	if len(manager.GetMigrations()) != 0 {
+		t.Errorf("Expected empty migrations slice, got %d migrations", len(manager.GetMigrations()))
+	}
+}
+
+func TestMigrationManager_UpDown(t *testing.T) {
+	db := newTestDB(t)
+	manager := NewMigrationManager(db)
+	manager.AddMigration(widgetsMigration())
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if !tableExists(t, db, "widgets") {
+		t.Error("Expected widgets table after Up")
+	}
+
+	applied, err := manager.IsMigrationApplied(1)
+	if err != nil || !applied {
+		t.Errorf("Expected migration 1 to be applied, got %v (err: %v)", applied, err)
+	}
+
+	// Running Up again must skip the applied migration
+	if err := manager.Up(); err != nil {
+		t.Errorf("Second Up failed: %v", err)
+	}
+
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if tableExists(t, db, "widgets") {
+		t.Error("Expected widgets table to be dropped after Down")
+	}
+
+	versions, err := manager.GetAppliedMigrations()
+	if err != nil {
+		t.Fatalf("GetAppliedMigrations failed: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Errorf("Expected no applied migrations, got %v", versions)
+	}
+}
+
+func TestMigrationManager_UpRollsBackFailedMigration(t *testing.T) {
+	db := newTestDB(t)
+	manager := NewMigrationManager(db)
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Broken migration",
+		Up: func(exec Executor) error {
+			if _, err := exec.Exec("CREATE TABLE half_done (id INTEGER)"); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+		Down: func(exec Executor) error { return nil },
+	})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	if err := manager.Up(); err == nil {
+		t.Fatal("Expected Up to fail")
+	}
+	if tableExists(t, db, "half_done") {
+		t.Error("Expected partial changes to be rolled back")
+	}
+
+	applied, err := manager.IsMigrationApplied(1)
+	if err != nil || applied {
+		t.Errorf("Expected migration 1 not to be recorded, got %v (err: %v)", applied, err)
+	}
+}
+
+func TestMigrationManager_Status(t *testing.T) {
+	db := newTestDB(t)
+	manager := NewMigrationManager(db)
+
+	second := widgetsMigration()
+	second.Version = 2
+	second.Description = "Second"
+	manager.AddMigration(second)
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "First",
+		Up:          func(exec Executor) error { return nil },
+		Down:        func(exec Executor) error { return nil },
+	})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+
+	status, err := manager.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status) != 2 {
+		t.Fatalf("Expected 2 statuses, got %d", len(status))
+	}
+	if status[0].Version != 1 || !status[0].Applied {
+		t.Errorf("Expected version 1 applied, got %+v", status[0])
+	}
+	if status[1].Version != 2 || status[1].Applied {
+		t.Errorf("Expected version 2 not applied, got %+v", status[1])
+	}
+}
+
+func TestMigrationManager_NoTransaction(t *testing.T) {
+	db := newTestDB(t)
+	manager := NewMigrationManager(db)
+
+	var got Executor
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Non-transactional migration",
+		Up: func(exec Executor) error {
+			got = exec
+			return nil
+		},
+		Down:          func(exec Executor) error { return nil },
+		NoTransaction: true,
+	})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	if _, ok := got.(*sql.DB); !ok {
+		t.Errorf("Expected NoTransaction migration to receive *sql.DB, got %T", got)
+	}
+}
+
+// newVersionedManager returns a manager with three migrations, each creating
+// table_N, registered out of order
+func newVersionedManager(t *testing.T) (*sql.DB, *MigrationManager) {
+	t.Helper()
+
+	db := newTestDB(t)
+	manager := NewMigrationManager(db)
+	for _, version := range []int{2, 1, 3} {
+		table := fmt.Sprintf("table_%d", version)
+		manager.AddMigration(Migration{
+			Version:     version,
+			Description: "Create " + table,
+			Up: func(exec Executor) error {
+				_, err := exec.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)")
+				return err
+			},
+			Down: func(exec Executor) error {
+				_, err := exec.Exec("DROP TABLE " + table)
+				return err
+			},
+		})
+	}
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	return db, manager
+}
+
+func appliedVersions(t *testing.T, manager *MigrationManager) []int {
+	t.Helper()
+
+	versions, err := manager.GetAppliedMigrations()
+	if err != nil {
+		t.Fatalf("GetAppliedMigrations failed: %v", err)
+	}
+	return versions
+}
+
+func TestMigrationManager_UpToDownTo(t *testing.T) {
+	db, manager := newVersionedManager(t)
+
+	if err := manager.UpTo(2); err != nil {
+		t.Fatalf("UpTo(2) failed: %v", err)
+	}
+	if got := appliedVersions(t, manager); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Expected versions [1 2] after UpTo(2), got %v", got)
+	}
+	if tableExists(t, db, "table_3") {
+		t.Error("Expected UpTo(2) not to apply migration 3")
+	}
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := manager.DownTo(1); err != nil {
+		t.Fatalf("DownTo(1) failed: %v", err)
+	}
+	if got := appliedVersions(t, manager); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Expected versions [1] after DownTo(1), got %v", got)
+	}
+
+	if err := manager.UpTo(4); err == nil {
+		t.Error("Expected UpTo with an unregistered version to fail")
+	}
+	if err := manager.DownTo(4); err == nil {
+		t.Error("Expected DownTo with an unregistered version to fail")
+	}
+}
+
+func TestMigrationManager_Redo(t *testing.T) {
+	db, manager := newVersionedManager(t)
+
+	if err := manager.Redo(); err != nil {
+		t.Fatalf("Redo with nothing applied failed: %v", err)
+	}
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO table_3 (id) VALUES (1)"); err != nil {
+		t.Fatalf("Failed to insert row: %v", err)
+	}
+
+	if err := manager.Redo(); err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	if got := appliedVersions(t, manager); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Expected versions [1 2 3] after Redo, got %v", got)
+	}
+
+	// The table was dropped and recreated, so the row is gone
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM table_3").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected table_3 to be recreated empty, got %d rows", count)
+	}
+}
+
+func TestMigrationManager_Reset(t *testing.T) {
+	db, manager := newVersionedManager(t)
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := manager.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+
+	if got := appliedVersions(t, manager); len(got) != 0 {
+		t.Errorf("Expected no applied migrations after Reset, got %v", got)
+	}
+	for _, table := range []string{"table_1", "table_2", "table_3"} {
+		if tableExists(t, db, table) {
+			t.Errorf("Expected %s to be dropped after Reset", table)
+		}
+	}
+}
+
+func TestMigrationManager_DetectsDrift(t *testing.T) {
+	db := newTestDB(t)
+	noop := func(exec Executor) error { return nil }
+
+	manager := NewMigrationManager(db)
+	manager.AddMigration(Migration{Version: 1, Description: "First", Up: noop, Down: noop, Checksum: Checksum("v1")})
+	manager.AddMigration(Migration{Version: 2, Description: "Second", Up: noop, Down: noop, Checksum: Checksum("v2")})
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := manager.Validate(); err != nil {
+		t.Errorf("Expected no drift right after Up, got %v", err)
+	}
+
+	// Migration 1 is edited and migration 2 disappears from the code base
+	edited := NewMigrationManager(db)
+	edited.AddMigration(Migration{Version: 1, Description: "First", Up: noop, Down: noop, Checksum: Checksum("v1 edited")})
+
+	status, err := edited.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status) != 2 {
+		t.Fatalf("Expected 2 statuses, got %d", len(status))
+	}
+	if !status[0].Modified {
+		t.Error("Expected migration 1 to be flagged as modified")
+	}
+	if !status[1].Unknown || !status[1].Applied || status[1].Description != "Second" {
+		t.Errorf("Expected migration 2 to be flagged as unknown, got %+v", status[1])
+	}
+
+	if err := edited.Validate(); !errors.Is(err, ErrMigrationDrift) {
+		t.Errorf("Expected ErrMigrationDrift, got %v", err)
+	}
+}
+
+func TestMigrationManager_UpgradesTableWithoutChecksum(t *testing.T) {
+	db := newTestDB(t)
+
+	// Table layout from before checksums were recorded
+	_, err := db.Exec("CREATE TABLE migrations (version INTEGER PRIMARY KEY, description VARCHAR(255) NOT NULL, applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)")
+	if err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO migrations (version, description) VALUES (1, 'First')"); err != nil {
+		t.Fatalf("Failed to insert legacy record: %v", err)
+	}
+
+	manager := NewMigrationManager(db)
+	noop := func(exec Executor) error { return nil }
+	manager.AddMigration(Migration{Version: 1, Description: "First", Up: noop, Down: noop, Checksum: Checksum("v1")})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	// Records without a checksum cannot be compared and are not drift
+	if err := manager.Validate(); err != nil {
+		t.Errorf("Expected no drift for legacy records, got %v", err)
+	}
+}
@@ -9,7 +9,18 @@ import { usePosts } from '@/hooks/usePosts'
 import type { Post } from '@/types/post'
 
 export default function HomePage() {
-  const { posts, loading, error, createPost, updatePost, deletePost, refreshPosts } = usePosts()
+  const {
+    posts,
+    hasMore,
+    loading,
+    loadingMore,
+    error,
+    createPost,
+    updatePost,
+    deletePost,
+    refreshPosts,
+    loadMorePosts,
+  } = usePosts()
   const [showCreateForm, setShowCreateForm] = useState(false)
 
   useEffect(() => {
@@ -93,6 +104,18 @@ export default function HomePage() {
         onDelete={handleDeletePost}
       />
 
+      {hasMore && (
+        <div className="flex justify-center">
+          <button
+            onClick={loadMorePosts}
+            disabled={loadingMore}
+            className="btn btn-secondary"
+          >
+            {loadingMore ? '読み込み中...' : 'もっと見る'}
+          </button>
+        </div>
+      )}
+
       {posts.length === 0 && (
         <div className="text-center py-12">
           <p className="text-gray-500 text-lg">まだ投稿がありません</p>
@@ -4,15 +4,19 @@ import type { Post } from '@/types/post'
 
 export function usePosts() {
   const [posts, setPosts] = useState<Post[]>([])
+  const [nextCursor, setNextCursor] = useState<string | undefined>()
   const [loading, setLoading] = useState(false)
+  const [loadingMore, setLoadingMore] = useState(false)
   const [error, setError] = useState<string | null>(null)
 
+  // Load the first page again
   const refreshPosts = useCallback(async () => {
     setLoading(true)
     setError(null)
     try {
-      const fetchedPosts = await postsApi.getPosts()
-      setPosts(fetchedPosts)
+      const page = await postsApi.getPosts()
+      setPosts(page.posts)
+      setNextCursor(page.next_cursor)
     } catch (err) {
       setError(err instanceof Error ? err.message : '投稿の取得に失敗しました')
     } finally {
@@ -20,6 +24,28 @@ export function usePosts() {
     }
   }, [])
 
+  // Append the page after the ones already loaded
+  const loadMorePosts = useCallback(async () => {
+    if (!nextCursor) {
+      return
+    }
+    setLoadingMore(true)
+    setError(null)
+    try {
+      const page = await postsApi.getPosts(nextCursor)
+      setPosts(prevPosts => {
+        // Posts created here are already at the top of the list
+        const loaded = new Set(prevPosts.map(post => post.id))
+        return [...prevPosts, ...page.posts.filter(post => !loaded.has(post.id))]
+      })
+      setNextCursor(page.next_cursor)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : '投稿の取得に失敗しました')
+    } finally {
+      setLoadingMore(false)
+    }
+  }, [nextCursor])
+
   const createPost = useCallback(async (content: string) => {
     setError(null)
     try {
@@ -62,9 +88,12 @@ export function usePosts() {
 
   return {
     posts,
+    hasMore: nextCursor !== undefined,
     loading,
+    loadingMore,
     error,
     refreshPosts,
+    loadMorePosts,
     createPost,
     updatePost,
     deletePost,
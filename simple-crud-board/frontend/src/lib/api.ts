@@ -1,8 +1,11 @@
 import axios from 'axios'
-import type { Post, CreatePostRequest, UpdatePostRequest } from '@/types/post'
+import type { Post, PostsResponse, CreatePostRequest, UpdatePostRequest } from '@/types/post'
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'
 
+// Posts per page for GET /api/posts; the page shows more on demand
+const POSTS_PAGE_SIZE = 20
+
 const api = axios.create({
   baseURL: API_BASE_URL,
   headers: {
@@ -35,18 +38,21 @@ api.interceptors.response.use(
 )
 
 export const postsApi = {
-  // Get all posts
-  getPosts: async (): Promise<Post[]> => {
+  // Get one page of posts, newest first. Pass the previous page's
+  // next_cursor to get the page after it; next_cursor is missing on the last page.
+  getPosts: async (cursor?: string): Promise<PostsResponse> => {
     try {
-      const response = await api.get('/api/posts')
+      const response = await api.get<PostsResponse | Post[]>('/api/posts', {
+        params: { limit: POSTS_PAGE_SIZE, cursor },
+      })
       const data = response.data
       if (Array.isArray(data)) {
-        return data
+        return { posts: data }
       }
-      if (data && Array.isArray(data.posts)) {
-        return data.posts
+      if (!data || !Array.isArray(data.posts)) {
+        return { posts: [] }
       }
-      return []
+      return data
     } catch (error) {
       console.error('Failed to fetch posts:', error)
       throw new Error('投稿の取得に失敗しました')
@@ -15,6 +15,8 @@ export interface UpdatePostRequest {
 
 export interface PostsResponse {
   posts: Post[]
+  // Omitted on the last page
+  next_cursor?: string
 }
 
 export interface PostResponse {
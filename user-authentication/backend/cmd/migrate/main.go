@@ -11,8 +11,13 @@ import (
 
 func main() {
 	var (
-		action = flag.String("action", "up", "Migration action: up, down, or status")
-		help   = flag.Bool("help", false, "Show help")
+		action      = flag.String("action", "up", "Migration action: up, down, goto, redo, reset, status, or validate")
+		dir         = flag.String("dir", os.Getenv("MIGRATIONS_DIR"), "Directory with additional NNN_name.up.sql/.down.sql migrations")
+		version     = flag.Int("version", -1, "Target version for -action=goto (0 rolls back everything)")
+		steps       = flag.Int("steps", 0, "Number of migrations to apply (up) or roll back (down); 0 means all for up and 1 for down")
+		dryRun      = flag.Bool("dry-run", false, "Print the migrations that would run without running them")
+		lockTimeout = flag.Duration("lock-timeout", services.DefaultLockTimeout, "How long to wait for another migration runner to finish")
+		help        = flag.Bool("help", false, "Show help")
 	)
 	flag.Parse()
 
@@ -25,7 +30,7 @@ func main() {
 	config := database.GetDefaultConfig()
 
 	// Create database if it doesn't exist (for up action)
-	if *action == "up" {
+	if *action == "up" && !*dryRun {
 		if err := database.CreateDatabaseIfNotExists(config); err != nil {
 			log.Fatalf("Failed to create database: %v", err)
 		}
@@ -40,6 +45,7 @@ func main() {
 
 	// Initialize migration manager
 	migrationManager := services.NewMigrationManager(db)
+	migrationManager.SetLockTimeout(*lockTimeout)
 
 	// Initialize migration table
 	if err := migrationManager.InitializeMigrationTable(); err != nil {
@@ -47,22 +53,105 @@ func main() {
 	}
 
 	// Register migrations
-	migrationManager.AddMigration(migrations.CreateUsersTableMigration())
+	if err := migrations.Register(migrationManager, *dir); err != nil {
+		log.Fatalf("Failed to register migrations: %v", err)
+	}
 
 	// Execute the requested action
 	switch *action {
 	case "up":
-		if err := migrationManager.Up(); err != nil {
+		target, err := upTarget(migrationManager, *steps)
+		if err != nil {
+			log.Fatalf("Failed to plan migrations: %v", err)
+		}
+		if *dryRun {
+			plan, err := migrationManager.PlanUpTo(target)
+			if err != nil {
+				log.Fatalf("Failed to plan migrations: %v", err)
+			}
+			printPlan("apply", plan)
+			return
+		}
+		if err := migrationManager.UpTo(target); err != nil {
 			log.Fatalf("Failed to run migrations: %v", err)
 		}
 		log.Println("Migrations completed successfully")
 
 	case "down":
-		if err := migrationManager.Down(); err != nil {
+		target, err := downTarget(migrationManager, *steps)
+		if err != nil {
+			log.Fatalf("Failed to plan rollback: %v", err)
+		}
+		if *dryRun {
+			plan, err := migrationManager.PlanDownTo(target)
+			if err != nil {
+				log.Fatalf("Failed to plan rollback: %v", err)
+			}
+			printPlan("roll back", plan)
+			return
+		}
+		if err := migrationManager.DownTo(target); err != nil {
 			log.Fatalf("Failed to rollback migration: %v", err)
 		}
 		log.Println("Migration rollback completed successfully")
 
+	case "goto":
+		if *version < 0 {
+			log.Fatal("-action=goto requires -version=N")
+		}
+		down, err := migrationManager.PlanDownTo(*version)
+		if err != nil {
+			log.Fatalf("Failed to plan migrations: %v", err)
+		}
+		up, err := migrationManager.PlanUpTo(*version)
+		if err != nil {
+			log.Fatalf("Failed to plan migrations: %v", err)
+		}
+		if *dryRun {
+			printPlan("roll back", down)
+			printPlan("apply", up)
+			return
+		}
+		if err := migrationManager.DownTo(*version); err != nil {
+			log.Fatalf("Failed to rollback migrations: %v", err)
+		}
+		if err := migrationManager.UpTo(*version); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		log.Printf("Database is now at version %d", *version)
+
+	case "redo":
+		if *dryRun {
+			migration, err := migrationManager.PlanRedo()
+			if err != nil {
+				log.Fatalf("Failed to plan redo: %v", err)
+			}
+			if migration == nil {
+				printPlan("redo", nil)
+			} else {
+				printPlan("redo", []services.Migration{*migration})
+			}
+			return
+		}
+		if err := migrationManager.Redo(); err != nil {
+			log.Fatalf("Failed to redo migration: %v", err)
+		}
+		log.Println("Migration redo completed successfully")
+
+	case "reset":
+		if *dryRun {
+			plan, err := migrationManager.PlanDownTo(0)
+			if err != nil {
+				log.Fatalf("Failed to plan reset: %v", err)
+			}
+			printPlan("roll back", plan)
+			return
+		}
+		if err := migrationManager.Reset(); err != nil {
+			log.Fatalf("Failed to reset migrations: %v", err)
+		}
+		log.Println("All migrations rolled back successfully")
+
 	case "status":
 		status, err := migrationManager.Status()
 		if err != nil {
@@ -76,11 +165,71 @@ func main() {
 			if s.Applied {
 				appliedStatus = "Applied"
 			}
+			if s.Modified {
+				appliedStatus += " (MODIFIED)"
+			}
+			if s.Unknown {
+				appliedStatus += " (UNKNOWN)"
+			}
 			log.Printf("Version %d: %s - %s", s.Version, s.Description, appliedStatus)
 		}
 
+	case "validate":
+		if err := migrationManager.Validate(); err != nil {
+			log.Fatalf("Validation failed: %v", err)
+		}
+		log.Println("Applied migrations match the registered migrations")
+
 	default:
-		log.Fatalf("Unknown action: %s. Use 'up', 'down', or 'status'", *action)
+		log.Fatalf("Unknown action: %s. Use 'up', 'down', 'goto', 'redo', 'reset', 'status', or 'validate'", *action)
+	}
+}
+
+// upTarget returns the version to migrate up to so that at most steps pending
+// migrations are applied (all of them when steps is 0)
+func upTarget(manager *services.MigrationManager, steps int) (int, error) {
+	latest := 0
+	for _, migration := range manager.GetMigrations() {
+		if migration.Version > latest {
+			latest = migration.Version
+		}
+	}
+
+	plan, err := manager.PlanUpTo(latest)
+	if err != nil {
+		return 0, err
+	}
+	if steps > 0 && steps < len(plan) {
+		return plan[steps-1].Version, nil
+	}
+	return latest, nil
+}
+
+// downTarget returns the version to roll back to so that steps applied
+// migrations are rolled back (one when steps is 0)
+func downTarget(manager *services.MigrationManager, steps int) (int, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	plan, err := manager.PlanDownTo(0)
+	if err != nil {
+		return 0, err
+	}
+	if steps < len(plan) {
+		return plan[steps].Version, nil
+	}
+	return 0, nil
+}
+
+// printPlan lists the migrations a dry run would verb
+func printPlan(verb string, plan []services.Migration) {
+	if len(plan) == 0 {
+		log.Printf("Dry run: no migrations to %s", verb)
+		return
+	}
+	for _, migration := range plan {
+		log.Printf("Dry run: would %s migration %d: %s", verb, migration.Version, migration.Description)
 	}
 }
 
@@ -91,14 +240,30 @@ func printHelp() {
 	log.Println("")
 	log.Println("Options:")
 	log.Println("  -action string")
-	log.Println("        Migration action: up, down, or status (default \"up\")")
+	log.Println("        Migration action: up, down, goto, redo, reset, status, or validate (default \"up\")")
+	log.Println("  -dir string")
+	log.Println("        Directory with additional NNN_name.up.sql/.down.sql migrations (default $MIGRATIONS_DIR)")
+	log.Println("  -version int")
+	log.Println("        Target version for -action=goto (0 rolls back everything)")
+	log.Println("  -steps int")
+	log.Println("        Number of migrations to apply (up) or roll back (down)")
+	log.Println("  -dry-run")
+	log.Println("        Print the migrations that would run without running them")
+	log.Println("  -lock-timeout duration")
+	log.Println("        How long to wait for another migration runner to finish (default 30s)")
 	log.Println("  -help")
 	log.Println("        Show this help message")
 	log.Println("")
 	log.Println("Examples:")
 	log.Println("  go run cmd/migrate/main.go -action=up      # Run all pending migrations")
 	log.Println("  go run cmd/migrate/main.go -action=down    # Rollback last migration")
+	log.Println("  go run cmd/migrate/main.go -action=down -steps=3  # Rollback the last three migrations")
+	log.Println("  go run cmd/migrate/main.go -action=goto -version=2 # Migrate up or down to version 2")
+	log.Println("  go run cmd/migrate/main.go -action=redo    # Rollback and re-apply the last migration")
+	log.Println("  go run cmd/migrate/main.go -action=reset   # Rollback all migrations")
+	log.Println("  go run cmd/migrate/main.go -action=up -dry-run # Show pending migrations without applying them")
 	log.Println("  go run cmd/migrate/main.go -action=status  # Show migration status")
+	log.Println("  go run cmd/migrate/main.go -action=validate # Exit non-zero if applied migrations were modified")
 	log.Println("")
 	log.Println("Environment Variables:")
 	log.Println("  DB_HOST     - Database host (default: localhost)")
@@ -106,4 +271,5 @@ func printHelp() {
 	log.Println("  DB_USER     - Database user (default: root)")
 	log.Println("  DB_PASSWORD - Database password (default: password)")
 	log.Println("  DB_NAME     - Database name (default: user_auth_board)")
+	log.Println("  MIGRATIONS_DIR - Directory with additional SQL migrations")
 }
\ No newline at end of file
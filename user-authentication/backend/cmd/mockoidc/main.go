@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"user-authentication/oidctest"
+)
+
+func main() {
+	var (
+		addr          = flag.String("addr", ":9000", "Address to listen on")
+		issuer        = flag.String("issuer", "http://localhost:9000", "Issuer URL the provider is reachable at")
+		clientID      = flag.String("client-id", "local-client", "Client ID the backend uses (OIDC_<NAME>_CLIENT_ID)")
+		clientSecret  = flag.String("client-secret", "local-secret", "Client secret the backend uses (OIDC_<NAME>_CLIENT_SECRET)")
+		subject       = flag.String("sub", "mock-user-1", "Subject of the user every login returns")
+		email         = flag.String("email", "mock.user@example.com", "Email address of the user every login returns")
+		emailVerified = flag.Bool("email-verified", true, "Whether the email address is reported as verified")
+		name          = flag.String("name", "Mock User", "Name of the user every login returns")
+	)
+	flag.Parse()
+
+	provider, err := oidctest.NewProvider(*clientID, *clientSecret, oidctest.User{
+		Subject:       *subject,
+		Email:         *email,
+		EmailVerified: *emailVerified,
+		Name:          *name,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create provider: %v", err)
+	}
+	provider.Issuer = strings.TrimSuffix(*issuer, "/")
+
+	log.Println("The mock OIDC provider logs anyone in without a password; use it for local development only")
+	log.Printf("Mock OIDC provider listening on %s with issuer %s", *addr, provider.Issuer)
+	if err := http.ListenAndServe(*addr, provider); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
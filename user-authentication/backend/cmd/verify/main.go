@@ -77,6 +77,13 @@ func verifyUsersTable(db *sql.DB) error {
 			Default: sql.NullString{},
 			Extra:   "",
 		},
+		"email_verified_at": {
+			Type:    "datetime",
+			Null:    "YES",
+			Key:     "",
+			Default: sql.NullString{},
+			Extra:   "",
+		},
 		"created_at": {
 			Type:    "timestamp",
 			Null:    "YES",
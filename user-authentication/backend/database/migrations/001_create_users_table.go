@@ -1,7 +1,6 @@
 package migrations
 
 import (
-	"database/sql"
 	"user-authentication/services"
 )
 
@@ -12,11 +11,11 @@ func CreateUsersTableMigration() services.Migration {
 		Description: "Create users table",
 		Up:          createUsersTableUp,
 		Down:        createUsersTableDown,
+		Checksum:    services.Checksum(createUsersTableSQL, dropUsersTableSQL),
 	}
 }
 
-func createUsersTableUp(db *sql.DB) error {
-	query := `
+const createUsersTableSQL = `
 		CREATE TABLE users (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			username VARCHAR(50) UNIQUE NOT NULL,
@@ -29,12 +28,14 @@ func createUsersTableUp(db *sql.DB) error {
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
 	`
 
-	_, err := db.Exec(query)
+const dropUsersTableSQL = "DROP TABLE IF EXISTS users"
+
+func createUsersTableUp(db services.Executor) error {
+	_, err := db.Exec(createUsersTableSQL)
 	return err
 }
 
-func createUsersTableDown(db *sql.DB) error {
-	query := "DROP TABLE IF EXISTS users"
-	_, err := db.Exec(query)
+func createUsersTableDown(db services.Executor) error {
+	_, err := db.Exec(dropUsersTableSQL)
 	return err
 }
\ No newline at end of file
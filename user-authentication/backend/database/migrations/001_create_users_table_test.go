@@ -1,7 +1,6 @@
 package migrations
 
 import (
-	"database/sql"
 	"testing"
 	"user-authentication/services"
 
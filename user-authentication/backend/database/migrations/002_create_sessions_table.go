@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreateSessionsTableMigration creates the sessions table migration
+func CreateSessionsTableMigration() services.Migration {
+	return services.Migration{
+		Version:     2,
+		Description: "Create sessions table",
+		Up:          createSessionsTableUp,
+		Down:        createSessionsTableDown,
+		Checksum:    services.Checksum(createSessionsTableSQL, dropSessionsTableSQL),
+	}
+}
+
+// id is the SHA-256 of the cookie value, so a leaked table cannot be used to
+// hijack sessions
+const createSessionsTableSQL = `
+		CREATE TABLE sessions (
+			id CHAR(64) PRIMARY KEY,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_seen_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			INDEX idx_sessions_user_id (user_id),
+			INDEX idx_sessions_expires_at (expires_at),
+			CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`
+
+const dropSessionsTableSQL = "DROP TABLE IF EXISTS sessions"
+
+func createSessionsTableUp(db services.Executor) error {
+	_, err := db.Exec(createSessionsTableSQL)
+	return err
+}
+
+func createSessionsTableDown(db services.Executor) error {
+	_, err := db.Exec(dropSessionsTableSQL)
+	return err
+}
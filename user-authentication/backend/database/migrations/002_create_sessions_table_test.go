@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"user-authentication/services"
+)
+
+func TestCreateSessionsTableMigration(t *testing.T) {
+	migration := CreateSessionsTableMigration()
+
+	if migration.Version != 2 {
+		t.Errorf("Expected migration version 2, got %d", migration.Version)
+	}
+
+	if migration.Up == nil || migration.Down == nil {
+		t.Error("Migration Up and Down functions should not be nil")
+	}
+
+	if !strings.Contains(createSessionsTableSQL, "REFERENCES users(id) ON DELETE CASCADE") {
+		t.Error("Expected sessions to be deleted together with their user")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	manager := services.NewMigrationManager(nil)
+	if err := Register(manager, ""); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	for i, migration := range manager.GetMigrations() {
+		if migration.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d", i, i+1, migration.Version)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreatePostsTableMigration creates the posts table migration
+func CreatePostsTableMigration() services.Migration {
+	return services.Migration{
+		Version:     3,
+		Description: "Create posts table",
+		Up:          createPostsTableUp,
+		Down:        createPostsTableDown,
+		Checksum:    services.Checksum(createPostsTableSQL, dropPostsTableSQL),
+	}
+}
+
+// Every post belongs to the user who wrote it and is deleted with them
+const createPostsTableSQL = `
+		CREATE TABLE posts (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			author_id INT NOT NULL,
+			content TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_posts_created_at (created_at),
+			INDEX idx_posts_author_id (author_id, created_at),
+			CONSTRAINT fk_posts_author FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`
+
+const dropPostsTableSQL = "DROP TABLE IF EXISTS posts"
+
+func createPostsTableUp(db services.Executor) error {
+	_, err := db.Exec(createPostsTableSQL)
+	return err
+}
+
+func createPostsTableDown(db services.Executor) error {
+	_, err := db.Exec(dropPostsTableSQL)
+	return err
+}
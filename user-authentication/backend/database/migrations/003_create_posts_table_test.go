@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreatePostsTableMigration(t *testing.T) {
+	migration := CreatePostsTableMigration()
+
+	if migration.Version != 3 {
+		t.Errorf("Expected migration version 3, got %d", migration.Version)
+	}
+
+	if migration.Up == nil || migration.Down == nil {
+		t.Error("Migration Up and Down functions should not be nil")
+	}
+
+	if !strings.Contains(createPostsTableSQL, "author_id INT NOT NULL") {
+		t.Error("Expected every post to have an author")
+	}
+}
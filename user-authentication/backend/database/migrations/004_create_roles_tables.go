@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreateRolesTablesMigration creates the roles, role_permissions and
+// user_roles tables and seeds the built-in roles
+func CreateRolesTablesMigration() services.Migration {
+	return services.Migration{
+		Version:     4,
+		Description: "Create roles tables",
+		Up:          createRolesTablesUp,
+		Down:        createRolesTablesDown,
+		Checksum:    services.Checksum(append(createRolesTablesSQL, dropRolesTablesSQL...)...),
+	}
+}
+
+var createRolesTablesSQL = []string{`
+		CREATE TABLE roles (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(50) UNIQUE NOT NULL,
+			description VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`, `
+		CREATE TABLE role_permissions (
+			role_id INT NOT NULL,
+			permission VARCHAR(100) NOT NULL,
+			PRIMARY KEY (role_id, permission),
+			CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`, `
+		CREATE TABLE user_roles (
+			user_id INT NOT NULL,
+			role_id INT NOT NULL,
+			granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, role_id),
+			INDEX idx_user_roles_role_id (role_id),
+			CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`,
+	// Built-in roles. More roles and permissions can be added with plain SQL.
+	`INSERT INTO roles (name, description) VALUES
+		('admin', 'Full access, including roles and migrations'),
+		('moderator', 'Can remove any post')`,
+	`INSERT INTO role_permissions (role_id, permission)
+		SELECT id, 'posts:update:any' FROM roles WHERE name = 'admin' UNION ALL
+		SELECT id, 'posts:delete:any' FROM roles WHERE name = 'admin' UNION ALL
+		SELECT id, 'roles:manage' FROM roles WHERE name = 'admin' UNION ALL
+		SELECT id, 'migrations:run' FROM roles WHERE name = 'admin' UNION ALL
+		SELECT id, 'posts:delete:any' FROM roles WHERE name = 'moderator'`,
+}
+
+var dropRolesTablesSQL = []string{
+	"DROP TABLE IF EXISTS user_roles",
+	"DROP TABLE IF EXISTS role_permissions",
+	"DROP TABLE IF EXISTS roles",
+}
+
+func createRolesTablesUp(db services.Executor) error {
+	for _, query := range createRolesTablesSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createRolesTablesDown(db services.Executor) error {
+	for _, query := range dropRolesTablesSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
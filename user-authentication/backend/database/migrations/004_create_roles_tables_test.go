@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"user-authentication/models"
+)
+
+func TestCreateRolesTablesMigration(t *testing.T) {
+	migration := CreateRolesTablesMigration()
+
+	if migration.Version != 4 {
+		t.Errorf("Expected migration version 4, got %d", migration.Version)
+	}
+
+	if migration.Up == nil || migration.Down == nil {
+		t.Error("Migration Up and Down functions should not be nil")
+	}
+
+	if migration.Checksum == "" {
+		t.Error("Expected migration to have a checksum")
+	}
+}
+
+func TestCreateRolesTablesMigration_SQLite(t *testing.T) {
+	db, manager := newSQLiteMigrator(t, 4)
+
+	assertSchema(t, db, "roles", []string{"id", "name", "description", "created_at"})
+	assertSchema(t, db, "role_permissions", []string{"role_id", "permission"})
+	assertSchema(t, db, "user_roles", []string{"user_id", "role_id", "granted_at"}, "idx_user_roles_role_id")
+
+	// The built-in roles get the permissions that exist at this version
+	admin := strings.Join(rolePermissions(t, db, "admin"), ",")
+	if admin != "migrations:run,posts:delete:any,posts:update:any,roles:manage" {
+		t.Errorf("Unexpected admin permissions: %s", admin)
+	}
+	moderator := strings.Join(rolePermissions(t, db, "moderator"), ",")
+	if moderator != models.PermissionPostsDeleteAny {
+		t.Errorf("Unexpected moderator permissions: %s", moderator)
+	}
+
+	// Grants are removed together with the user
+	mustExec(t, db, "INSERT INTO users (username, email, password_hash) VALUES ('alice', 'alice@example.com', 'x')")
+	mustExec(t, db, "INSERT INTO user_roles (user_id, role_id) SELECT 1, id FROM roles WHERE name = 'admin'")
+	mustExec(t, db, "DELETE FROM users WHERE id = 1")
+	var grants int
+	db.QueryRow("SELECT COUNT(*) FROM user_roles").Scan(&grants)
+	if grants != 0 {
+		t.Errorf("Expected the user's roles to be deleted with the user, got %d", grants)
+	}
+
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	for _, table := range []string{"roles", "role_permissions", "user_roles"} {
+		if schemaObjectExists(t, db, "table", table) {
+			t.Errorf("Expected Down to drop %s", table)
+		}
+	}
+	if !schemaObjectExists(t, db, "table", "posts") {
+		t.Error("Expected Down to leave earlier tables alone")
+	}
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreatePasswordResetsTableMigration creates the password_resets table migration
+func CreatePasswordResetsTableMigration() services.Migration {
+	return services.Migration{
+		Version:     5,
+		Description: "Create password resets table",
+		Up:          createPasswordResetsTableUp,
+		Down:        createPasswordResetsTableDown,
+		Checksum:    services.Checksum(createPasswordResetsTableSQL, dropPasswordResetsTableSQL),
+	}
+}
+
+// id is the SHA-256 of the emailed token. used_at is set when the token is
+// redeemed, which makes every token single use.
+const createPasswordResetsTableSQL = `
+		CREATE TABLE password_resets (
+			id CHAR(64) PRIMARY KEY,
+			user_id INT NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME NULL,
+			INDEX idx_password_resets_user_id (user_id),
+			INDEX idx_password_resets_expires_at (expires_at),
+			CONSTRAINT fk_password_resets_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`
+
+const dropPasswordResetsTableSQL = "DROP TABLE IF EXISTS password_resets"
+
+func createPasswordResetsTableUp(db services.Executor) error {
+	_, err := db.Exec(createPasswordResetsTableSQL)
+	return err
+}
+
+func createPasswordResetsTableDown(db services.Executor) error {
+	_, err := db.Exec(dropPasswordResetsTableSQL)
+	return err
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import "testing"
+
+func TestCreatePasswordResetsTableMigration_SQLite(t *testing.T) {
+	db, manager := newSQLiteMigrator(t, 5)
+
+	assertSchema(t, db, "password_resets", []string{"id", "user_id", "created_at", "expires_at", "used_at"},
+		"idx_password_resets_user_id", "idx_password_resets_expires_at")
+
+	// Tokens are stored by hash, so the same hash cannot be issued twice
+	userID := insertUser(t, db, "alice")
+	insertReset := "INSERT INTO password_resets (id, user_id, created_at, expires_at) VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)"
+	mustExec(t, db, insertReset, "hash-1", userID)
+	if _, err := db.Exec(insertReset, "hash-1", userID); err == nil {
+		t.Error("Expected a duplicate token hash to be rejected")
+	}
+	if _, err := db.Exec(insertReset, "hash-2", 999); err == nil {
+		t.Error("Expected a reset for an unknown user to be rejected")
+	}
+
+	// Pending resets are removed together with the user
+	mustExec(t, db, "DELETE FROM users WHERE id = ?", userID)
+	if n := countRows(t, db, "password_resets"); n != 0 {
+		t.Errorf("Expected the user's resets to be deleted with the user, got %d", n)
+	}
+
+	assertDropped(t, db, manager, "password_resets")
+}
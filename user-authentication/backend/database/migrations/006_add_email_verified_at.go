@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// AddEmailVerifiedAtMigration adds the email_verified_at column to users
+func AddEmailVerifiedAtMigration() services.Migration {
+	return services.Migration{
+		Version:     6,
+		Description: "Add email verified at to users",
+		Up:          addEmailVerifiedAtUp,
+		Down:        addEmailVerifiedAtDown,
+		Checksum:    services.Checksum(addEmailVerifiedAtSQL, dropEmailVerifiedAtSQL),
+	}
+}
+
+// email_verified_at stays NULL until the user opens the verification link.
+// Existing users are not backfilled because their addresses were never checked.
+const addEmailVerifiedAtSQL = "ALTER TABLE users ADD COLUMN email_verified_at DATETIME NULL AFTER password_hash"
+
+const dropEmailVerifiedAtSQL = "ALTER TABLE users DROP COLUMN email_verified_at"
+
+func addEmailVerifiedAtUp(db services.Executor) error {
+	_, err := db.Exec(addEmailVerifiedAtSQL)
+	return err
+}
+
+func addEmailVerifiedAtDown(db services.Executor) error {
+	_, err := db.Exec(dropEmailVerifiedAtSQL)
+	return err
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddEmailVerifiedAtMigration_SQLite(t *testing.T) {
+	db, manager := newSQLiteMigrator(t, 5)
+	userID := insertUser(t, db, "alice")
+
+	if err := manager.UpTo(6); err != nil {
+		t.Fatalf("Failed to apply migration 6: %v", err)
+	}
+	if columns := strings.Join(tableColumns(t, db, "users"), ","); !strings.Contains(columns, ",email_verified_at") {
+		t.Fatalf("Expected users.email_verified_at, got [%s]", columns)
+	}
+
+	// Existing users start unverified
+	var verified *string
+	if err := db.QueryRow("SELECT email_verified_at FROM users WHERE id = ?", userID).Scan(&verified); err != nil {
+		t.Fatalf("Failed to read user: %v", err)
+	}
+	if verified != nil {
+		t.Errorf("Expected existing users to be unverified, got %s", *verified)
+	}
+	mustExec(t, db, "UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ?", userID)
+
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if columns := strings.Join(tableColumns(t, db, "users"), ","); strings.Contains(columns, "email_verified_at") {
+		t.Errorf("Expected Down to drop users.email_verified_at, got [%s]", columns)
+	}
+	if n := countRows(t, db, "users"); n != 1 {
+		t.Errorf("Expected Down to keep the users, got %d", n)
+	}
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreateLoginFailuresTableMigration creates the login_failures table used to
+// throttle logins and grants the admin role the users:unlock permission
+func CreateLoginFailuresTableMigration() services.Migration {
+	return services.Migration{
+		Version:     7,
+		Description: "Create login failures table",
+		Up:          createLoginFailuresTableUp,
+		Down:        createLoginFailuresTableDown,
+		Checksum:    services.Checksum(append(createLoginFailuresTableSQL, dropLoginFailuresTableSQL...)...),
+	}
+}
+
+// scope is "account" (subject is the email address, registered or not) or
+// "ip" (subject is the client IP). A row only exists while there are recent
+// failures.
+var createLoginFailuresTableSQL = []string{`
+		CREATE TABLE login_failures (
+			scope VARCHAR(10) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			failures INT NOT NULL,
+			last_failure_at DATETIME NOT NULL,
+			PRIMARY KEY (scope, subject),
+			INDEX idx_login_failures_last_failure_at (last_failure_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`,
+	`INSERT INTO role_permissions (role_id, permission)
+		SELECT id, 'users:unlock' FROM roles WHERE name = 'admin'`,
+}
+
+var dropLoginFailuresTableSQL = []string{
+	"DELETE FROM role_permissions WHERE permission = 'users:unlock'",
+	"DROP TABLE IF EXISTS login_failures",
+}
+
+func createLoginFailuresTableUp(db services.Executor) error {
+	for _, query := range createLoginFailuresTableSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createLoginFailuresTableDown(db services.Executor) error {
+	for _, query := range dropLoginFailuresTableSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"user-authentication/models"
+)
+
+func TestCreateLoginFailuresTableMigration_SQLite(t *testing.T) {
+	db, manager := newSQLiteMigrator(t, 7)
+
+	assertSchema(t, db, "login_failures", []string{"scope", "subject", "failures", "last_failure_at"},
+		"idx_login_failures_last_failure_at")
+
+	// One row per scope and subject
+	insertFailure := "INSERT INTO login_failures (scope, subject, failures, last_failure_at) VALUES (?, ?, 1, CURRENT_TIMESTAMP)"
+	mustExec(t, db, insertFailure, "account", "alice@example.com")
+	mustExec(t, db, insertFailure, "ip", "alice@example.com")
+	if _, err := db.Exec(insertFailure, "account", "alice@example.com"); err == nil {
+		t.Error("Expected a second row for the same scope and subject to be rejected")
+	}
+
+	if !strings.Contains(strings.Join(rolePermissions(t, db, "admin"), ","), models.PermissionUsersUnlock) {
+		t.Error("Expected the admin role to be granted users:unlock")
+	}
+
+	assertDropped(t, db, manager, "login_failures")
+	if strings.Contains(strings.Join(rolePermissions(t, db, "admin"), ","), models.PermissionUsersUnlock) {
+		t.Error("Expected Down to revoke users:unlock")
+	}
+	if len(rolePermissions(t, db, "admin")) != 4 {
+		t.Errorf("Expected Down to keep the other admin permissions, got %v", rolePermissions(t, db, "admin"))
+	}
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreateTwoFactorTablesMigration creates the user_totp and recovery_codes tables
+func CreateTwoFactorTablesMigration() services.Migration {
+	return services.Migration{
+		Version:     8,
+		Description: "Create two factor tables",
+		Up:          createTwoFactorTablesUp,
+		Down:        createTwoFactorTablesDown,
+		Checksum:    services.Checksum(append(createTwoFactorTablesSQL, dropTwoFactorTablesSQL...)...),
+	}
+}
+
+// A user_totp row with a NULL enabled_at is an enrollment that has not been
+// confirmed with a code yet. last_used_step is the last accepted 30-second
+// time step, so that a code cannot be used twice. recovery_codes holds the
+// SHA-256 of each code.
+var createTwoFactorTablesSQL = []string{`
+		CREATE TABLE user_totp (
+			user_id INT PRIMARY KEY,
+			secret VARCHAR(64) NOT NULL,
+			enabled_at DATETIME NULL,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			CONSTRAINT fk_user_totp_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`, `
+		CREATE TABLE recovery_codes (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			code_hash CHAR(64) NOT NULL,
+			used_at DATETIME NULL,
+			UNIQUE INDEX idx_recovery_codes_user_code (user_id, code_hash),
+			CONSTRAINT fk_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`,
+}
+
+var dropTwoFactorTablesSQL = []string{
+	"DROP TABLE IF EXISTS recovery_codes",
+	"DROP TABLE IF EXISTS user_totp",
+}
+
+func createTwoFactorTablesUp(db services.Executor) error {
+	for _, query := range createTwoFactorTablesSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createTwoFactorTablesDown(db services.Executor) error {
+	for _, query := range dropTwoFactorTablesSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
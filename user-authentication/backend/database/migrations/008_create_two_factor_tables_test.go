@@ -0,0 +1,36 @@
+package migrations
+
+import "testing"
+
+func TestCreateTwoFactorTablesMigration_SQLite(t *testing.T) {
+	db, manager := newSQLiteMigrator(t, 8)
+
+	assertSchema(t, db, "user_totp", []string{"user_id", "secret", "enabled_at", "last_used_step", "created_at"})
+	assertSchema(t, db, "recovery_codes", []string{"id", "user_id", "code_hash", "used_at"},
+		"idx_recovery_codes_user_code")
+
+	alice, bob := insertUser(t, db, "alice"), insertUser(t, db, "bob")
+	insertTOTP := "INSERT INTO user_totp (user_id, secret, created_at) VALUES (?, 'secret', CURRENT_TIMESTAMP)"
+	mustExec(t, db, insertTOTP, alice)
+	if _, err := db.Exec(insertTOTP, alice); err == nil {
+		t.Error("Expected a second TOTP secret for the same user to be rejected")
+	}
+
+	// A code hash is unique per user, not globally
+	insertCode := "INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)"
+	mustExec(t, db, insertCode, alice, "hash-1")
+	mustExec(t, db, insertCode, bob, "hash-1")
+	if _, err := db.Exec(insertCode, alice, "hash-1"); err == nil {
+		t.Error("Expected a duplicate recovery code for the same user to be rejected")
+	}
+
+	mustExec(t, db, "DELETE FROM users WHERE id = ?", alice)
+	if n := countRows(t, db, "user_totp"); n != 0 {
+		t.Errorf("Expected the TOTP secret to be deleted with the user, got %d", n)
+	}
+	if n := countRows(t, db, "recovery_codes"); n != 1 {
+		t.Errorf("Expected only the other user's recovery code to remain, got %d", n)
+	}
+
+	assertDropped(t, db, manager, "user_totp", "recovery_codes")
+}
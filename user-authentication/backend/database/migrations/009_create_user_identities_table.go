@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreateUserIdentitiesTableMigration creates the user_identities table that
+// links accounts at external OpenID Connect providers to users
+func CreateUserIdentitiesTableMigration() services.Migration {
+	return services.Migration{
+		Version:     9,
+		Description: "Create user identities table",
+		Up:          createUserIdentitiesTableUp,
+		Down:        createUserIdentitiesTableDown,
+		Checksum:    services.Checksum(append(createUserIdentitiesTableSQL, dropUserIdentitiesTableSQL...)...),
+	}
+}
+
+// provider is the configured provider name and subject the provider's
+// stable user ID ("sub"). email is what the provider reported when the
+// identity was linked; users.email is not changed by it.
+var createUserIdentitiesTableSQL = []string{`
+		CREATE TABLE user_identities (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			provider VARCHAR(50) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			email VARCHAR(100) NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			UNIQUE INDEX idx_user_identities_provider_subject (provider, subject),
+			INDEX idx_user_identities_user_id (user_id),
+			CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`,
+}
+
+var dropUserIdentitiesTableSQL = []string{
+	"DROP TABLE IF EXISTS user_identities",
+}
+
+func createUserIdentitiesTableUp(db services.Executor) error {
+	for _, query := range createUserIdentitiesTableSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createUserIdentitiesTableDown(db services.Executor) error {
+	for _, query := range dropUserIdentitiesTableSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
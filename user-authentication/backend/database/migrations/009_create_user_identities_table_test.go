@@ -0,0 +1,26 @@
+package migrations
+
+import "testing"
+
+func TestCreateUserIdentitiesTableMigration_SQLite(t *testing.T) {
+	db, manager := newSQLiteMigrator(t, 9)
+
+	assertSchema(t, db, "user_identities", []string{"id", "user_id", "provider", "subject", "email", "created_at"},
+		"idx_user_identities_provider_subject", "idx_user_identities_user_id")
+
+	// An identity at a provider belongs to one user; a user may link several
+	alice, bob := insertUser(t, db, "alice"), insertUser(t, db, "bob")
+	insertIdentity := "INSERT INTO user_identities (user_id, provider, subject, created_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)"
+	mustExec(t, db, insertIdentity, alice, "google", "sub-1")
+	mustExec(t, db, insertIdentity, alice, "github", "sub-1")
+	if _, err := db.Exec(insertIdentity, bob, "google", "sub-1"); err == nil {
+		t.Error("Expected an identity linked to two users to be rejected")
+	}
+
+	mustExec(t, db, "DELETE FROM users WHERE id = ?", alice)
+	if n := countRows(t, db, "user_identities"); n != 0 {
+		t.Errorf("Expected the identities to be deleted with the user, got %d", n)
+	}
+
+	assertDropped(t, db, manager, "user_identities")
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"user-authentication/services"
+)
+
+// CreateAPITokensTableMigration creates the api_tokens table holding
+// personal access tokens for scripts
+func CreateAPITokensTableMigration() services.Migration {
+	return services.Migration{
+		Version:     10,
+		Description: "Create API tokens table",
+		Up:          createAPITokensTableUp,
+		Down:        createAPITokensTableDown,
+		Checksum:    services.Checksum(append(createAPITokensTableSQL, dropAPITokensTableSQL...)...),
+	}
+}
+
+// token_hash is the SHA-256 of the token; the token itself is only shown
+// when it is created. prefix is the start of the token, so that users can
+// tell their tokens apart. scopes is space-separated.
+var createAPITokensTableSQL = []string{`
+		CREATE TABLE api_tokens (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			token_hash CHAR(64) NOT NULL,
+			prefix VARCHAR(16) NOT NULL,
+			scopes VARCHAR(255) NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NULL,
+			last_used_at DATETIME NULL,
+			UNIQUE INDEX idx_api_tokens_token_hash (token_hash),
+			INDEX idx_api_tokens_user_id (user_id),
+			INDEX idx_api_tokens_expires_at (expires_at),
+			CONSTRAINT fk_api_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`,
+}
+
+var dropAPITokensTableSQL = []string{
+	"DROP TABLE IF EXISTS api_tokens",
+}
+
+func createAPITokensTableUp(db services.Executor) error {
+	for _, query := range createAPITokensTableSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createAPITokensTableDown(db services.Executor) error {
+	for _, query := range dropAPITokensTableSQL {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
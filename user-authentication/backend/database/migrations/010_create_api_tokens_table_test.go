@@ -0,0 +1,27 @@
+package migrations
+
+import "testing"
+
+func TestCreateAPITokensTableMigration_SQLite(t *testing.T) {
+	db, manager := newSQLiteMigrator(t, 10)
+
+	assertSchema(t, db, "api_tokens",
+		[]string{"id", "user_id", "name", "token_hash", "prefix", "scopes", "created_at", "expires_at", "last_used_at"},
+		"idx_api_tokens_token_hash", "idx_api_tokens_user_id", "idx_api_tokens_expires_at")
+
+	// Tokens are looked up by hash, so a hash may only be stored once
+	alice, bob := insertUser(t, db, "alice"), insertUser(t, db, "bob")
+	insertToken := "INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, created_at) VALUES (?, ?, ?, 'uat_abcd', 'posts:read', CURRENT_TIMESTAMP)"
+	mustExec(t, db, insertToken, alice, "ci", "hash-1")
+	mustExec(t, db, insertToken, alice, "ci", "hash-2")
+	if _, err := db.Exec(insertToken, bob, "backup", "hash-1"); err == nil {
+		t.Error("Expected a duplicate token hash to be rejected")
+	}
+
+	mustExec(t, db, "DELETE FROM users WHERE id = ?", alice)
+	if n := countRows(t, db, "api_tokens"); n != 0 {
+		t.Errorf("Expected the tokens to be deleted with the user, got %d", n)
+	}
+
+	assertDropped(t, db, manager, "api_tokens")
+}
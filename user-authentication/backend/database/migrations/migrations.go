@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"fmt"
+	"user-authentication/services"
+)
+
+// Register adds the built-in migrations to manager. When dir is not empty,
+// the NNN_name.up.sql / NNN_name.down.sql files in it are registered as well
+// and must continue the version numbering without gaps.
+func Register(manager *services.MigrationManager, dir string) error {
+	manager.AddMigration(CreateUsersTableMigration())
+	manager.AddMigration(CreateSessionsTableMigration())
+	manager.AddMigration(CreatePostsTableMigration())
+	manager.AddMigration(CreateRolesTablesMigration())
+	manager.AddMigration(CreatePasswordResetsTableMigration())
+	manager.AddMigration(AddEmailVerifiedAtMigration())
+	manager.AddMigration(CreateLoginFailuresTableMigration())
+	manager.AddMigration(CreateTwoFactorTablesMigration())
+	manager.AddMigration(CreateUserIdentitiesTableMigration())
+	manager.AddMigration(CreateAPITokensTableMigration())
+
+	if dir == "" {
+		return nil
+	}
+
+	if err := manager.AddMigrationsFromDir(dir); err != nil {
+		return fmt.Errorf("failed to load SQL migrations from %s: %w", dir, err)
+	}
+	return nil
+}
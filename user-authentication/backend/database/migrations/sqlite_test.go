@@ -0,0 +1,274 @@
+package migrations
+
+import (
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"user-authentication/models"
+	"user-authentication/services"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The migrations are written for MySQL. These rewrite the few MySQL-only
+// parts so that the tests can apply and roll them back on SQLite.
+var (
+	mysqlTableOptions = regexp.MustCompile(`\)\s*ENGINE=[^)]*$`)
+	mysqlInlineIndex  = regexp.MustCompile(`,\s*(UNIQUE )?INDEX (\w+) \(([^)]*)\)`)
+	mysqlCreateTable  = regexp.MustCompile(`^CREATE TABLE (\w+)`)
+	mysqlAfterColumn  = regexp.MustCompile(` AFTER \w+$`)
+)
+
+// toSQLite translates a MySQL statement into SQLite statements. Indexes
+// declared inside CREATE TABLE become separate CREATE INDEX statements.
+func toSQLite(query string) []string {
+	query = strings.TrimSpace(query)
+	query = mysqlTableOptions.ReplaceAllString(query, ")")
+	query = mysqlAfterColumn.ReplaceAllString(query, "")
+	query = strings.ReplaceAll(query, "INT AUTO_INCREMENT PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT")
+	query = strings.ReplaceAll(query, " ON UPDATE CURRENT_TIMESTAMP", "")
+
+	table := mysqlCreateTable.FindStringSubmatch(query)
+	if table == nil {
+		return []string{query}
+	}
+	var indexes []string
+	for _, index := range mysqlInlineIndex.FindAllStringSubmatch(query, -1) {
+		indexes = append(indexes, "CREATE "+index[1]+"INDEX "+index[2]+" ON "+table[1]+" ("+index[3]+")")
+	}
+	return append([]string{mysqlInlineIndex.ReplaceAllString(query, "")}, indexes...)
+}
+
+// sqliteExecutor runs the statements of a MySQL migration on SQLite
+type sqliteExecutor struct {
+	services.Executor
+}
+
+func (e sqliteExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	for i, statement := range toSQLite(query) {
+		if i > 0 {
+			args = nil
+		}
+		var err error
+		if result, err = e.Executor.Exec(statement, args...); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// registeredMigrations returns the built-in migrations in version order
+func registeredMigrations(t *testing.T) []services.Migration {
+	t.Helper()
+	manager := services.NewMigrationManager(nil)
+	if err := Register(manager, ""); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	migrations := manager.GetMigrations()
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// newSQLiteMigrator returns an in-memory SQLite database with foreign keys
+// enforced and a manager holding the built-in migrations, applied up to
+// version
+func newSQLiteMigrator(t *testing.T, version int) (*sql.DB, *services.MigrationManager) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	manager := services.NewMigrationManagerWithDialect(db, services.SQLiteDialect{})
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	for _, migration := range registeredMigrations(t) {
+		up, down := migration.Up, migration.Down
+		migration.Up = func(exec services.Executor) error { return up(sqliteExecutor{exec}) }
+		migration.Down = func(exec services.Executor) error { return down(sqliteExecutor{exec}) }
+		manager.AddMigration(migration)
+	}
+	if err := manager.UpTo(version); err != nil {
+		t.Fatalf("Failed to migrate to version %d: %v", version, err)
+	}
+	return db, manager
+}
+
+// mustExec runs a statement that test setup depends on
+func mustExec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s failed: %v", strings.Fields(query)[0], err)
+	}
+}
+
+// insertUser creates a user and returns its ID
+func insertUser(t *testing.T, db *sql.DB, username string) int64 {
+	t.Helper()
+	result, err := db.Exec("INSERT INTO users (username, email, password_hash) VALUES (?, ?, 'x')", username, username+"@example.com")
+	if err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+// countRows returns the number of rows in table
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		t.Fatalf("Failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+// assertDropped rolls back the latest migration and checks that it dropped
+// tables and left users alone
+func assertDropped(t *testing.T, db *sql.DB, manager *services.MigrationManager, tables ...string) {
+	t.Helper()
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	for _, table := range tables {
+		if schemaObjectExists(t, db, "table", table) {
+			t.Errorf("Expected Down to drop %s", table)
+		}
+	}
+	if !schemaObjectExists(t, db, "table", "users") {
+		t.Error("Expected Down to leave the users table alone")
+	}
+}
+
+// schemaObjectExists reports whether a table or index called name exists
+func schemaObjectExists(t *testing.T, db *sql.DB, kind, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = ? AND name = ?", kind, name).Scan(&count); err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	return count > 0
+}
+
+// tableColumns returns the column names of table in order
+func tableColumns(t *testing.T, db *sql.DB, table string) []string {
+	t.Helper()
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		t.Fatalf("Failed to read columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Failed to read columns of %s: %v", table, err)
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+// assertSchema checks that table exists with exactly columns and has indexes
+func assertSchema(t *testing.T, db *sql.DB, table string, columns []string, indexes ...string) {
+	t.Helper()
+	if got := strings.Join(tableColumns(t, db, table), ","); got != strings.Join(columns, ",") {
+		t.Errorf("Expected %s columns %v, got [%s]", table, columns, got)
+	}
+	for _, index := range indexes {
+		if !schemaObjectExists(t, db, "index", index) {
+			t.Errorf("Expected index %s on %s", index, table)
+		}
+	}
+}
+
+// rolePermissions returns the permissions granted to role
+func rolePermissions(t *testing.T, db *sql.DB, role string) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT permission FROM role_permissions
+		JOIN roles ON roles.id = role_permissions.role_id
+		WHERE roles.name = ? ORDER BY permission`, role)
+	if err != nil {
+		t.Fatalf("Failed to read permissions of %s: %v", role, err)
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			t.Fatalf("Failed to read permissions of %s: %v", role, err)
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions
+}
+
+func TestRegisteredMigrations(t *testing.T) {
+	checksums := make(map[string]int)
+	for i, migration := range registeredMigrations(t) {
+		if migration.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d", i, i+1, migration.Version)
+		}
+		if migration.Description == "" {
+			t.Errorf("Migration %d: expected a description", migration.Version)
+		}
+		if migration.Up == nil || migration.Down == nil {
+			t.Errorf("Migration %d: Up and Down functions should not be nil", migration.Version)
+		}
+		if migration.Checksum == "" {
+			t.Errorf("Migration %d: expected a checksum", migration.Version)
+		}
+		if other, ok := checksums[migration.Checksum]; ok {
+			t.Errorf("Migration %d has the same checksum as migration %d", migration.Version, other)
+		}
+		checksums[migration.Checksum] = migration.Version
+	}
+}
+
+// TestMigrations_SQLite applies every migration, rolls all of them back and
+// applies them again, so each Down must undo its Up completely
+func TestMigrations_SQLite(t *testing.T) {
+	migrations := registeredMigrations(t)
+	latest := migrations[len(migrations)-1].Version
+	db, manager := newSQLiteMigrator(t, latest)
+
+	// Every permission the code checks is granted to the admin role
+	granted := strings.Join(rolePermissions(t, db, "admin"), ",")
+	for _, permission := range models.Permissions {
+		if !strings.Contains(","+granted+",", ","+permission+",") {
+			t.Errorf("Expected permission '%s' to be granted to admin, got [%s]", permission, granted)
+		}
+	}
+
+	if err := manager.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	// Only the manager's own bookkeeping tables may remain
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%' AND name NOT IN ('migrations', 'migration_lock')")
+	if err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	var leftovers []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		leftovers = append(leftovers, name)
+	}
+	rows.Close()
+	if len(leftovers) != 0 {
+		t.Errorf("Expected rolling back all migrations to leave nothing behind, got %v", leftovers)
+	}
+
+	if err := manager.UpTo(latest); err != nil {
+		t.Errorf("Expected the migrations to apply again after a rollback, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxAPITokenNameLength matches api_tokens.name (VARCHAR(100))
+	maxAPITokenNameLength = 100
+	// maxAPITokenExpiryDays caps the expiry a user can choose for a token
+	maxAPITokenExpiryDays = 365
+)
+
+// APITokenHandler lets users manage personal API tokens for scripts, and
+// other services check them
+type APITokenHandler struct {
+	tokens *services.APITokenService
+	roles  repository.RoleRepository
+}
+
+// NewAPITokenHandler creates a new APITokenHandler. roles supplies the
+// role names returned by token introspection.
+func NewAPITokenHandler(tokens *services.APITokenService, roles repository.RoleRepository) *APITokenHandler {
+	return &APITokenHandler{tokens: tokens, roles: roles}
+}
+
+// RegisterRoutes mounts the token management endpoints on rg. They require a
+// logged in user and cannot be used with an API token, so that a leaked
+// token cannot create more.
+func (h *APITokenHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("", middleware.RequireUser(), h.ListTokens)
+	rg.POST("", middleware.RequireUser(), h.CreateToken)
+	rg.DELETE("/:id", middleware.RequireUser(), h.RevokeToken)
+}
+
+// ListTokens handles GET /api/auth/tokens
+func (h *APITokenHandler) ListTokens(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	tokens, err := h.tokens.List(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken handles POST /api/auth/tokens. The token is only returned by
+// this request.
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPITokenNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be 1-100 characters"})
+		return
+	}
+	scopes, ok := validateScopes(c, req.Scopes)
+	if !ok {
+		return
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPITokenExpiryDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 0 (no expiry) and 365"})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, apiToken, err := h.tokens.Create(c.Request.Context(), user.ID, name, scopes, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateAPITokenResponse{Token: token, APIToken: apiToken})
+}
+
+// RevokeToken handles DELETE /api/auth/tokens/:id
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	err = h.tokens.Revoke(c.Request.Context(), user.ID, id)
+	if errors.Is(err, repository.ErrAPITokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
+
+// Introspect handles POST /api/auth/tokens/introspect, with which services
+// that cannot reach the database, such as the Lambda API, check API tokens.
+// The response follows RFC 7662. Access control is left to the caller, e.g.
+// middleware.RequireAdminToken.
+func (h *APITokenHandler) Introspect(c *gin.Context) {
+	var req models.IntrospectTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	user, apiToken, err := h.tokens.Authenticate(c.Request.Context(), req.Token)
+	if errors.Is(err, services.ErrInvalidAPIToken) {
+		c.JSON(http.StatusOK, models.IntrospectTokenResponse{Active: false})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API token"})
+		return
+	}
+
+	roles, err := h.roles.ListByUser(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user roles"})
+		return
+	}
+
+	resp := models.IntrospectTokenResponse{
+		Active:   true,
+		Scope:    strings.Join(apiToken.Scopes, " "),
+		Subject:  strconv.Itoa(user.ID),
+		Username: user.Username,
+		Roles:    make([]string, 0, len(roles)),
+	}
+	for _, role := range roles {
+		resp.Roles = append(resp.Roles, role.Name)
+	}
+	if apiToken.ExpiresAt != nil {
+		resp.ExpiresAt = apiToken.ExpiresAt.Unix()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// validateScopes checks that scopes is a non-empty list of known scopes and
+// writes a 400 response on failure. Duplicates are dropped.
+func validateScopes(c *gin.Context, scopes []string) ([]string, bool) {
+	known := make(map[string]bool, len(models.Scopes))
+	for _, scope := range models.Scopes {
+		known[scope] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	valid := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !known[scope] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope " + strconv.Quote(scope), "scopes": models.Scopes})
+			return nil, false
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			valid = append(valid, scope)
+		}
+	}
+	if len(valid) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required", "scopes": models.Scopes})
+		return nil, false
+	}
+	return valid, true
+}
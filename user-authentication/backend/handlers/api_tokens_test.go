@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAPITokenRepository is an in-memory APITokenRepository for handler tests
+type fakeAPITokenRepository struct {
+	tokens map[int]*models.APIToken
+	nextID int
+}
+
+func (r *fakeAPITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	r.nextID++
+	token.ID = r.nextID
+	stored := *token
+	r.tokens[token.ID] = &stored
+	return nil
+}
+
+func (r *fakeAPITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrAPITokenNotFound
+}
+
+func (r *fakeAPITokenRepository) ListByUser(ctx context.Context, userID int) ([]*models.APIToken, error) {
+	tokens := make([]*models.APIToken, 0)
+	for id := 1; id <= r.nextID; id++ {
+		if token, ok := r.tokens[id]; ok && token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *fakeAPITokenRepository) Touch(ctx context.Context, id int, lastUsedAt time.Time) error {
+	if token, ok := r.tokens[id]; ok {
+		token.LastUsedAt = &lastUsedAt
+	}
+	return nil
+}
+
+func (r *fakeAPITokenRepository) Delete(ctx context.Context, userID, id int) error {
+	if token, ok := r.tokens[id]; !ok || token.UserID != userID {
+		return repository.ErrAPITokenNotFound
+	}
+	delete(r.tokens, id)
+	return nil
+}
+
+func (r *fakeAPITokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// setupAPITokenRouter returns a router that accepts API tokens next to the
+// X-User-ID header, with the token, post and role endpoints. Token
+// introspection requires the "lambda-secret" admin token. Users 1 (alice)
+// and adminUserID (an admin) exist.
+func setupAPITokenRouter() (*gin.Engine, *fakePostRepository) {
+	users := newFakeUserRepository()
+	users.users[1] = &models.User{ID: 1, Username: "alice", Email: "alice@example.com"}
+	users.users[adminUserID] = &models.User{ID: adminUserID, Username: "admin", Email: "admin@example.com"}
+
+	roles := newFakeRoleRepository()
+	roles.grants[adminUserID] = []string{"admin"}
+	tokens := services.NewAPITokenService(&fakeAPITokenRepository{tokens: make(map[int]*models.APIToken)}, users)
+	posts := newFakePostRepository()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(middleware.NewAPITokenAuthenticator(tokens, headerAuthenticator{})), middleware.LoadRoles(roles))
+	h := NewAPITokenHandler(tokens, roles)
+	tokenRoutes := r.Group("/api/auth/tokens")
+	h.RegisterRoutes(tokenRoutes)
+	tokenRoutes.POST("/introspect", middleware.RequireAdminToken(middleware.ParseAdminTokens("lambda:lambda-secret")), h.Introspect)
+	NewPostHandler(posts).RegisterRoutes(r.Group("/api/posts"))
+	NewRoleHandler(roles, users, nil).RegisterRoutes(r.Group("/api/admin", middleware.RequirePermission(models.PermissionRolesManage)))
+	return r, posts
+}
+
+// doTokenRequest sends a request with token as bearer token
+func doTokenRequest(r *gin.Engine, method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// createAPIToken creates a token as userID and returns it
+func createAPIToken(t *testing.T, r *gin.Engine, userID int, body string) (string, *models.APIToken) {
+	t.Helper()
+	w := doUserRequest(r, http.MethodPost, "/api/auth/tokens", body, userID)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.CreateAPITokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	return resp.Token, resp.APIToken
+}
+
+func TestAPITokens_CreateUseRevoke(t *testing.T) {
+	r, posts := setupAPITokenRouter()
+
+	token, apiToken := createAPIToken(t, r, 1, `{"name":"deploy","scopes":["posts:write","posts:write"],"expires_in_days":30}`)
+	if !strings.HasPrefix(token, services.APITokenPrefix) || len(apiToken.Scopes) != 1 || apiToken.ExpiresAt == nil {
+		t.Errorf("Expected a posts:write token expiring in 30 days, got %q %+v", token, apiToken)
+	}
+
+	w := doUserRequest(r, http.MethodGet, "/api/auth/tokens", "", 1)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"deploy"`) || strings.Contains(w.Body.String(), token) {
+		t.Errorf("Expected the token listed without its value, got %d %s", w.Code, w.Body.String())
+	}
+	if w := doUserRequest(r, http.MethodGet, "/api/auth/tokens", "", adminUserID); strings.Contains(w.Body.String(), "deploy") {
+		t.Errorf("Expected other users not to see the token, got %s", w.Body.String())
+	}
+
+	w = doTokenRequest(r, http.MethodPost, "/api/posts", `{"content":"from a script"}`, token)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if post := posts.posts[1]; post == nil || post.AuthorID != 1 {
+		t.Errorf("Expected a post by alice, got %+v", post)
+	}
+
+	// The token only has the scopes it was given
+	if w := doTokenRequest(r, http.MethodGet, "/api/posts/my", "", token); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without posts:read, got %d", w.Code)
+	}
+	// and cannot be used where no scope is accepted, such as creating tokens
+	if w := doTokenRequest(r, http.MethodPost, "/api/auth/tokens", `{"name":"more","scopes":["posts:read"]}`, token); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 when creating a token with a token, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodDelete, "/api/auth/tokens/"+strconv.Itoa(apiToken.ID), "", adminUserID)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's token, got %d", w.Code)
+	}
+	w = doUserRequest(r, http.MethodDelete, "/api/auth/tokens/"+strconv.Itoa(apiToken.ID), "", 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doTokenRequest(r, http.MethodPost, "/api/posts", `{"content":"after revoke"}`, token)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), "invalid_token") {
+		t.Errorf("Expected status 401 with invalid_token for a revoked token, got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestAPITokens_NoAdminPermissions(t *testing.T) {
+	r, _ := setupAPITokenRouter()
+	token, _ := createAPIToken(t, r, adminUserID, `{"name":"admin script","scopes":["posts:read","posts:write"]}`)
+
+	if w := doUserRequest(r, http.MethodGet, "/api/admin/roles", "", adminUserID); w.Code != http.StatusOK {
+		t.Fatalf("Expected the admin to list roles, got %d", w.Code)
+	}
+	if w := doTokenRequest(r, http.MethodGet, "/api/admin/roles", "", token); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for an admin's API token, got %d", w.Code)
+	}
+	if w := doTokenRequest(r, http.MethodGet, "/api/posts/my", "", token); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with posts:read, got %d", w.Code)
+	}
+}
+
+func TestAPITokens_Validation(t *testing.T) {
+	r, _ := setupAPITokenRouter()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing name", `{"scopes":["posts:read"]}`},
+		{"blank name", `{"name":"  ","scopes":["posts:read"]}`},
+		{"long name", `{"name":"` + strings.Repeat("n", 101) + `","scopes":["posts:read"]}`},
+		{"no scopes", `{"name":"ci","scopes":[]}`},
+		{"unknown scope", `{"name":"ci","scopes":["roles:manage"]}`},
+		{"negative expiry", `{"name":"ci","scopes":["posts:read"],"expires_in_days":-1}`},
+		{"long expiry", `{"name":"ci","scopes":["posts:read"],"expires_in_days":366}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doUserRequest(r, http.MethodPost, "/api/auth/tokens", tt.body, 1)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := doUserRequest(r, http.MethodPost, "/api/auth/tokens", `{"name":"ci","scopes":["posts:read"]}`, 0); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 when logged out, got %d", w.Code)
+	}
+}
+
+func TestAPITokens_Introspect(t *testing.T) {
+	r, _ := setupAPITokenRouter()
+	token, _ := createAPIToken(t, r, adminUserID, `{"name":"lambda","scopes":["posts:write"]}`)
+
+	if w := doTokenRequest(r, http.MethodPost, "/api/auth/tokens/introspect", `{"token":"`+token+`"}`, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without the introspection token, got %d", w.Code)
+	}
+
+	w := doTokenRequest(r, http.MethodPost, "/api/auth/tokens/introspect", `{"token":"`+token+`"}`, "lambda-secret")
+	var resp models.IntrospectTokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !resp.Active || resp.Subject != strconv.Itoa(adminUserID) || resp.Username != "admin" || resp.Scope != "posts:write" || len(resp.Roles) != 1 || resp.Roles[0] != "admin" || resp.ExpiresAt != 0 {
+		t.Errorf("Unexpected introspection response: %+v", resp)
+	}
+
+	w = doTokenRequest(r, http.MethodPost, "/api/auth/tokens/introspect", `{"token":"pat_unknown"}`, "lambda-secret")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"active":false}` {
+		t.Errorf(`Expected {"active":false}, got %d %s`, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usernamePattern limits usernames to what fits users.username (VARCHAR(50))
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,50}$`)
+
+const (
+	maxEmailLength    = 100 // users.email is VARCHAR(100)
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores everything after 72 bytes
+)
+
+// AuthFeatures are the optional parts of the login flow. A nil field
+// disables the feature.
+type AuthFeatures struct {
+	// Verification sends new users a verification email
+	Verification *services.EmailVerificationService
+	// Throttle slows down failed logins
+	Throttle *services.LoginThrottle
+	// TwoFactor asks users who have set up an authenticator app for a code
+	TwoFactor *services.TwoFactorService
+}
+
+// AuthHandler handles user registration and authentication requests
+type AuthHandler struct {
+	users        repository.UserRepository
+	hasher       services.PasswordHasher
+	auth         middleware.Authenticator
+	verification *services.EmailVerificationService
+	throttle     *services.LoginThrottle
+	twoFactor    *services.TwoFactorService
+}
+
+// NewAuthHandler creates a new AuthHandler that logs users in with auth
+func NewAuthHandler(users repository.UserRepository, hasher services.PasswordHasher, auth middleware.Authenticator, features AuthFeatures) *AuthHandler {
+	return &AuthHandler{
+		users:        users,
+		hasher:       hasher,
+		auth:         auth,
+		verification: features.Verification,
+		throttle:     features.Throttle,
+		twoFactor:    features.TwoFactor,
+	}
+}
+
+// RegisterRoutes mounts the auth endpoints on rg. The router must run
+// middleware.Authenticate with the same authenticator before these routes.
+// POST /refresh is only mounted when the authenticator issues refresh tokens,
+// and POST /login/2fa when two-factor authentication is configured.
+func (h *AuthHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/register", h.Register)
+	rg.POST("/login", h.Login)
+	if h.twoFactor != nil {
+		rg.POST("/login/2fa", h.LoginTwoFactor)
+	}
+	rg.POST("/logout", h.Logout)
+	rg.GET("/me", middleware.RequireUser(), h.Me)
+	if _, ok := h.auth.(middleware.Refresher); ok {
+		rg.POST("/refresh", h.Refresh)
+	}
+}
+
+// Register handles POST /api/auth/register
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	email, ok := validateRegistration(c, &req)
+	if !ok {
+		return
+	}
+
+	hash, err := h.hasher.Hash(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		return
+	}
+
+	user := &models.User{Username: req.Username, Email: email, PasswordHash: hash}
+	err = h.users.Create(c.Request.Context(), user)
+	switch {
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		return
+	case errors.Is(err, repository.ErrDuplicateUsername):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		return
+	}
+
+	// The user is registered either way and can ask for the email again
+	if h.verification != nil {
+		if err := h.verification.Send(c.Request.Context(), user); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// Login handles POST /api/auth/login. Users with two-factor authentication
+// get a models.TwoFactorChallenge instead of being logged in.
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !checkThrottle(c, h.throttle, email) {
+		return
+	}
+
+	user, err := h.users.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		// Hash anyway so unknown emails take as long as wrong passwords
+		_, _ = h.hasher.Hash(req.Password)
+		h.loginFailed(c, email)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	ok, err := services.VerifyPassword(user.PasswordHash, req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	if !ok {
+		h.loginFailed(c, email)
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// completeLogin logs in user, whose password or other first factor has been
+// checked, unless the email address has to be verified first or a second
+// factor is needed
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User) {
+	// Checked after the password so that it does not reveal registered emails
+	if h.verification != nil && h.verification.Required() == services.VerificationRequiredLogin && !user.EmailVerified() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+		return
+	}
+
+	if h.twoFactor != nil {
+		enabled, err := h.twoFactor.Enabled(c.Request.Context(), user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+			return
+		}
+		// The failure count is only cleared once the code is right too, so
+		// that a known password cannot be used to reset it
+		if enabled {
+			c.JSON(http.StatusOK, h.twoFactor.IssueChallenge(user))
+			return
+		}
+	}
+
+	h.loginSucceeded(c, user)
+}
+
+// LoginTwoFactor handles POST /api/auth/login/2fa, the second login step for
+// users with two-factor authentication
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.twoFactor.VerifyChallenge(ctx, req.Token)
+	if errors.Is(err, services.ErrInvalidTwoFactorChallenge) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired two-factor token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	// Wrong codes count as failed logins, so guessing codes is throttled
+	// like guessing passwords
+	if !checkThrottle(c, h.throttle, user.Email) {
+		return
+	}
+
+	err = h.twoFactor.Verify(ctx, user.ID, req.Code)
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		recordLoginFailure(c, h.throttle, user.Email)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		// Disabled since the password step; the password alone is enough now
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	h.loginSucceeded(c, user)
+}
+
+// checkThrottle writes a 429 response if a login for email from this client
+// has to wait. A nil throttle lets everything through.
+func checkThrottle(c *gin.Context, throttle *services.LoginThrottle, email string) bool {
+	if throttle == nil {
+		return true
+	}
+
+	wait, err := throttle.Check(c.Request.Context(), email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return false
+	}
+	if wait > 0 {
+		// Round up so that retrying after Retry-After is never too early
+		seconds := int((wait + time.Second - 1) / time.Second)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts", "retry_after": seconds})
+		return false
+	}
+	return true
+}
+
+// loginSucceeded clears the failed logins of user and logs them in
+func (h *AuthHandler) loginSucceeded(c *gin.Context, user *models.User) {
+	if h.throttle != nil {
+		if err := h.throttle.RecordSuccess(c.Request.Context(), user.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+			return
+		}
+	}
+
+	body, err := h.auth.Login(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+// loginFailed counts a failed login for email and writes the 401 response.
+// Unknown emails are counted too, so that the throttle does not reveal which
+// emails are registered.
+func (h *AuthHandler) loginFailed(c *gin.Context, email string) {
+	recordLoginFailure(c, h.throttle, email)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+}
+
+// recordLoginFailure counts a failed login for email from this client
+func recordLoginFailure(c *gin.Context, throttle *services.LoginThrottle, email string) {
+	if throttle != nil {
+		if err := throttle.RecordFailure(c.Request.Context(), email, c.ClientIP()); err != nil {
+			log.Printf("Failed to record failed login: %v", err)
+		}
+	}
+}
+
+// Logout handles POST /api/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.auth.Logout(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// Refresh handles POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	refresher, ok := h.auth.(middleware.Refresher)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token refresh is not supported"})
+		return
+	}
+
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	body, err := refresher.Refresh(c, req.RefreshToken)
+	if errors.Is(err, middleware.ErrUnauthenticated) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+// Me handles GET /api/auth/me
+func (h *AuthHandler) Me(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+	c.JSON(http.StatusOK, user)
+}
+
+// validateRegistration checks the registration fields and writes a 400
+// response on failure. It returns the normalized email address.
+func validateRegistration(c *gin.Context, req *models.RegisterRequest) (string, bool) {
+	if !usernamePattern.MatchString(req.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username must be 3-50 characters of letters, digits and underscores"})
+		return "", false
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > maxEmailLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return "", false
+	}
+
+	if !validatePassword(c, req.Password) {
+		return "", false
+	}
+
+	return email, true
+}
+
+// validatePassword checks the length of a new password and writes a 400
+// response on failure
+func validatePassword(c *gin.Context, password string) bool {
+	if len(password) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
+		return false
+	}
+
+	if len(password) > maxPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password cannot exceed 72 bytes"})
+		return false
+	}
+
+	return true
+}
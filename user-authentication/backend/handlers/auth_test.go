@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUserRepository is an in-memory UserRepository for handler tests
+type fakeUserRepository struct {
+	users  map[int]*models.User
+	nextID int
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{users: make(map[int]*models.User), nextID: 1}
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *models.User) error {
+	for _, u := range r.users {
+		if u.Email == user.Email {
+			return repository.ErrDuplicateEmail
+		}
+		if u.Username == user.Username {
+			return repository.ErrDuplicateUsername
+		}
+	}
+
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	stored := *user
+	r.users[user.ID] = &stored
+	r.nextID++
+	return nil
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *fakeUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *fakeUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	u, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	u.PasswordHash = passwordHash
+	return nil
+}
+
+func (r *fakeUserRepository) MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error {
+	if u, ok := r.users[id]; ok && u.EmailVerifiedAt == nil {
+		u.EmailVerifiedAt = &verifiedAt
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	for _, u := range r.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+// fakeSessionRepository is an in-memory SessionRepository for handler tests
+type fakeSessionRepository struct {
+	sessions map[string]*models.Session
+}
+
+func newFakeSessionRepository() *fakeSessionRepository {
+	return &fakeSessionRepository{sessions: make(map[string]*models.Session)}
+}
+
+func (r *fakeSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *fakeSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	if s, ok := r.sessions[id]; ok {
+		session := *s
+		return &session, nil
+	}
+	return nil, repository.ErrSessionNotFound
+}
+
+func (r *fakeSessionRepository) Touch(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error {
+	if s, ok := r.sessions[id]; ok {
+		s.LastSeenAt = lastSeenAt
+		s.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (r *fakeSessionRepository) Delete(ctx context.Context, id string) (bool, error) {
+	_, ok := r.sessions[id]
+	delete(r.sessions, id)
+	return ok, nil
+}
+
+func (r *fakeSessionRepository) DeleteByUser(ctx context.Context, userID int) error {
+	for id, s := range r.sessions {
+		if s.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	for id, s := range r.sessions {
+		if s.ExpiresAt.Before(now) {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func setupAuthRouter(users repository.UserRepository) *gin.Engine {
+	r, _ := setupSessionRouter(users)
+	return r
+}
+
+// setupSessionRouter returns a router with the auth routes and the session
+// repository backing them
+func setupSessionRouter(users repository.UserRepository) (*gin.Engine, *fakeSessionRepository) {
+	sessionRepo := newFakeSessionRepository()
+	sessions := services.NewSessionService(sessionRepo, users, &services.SessionConfig{TTL: time.Hour})
+	cookie := middleware.SessionCookie{Name: "session_id", Path: "/", SameSite: http.SameSiteLaxMode}
+
+	return setupRouterWith(users, middleware.NewSessionAuthenticator(sessions, cookie)), sessionRepo
+}
+
+// setupJWTRouter returns a router with the auth routes in JWT mode and the
+// repository holding the refresh tokens
+func setupJWTRouter(t *testing.T, users repository.UserRepository) (*gin.Engine, *fakeSessionRepository) {
+	sessionRepo := newFakeSessionRepository()
+	return setupJWTRouterWith(t, users, sessionRepo), sessionRepo
+}
+
+// setupJWTRouterWith returns a router with the auth routes in JWT mode that
+// keeps its refresh tokens in sessionRepo
+func setupJWTRouterWith(t *testing.T, users repository.UserRepository, sessionRepo repository.SessionRepository) *gin.Engine {
+	key, err := services.ParseJWTKey(services.JWTAlgorithmHS256, "test", strings.Repeat("k", 32))
+	if err != nil {
+		t.Fatalf("ParseJWTKey failed: %v", err)
+	}
+	tokens, err := services.NewJWTService(&services.JWTConfig{
+		Algorithm:  services.JWTAlgorithmHS256,
+		Keys:       []services.JWTKey{key},
+		Issuer:     "test",
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTService failed: %v", err)
+	}
+
+	refresh := middleware.NewRefreshTokenService(sessionRepo, users, tokens)
+	return setupRouterWith(users, middleware.NewJWTAuthenticator(tokens, users, refresh, newFakeRoleRepository()))
+}
+
+func setupRouterWith(users repository.UserRepository, auth middleware.Authenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(auth))
+	h := NewAuthHandler(users, &services.BcryptHasher{Cost: bcrypt.MinCost}, auth, AuthFeatures{})
+	h.RegisterRoutes(r.Group("/api/auth"))
+	return r
+}
+
+func doJSONRequest(r *gin.Engine, method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func doBearerRequest(r *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// sessionCookie returns the session cookie set by a response. Like a
+// browser, the last Set-Cookie for the name wins.
+func sessionCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	var found *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session_id" {
+			found = cookie
+		}
+	}
+	return found
+}
+
+func TestRegister(t *testing.T) {
+	users := newFakeUserRepository()
+	r := setupAuthRouter(users)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"Alice@Example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if strings.Contains(w.Body.String(), "password") || strings.Contains(w.Body.String(), "$2a$") {
+		t.Errorf("Response must not contain the password hash: %s", w.Body.String())
+	}
+
+	var user models.User
+	if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if user.ID != 1 || user.Email != "alice@example.com" {
+		t.Errorf("Expected user 1 with normalized email, got %+v", user)
+	}
+
+	ok, err := services.VerifyPassword(users.users[1].PasswordHash, "s3cret-pass")
+	if err != nil || !ok {
+		t.Errorf("Expected stored hash to verify, got %v (err: %v)", ok, err)
+	}
+}
+
+func TestRegister_Conflict(t *testing.T) {
+	r := setupAuthRouter(newFakeUserRepository())
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice2","email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "Email") {
+		t.Errorf("Expected 409 for duplicate email, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"other@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "Username") {
+		t.Errorf("Expected 409 for duplicate username, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegister_Validation(t *testing.T) {
+	r := setupAuthRouter(newFakeUserRepository())
+
+	bodies := map[string]string{
+		"invalid json":     `{"username":`,
+		"missing fields":   `{"username":"alice"}`,
+		"short username":   `{"username":"al","email":"alice@example.com","password":"s3cret-pass"}`,
+		"invalid username": `{"username":"alice smith","email":"alice@example.com","password":"s3cret-pass"}`,
+		"invalid email":    `{"username":"alice","email":"not-an-email","password":"s3cret-pass"}`,
+		"display name":     `{"username":"alice","email":"Alice <alice@example.com>","password":"s3cret-pass"}`,
+		"short password":   `{"username":"alice","email":"alice@example.com","password":"short"}`,
+		"long password":    `{"username":"alice","email":"alice@example.com","password":"` + strings.Repeat("a", 73) + `"}`,
+	}
+
+	for name, body := range bodies {
+		w := doJSONRequest(r, http.MethodPost, "/api/auth/register", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestLoginMeLogout(t *testing.T) {
+	r, sessionRepo := setupSessionRouter(newFakeUserRepository())
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"Alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	cookie := sessionCookie(w)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("Expected login to set a session cookie")
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Expected HttpOnly SameSite=Lax cookie, got %+v", cookie)
+	}
+	if _, ok := sessionRepo.sessions[cookie.Value]; ok {
+		t.Error("Session must be stored by hash, not by the raw token")
+	}
+	if len(sessionRepo.sessions) != 1 {
+		t.Errorf("Expected 1 stored session, got %d", len(sessionRepo.sessions))
+	}
+
+	w = doJSONRequest(r, http.MethodGet, "/api/auth/me", "", cookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"username":"alice"`) {
+		t.Errorf("Expected /me to return alice, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/logout", "", cookie)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if cleared := sessionCookie(w); cleared == nil || cleared.MaxAge >= 0 {
+		t.Errorf("Expected logout to clear the cookie, got %+v", cleared)
+	}
+	if len(sessionRepo.sessions) != 0 {
+		t.Errorf("Expected logout to revoke the session, %d left", len(sessionRepo.sessions))
+	}
+
+	// The old cookie must not work after logout
+	w = doJSONRequest(r, http.MethodGet, "/api/auth/me", "", cookie)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 after logout, got %d", w.Code)
+	}
+}
+
+func TestLogin_InvalidCredentials(t *testing.T) {
+	r, sessionRepo := setupSessionRouter(newFakeUserRepository())
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	for name, body := range map[string]string{
+		"wrong password": `{"email":"alice@example.com","password":"wrong-pass"}`,
+		"unknown email":  `{"email":"bob@example.com","password":"s3cret-pass"}`,
+	} {
+		w := doJSONRequest(r, http.MethodPost, "/api/auth/login", body)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected status 401, got %d", name, w.Code)
+		}
+		if sessionCookie(w) != nil {
+			t.Errorf("%s: expected no session cookie", name)
+		}
+	}
+
+	if len(sessionRepo.sessions) != 0 {
+		t.Errorf("Expected no sessions, got %d", len(sessionRepo.sessions))
+	}
+}
+
+func TestLogin_ReplacesExistingSession(t *testing.T) {
+	r, sessionRepo := setupSessionRouter(newFakeUserRepository())
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	body := `{"email":"alice@example.com","password":"s3cret-pass"}`
+	first := sessionCookie(doJSONRequest(r, http.MethodPost, "/api/auth/login", body))
+	second := sessionCookie(doJSONRequest(r, http.MethodPost, "/api/auth/login", body, first))
+
+	if first == nil || second == nil || first.Value == second.Value {
+		t.Fatal("Expected a new session token on every login")
+	}
+	if len(sessionRepo.sessions) != 1 {
+		t.Errorf("Expected the previous session to be revoked, got %d sessions", len(sessionRepo.sessions))
+	}
+}
+
+func TestMe_Unauthenticated(t *testing.T) {
+	r := setupAuthRouter(newFakeUserRepository())
+
+	w := doJSONRequest(r, http.MethodGet, "/api/auth/me", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodGet, "/api/auth/me", "", &http.Cookie{Name: "session_id", Value: "bogus"})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for unknown session, got %d", w.Code)
+	}
+	if cleared := sessionCookie(w); cleared == nil || cleared.MaxAge >= 0 {
+		t.Error("Expected an unknown session cookie to be cleared")
+	}
+}
+
+func TestJWTLoginRefreshLogout(t *testing.T) {
+	r, sessionRepo := setupJWTRouter(t, newFakeUserRepository())
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if sessionCookie(w) != nil {
+		t.Error("Expected no session cookie in JWT mode")
+	}
+
+	var tokens models.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.TokenType != "Bearer" || tokens.ExpiresIn != 60 {
+		t.Fatalf("Unexpected token response: %+v", tokens)
+	}
+
+	w = doBearerRequest(r, http.MethodGet, "/api/auth/me", tokens.AccessToken)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"username":"alice"`) {
+		t.Errorf("Expected /me to return alice, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doBearerRequest(r, http.MethodGet, "/api/auth/me", tokens.AccessToken+"x")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected 401 with WWW-Authenticate for a bad token, got %d", w.Code)
+	}
+
+	// Refreshing rotates the refresh token
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var refreshed models.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Error("Expected a new refresh token")
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a used refresh token to be rejected, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/logout", `{"refresh_token":"`+refreshed.RefreshToken+`"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if len(sessionRepo.sessions) != 0 {
+		t.Errorf("Expected logout to revoke the refresh token, %d left", len(sessionRepo.sessions))
+	}
+}
+
+// racingSessionRepository deletes a session right after looking it up, as if
+// a concurrent refresh with the same token had consumed it in between
+type racingSessionRepository struct {
+	*fakeSessionRepository
+}
+
+func (r racingSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	session, err := r.fakeSessionRepository.Get(ctx, id)
+	if err == nil {
+		r.fakeSessionRepository.Delete(ctx, id)
+	}
+	return session, err
+}
+
+func TestRefresh_ConcurrentReuse(t *testing.T) {
+	users := newFakeUserRepository()
+	sessionRepo := newFakeSessionRepository()
+	r := setupJWTRouterWith(t, users, racingSessionRepository{sessionRepo})
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	var tokens models.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// The token is valid when looked up but gone by the time it is revoked
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a refresh token consumed concurrently to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(sessionRepo.sessions) != 0 {
+		t.Errorf("Expected no new refresh token to be issued, %d stored", len(sessionRepo.sessions))
+	}
+}
+
+func TestRefresh_SessionMode(t *testing.T) {
+	r := setupAuthRouter(newFakeUserRepository())
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/refresh", `{"refresh_token":"x"}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without refresh support, got %d", w.Code)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"user-authentication/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRFHandler issues CSRF tokens to browser clients
+type CSRFHandler struct {
+	csrf *middleware.CSRFProtection
+}
+
+// NewCSRFHandler creates a new CSRFHandler
+func NewCSRFHandler(csrf *middleware.CSRFProtection) *CSRFHandler {
+	return &CSRFHandler{csrf: csrf}
+}
+
+// RegisterRoutes mounts the CSRF token endpoint on rg
+func (h *CSRFHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/csrf", h.Token)
+}
+
+// Token handles GET /api/auth/csrf. The token is bound to the current
+// session, so clients fetch a new one after logging in or out.
+func (h *CSRFHandler) Token(c *gin.Context) {
+	token, err := h.csrf.Token(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create CSRF token"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"csrf_token": token, "header": middleware.CSRFHeader})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-authentication/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRFToken(t *testing.T) {
+	csrf, err := middleware.NewCSRFProtection([]byte("0123456789abcdef0123456789abcdef"), "session_id", nil)
+	if err != nil {
+		t.Fatalf("Failed to create CSRF protection: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(csrf.Protect())
+	NewCSRFHandler(csrf).RegisterRoutes(r.Group("/api/auth"))
+	r.POST("/api/posts", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	session := &http.Cookie{Name: "session_id", Value: "alice-session"}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/csrf", nil)
+	req.AddCookie(session)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("Expected Cache-Control 'no-store', got '%s'", cacheControl)
+	}
+	var response struct {
+		CSRFToken string `json:"csrf_token"`
+		Header    string `json:"header"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.CSRFToken == "" || response.Header != middleware.CSRFHeader {
+		t.Fatalf("Unexpected response: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/posts", nil)
+	req.AddCookie(session)
+	req.Header.Set(response.Header, response.CSRFToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected the issued token to be accepted, got status %d", w.Code)
+	}
+}
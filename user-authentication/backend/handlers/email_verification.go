@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"user-authentication/models"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailVerificationHandler handles email verification links
+type EmailVerificationHandler struct {
+	verification *services.EmailVerificationService
+	throttle     *services.LoginThrottle
+}
+
+// NewEmailVerificationHandler creates a new EmailVerificationHandler. Resends
+// are counted by throttle like failed logins, so that the endpoint cannot be
+// used to flood an inbox; nil disables that.
+func NewEmailVerificationHandler(verification *services.EmailVerificationService, throttle *services.LoginThrottle) *EmailVerificationHandler {
+	return &EmailVerificationHandler{verification: verification, throttle: throttle}
+}
+
+// RegisterRoutes mounts the email verification endpoints on rg
+func (h *EmailVerificationHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/verify-email", h.Verify)
+	rg.POST("/verify-email/resend", h.Resend)
+}
+
+// Verify handles GET /api/auth/verify-email?token=...
+func (h *EmailVerificationHandler) Verify(c *gin.Context) {
+	user, err := h.verification.Verify(c.Request.Context(), c.Query("token"))
+	if errors.Is(err, services.ErrInvalidVerificationToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified", "user": user})
+}
+
+// Resend handles POST /api/auth/verify-email/resend. It does not require
+// login because unverified users may not be allowed to log in, and it
+// responds the same way whether or not the email is registered.
+func (h *EmailVerificationHandler) Resend(c *gin.Context) {
+	var req models.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	// Every resend is counted, whether or not the email is registered, so
+	// that the throttle does not tell either
+	if !checkThrottle(c, h.throttle, req.Email) {
+		return
+	}
+	recordLoginFailure(c, h.throttle, req.Email)
+
+	// A failure is only logged, otherwise it would tell that the email exists
+	if err := h.verification.Resend(c.Request.Context(), req.Email); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered and not yet verified, a verification link has been sent"})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setupVerificationRouter returns a session mode router with the auth and
+// email verification routes, requiring verification as required and
+// throttled by throttle, and the mailer receiving the verification links
+func setupVerificationRouter(t *testing.T, users repository.UserRepository, required string, throttle *services.LoginThrottle) (*gin.Engine, *fakeMailer) {
+	t.Helper()
+	mailer := &fakeMailer{}
+	verification, err := services.NewEmailVerificationService(users, mailer, &services.EmailVerificationConfig{
+		Secret:   []byte(strings.Repeat("s", 32)),
+		TTL:      time.Hour,
+		URL:      "http://localhost:8080/api/auth/verify-email",
+		Required: required,
+	})
+	if err != nil {
+		t.Fatalf("NewEmailVerificationService failed: %v", err)
+	}
+
+	sessions := services.NewSessionService(newFakeSessionRepository(), users, &services.SessionConfig{TTL: time.Hour})
+	auth := middleware.NewSessionAuthenticator(sessions, middleware.SessionCookie{Name: "session_id", Path: "/", SameSite: http.SameSiteLaxMode})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(auth))
+	NewAuthHandler(users, &services.BcryptHasher{Cost: bcrypt.MinCost}, auth, AuthFeatures{Verification: verification, Throttle: throttle}).RegisterRoutes(r.Group("/api/auth"))
+	NewEmailVerificationHandler(verification, throttle).RegisterRoutes(r.Group("/api/auth"))
+	return r, mailer
+}
+
+// verificationPath returns the path and query of the link in the last email
+func verificationPath(t *testing.T, mailer *fakeMailer) string {
+	t.Helper()
+	if len(mailer.messages) == 0 {
+		t.Fatal("Expected a verification email")
+	}
+	body := mailer.messages[len(mailer.messages)-1].Body
+	link, err := url.Parse(strings.Fields(body[strings.Index(body, "http://"):])[0])
+	if err != nil {
+		t.Fatalf("Invalid verification link: %v", err)
+	}
+	return link.RequestURI()
+}
+
+func TestEmailVerification(t *testing.T) {
+	users := newFakeUserRepository()
+	r, mailer := setupVerificationRouter(t, users, services.VerificationRequiredLogin, nil)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"email_verified_at":null`) {
+		t.Fatalf("Expected an unverified user, got %d %s", w.Code, w.Body.String())
+	}
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "alice@example.com" {
+		t.Fatalf("Expected a verification email to alice@example.com, got %+v", mailer.messages)
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 before verification, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/verify-email/resend", `{"email":"alice@example.com"}`)
+	if w.Code != http.StatusAccepted || len(mailer.messages) != 2 {
+		t.Errorf("Expected status 202 and a second email, got %d and %d emails", w.Code, len(mailer.messages))
+	}
+
+	w = doJSONRequest(r, http.MethodGet, "/api/auth/verify-email?token=bogus", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a bogus token, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodGet, verificationPath(t, mailer), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if users.users[1].EmailVerifiedAt == nil {
+		t.Error("Expected the email to be verified")
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after verification, got %d", w.Code)
+	}
+}
+
+func TestEmailVerification_NotRequiredForLogin(t *testing.T) {
+	r, _ := setupVerificationRouter(t, newFakeUserRepository(), services.VerificationRequiredPosts, nil)
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected unverified users to log in, got %d", w.Code)
+	}
+}
+
+func TestEmailVerification_ResendThrottled(t *testing.T) {
+	throttle := services.NewLoginThrottle(&fakeLoginFailureRepository{failures: make(map[string]*models.LoginFailure)}, &services.LoginThrottleConfig{
+		MaxFailures: 2,
+		BackoffBase: time.Minute,
+		BackoffMax:  time.Minute,
+		Lockout:     time.Hour,
+		Window:      time.Hour,
+	})
+	r, mailer := setupVerificationRouter(t, newFakeUserRepository(), services.VerificationRequiredLogin, throttle)
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/verify-email/resend", `{"email":"alice@example.com"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/verify-email/resend", `{"email":"alice@example.com"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Expected Retry-After 60, got '%s'", retryAfter)
+	}
+	if len(mailer.messages) != 2 {
+		t.Errorf("Expected the registration and one resent email, got %d emails", len(mailer.messages))
+	}
+
+	// Unknown emails are throttled the same way
+	doJSONRequest(r, http.MethodPost, "/api/auth/verify-email/resend", `{"email":"nobody@example.com"}`)
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/verify-email/resend", `{"email":"nobody@example.com"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for an unknown email, got %d", w.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginLockoutHandler lets admins clear the failed logins that lock an
+// account or IP out. Every unlock is written to the audit log.
+type LoginLockoutHandler struct {
+	throttle *services.LoginThrottle
+	users    repository.UserRepository
+	audit    *log.Logger
+}
+
+// NewLoginLockoutHandler creates a new LoginLockoutHandler that audits to audit
+func NewLoginLockoutHandler(throttle *services.LoginThrottle, users repository.UserRepository, audit *log.Logger) *LoginLockoutHandler {
+	return &LoginLockoutHandler{throttle: throttle, users: users, audit: audit}
+}
+
+// RegisterRoutes mounts the unlock endpoints on rg. Access control is left to
+// the caller, e.g. middleware.RequirePermission(models.PermissionUsersUnlock).
+func (h *LoginLockoutHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/users/:id/unlock", h.UnlockUser)
+	rg.POST("/ips/:ip/unlock", h.UnlockIP)
+}
+
+// UnlockUser handles POST /api/admin/users/:id/unlock
+func (h *LoginLockoutHandler) UnlockUser(c *gin.Context) {
+	user, ok := findUserParam(c, h.users)
+	if !ok {
+		return
+	}
+
+	err := h.throttle.UnlockAccount(c.Request.Context(), user.Email)
+	h.audit.Printf("unlock account user=%d by=%s ip=%s result=%q", user.ID, auditActor(c), c.ClientIP(), auditResult(err))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked", "user_id": user.ID})
+}
+
+// UnlockIP handles POST /api/admin/ips/:ip/unlock
+func (h *LoginLockoutHandler) UnlockIP(c *gin.Context) {
+	ip := c.Param("ip")
+	if net.ParseIP(ip) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+
+	err := h.throttle.UnlockIP(c.Request.Context(), ip)
+	h.audit.Printf("unlock ip=%s by=%s from=%s result=%q", ip, auditActor(c), c.ClientIP(), auditResult(err))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock IP address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "IP address unlocked", "ip": ip})
+}
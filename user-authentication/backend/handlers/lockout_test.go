@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeLoginFailureRepository is an in-memory LoginFailureRepository for handler tests
+type fakeLoginFailureRepository struct {
+	failures map[string]*models.LoginFailure
+}
+
+func (r *fakeLoginFailureRepository) Get(ctx context.Context, scope, subject string) (*models.LoginFailure, error) {
+	if stored, ok := r.failures[scope+":"+subject]; ok {
+		failure := *stored
+		return &failure, nil
+	}
+	return nil, repository.ErrLoginFailureNotFound
+}
+
+func (r *fakeLoginFailureRepository) Increment(ctx context.Context, scope, subject string, now, resetBefore time.Time) (*models.LoginFailure, error) {
+	key := scope + ":" + subject
+	stored, ok := r.failures[key]
+	if !ok || stored.LastFailureAt.Before(resetBefore) {
+		stored = &models.LoginFailure{Scope: scope, Subject: subject}
+		r.failures[key] = stored
+	}
+	stored.Failures++
+	stored.LastFailureAt = now
+	failure := *stored
+	return &failure, nil
+}
+
+func (r *fakeLoginFailureRepository) Delete(ctx context.Context, scope, subject string) error {
+	delete(r.failures, scope+":"+subject)
+	return nil
+}
+
+func (r *fakeLoginFailureRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// setupLockoutRouter returns a router with throttled logins and the unlock
+// endpoints. adminUserID has the admin role.
+func setupLockoutRouter(config services.LoginThrottleConfig, audit *bytes.Buffer) (*gin.Engine, *fakeLoginFailureRepository) {
+	users := newFakeUserRepository()
+	failures := &fakeLoginFailureRepository{failures: make(map[string]*models.LoginFailure)}
+	throttle := services.NewLoginThrottle(failures, &config)
+	roles := newFakeRoleRepository()
+	roles.grants[adminUserID] = []string{"admin"}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(headerAuthenticator{}), middleware.LoadRoles(roles))
+	NewAuthHandler(users, &services.BcryptHasher{Cost: bcrypt.MinCost}, headerAuthenticator{}, AuthFeatures{Throttle: throttle}).RegisterRoutes(r.Group("/api/auth"))
+	NewLoginLockoutHandler(throttle, users, log.New(audit, "", 0)).RegisterRoutes(r.Group("/api/admin", middleware.RequirePermission(models.PermissionUsersUnlock)))
+	return r, failures
+}
+
+func TestLogin_Throttled(t *testing.T) {
+	var audit bytes.Buffer
+	r, failures := setupLockoutRouter(services.LoginThrottleConfig{
+		MaxFailures: 2,
+		BackoffBase: time.Minute,
+		BackoffMax:  time.Minute,
+		Lockout:     time.Hour,
+		Window:      time.Hour,
+	}, &audit)
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"wrong-pass"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d", w.Code)
+	}
+
+	// Even the right password has to wait
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Expected Retry-After 60, got '%s'", retryAfter)
+	}
+
+	// Unknown emails are throttled the same way
+	doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"nobody@example.com","password":"wrong-pass"}`)
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"nobody@example.com","password":"wrong-pass"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for an unknown email, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/admin/users/1/unlock", "", 1)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a user without users:unlock, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/admin/users/1/unlock", "", adminUserID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(audit.String(), "unlock account user=1 by=user:user99") {
+		t.Errorf("Expected the unlock to be audited, got %q", audit.String())
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after unlocking, got %d", w.Code)
+	}
+	if _, ok := failures.failures["account:alice@example.com"]; ok {
+		t.Error("Expected a successful login to clear the failures")
+	}
+}
+
+func TestUnlockIP(t *testing.T) {
+	var audit bytes.Buffer
+	r, failures := setupLockoutRouter(services.LoginThrottleConfig{
+		IPMaxFailures: 1,
+		Lockout:       time.Hour,
+		Window:        time.Hour,
+	}, &audit)
+
+	doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"nobody@example.com","password":"wrong-pass"}`)
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"other@example.com","password":"wrong-pass"}`)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "3600" {
+		t.Fatalf("Expected status 429 with Retry-After 3600, got %d '%s'", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/admin/ips/not-an-ip/unlock", "", adminUserID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid IP, got %d", w.Code)
+	}
+
+	// httptest requests come from 192.0.2.1
+	w = doUserRequest(r, http.MethodPost, "/api/admin/ips/192.0.2.1/unlock", "", adminUserID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(failures.failures) != 0 {
+		t.Errorf("Expected the IP's failures to be cleared, got %v", failures.failures)
+	}
+}
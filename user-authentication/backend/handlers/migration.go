@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"user-authentication/middleware"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MigrationHandler exposes the migration manager over HTTP. Every call is
+// written to the audit log together with the admin or user that made it.
+type MigrationHandler struct {
+	manager *services.MigrationManager
+	audit   *log.Logger
+}
+
+// NewMigrationHandler creates a new MigrationHandler that audits to audit
+func NewMigrationHandler(manager *services.MigrationManager, audit *log.Logger) *MigrationHandler {
+	return &MigrationHandler{manager: manager, audit: audit}
+}
+
+// RegisterRoutes mounts the migration endpoints on rg
+func (h *MigrationHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/up", h.Up)
+	rg.POST("/down", h.Down)
+	rg.GET("/status", h.Status)
+}
+
+// Up handles POST /api/migrate/up
+func (h *MigrationHandler) Up(c *gin.Context) {
+	err := h.manager.Up()
+	h.record(c, "up", err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Migrations applied successfully"})
+}
+
+// Down handles POST /api/migrate/down
+func (h *MigrationHandler) Down(c *gin.Context) {
+	err := h.manager.Down()
+	h.record(c, "down", err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Migration rolled back successfully"})
+}
+
+// Status handles GET /api/migrate/status
+func (h *MigrationHandler) Status(c *gin.Context) {
+	status, err := h.manager.Status()
+	h.record(c, "status", err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"migrations": status})
+}
+
+// record writes one audit line for a migration request
+func (h *MigrationHandler) record(c *gin.Context, action string, err error) {
+	h.audit.Printf("migrate action=%s admin=%s ip=%s result=%q", action, auditActor(c), c.ClientIP(), auditResult(err))
+}
+
+// auditActor names who made an admin request: the name of the admin token,
+// or "user:<username>" for a signed-in user
+func auditActor(c *gin.Context) string {
+	if admin := c.GetString(middleware.AdminContextKey); admin != "" {
+		return admin
+	}
+	if user, ok := middleware.CurrentUser(c); ok {
+		return "user:" + user.Username
+	}
+	return "-"
+}
+
+// auditResult describes the outcome of an audited action
+func auditResult(err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newMigrationRouter(t *testing.T, audit *bytes.Buffer) *gin.Engine {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	manager := services.NewMigrationManagerWithDialect(db, services.SQLiteDialect{})
+	manager.AddMigration(services.Migration{
+		Version:     1,
+		Description: "Create widgets table",
+		Up: func(exec services.Executor) error {
+			_, err := exec.Exec("CREATE TABLE widgets (id INTEGER)")
+			return err
+		},
+		Down: func(exec services.Executor) error {
+			_, err := exec.Exec("DROP TABLE widgets")
+			return err
+		},
+	})
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	// User 1 is an admin; user 2 has no roles
+	roles := newFakeRoleRepository()
+	roles.grants[1] = []string{"admin"}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(headerAuthenticator{}), middleware.LoadRoles(roles))
+	h := NewMigrationHandler(manager, log.New(audit, "", 0))
+	h.RegisterRoutes(r.Group("/api/migrate", middleware.RequireAdminTokenOr(middleware.ParseAdminTokens("alice:s3cret"), middleware.RequirePermission(models.PermissionMigrationsRun))))
+	return r
+}
+
+func doMigrationRequest(r *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMigrationHandler_RequiresAdmin(t *testing.T) {
+	var audit bytes.Buffer
+	r := newMigrationRouter(t, &audit)
+
+	for _, path := range []string{"/api/migrate/up", "/api/migrate/down"} {
+		w := doMigrationRequest(r, http.MethodPost, path, "")
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s without token: expected status 401, got %d", path, w.Code)
+		}
+	}
+
+	w := doMigrationRequest(r, http.MethodGet, "/api/migrate/status", "wrong")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a wrong token, got %d", w.Code)
+	}
+
+	if audit.Len() != 0 {
+		t.Errorf("Rejected requests must not reach the handler, got audit log %q", audit.String())
+	}
+}
+
+func TestMigrationHandler_RequiresPermission(t *testing.T) {
+	var audit bytes.Buffer
+	r := newMigrationRouter(t, &audit)
+
+	w := doUserRequest(r, http.MethodPost, "/api/migrate/up", "", 2)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a user without migrations:run, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodGet, "/api/migrate/status", "", 1)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for an admin user, got %d", w.Code)
+	}
+	if !strings.Contains(audit.String(), "admin=user:user1") {
+		t.Errorf("Expected the user in the audit log, got %q", audit.String())
+	}
+}
+
+func TestMigrationHandler_UpDownAudited(t *testing.T) {
+	var audit bytes.Buffer
+	r := newMigrationRouter(t, &audit)
+
+	w := doMigrationRequest(r, http.MethodPost, "/api/migrate/up", "s3cret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doMigrationRequest(r, http.MethodGet, "/api/migrate/status", "s3cret")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"applied":true`) {
+		t.Errorf("Expected applied migration in status, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doMigrationRequest(r, http.MethodPost, "/api/migrate/down", "s3cret")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 audit lines, got %d: %q", len(lines), audit.String())
+	}
+	if !strings.Contains(lines[0], "action=up") || !strings.Contains(lines[0], "admin=alice") || !strings.Contains(lines[0], `result="ok"`) {
+		t.Errorf("Unexpected audit line: %s", lines[0])
+	}
+	if !strings.Contains(lines[2], "action=down") {
+		t.Errorf("Unexpected audit line: %s", lines[2])
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"user-authentication/middleware"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie carries the state of a login with an identity provider
+// from its start to the callback
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler handles logging in with external OpenID Connect providers
+type OIDCHandler struct {
+	oidc  *services.OIDCService
+	login *AuthHandler
+	// secure marks the state cookie Secure
+	secure     bool
+	cookiePath string
+}
+
+// NewOIDCHandler creates a new OIDCHandler. Logins are finished by login, so
+// that email verification and two-factor authentication apply to them as
+// they do to password logins.
+func NewOIDCHandler(oidc *services.OIDCService, login *AuthHandler, secureCookie bool) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc, login: login, secure: secureCookie}
+}
+
+// RegisterRoutes mounts the OIDC endpoints on rg. The router must run
+// middleware.Authenticate before these routes, so that a logged in user who
+// logs in with a provider links it to their account.
+func (h *OIDCHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	h.cookiePath = rg.BasePath()
+	rg.GET("", h.Providers)
+	rg.GET("/identities", middleware.RequireUser(), h.Identities)
+	rg.GET("/:provider/login", h.Begin)
+	rg.GET("/:provider/callback", h.Callback)
+}
+
+// Providers handles GET /api/auth/oidc
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.oidc.Providers()})
+}
+
+// Identities handles GET /api/auth/oidc/identities
+func (h *OIDCHandler) Identities(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	identities, err := h.oidc.Identities(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// Begin handles GET /api/auth/oidc/:provider/login by redirecting the
+// browser to the provider
+func (h *OIDCHandler) Begin(c *gin.Context) {
+	login, err := h.oidc.Begin(c.Request.Context(), c.Param("provider"))
+	if errors.Is(err, services.ErrUnknownOIDCProvider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to start OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is not available"})
+		return
+	}
+
+	h.setStateCookie(c, login.State, int(h.oidc.StateTTL().Seconds()))
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+// Callback handles GET /api/auth/oidc/:provider/callback, where the provider
+// sends the browser back after the login. A logged in user gets the provider
+// account linked; anyone else is logged in, and registered if the provider
+// account is new.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	sealed, _ := c.Cookie(oidcStateCookie)
+	// The state is for one callback only
+	h.setStateCookie(c, "", -1)
+
+	if c.Query("error") != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was cancelled or denied at the identity provider"})
+		return
+	}
+
+	current, _ := middleware.CurrentUser(c)
+	user, created, err := h.oidc.Complete(c.Request.Context(), c.Param("provider"), sealed, c.Query("state"), c.Query("code"), current)
+	switch {
+	case errors.Is(err, services.ErrUnknownOIDCProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	case errors.Is(err, services.ErrInvalidOIDCState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state; please start again"})
+		return
+	case errors.Is(err, services.ErrInvalidIDToken):
+		log.Printf("Rejected OIDC ID token: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+		return
+	case errors.Is(err, services.ErrOIDCNoEmail):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The identity provider did not share an email address"})
+		return
+	case errors.Is(err, services.ErrOIDCEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered; log in and sign in with the provider again to link it"})
+		return
+	case errors.Is(err, services.ErrIdentityLinkedToOtherUser):
+		c.JSON(http.StatusConflict, gin.H{"error": "This account is already linked to another user"})
+		return
+	case err != nil:
+		log.Printf("Failed to complete OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to log in with the identity provider"})
+		return
+	}
+
+	if created && h.login.verification != nil {
+		// Only sent when the provider did not vouch for the address
+		if err := h.login.verification.Send(c.Request.Context(), user); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}
+
+	if current != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Identity linked", "user": user})
+		return
+	}
+	h.login.completeLogin(c, user)
+}
+
+// setStateCookie sets or, with maxAge -1, clears the state cookie. It must
+// be SameSite=Lax, because the callback is a cross-site navigation from the
+// provider.
+func (h *OIDCHandler) setStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     h.cookiePath,
+		MaxAge:   maxAge,
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
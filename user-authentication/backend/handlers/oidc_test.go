@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/oidctest"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeIdentityRepository is an in-memory IdentityRepository for handler tests
+type fakeIdentityRepository struct {
+	identities []*models.UserIdentity
+}
+
+func (r *fakeIdentityRepository) Get(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, repository.ErrIdentityNotFound
+}
+
+func (r *fakeIdentityRepository) ListByUser(ctx context.Context, userID int) ([]*models.UserIdentity, error) {
+	var identities []*models.UserIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *fakeIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	if _, err := r.Get(ctx, identity.Provider, identity.Subject); err == nil {
+		return repository.ErrDuplicateIdentity
+	}
+	identity.ID = len(r.identities) + 1
+	identity.CreatedAt = time.Now()
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+// setupOIDCRouter returns a router with the auth and OIDC routes in session
+// mode, and the fake provider it logs in with
+func setupOIDCRouter(t *testing.T, users repository.UserRepository) (*gin.Engine, *oidctest.Provider) {
+	provider, server, err := oidctest.NewServer("client", "client-secret", oidctest.User{
+		Subject:       "alice-sub",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Name:          "Alice",
+	})
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	oidc, err := services.NewOIDCService(&fakeIdentityRepository{}, users, &services.OIDCConfig{
+		Providers: []services.OIDCProviderConfig{{
+			Name:         "mock",
+			Issuer:       provider.Issuer,
+			ClientID:     "client",
+			ClientSecret: "client-secret",
+			RedirectURL:  "http://localhost/api/auth/oidc/mock/callback",
+			Scopes:       []string{"openid", "email"},
+		}},
+		StateSecret: []byte(strings.Repeat("s", 32)),
+		StateTTL:    time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCService failed: %v", err)
+	}
+
+	sessions := services.NewSessionService(newFakeSessionRepository(), users, &services.SessionConfig{TTL: time.Hour})
+	auth := middleware.NewSessionAuthenticator(sessions, middleware.SessionCookie{Name: "session_id", Path: "/", SameSite: http.SameSiteLaxMode})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(auth))
+	login := NewAuthHandler(users, &services.BcryptHasher{Cost: bcrypt.MinCost}, auth, AuthFeatures{})
+	login.RegisterRoutes(r.Group("/api/auth"))
+	NewOIDCHandler(oidc, login, false).RegisterRoutes(r.Group("/api/auth/oidc"))
+	return r, provider
+}
+
+// oidcLogin runs a login with the fake provider like a browser would and
+// returns the callback's response
+func oidcLogin(t *testing.T, r *gin.Engine, provider *oidctest.Provider, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	w := doJSONRequest(r, http.MethodGet, "/api/auth/oidc/mock/login", "", cookies...)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status 302, got %d: %s", w.Code, w.Body.String())
+	}
+	var state *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcStateCookie {
+			state = cookie
+		}
+	}
+	if state == nil || !state.HttpOnly || state.SameSite != http.SameSiteLaxMode || state.Path != "/api/auth/oidc" {
+		t.Fatalf("Expected an HttpOnly SameSite=Lax state cookie, got %+v", state)
+	}
+
+	callback, err := provider.Approve(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatalf("Invalid callback URL %q: %v", callback, err)
+	}
+	return doJSONRequest(r, http.MethodGet, u.RequestURI(), "", append(cookies, state)...)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	users := newFakeUserRepository()
+	r, provider := setupOIDCRouter(t, users)
+
+	w := doJSONRequest(r, http.MethodGet, "/api/auth/oidc", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"mock"`) {
+		t.Errorf("Expected the provider list, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = oidcLogin(t, r, provider)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	cookie := sessionCookie(w)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("Expected the login to set a session cookie")
+	}
+
+	user, err := users.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("Expected the login to register alice: %v", err)
+	}
+	if user.PasswordHash != "" || !user.EmailVerified() {
+		t.Errorf("Expected a verified user without a password, got %+v", user)
+	}
+
+	w = doJSONRequest(r, http.MethodGet, "/api/auth/oidc/identities", "", cookie)
+	var identities []models.UserIdentity
+	if err := json.Unmarshal(w.Body.Bytes(), &identities); err != nil || len(identities) != 1 || identities[0].Subject != "alice-sub" {
+		t.Errorf("Expected alice's identity, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Logging in again finds the same user
+	w = oidcLogin(t, r, provider)
+	if w.Code != http.StatusOK || len(users.users) != 1 {
+		t.Errorf("Expected the second login to reuse the user, got %d with %d users", w.Code, len(users.users))
+	}
+
+	// The password login must not accept anything for a user without one
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":""}`)
+	if w.Code == http.StatusOK {
+		t.Error("Expected password login to fail for a user without a password")
+	}
+}
+
+func TestOIDCCallback_InvalidState(t *testing.T) {
+	r, provider := setupOIDCRouter(t, newFakeUserRepository())
+
+	w := doJSONRequest(r, http.MethodGet, "/api/auth/oidc/mock/login", "")
+	callback, err := provider.Approve(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	u, _ := url.Parse(callback)
+
+	// Without the state cookie, e.g. a callback forged into another browser
+	w = doJSONRequest(r, http.MethodGet, u.RequestURI(), "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSONRequest(r, http.MethodGet, "/api/auth/oidc/other/login", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown provider, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodGet, "/api/auth/oidc/mock/callback?error=access_denied", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a denied login, got %d", w.Code)
+	}
+}
+
+func TestOIDCLogin_LinkExistingUser(t *testing.T) {
+	users := newFakeUserRepository()
+	r, provider := setupOIDCRouter(t, users)
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+
+	// A provider account with the email of an existing user is not linked
+	// to that user without them logging in
+	w := oidcLogin(t, r, provider)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	cookie := sessionCookie(w)
+	w = oidcLogin(t, r, provider, cookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Identity linked") {
+		t.Fatalf("Expected the identity to be linked, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Now the provider logs alice in
+	w = oidcLogin(t, r, provider)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"username":"alice"`) {
+		t.Errorf("Expected the provider to log alice in, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Another user cannot take over alice's provider account
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"bob","email":"bob@example.com","password":"s3cret-pass"}`)
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"bob@example.com","password":"s3cret-pass"}`)
+	w = oidcLogin(t, r, provider, sessionCookie(w))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"user-authentication/models"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetHandler handles forgotten passwords
+type PasswordResetHandler struct {
+	resets *services.PasswordResetService
+}
+
+// NewPasswordResetHandler creates a new PasswordResetHandler
+func NewPasswordResetHandler(resets *services.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{resets: resets}
+}
+
+// RegisterRoutes mounts the password reset endpoints on rg
+func (h *PasswordResetHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/password/forgot", h.Forgot)
+	rg.POST("/password/reset", h.Reset)
+}
+
+// Forgot handles POST /api/auth/password/forgot. It responds the same way
+// whether or not the email is registered.
+func (h *PasswordResetHandler) Forgot(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	// A failure is only logged, otherwise it would tell that the email exists
+	if err := h.resets.RequestReset(c.Request.Context(), req.Email); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+// Reset handles POST /api/auth/password/reset
+func (h *PasswordResetHandler) Reset(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	if !validatePassword(c, req.Password) {
+		return
+	}
+
+	err := h.resets.Reset(c.Request.Context(), req.Token, req.Password)
+	if errors.Is(err, services.ErrInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
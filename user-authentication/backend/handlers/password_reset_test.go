@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakePasswordResetRepository is an in-memory PasswordResetRepository for handler tests
+type fakePasswordResetRepository struct {
+	resets map[string]*models.PasswordReset
+}
+
+func (r *fakePasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	stored := *reset
+	r.resets[reset.ID] = &stored
+	return nil
+}
+
+func (r *fakePasswordResetRepository) Get(ctx context.Context, id string) (*models.PasswordReset, error) {
+	if stored, ok := r.resets[id]; ok {
+		reset := *stored
+		return &reset, nil
+	}
+	return nil, repository.ErrPasswordResetNotFound
+}
+
+func (r *fakePasswordResetRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	stored, ok := r.resets[id]
+	if !ok || stored.UsedAt != nil {
+		return repository.ErrPasswordResetNotFound
+	}
+	stored.UsedAt = &usedAt
+	return nil
+}
+
+func (r *fakePasswordResetRepository) DeleteByUser(ctx context.Context, userID int) error {
+	for id, reset := range r.resets {
+		if reset.UserID == userID {
+			delete(r.resets, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakePasswordResetRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeMailer records sent messages
+type fakeMailer struct {
+	messages []*services.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg *services.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// setupPasswordResetRouter returns a session mode router with the auth and
+// password reset routes and the mailer receiving the reset links
+func setupPasswordResetRouter(users repository.UserRepository) (*gin.Engine, *fakeSessionRepository, *fakeMailer) {
+	sessionRepo := newFakeSessionRepository()
+	sessions := services.NewSessionService(sessionRepo, users, &services.SessionConfig{TTL: time.Hour})
+	cookie := middleware.SessionCookie{Name: "session_id", Path: "/", SameSite: http.SameSiteLaxMode}
+	r := setupRouterWith(users, middleware.NewSessionAuthenticator(sessions, cookie))
+
+	mailer := &fakeMailer{}
+	resets := services.NewPasswordResetService(
+		&fakePasswordResetRepository{resets: make(map[string]*models.PasswordReset)},
+		users, sessionRepo, &services.BcryptHasher{Cost: bcrypt.MinCost}, mailer,
+		&services.PasswordResetConfig{TTL: time.Hour, URL: "http://localhost:3000/reset-password"},
+	)
+	NewPasswordResetHandler(resets).RegisterRoutes(r.Group("/api/auth"))
+	return r, sessionRepo, mailer
+}
+
+func TestPasswordReset(t *testing.T) {
+	r, sessionRepo, mailer := setupPasswordResetRouter(newFakeUserRepository())
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+	doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/password/forgot", `{"email":"alice@example.com"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(mailer.messages) != 1 {
+		t.Fatalf("Expected 1 reset email, got %d", len(mailer.messages))
+	}
+	link, err := url.Parse(strings.Fields(mailer.messages[0].Body[strings.Index(mailer.messages[0].Body, "http://"):])[0])
+	if err != nil {
+		t.Fatalf("Invalid reset link: %v", err)
+	}
+	token := link.Query().Get("token")
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/password/reset", `{"token":"`+token+`","password":"short"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a short password, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/password/reset", `{"token":"`+token+`","password":"n3w-s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(sessionRepo.sessions) != 0 {
+		t.Errorf("Expected the reset to revoke all sessions, %d left", len(sessionRepo.sessions))
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old password to be rejected, got %d", w.Code)
+	}
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"n3w-s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the new password to work, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/password/reset", `{"token":"`+token+`","password":"an0ther-pass"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a used token, got %d", w.Code)
+	}
+}
+
+func TestPasswordReset_UnknownEmail(t *testing.T) {
+	r, _, mailer := setupPasswordResetRouter(newFakeUserRepository())
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/password/forgot", `{"email":"nobody@example.com"}`)
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202 for an unknown email, got %d", w.Code)
+	}
+	if len(mailer.messages) != 0 {
+		t.Errorf("Expected no email for an unknown email, got %d", len(mailer.messages))
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/password/reset", `{"token":"bogus","password":"n3w-s3cret-pass"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Invalid or expired reset token") {
+		t.Errorf("Expected 400 for an unknown token, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PostHandler handles post-related HTTP requests. Anyone can read posts,
+// signed-in users can write them, and only a post's author or a user whose
+// roles allow it can change or delete it.
+type PostHandler struct {
+	posts repository.PostRepository
+}
+
+// NewPostHandler creates a new PostHandler
+func NewPostHandler(posts repository.PostRepository) *PostHandler {
+	return &PostHandler{posts: posts}
+}
+
+// RegisterRoutes mounts the post endpoints on rg. The router must run
+// middleware.Authenticate and middleware.LoadRoles before these routes.
+// write are extra checks run before the endpoints that change posts, such as
+// middleware.RequireVerifiedEmail. API tokens need the posts:read scope to
+// list their user's posts and posts:write to change posts.
+func (h *PostHandler) RegisterRoutes(rg *gin.RouterGroup, write ...gin.HandlerFunc) {
+	writeHandlers := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		handlers := append([]gin.HandlerFunc{middleware.RequireScope(models.ScopePostsWrite), middleware.RequireUser()}, write...)
+		return append(handlers, handler)
+	}
+
+	rg.GET("", h.GetPosts)
+	rg.GET("/my", middleware.RequireScope(models.ScopePostsRead), middleware.RequireUser(), h.GetMyPosts)
+	rg.GET("/:id", h.GetPost)
+	rg.POST("", writeHandlers(h.CreatePost)...)
+	rg.PUT("/:id", writeHandlers(h.UpdatePost)...)
+	rg.DELETE("/:id", writeHandlers(h.DeletePost)...)
+}
+
+// GetPosts handles GET /api/posts
+func (h *PostHandler) GetPosts(c *gin.Context) {
+	posts, err := h.posts.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, posts)
+}
+
+// GetMyPosts handles GET /api/posts/my
+func (h *PostHandler) GetMyPosts(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	posts, err := h.posts.ListByAuthor(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, posts)
+}
+
+// GetPost handles GET /api/posts/:id
+func (h *PostHandler) GetPost(c *gin.Context) {
+	id, ok := parsePostID(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.posts.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// CreatePost handles POST /api/posts. The post is owned by the current user.
+func (h *PostHandler) CreatePost(c *gin.Context) {
+	var req models.CreatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	if !validatePostContent(c, req.Content) {
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	post := &models.Post{AuthorID: user.ID, Content: req.Content}
+	if err := h.posts.Create(c.Request.Context(), post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, post)
+}
+
+// UpdatePost handles PUT /api/posts/:id
+func (h *PostHandler) UpdatePost(c *gin.Context) {
+	id, ok := parsePostID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	if !validatePostContent(c, req.Content) {
+		return
+	}
+
+	if !h.authorizeModify(c, id, models.PermissionPostsUpdateAny) {
+		return
+	}
+
+	post, err := h.posts.Update(c.Request.Context(), id, req.Content)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// DeletePost handles DELETE /api/posts/:id
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	id, ok := parsePostID(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorizeModify(c, id, models.PermissionPostsDeleteAny) {
+		return
+	}
+
+	err := h.posts.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// authorizeModify checks that the current user wrote post id or has the
+// permission to modify any post, and writes a 404 or 403 response otherwise
+func (h *PostHandler) authorizeModify(c *gin.Context, id int, anyPermission string) bool {
+	post, err := h.posts.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve post"})
+		return false
+	}
+
+	user, _ := middleware.CurrentUser(c)
+	if post.AuthorID != user.ID && !middleware.HasPermission(c, anyPermission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only modify your own posts"})
+		return false
+	}
+	return true
+}
+
+// parsePostID reads the :id URL parameter and writes a 400 response if it is not a positive number
+func parsePostID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// validatePostContent applies the server-side content rules and writes a 400 response on failure
+func validatePostContent(c *gin.Context, content string) bool {
+	if len(content) < 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content must be at least 3 characters long"})
+		return false
+	}
+
+	if len(content) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Post content cannot exceed 1000 characters"})
+		return false
+	}
+
+	return true
+}
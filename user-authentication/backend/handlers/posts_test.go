@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakePostRepository is an in-memory PostRepository for handler tests
+type fakePostRepository struct {
+	posts  map[int]*models.Post
+	nextID int
+}
+
+func newFakePostRepository() *fakePostRepository {
+	return &fakePostRepository{posts: make(map[int]*models.Post), nextID: 1}
+}
+
+func (r *fakePostRepository) List(ctx context.Context) ([]*models.Post, error) {
+	return r.filter(func(*models.Post) bool { return true }), nil
+}
+
+func (r *fakePostRepository) ListByAuthor(ctx context.Context, authorID int) ([]*models.Post, error) {
+	return r.filter(func(p *models.Post) bool { return p.AuthorID == authorID }), nil
+}
+
+func (r *fakePostRepository) filter(keep func(*models.Post) bool) []*models.Post {
+	posts := []*models.Post{}
+	for _, p := range r.posts {
+		if keep(p) {
+			post := *p
+			posts = append(posts, &post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID > posts[j].ID })
+	return posts
+}
+
+func (r *fakePostRepository) Get(ctx context.Context, id int) (*models.Post, error) {
+	if p, ok := r.posts[id]; ok {
+		post := *p
+		return &post, nil
+	}
+	return nil, repository.ErrPostNotFound
+}
+
+func (r *fakePostRepository) Create(ctx context.Context, post *models.Post) error {
+	now := time.Now()
+	post.ID = r.nextID
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	stored := *post
+	r.posts[post.ID] = &stored
+	r.nextID++
+	return nil
+}
+
+func (r *fakePostRepository) Update(ctx context.Context, id int, content string) (*models.Post, error) {
+	p, ok := r.posts[id]
+	if !ok {
+		return nil, repository.ErrPostNotFound
+	}
+	p.Content = content
+	p.UpdatedAt = time.Now()
+	post := *p
+	return &post, nil
+}
+
+func (r *fakePostRepository) Delete(ctx context.Context, id int) error {
+	if _, ok := r.posts[id]; !ok {
+		return repository.ErrPostNotFound
+	}
+	delete(r.posts, id)
+	return nil
+}
+
+// headerAuthenticator authenticates the user whose ID is in the X-User-ID
+// header, so tests can act as any user without logging in
+type headerAuthenticator struct{}
+
+func (headerAuthenticator) Authenticate(c *gin.Context) (*models.User, error) {
+	id, err := strconv.Atoi(c.GetHeader("X-User-ID"))
+	if err != nil {
+		return nil, middleware.ErrUnauthenticated
+	}
+	return &models.User{ID: id, Username: "user" + strconv.Itoa(id)}, nil
+}
+
+func (headerAuthenticator) Login(c *gin.Context, user *models.User) (interface{}, error) {
+	return user, nil
+}
+
+func (headerAuthenticator) Logout(c *gin.Context) error {
+	return nil
+}
+
+// Users that setupPostRouter grants roles to
+const (
+	adminUserID     = 99
+	moderatorUserID = 98
+)
+
+func setupPostRouter(posts repository.PostRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	roles := newFakeRoleRepository()
+	roles.grants[adminUserID] = []string{"admin"}
+	roles.grants[moderatorUserID] = []string{"moderator"}
+
+	r := gin.New()
+	r.Use(middleware.Authenticate(headerAuthenticator{}), middleware.LoadRoles(roles))
+	h := NewPostHandler(posts)
+	h.RegisterRoutes(r.Group("/api/posts"))
+	return r
+}
+
+// doUserRequest sends a request as userID; 0 sends it anonymously
+func doUserRequest(r *gin.Engine, method, path, body string, userID int) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		req.Header.Set("X-User-ID", strconv.Itoa(userID))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreatePost(t *testing.T) {
+	posts := newFakePostRepository()
+	r := setupPostRouter(posts)
+
+	w := doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"Hello, world!"}`, 0)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for anonymous post, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"Hello, world!","author_id":2}`, 1)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var post models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if post.AuthorID != 1 {
+		t.Errorf("Expected post to be owned by user 1, got %d", post.AuthorID)
+	}
+}
+
+func TestCreatePost_RequiresVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(headerAuthenticator{}), middleware.LoadRoles(newFakeRoleRepository()))
+	NewPostHandler(newFakePostRepository()).RegisterRoutes(r.Group("/api/posts"), middleware.RequireVerifiedEmail())
+
+	// headerAuthenticator users have not verified their email
+	w := doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"Hello, world!"}`, 1)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for an unverified user, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodGet, "/api/posts", "", 1)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected unverified users to read posts, got %d", w.Code)
+	}
+}
+
+func TestCreatePost_Validation(t *testing.T) {
+	r := setupPostRouter(newFakePostRepository())
+
+	bodies := map[string]string{
+		"invalid json":  `{"content":`,
+		"missing":       `{}`,
+		"short content": `{"content":"hi"}`,
+		"long content":  `{"content":"` + strings.Repeat("a", 1001) + `"}`,
+	}
+
+	for name, body := range bodies {
+		w := doUserRequest(r, http.MethodPost, "/api/posts", body, 1)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestGetMyPosts(t *testing.T) {
+	posts := newFakePostRepository()
+	r := setupPostRouter(posts)
+	doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"first by 1"}`, 1)
+	doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"first by 2"}`, 2)
+	doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"second by 1"}`, 1)
+
+	w := doUserRequest(r, http.MethodGet, "/api/posts/my", "", 0)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for anonymous request, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodGet, "/api/posts/my", "", 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var mine []models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &mine); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(mine) != 2 || mine[0].Content != "second by 1" || mine[1].Content != "first by 1" {
+		t.Errorf("Expected user 1's posts newest first, got %+v", mine)
+	}
+
+	w = doUserRequest(r, http.MethodGet, "/api/posts", "", 0)
+	var all []models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &all); err != nil || len(all) != 3 {
+		t.Errorf("Expected anyone to list all 3 posts, got %d (err: %v)", len(all), err)
+	}
+}
+
+func TestUpdatePost_Ownership(t *testing.T) {
+	posts := newFakePostRepository()
+	r := setupPostRouter(posts)
+	doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"original"}`, 1)
+
+	tests := []struct {
+		name     string
+		userID   int
+		expected int
+	}{
+		{"anonymous", 0, http.StatusUnauthorized},
+		{"other user", 2, http.StatusForbidden},
+		{"moderator", moderatorUserID, http.StatusForbidden},
+		{"author", 1, http.StatusOK},
+		{"admin", adminUserID, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := doUserRequest(r, http.MethodPut, "/api/posts/1", `{"content":"edited by `+tt.name+`"}`, tt.userID)
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, w.Code)
+		}
+	}
+
+	if posts.posts[1].Content != "edited by admin" {
+		t.Errorf("Expected the admin's edit to be stored, got '%s'", posts.posts[1].Content)
+	}
+
+	w := doUserRequest(r, http.MethodPut, "/api/posts/42", `{"content":"missing"}`, 1)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for missing post, got %d", w.Code)
+	}
+}
+
+func TestDeletePost_Ownership(t *testing.T) {
+	posts := newFakePostRepository()
+	r := setupPostRouter(posts)
+	doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"by user 1"}`, 1)
+	doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"by user 2"}`, 2)
+	doUserRequest(r, http.MethodPost, "/api/posts", `{"content":"also by user 2"}`, 2)
+
+	w := doUserRequest(r, http.MethodDelete, "/api/posts/1", "", 2)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for another user's post, got %d", w.Code)
+	}
+	if _, ok := posts.posts[1]; !ok {
+		t.Error("Forbidden delete must not remove the post")
+	}
+
+	w = doUserRequest(r, http.MethodDelete, "/api/posts/1", "", 1)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 for the author, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodDelete, "/api/posts/2", "", adminUserID)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 for an admin, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodDelete, "/api/posts/3", "", moderatorUserID)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 for a moderator, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodDelete, "/api/posts/abc", "", 1)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid ID, got %d", w.Code)
+	}
+}
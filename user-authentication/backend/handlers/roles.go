@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"user-authentication/models"
+	"user-authentication/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler lets admins list roles and grant or revoke them. Every change
+// is written to the audit log.
+type RoleHandler struct {
+	roles repository.RoleRepository
+	users repository.UserRepository
+	audit *log.Logger
+}
+
+// NewRoleHandler creates a new RoleHandler that audits to audit
+func NewRoleHandler(roles repository.RoleRepository, users repository.UserRepository, audit *log.Logger) *RoleHandler {
+	return &RoleHandler{roles: roles, users: users, audit: audit}
+}
+
+// RegisterRoutes mounts the role endpoints on rg. Access control is left to
+// the caller, e.g. middleware.RequirePermission(models.PermissionRolesManage).
+func (h *RoleHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/roles", h.ListRoles)
+	rg.GET("/users/:id/roles", h.GetUserRoles)
+	rg.PUT("/users/:id/roles/:role", h.GrantRole)
+	rg.DELETE("/users/:id/roles/:role", h.RevokeRole)
+}
+
+// ListRoles handles GET /api/admin/roles
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roles.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GetUserRoles handles GET /api/admin/users/:id/roles
+func (h *RoleHandler) GetUserRoles(c *gin.Context) {
+	userID, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	h.respondUserRoles(c, userID)
+}
+
+// GrantRole handles PUT /api/admin/users/:id/roles/:role
+func (h *RoleHandler) GrantRole(c *gin.Context) {
+	h.change(c, "grant", h.roles.Grant)
+}
+
+// RevokeRole handles DELETE /api/admin/users/:id/roles/:role
+func (h *RoleHandler) RevokeRole(c *gin.Context) {
+	h.change(c, "revoke", h.roles.Revoke)
+}
+
+// change applies a grant or revoke and responds with the user's roles
+func (h *RoleHandler) change(c *gin.Context, action string, apply func(ctx context.Context, userID int, role string) error) {
+	userID, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	role := c.Param("role")
+
+	err := apply(c.Request.Context(), userID, role)
+	h.audit.Printf("role action=%s role=%s user=%d by=%s ip=%s result=%q", action, role, userID, auditActor(c), c.ClientIP(), auditResult(err))
+	if errors.Is(err, repository.ErrRoleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + " role"})
+		return
+	}
+
+	h.respondUserRoles(c, userID)
+}
+
+// findUser reads the :id URL parameter and checks that the user exists,
+// writing a 400 or 404 response otherwise
+func (h *RoleHandler) findUser(c *gin.Context) (int, bool) {
+	user, ok := findUserParam(c, h.users)
+	if !ok {
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// findUserParam returns the user whose ID is in the :id URL parameter,
+// writing a 400 or 404 response if there is none
+func findUserParam(c *gin.Context, users repository.UserRepository) (*models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	user, err := users.GetByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return nil, false
+	}
+	return user, true
+}
+
+// respondUserRoles writes the roles currently granted to userID
+func (h *RoleHandler) respondUserRoles(c *gin.Context, userID int) {
+	roles, err := h.roles.ListByUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "roles": roles})
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeRoleRepository is an in-memory RoleRepository with the built-in roles
+type fakeRoleRepository struct {
+	roles  map[string]*models.Role
+	grants map[int][]string
+}
+
+func newFakeRoleRepository() *fakeRoleRepository {
+	return &fakeRoleRepository{
+		roles: map[string]*models.Role{
+			"admin":     {ID: 1, Name: "admin", Permissions: models.Permissions},
+			"moderator": {ID: 2, Name: "moderator", Permissions: []string{models.PermissionPostsDeleteAny}},
+		},
+		grants: make(map[int][]string),
+	}
+}
+
+func (r *fakeRoleRepository) List(ctx context.Context) ([]*models.Role, error) {
+	roles := []*models.Role{}
+	for _, role := range r.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (r *fakeRoleRepository) ListByUser(ctx context.Context, userID int) ([]*models.Role, error) {
+	roles := []*models.Role{}
+	for _, name := range r.grants[userID] {
+		roles = append(roles, r.roles[name])
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (r *fakeRoleRepository) Grant(ctx context.Context, userID int, role string) error {
+	if _, ok := r.roles[role]; !ok {
+		return repository.ErrRoleNotFound
+	}
+	for _, name := range r.grants[userID] {
+		if name == role {
+			return nil
+		}
+	}
+	r.grants[userID] = append(r.grants[userID], role)
+	return nil
+}
+
+func (r *fakeRoleRepository) Revoke(ctx context.Context, userID int, role string) error {
+	if _, ok := r.roles[role]; !ok {
+		return repository.ErrRoleNotFound
+	}
+	kept := []string{}
+	for _, name := range r.grants[userID] {
+		if name != role {
+			kept = append(kept, name)
+		}
+	}
+	r.grants[userID] = kept
+	return nil
+}
+
+// setupRoleRouter mounts the role endpoints the way main does. User 1 is an
+// admin; users 2 and 3 exist without roles.
+func setupRoleRouter(t *testing.T, audit *bytes.Buffer) (*gin.Engine, *fakeRoleRepository) {
+	t.Helper()
+	users := newFakeUserRepository()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if err := users.Create(context.Background(), &models.User{Username: name, Email: name + "@example.com"}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	roles := newFakeRoleRepository()
+	roles.grants[1] = []string{"admin"}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(headerAuthenticator{}), middleware.LoadRoles(roles))
+	h := NewRoleHandler(roles, users, log.New(audit, "", 0))
+	h.RegisterRoutes(r.Group("/api/admin", middleware.RequireAdminTokenOr(middleware.ParseAdminTokens("ops:s3cret"), middleware.RequirePermission(models.PermissionRolesManage))))
+	return r, roles
+}
+
+func TestRoleHandler_RequiresPermission(t *testing.T) {
+	var audit bytes.Buffer
+	r, roles := setupRoleRouter(t, &audit)
+
+	w := doUserRequest(r, http.MethodPut, "/api/admin/users/2/roles/admin", "", 0)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for anonymous request, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodPut, "/api/admin/users/2/roles/admin", "", 2)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a user without roles:manage, got %d", w.Code)
+	}
+
+	if len(roles.grants[2]) != 0 || audit.Len() != 0 {
+		t.Errorf("Rejected requests must not change roles, got %v (audit %q)", roles.grants[2], audit.String())
+	}
+}
+
+func TestRoleHandler_GrantAndRevoke(t *testing.T) {
+	var audit bytes.Buffer
+	r, roles := setupRoleRouter(t, &audit)
+
+	w := doUserRequest(r, http.MethodPut, "/api/admin/users/2/roles/moderator", "", 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		UserID int            `json:"user_id"`
+		Roles  []*models.Role `json:"roles"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.UserID != 2 || len(resp.Roles) != 1 || resp.Roles[0].Name != "moderator" {
+		t.Errorf("Expected user 2 to be a moderator, got %+v", resp)
+	}
+
+	// Granting twice is harmless
+	w = doUserRequest(r, http.MethodPut, "/api/admin/users/2/roles/moderator", "", 1)
+	if w.Code != http.StatusOK || len(roles.grants[2]) != 1 {
+		t.Errorf("Expected a repeated grant to succeed once, got %d %v", w.Code, roles.grants[2])
+	}
+
+	w = doUserRequest(r, http.MethodDelete, "/api/admin/users/2/roles/moderator", "", 1)
+	if w.Code != http.StatusOK || len(roles.grants[2]) != 0 {
+		t.Errorf("Expected the role to be revoked, got %d %v", w.Code, roles.grants[2])
+	}
+
+	if !strings.Contains(audit.String(), "role action=grant role=moderator user=2 by=user:user1") {
+		t.Errorf("Expected the grant to be audited, got %q", audit.String())
+	}
+	if !strings.Contains(audit.String(), "role action=revoke role=moderator user=2") {
+		t.Errorf("Expected the revoke to be audited, got %q", audit.String())
+	}
+}
+
+func TestRoleHandler_AdminToken(t *testing.T) {
+	var audit bytes.Buffer
+	r, roles := setupRoleRouter(t, &audit)
+
+	w := doBearerRequest(r, http.MethodPut, "/api/admin/users/3/roles/admin", "s3cret")
+	if w.Code != http.StatusOK || len(roles.grants[3]) != 1 {
+		t.Errorf("Expected an admin token to grant roles, got %d %v", w.Code, roles.grants[3])
+	}
+	if !strings.Contains(audit.String(), "by=ops") {
+		t.Errorf("Expected the token name in the audit log, got %q", audit.String())
+	}
+}
+
+func TestRoleHandler_NotFound(t *testing.T) {
+	var audit bytes.Buffer
+	r, _ := setupRoleRouter(t, &audit)
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/admin/users/2/roles/superuser", http.StatusNotFound},
+		{"/api/admin/users/42/roles/admin", http.StatusNotFound},
+		{"/api/admin/users/abc/roles/admin", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := doUserRequest(r, http.MethodPut, tt.path, "", 1)
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expected, w.Code)
+		}
+	}
+}
+
+func TestRoleHandler_ListRoles(t *testing.T) {
+	var audit bytes.Buffer
+	r, _ := setupRoleRouter(t, &audit)
+
+	w := doUserRequest(r, http.MethodGet, "/api/admin/roles", "", 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Roles []*models.Role `json:"roles"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Roles) != 2 || resp.Roles[0].Name != "admin" {
+		t.Errorf("Expected the built-in roles, got %+v", resp.Roles)
+	}
+}
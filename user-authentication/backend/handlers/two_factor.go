@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler lets logged in users set up and manage an authenticator
+// app and their recovery codes
+type TwoFactorHandler struct {
+	twoFactor *services.TwoFactorService
+	throttle  *services.LoginThrottle
+}
+
+// NewTwoFactorHandler creates a new TwoFactorHandler. Wrong codes are counted
+// by throttle like failed logins, so that a stolen session cannot be used to
+// guess codes; nil disables that.
+func NewTwoFactorHandler(twoFactor *services.TwoFactorService, throttle *services.LoginThrottle) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor, throttle: throttle}
+}
+
+// RegisterRoutes mounts the two-factor endpoints on rg. They all require a
+// logged in user.
+func (h *TwoFactorHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.Use(middleware.RequireUser())
+	rg.GET("", h.Status)
+	rg.POST("/setup", h.Setup)
+	rg.POST("/enable", h.Enable)
+	rg.POST("/disable", h.Disable)
+	rg.POST("/recovery-codes", h.RegenerateRecoveryCodes)
+}
+
+// Status handles GET /api/auth/2fa
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	status, err := h.twoFactor.Status(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Setup handles POST /api/auth/2fa/setup. It returns a new secret to add to
+// an authenticator app; two-factor authentication is enabled once a code
+// from the app is sent to POST /api/auth/2fa/enable.
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	enrollment, err := h.twoFactor.BeginEnrollment(c.Request.Context(), user)
+	if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Enable handles POST /api/auth/2fa/enable. The recovery codes in the
+// response are not shown again.
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	user, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactor.Enable(c.Request.Context(), user.ID, req.Code)
+	switch {
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor setup has not been started"})
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		h.codeFailed(c, user)
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+	}
+}
+
+// Disable handles POST /api/auth/2fa/disable
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	user, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	err := h.twoFactor.Disable(c.Request.Context(), user.ID, req.Code)
+	if h.writeVerifyError(c, user, err, "Failed to disable two-factor authentication") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes handles POST /api/auth/2fa/recovery-codes. The old
+// recovery codes stop working.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(c.Request.Context(), user.ID, req.Code)
+	if h.writeVerifyError(c, user, err, "Failed to generate recovery codes") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// bindCode reads the code from the request body of the current user, unless
+// the user has to wait after too many wrong codes
+func (h *TwoFactorHandler) bindCode(c *gin.Context) (*models.User, *models.TwoFactorCodeRequest, bool) {
+	user, _ := middleware.CurrentUser(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return nil, nil, false
+	}
+
+	if !checkThrottle(c, h.throttle, user.Email) {
+		return nil, nil, false
+	}
+	return user, &req, true
+}
+
+// writeVerifyError writes the response for an error of a code check, if any
+func (h *TwoFactorHandler) writeVerifyError(c *gin.Context, user *models.User, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		h.codeFailed(c, user)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return true
+}
+
+// codeFailed counts a wrong code and writes the 400 response
+func (h *TwoFactorHandler) codeFailed(c *gin.Context, user *models.User) {
+	recordLoginFailure(c, h.throttle, user.Email)
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeTwoFactorRepository is an in-memory TwoFactorRepository for handler tests
+type fakeTwoFactorRepository struct {
+	totps map[int]*models.TOTP
+	// codes maps user IDs to recovery code hashes and whether they are used
+	codes map[int]map[string]bool
+}
+
+func (r *fakeTwoFactorRepository) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	if stored, ok := r.totps[userID]; ok {
+		totp := *stored
+		return &totp, nil
+	}
+	return nil, repository.ErrTOTPNotFound
+}
+
+func (r *fakeTwoFactorRepository) SaveTOTP(ctx context.Context, totp *models.TOTP) error {
+	stored := *totp
+	r.totps[totp.UserID] = &stored
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) EnableTOTP(ctx context.Context, userID int, enabledAt time.Time, step int64, recoveryCodeHashes []string) error {
+	stored, ok := r.totps[userID]
+	if !ok || stored.EnabledAt != nil {
+		return repository.ErrTOTPNotFound
+	}
+	stored.EnabledAt = &enabledAt
+	stored.LastUsedStep = step
+	return r.ReplaceRecoveryCodes(ctx, userID, recoveryCodeHashes)
+}
+
+func (r *fakeTwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	stored, ok := r.totps[userID]
+	if !ok || stored.LastUsedStep >= step {
+		return repository.ErrTOTPNotFound
+	}
+	stored.LastUsedStep = step
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	delete(r.totps, userID)
+	delete(r.codes, userID)
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	r.codes[userID] = make(map[string]bool)
+	for _, hash := range hashes {
+		r.codes[userID][hash] = false
+	}
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, hash string, usedAt time.Time) error {
+	used, ok := r.codes[userID][hash]
+	if !ok || used {
+		return repository.ErrRecoveryCodeNotFound
+	}
+	r.codes[userID][hash] = true
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	count := 0
+	for _, used := range r.codes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// setupTwoFactorRouter returns a router with two-factor login and the 2fa
+// endpoints, and alice registered as user 1
+func setupTwoFactorRouter(t *testing.T, config services.LoginThrottleConfig) *gin.Engine {
+	t.Helper()
+	users := newFakeUserRepository()
+	store := &fakeTwoFactorRepository{totps: make(map[int]*models.TOTP), codes: make(map[int]map[string]bool)}
+	twoFactor, err := services.NewTwoFactorService(store, users, &services.TwoFactorConfig{
+		Issuer:       "Test",
+		Secret:       []byte(strings.Repeat("s", 32)),
+		ChallengeTTL: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewTwoFactorService failed: %v", err)
+	}
+	failures := &fakeLoginFailureRepository{failures: make(map[string]*models.LoginFailure)}
+	throttle := services.NewLoginThrottle(failures, &config)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Authenticate(headerAuthenticator{}))
+	NewAuthHandler(users, &services.BcryptHasher{Cost: bcrypt.MinCost}, headerAuthenticator{}, AuthFeatures{Throttle: throttle, TwoFactor: twoFactor}).RegisterRoutes(r.Group("/api/auth"))
+	NewTwoFactorHandler(twoFactor, throttle).RegisterRoutes(r.Group("/api/auth/2fa"))
+
+	doJSONRequest(r, http.MethodPost, "/api/auth/register", `{"username":"alice","email":"alice@example.com","password":"s3cret-pass"}`)
+	return r
+}
+
+// totpCode returns the authenticator app code for secret, steps time steps
+// from now
+func totpCode(t *testing.T, secret string, steps int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("Invalid secret %q: %v", secret, err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30+steps))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// enableTwoFactor sets up an authenticator app for user 1 and returns its
+// secret and the recovery codes
+func enableTwoFactor(t *testing.T, r *gin.Engine) (string, []string) {
+	t.Helper()
+	w := doUserRequest(r, http.MethodPost, "/api/auth/2fa/setup", "", 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 from setup, got %d: %s", w.Code, w.Body.String())
+	}
+	var enrollment models.TOTPEnrollment
+	if err := json.Unmarshal(w.Body.Bytes(), &enrollment); err != nil {
+		t.Fatalf("Failed to parse enrollment: %v", err)
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/enable", `{"code":"`+totpCode(t, enrollment.Secret, 0)+`"}`, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 from enable, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse recovery codes: %v", err)
+	}
+	return enrollment.Secret, resp.RecoveryCodes
+}
+
+// loginChallenge logs alice in with her password and returns the two-factor token
+func loginChallenge(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var challenge models.TwoFactorChallenge
+	if err := json.Unmarshal(w.Body.Bytes(), &challenge); err != nil || !challenge.TwoFactorRequired || challenge.Token == "" {
+		t.Fatalf("Expected a two-factor challenge, got %s", w.Body.String())
+	}
+	return challenge.Token
+}
+
+func TestTwoFactorSetup(t *testing.T) {
+	r := setupTwoFactorRouter(t, services.LoginThrottleConfig{})
+
+	w := doUserRequest(r, http.MethodGet, "/api/auth/2fa", "", 0)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without login, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/enable", `{"code":"123456"}`, 1)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 before setup, got %d", w.Code)
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/setup", "", 1)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "otpauth://totp/") {
+		t.Fatalf("Expected an otpauth URI, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/enable", `{"code":"000000"}`, 1)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a wrong code, got %d", w.Code)
+	}
+
+	_, codes := enableTwoFactor(t, r)
+	if len(codes) != 10 {
+		t.Errorf("Expected 10 recovery codes, got %d", len(codes))
+	}
+
+	w = doUserRequest(r, http.MethodGet, "/api/auth/2fa", "", 1)
+	if !strings.Contains(w.Body.String(), `"enabled":true`) || !strings.Contains(w.Body.String(), `"recovery_codes_remaining":10`) {
+		t.Errorf("Expected two-factor enabled with 10 codes, got %s", w.Body.String())
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/setup", "", 1)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 when already enabled, got %d", w.Code)
+	}
+}
+
+func TestLogin_TwoFactor(t *testing.T) {
+	r := setupTwoFactorRouter(t, services.LoginThrottleConfig{})
+	secret, codes := enableTwoFactor(t, r)
+	token := loginChallenge(t, r)
+
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login/2fa", `{"two_factor_token":"`+token+`x","code":"`+codes[0]+`"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a tampered token, got %d", w.Code)
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login/2fa", `{"two_factor_token":"`+token+`","code":"000000"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a wrong code, got %d", w.Code)
+	}
+
+	// The enrollment used the current step; the next one is still accepted
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login/2fa", `{"two_factor_token":"`+token+`","code":"`+totpCode(t, secret, 1)+`"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"email":"alice@example.com"`) {
+		t.Fatalf("Expected alice to be logged in, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login/2fa", `{"two_factor_token":"`+token+`","code":"`+codes[0]+`"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected recovery code to log in, got %d", w.Code)
+	}
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login/2fa", `{"two_factor_token":"`+token+`","code":"`+codes[0]+`"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected used recovery code to be rejected, got %d", w.Code)
+	}
+}
+
+func TestLogin_TwoFactorThrottled(t *testing.T) {
+	r := setupTwoFactorRouter(t, services.LoginThrottleConfig{
+		MaxFailures: 1,
+		Lockout:     time.Hour,
+		Window:      time.Hour,
+	})
+	_, codes := enableTwoFactor(t, r)
+	token := loginChallenge(t, r)
+
+	doJSONRequest(r, http.MethodPost, "/api/auth/login/2fa", `{"two_factor_token":"`+token+`","code":"000000"}`)
+	w := doJSONRequest(r, http.MethodPost, "/api/auth/login/2fa", `{"two_factor_token":"`+token+`","code":"`+codes[0]+`"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 after a wrong code, got %d", w.Code)
+	}
+
+	// The right password does not clear the failures either
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for the password step, got %d", w.Code)
+	}
+}
+
+func TestTwoFactorDisable(t *testing.T) {
+	r := setupTwoFactorRouter(t, services.LoginThrottleConfig{})
+	_, codes := enableTwoFactor(t, r)
+
+	w := doUserRequest(r, http.MethodPost, "/api/auth/2fa/recovery-codes", `{"code":"`+codes[0]+`"}`, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.RecoveryCodes) != 10 {
+		t.Fatalf("Expected 10 new recovery codes, got %s", w.Body.String())
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/disable", `{"code":"`+codes[1]+`"}`, 1)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected old recovery code to be rejected, got %d", w.Code)
+	}
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/disable", `{"code":"`+resp.RecoveryCodes[0]+`"}`, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSONRequest(r, http.MethodPost, "/api/auth/login", `{"email":"alice@example.com","password":"s3cret-pass"}`)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "two_factor_required") {
+		t.Errorf("Expected login without a second factor, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doUserRequest(r, http.MethodPost, "/api/auth/2fa/disable", `{"code":"`+resp.RecoveryCodes[1]+`"}`, 1)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when not enabled, got %d", w.Code)
+	}
+}
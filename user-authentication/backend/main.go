@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"time"
 	"user-authentication/database"
 	"user-authentication/database/migrations"
+	"user-authentication/handlers"
+	"user-authentication/middleware"
+	"user-authentication/models"
+	"user-authentication/repository"
 	"user-authentication/services"
 
 	"github.com/gin-gonic/gin"
@@ -28,13 +38,24 @@ func main() {
 	// Initialize migration manager
 	migrationManager := services.NewMigrationManager(db)
 
+	// Replicas starting together wait for each other's migrations
+	if value := os.Getenv("MIGRATION_LOCK_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid MIGRATION_LOCK_TIMEOUT %q: %v", value, err)
+		}
+		migrationManager.SetLockTimeout(timeout)
+	}
+
 	// Initialize migration table
 	if err := migrationManager.InitializeMigrationTable(); err != nil {
 		log.Fatalf("Failed to initialize migration table: %v", err)
 	}
 
 	// Register migrations
-	migrationManager.AddMigration(migrations.CreateUsersTableMigration())
+	if err := migrations.Register(migrationManager, os.Getenv("MIGRATIONS_DIR")); err != nil {
+		log.Fatalf("Failed to register migrations: %v", err)
+	}
 
 	// Run migrations
 	if err := migrationManager.Up(); err != nil {
@@ -44,19 +65,161 @@ func main() {
 	// Initialize Gin router
 	r := gin.Default()
 
-	// Add CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+	// Client IPs are used for login throttling and audit logs, so
+	// X-Forwarded-For is only believed from the proxies in TRUSTED_PROXIES
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
 		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Browsers send the session cookie along, so only the frontends in
+	// CORS_ALLOWED_ORIGINS may call the API cross-origin, and mutations with
+	// the cookie need a CSRF token from GET /api/auth/csrf
+	allowedOrigins, err := middleware.ParseOrigins(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000"))
+	if err != nil {
+		log.Fatalf("Invalid CORS_ALLOWED_ORIGINS: %v", err)
+	}
+	r.Use(middleware.CORS(allowedOrigins))
+	csrfSecret := []byte(os.Getenv("CSRF_SECRET"))
+	if len(csrfSecret) == 0 {
+		csrfSecret = randomSecret("CSRF_SECRET", "CSRF tokens")
+	}
+	csrf, err := middleware.NewCSRFProtection(csrfSecret, middleware.GetDefaultSessionCookie().Name, allowedOrigins)
+	if err != nil {
+		log.Fatalf("Invalid CSRF_SECRET: %v", err)
+	}
+	r.Use(csrf.Protect())
+	handlers.NewCSRFHandler(csrf).RegisterRoutes(r.Group("/api/auth"))
+
+	// Password hashing
+	hasher, err := services.NewPasswordHasher(services.GetDefaultPasswordConfig())
+	if err != nil {
+		log.Fatalf("Invalid password hashing configuration: %v", err)
+	}
+
+	// Authentication: every request carrying a valid session cookie, access
+	// token or personal API token gets its user loaded
+	userRepo := repository.NewMySQLUserRepository(db)
+	roleRepo := repository.NewMySQLRoleRepository(db)
+	sessionRepo := repository.NewMySQLSessionRepository(db)
+	authenticator, sessionService, err := newAuthenticator(getEnv("AUTH_MODE", "session"), userRepo, sessionRepo, roleRepo)
+	if err != nil {
+		log.Fatalf("Invalid authentication configuration: %v", err)
+	}
+	apiTokenService := services.NewAPITokenService(repository.NewMySQLAPITokenRepository(db), userRepo)
+	r.Use(middleware.Authenticate(middleware.NewAPITokenAuthenticator(apiTokenService, authenticator)), middleware.LoadRoles(roleRepo))
+	go purgeExpired("sessions", sessionService.PurgeExpired, time.Hour)
+	go purgeExpired("API tokens", apiTokenService.PurgeExpired, time.Hour)
 
-		c.Next()
+	// Migration admin tokens are only accepted by the migration API. Role
+	// admin tokens are only accepted by role management, to grant the first
+	// admin role.
+	migrationAdminTokens := middleware.ParseAdminTokens(os.Getenv("MIGRATION_ADMIN_TOKENS"))
+	roleAdminTokens := middleware.ParseAdminTokens(os.Getenv("ROLE_ADMIN_TOKENS"))
+	audit := log.New(os.Stderr, "[AUDIT] ", log.LstdFlags|log.LUTC)
+
+	// Verification and password reset links are sent with the mailer
+	// selected by MAIL_DRIVER
+	mailer, err := services.NewMailer(services.GetDefaultMailConfig())
+	if err != nil {
+		log.Fatalf("Invalid mail configuration: %v", err)
+	}
+
+	// Email verification
+	verificationConfig, err := services.LoadEmailVerificationConfig()
+	if err != nil {
+		log.Fatalf("Invalid email verification configuration: %v", err)
+	}
+	if verificationConfig.Secret == nil {
+		verificationConfig.Secret = randomSecret("EMAIL_VERIFICATION_SECRET", "verification links")
+	}
+	verificationService, err := services.NewEmailVerificationService(userRepo, mailer, verificationConfig)
+	if err != nil {
+		log.Fatalf("Invalid email verification configuration: %v", err)
+	}
+
+	// Failed logins and verification email resends slow down further
+	// attempts per email and per IP
+	loginThrottle := services.NewLoginThrottle(repository.NewMySQLLoginFailureRepository(db), services.GetDefaultLoginThrottleConfig())
+	go purgeExpired("login failures", loginThrottle.PurgeStale, time.Hour)
+	handlers.NewEmailVerificationHandler(verificationService, loginThrottle).RegisterRoutes(r.Group("/api/auth"))
+
+	// Two-factor authentication with an authenticator app, for users who
+	// set it up
+	twoFactorConfig, err := services.LoadTwoFactorConfig()
+	if err != nil {
+		log.Fatalf("Invalid two-factor configuration: %v", err)
+	}
+	if twoFactorConfig.Secret == nil {
+		twoFactorConfig.Secret = randomSecret("TWO_FACTOR_SECRET", "pending two-factor logins")
+	}
+	twoFactorService, err := services.NewTwoFactorService(repository.NewMySQLTwoFactorRepository(db), userRepo, twoFactorConfig)
+	if err != nil {
+		log.Fatalf("Invalid two-factor configuration: %v", err)
+	}
+	handlers.NewTwoFactorHandler(twoFactorService, loginThrottle).RegisterRoutes(r.Group("/api/auth/2fa"))
+
+	// Auth endpoints
+	authHandler := handlers.NewAuthHandler(userRepo, hasher, authenticator, handlers.AuthFeatures{
+		Verification: verificationService,
+		Throttle:     loginThrottle,
+		TwoFactor:    twoFactorService,
 	})
+	authHandler.RegisterRoutes(r.Group("/api/auth"))
+
+	// Login with external OpenID Connect providers listed in OIDC_PROVIDERS
+	oidcConfig, err := services.LoadOIDCConfig()
+	if err != nil {
+		log.Fatalf("Invalid OIDC configuration: %v", err)
+	}
+	if len(oidcConfig.Providers) > 0 {
+		if oidcConfig.StateSecret == nil {
+			oidcConfig.StateSecret = randomSecret("OIDC_STATE_SECRET", "logins in progress")
+		}
+		oidcService, err := services.NewOIDCService(repository.NewMySQLIdentityRepository(db), userRepo, oidcConfig)
+		if err != nil {
+			log.Fatalf("Invalid OIDC configuration: %v", err)
+		}
+		handlers.NewOIDCHandler(oidcService, authHandler, middleware.GetDefaultSessionCookie().Secure).RegisterRoutes(r.Group("/api/auth/oidc"))
+	}
+
+	// Personal API tokens. Services that cannot reach the database check
+	// them at the introspection endpoint with a token from
+	// API_TOKEN_INTROSPECTION_TOKENS.
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService, roleRepo)
+	apiTokenRoutes := r.Group("/api/auth/tokens")
+	apiTokenHandler.RegisterRoutes(apiTokenRoutes)
+	if introspectionTokens := middleware.ParseAdminTokens(os.Getenv("API_TOKEN_INTROSPECTION_TOKENS")); len(introspectionTokens) > 0 {
+		apiTokenRoutes.POST("/introspect", middleware.RequireAdminToken(introspectionTokens), apiTokenHandler.Introspect)
+	}
+
+	// Password reset
+	passwordResetService := services.NewPasswordResetService(repository.NewMySQLPasswordResetRepository(db), userRepo, sessionRepo, hasher, mailer, services.GetDefaultPasswordResetConfig())
+	handlers.NewPasswordResetHandler(passwordResetService).RegisterRoutes(r.Group("/api/auth"))
+	go purgeExpired("password reset tokens", passwordResetService.PurgeExpired, time.Hour)
+
+	// Post endpoints. Other users' posts can only be changed with the
+	// posts:update:any and posts:delete:any permissions. Unless
+	// EMAIL_VERIFICATION_REQUIRED=none, writing needs a verified email.
+	var postChecks []gin.HandlerFunc
+	if verificationConfig.Required != services.VerificationRequiredNone {
+		postChecks = append(postChecks, middleware.RequireVerifiedEmail())
+	}
+	postHandler := handlers.NewPostHandler(repository.NewMySQLPostRepository(db))
+	postHandler.RegisterRoutes(r.Group("/api/posts"), postChecks...)
+
+	// Role management
+	roleHandler := handlers.NewRoleHandler(roleRepo, userRepo, audit)
+	roleHandler.RegisterRoutes(r.Group("/api/admin", middleware.RequireAdminTokenOr(roleAdminTokens, middleware.RequirePermission(models.PermissionRolesManage))))
+
+	// Unlocking accounts and IPs locked out by failed logins
+	lockoutHandler := handlers.NewLoginLockoutHandler(loginThrottle, userRepo, audit)
+	lockoutHandler.RegisterRoutes(r.Group("/api/admin", middleware.RequirePermission(models.PermissionUsersUnlock)))
 
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
@@ -66,35 +229,90 @@ func main() {
 		})
 	})
 
-	// Migration endpoints
-	r.POST("/api/migrate/up", func(c *gin.Context) {
-		if err := migrationManager.Up(); err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(200, gin.H{"message": "Migrations applied successfully"})
-	})
-
-	r.POST("/api/migrate/down", func(c *gin.Context) {
-		if err := migrationManager.Down(); err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(200, gin.H{"message": "Migration rolled back successfully"})
-	})
+	// Migration endpoints. They can drop tables, so they require an admin
+	// token or the migrations:run permission and are off in release mode
+	// unless MIGRATION_API_ENABLED=true.
+	migrationAPIEnabled := gin.Mode() != gin.ReleaseMode
+	if value := os.Getenv("MIGRATION_API_ENABLED"); value != "" {
+		migrationAPIEnabled = value == "true"
+	}
 
-	r.GET("/api/migrate/status", func(c *gin.Context) {
-		status, err := migrationManager.Status()
-		if err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(200, gin.H{"migrations": status})
-	})
+	if migrationAPIEnabled {
+		migrationHandler := handlers.NewMigrationHandler(migrationManager, audit)
+		migrationHandler.RegisterRoutes(r.Group("/api/migrate", middleware.RequireAdminTokenOr(migrationAdminTokens, middleware.RequirePermission(models.PermissionMigrationsRun))))
+	} else {
+		log.Println("Migration API is disabled")
+	}
 
 	// Start server
 	log.Println("Starting server on :8080")
 	if err := r.Run(":8080"); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+}
+
+// newAuthenticator returns the authenticator for mode ("session" or "jwt")
+// and the SessionService holding its sessions or refresh tokens
+func newAuthenticator(mode string, users repository.UserRepository, sessions repository.SessionRepository, roles repository.RoleRepository) (middleware.Authenticator, *services.SessionService, error) {
+	switch mode {
+	case "session":
+		sessionService := services.NewSessionService(sessions, users, services.GetDefaultSessionConfig())
+		return middleware.NewSessionAuthenticator(sessionService, middleware.GetDefaultSessionCookie()), sessionService, nil
+
+	case "jwt":
+		config, err := services.LoadJWTConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		tokens, err := services.NewJWTService(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !tokens.CanSign() {
+			return nil, nil, fmt.Errorf("JWT signing key must be a secret or private key")
+		}
+		refreshTokens := middleware.NewRefreshTokenService(sessions, users, tokens)
+		return middleware.NewJWTAuthenticator(tokens, users, refreshTokens, roles), refreshTokens, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown AUTH_MODE %q (use session or jwt)", mode)
+	}
+}
+
+// getEnv gets environment variable with fallback
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// randomSecret returns a random signing secret for when the environment
+// variable name is not set. What it signs stops working on restart.
+func randomSecret(name, signs string) []byte {
+	log.Printf("%s is not set; %s stop working when the server restarts", name, signs)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate %s: %v", name, err)
+	}
+	return secret
+}
+
+// purgeExpired calls purge every interval to delete expired rows of what.
+// Expired sessions and tokens are already rejected on use; this only keeps
+// the tables small.
+func purgeExpired(what string, purge func(ctx context.Context) (int64, error), interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := purge(context.Background())
+		if err != nil {
+			log.Printf("Failed to purge expired %s: %v", what, err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("Purged %d expired %s", deleted, what)
+		}
+	}
 }
\ No newline at end of file
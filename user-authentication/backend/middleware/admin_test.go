@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseAdminTokens(t *testing.T) {
+	tokens := ParseAdminTokens(" alice:s3cret, deploy:t0ken ,,bare,empty:")
+
+	if len(tokens) != 3 {
+		t.Fatalf("Expected 3 tokens, got %d: %+v", len(tokens), tokens)
+	}
+	if tokens[0].Name != "alice" || tokens[0].Token != "s3cret" {
+		t.Errorf("Unexpected first token: %+v", tokens[0])
+	}
+	if tokens[2].Name != "admin" || tokens[2].Token != "bare" {
+		t.Errorf("Expected unnamed token to be called 'admin', got %+v", tokens[2])
+	}
+
+	if len(ParseAdminTokens("")) != 0 {
+		t.Error("Expected no tokens for an empty value")
+	}
+}
+
+func TestRequireAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin", RequireAdminToken(ParseAdminTokens("alice:s3cret")), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(AdminContextKey))
+	})
+
+	tests := []struct {
+		header string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Basic s3cret", http.StatusUnauthorized},
+		{"Bearer s3cret", http.StatusOK},
+		{"bearer s3cret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("Authorization %q: expected status %d, got %d", tt.header, tt.status, w.Code)
+		}
+		if w.Code == http.StatusOK && w.Body.String() != "alice" {
+			t.Errorf("Expected admin name 'alice' in context, got '%s'", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"user-authentication/models"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APITokenContextKey is the gin context key holding the *models.APIToken
+	// of a request authenticated with a personal API token
+	APITokenContextKey = "api_token"
+	// scopeCheckedContextKey marks a request whose API token was accepted by
+	// RequireScope
+	scopeCheckedContextKey = "api_token_scope_checked"
+)
+
+// APITokenAuthenticator accepts personal API tokens in the "Authorization:
+// Bearer" header and hands every other request, as well as login and
+// logout, to the wrapped Authenticator
+type APITokenAuthenticator struct {
+	Authenticator
+	tokens *services.APITokenService
+}
+
+// NewAPITokenAuthenticator creates an APITokenAuthenticator that falls back
+// to next for requests without an API token
+func NewAPITokenAuthenticator(tokens *services.APITokenService, next Authenticator) *APITokenAuthenticator {
+	return &APITokenAuthenticator{Authenticator: next, tokens: tokens}
+}
+
+// Authenticate verifies the API token and loads its user. The token is
+// stored under APITokenContextKey.
+func (a *APITokenAuthenticator) Authenticate(c *gin.Context) (*models.User, error) {
+	token, ok := bearerToken(c.GetHeader("Authorization"))
+	if !ok || !services.IsAPIToken(token) {
+		return a.Authenticator.Authenticate(c)
+	}
+
+	user, apiToken, err := a.tokens.Authenticate(c.Request.Context(), token)
+	if errors.Is(err, services.ErrInvalidAPIToken) {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.Set(APITokenContextKey, apiToken)
+	return user, nil
+}
+
+// CurrentAPIToken returns the API token the request was authenticated with
+func CurrentAPIToken(c *gin.Context) (*models.APIToken, bool) {
+	value, ok := c.Get(APITokenContextKey)
+	if !ok {
+		return nil, false
+	}
+	token, ok := value.(*models.APIToken)
+	return token, ok
+}
+
+// RequireScope rejects requests authenticated with an API token that lacks
+// scope with 403. Sessions and JWT access tokens are not limited by scopes.
+// API tokens are only accepted on routes that use RequireScope before
+// RequireUser or RequirePermission.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, ok := CurrentAPIToken(c); ok {
+			if !token.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token lacks the " + scope + " scope"})
+				return
+			}
+			c.Set(scopeCheckedContextKey, true)
+		}
+		c.Next()
+	}
+}
+
+// apiTokenAllowed reports whether the route accepts the request's
+// credential: API tokens only pass routes that checked their scope
+func apiTokenAllowed(c *gin.Context) bool {
+	_, ok := CurrentAPIToken(c)
+	return !ok || c.GetBool(scopeCheckedContextKey)
+}
+
+// rejectAPIToken aborts a request whose API token the route does not accept
+func rejectAPIToken(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API tokens cannot be used for this endpoint"})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"user-authentication/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrUnauthenticated is returned by Authenticator.Authenticate when the
+// request carries no credential or one that is not valid (anymore)
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator is a way of proving who a request comes from, such as a
+// session cookie or a bearer token
+type Authenticator interface {
+	// Authenticate returns the user behind the request's credential, or
+	// ErrUnauthenticated when there is none
+	Authenticate(c *gin.Context) (*models.User, error)
+	// Login issues a credential for user after the password was checked and
+	// returns the response body for the login request
+	Login(c *gin.Context, user *models.User) (interface{}, error)
+	// Logout revokes the request's credential
+	Logout(c *gin.Context) error
+}
+
+// Refresher is implemented by authenticators whose credentials can be
+// renewed with a refresh token
+type Refresher interface {
+	// Refresh exchanges refreshToken for new credentials and returns the
+	// response body. It returns ErrUnauthenticated for a bad refresh token.
+	Refresh(c *gin.Context, refreshToken string) (interface{}, error)
+}
+
+// Authenticate stores the user authenticated by auth under UserContextKey.
+// Requests without a valid credential continue anonymously; use RequireUser
+// to reject them.
+func Authenticate(auth Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := auth.Authenticate(c)
+		if errors.Is(err, ErrUnauthenticated) {
+			c.Next()
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate request"})
+			return
+		}
+
+		c.Set(UserContextKey, user)
+		c.Next()
+	}
+}
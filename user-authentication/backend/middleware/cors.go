@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseOrigins parses a comma-separated list of origins such as
+// "https://board.example.com,http://localhost:3000". "*" is not allowed,
+// because the origins are trusted with the session cookie.
+func ParseOrigins(value string) ([]string, error) {
+	var origins []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		origin, ok := normalizeOrigin(entry)
+		if !ok {
+			return nil, fmt.Errorf("origin %q must be scheme://host[:port] without a path", entry)
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// normalizeOrigin returns the canonical form of an origin, as browsers send
+// it in the Origin header
+func normalizeOrigin(value string) (string, bool) {
+	u, err := url.Parse(strings.TrimSuffix(value, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+		return "", false
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), true
+}
+
+// originSet returns origins as a set for lookups
+func originSet(origins []string) map[string]bool {
+	set := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		set[origin] = true
+	}
+	return set
+}
+
+// CORS lets pages on origins call the API with credentials, i.e. with the
+// session cookie. Other origins get no CORS headers, so browsers do not let
+// them read responses or send preflighted requests. OPTIONS requests are
+// answered with 204.
+func CORS(origins []string) gin.HandlerFunc {
+	allowed := originSet(origins)
+	return func(c *gin.Context) {
+		// Responses differ by origin, so caches must not share them
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if origin := c.GetHeader("Origin"); allowed[origin] {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+CSRFHeader)
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseOrigins(t *testing.T) {
+	origins, err := ParseOrigins(" https://Board.Example.com/ ,,http://localhost:3000")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(origins) != 2 || origins[0] != "https://board.example.com" || origins[1] != "http://localhost:3000" {
+		t.Errorf("Unexpected origins: %v", origins)
+	}
+
+	for _, value := range []string{"*", "localhost:3000", "https://example.com/app", "ftp://example.com", "https://user@example.com"} {
+		if _, err := ParseOrigins(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS([]string{"http://localhost:3000"}))
+	r.POST("/posts", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	tests := []struct {
+		method string
+		origin string
+		status int
+		allow  string
+	}{
+		{http.MethodPost, "http://localhost:3000", http.StatusCreated, "http://localhost:3000"},
+		{http.MethodPost, "https://evil.example", http.StatusCreated, ""},
+		{http.MethodPost, "", http.StatusCreated, ""},
+		{http.MethodOptions, "http://localhost:3000", http.StatusNoContent, "http://localhost:3000"},
+		{http.MethodOptions, "https://evil.example", http.StatusNoContent, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/posts", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s from %q: expected status %d, got %d", tt.method, tt.origin, tt.status, w.Code)
+		}
+		if allow := w.Header().Get("Access-Control-Allow-Origin"); allow != tt.allow {
+			t.Errorf("%s from %q: expected Access-Control-Allow-Origin '%s', got '%s'", tt.method, tt.origin, tt.allow, allow)
+		}
+		credentials := w.Header().Get("Access-Control-Allow-Credentials")
+		if (tt.allow != "") != (credentials == "true") {
+			t.Errorf("%s from %q: unexpected Access-Control-Allow-Credentials '%s'", tt.method, tt.origin, credentials)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Origin" {
+			t.Errorf("%s from %q: expected Vary 'Origin', got '%s'", tt.method, tt.origin, vary)
+		}
+	}
+}
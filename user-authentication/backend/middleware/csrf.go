@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRFHeader is the request header that carries the CSRF token
+const CSRFHeader = "X-CSRF-Token"
+
+// csrfRandomBytes is the amount of randomness in a CSRF token
+const csrfRandomBytes = 16
+
+// CSRFProtection protects requests authenticated with the session cookie
+// from cross-site request forgery. Its tokens are synchronizer tokens that
+// are not stored: a random value and an HMAC that binds it to the session
+// cookie, so a token only works with the session it was issued for.
+type CSRFProtection struct {
+	secret  []byte
+	cookie  string
+	origins map[string]bool
+}
+
+// NewCSRFProtection creates a CSRFProtection that signs tokens with secret
+// and checks requests carrying the cookie named sessionCookie. Unsafe
+// requests from pages on other origins than the API's own and
+// trustedOrigins are rejected.
+func NewCSRFProtection(secret []byte, sessionCookie string, trustedOrigins []string) (*CSRFProtection, error) {
+	if len(secret) < sha256.Size {
+		return nil, errors.New("CSRF secret must be at least 32 bytes")
+	}
+	return &CSRFProtection{secret: secret, cookie: sessionCookie, origins: originSet(trustedOrigins)}, nil
+}
+
+// Token returns a new token for the request's session. A request without a
+// session gets a token that only works until it logs in.
+func (p *CSRFProtection) Token(c *gin.Context) (string, error) {
+	random := make([]byte, csrfRandomBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random) + "." + base64.RawURLEncoding.EncodeToString(p.sign(c, random)), nil
+}
+
+// Protect rejects unsafe requests (anything but GET, HEAD and OPTIONS) with
+// 403 when they come from a page on an untrusted origin, or carry the
+// session cookie without a valid token in the X-CSRF-Token header. Requests
+// without the cookie, such as those with a bearer token, need no token.
+func (p *CSRFProtection) Protect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		if !p.trustedOrigin(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Cross-origin request blocked"})
+			return
+		}
+		if _, err := c.Cookie(p.cookie); err == nil && !p.valid(c, c.GetHeader(CSRFHeader)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// trustedOrigin reports whether the page that sent the request may change
+// things. Browsers send Origin, or at least Referer, with cross-site unsafe
+// requests; requests with neither do not come from a browser page.
+func (p *CSRFProtection) trustedOrigin(c *gin.Context) bool {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		referer, err := url.Parse(c.GetHeader("Referer"))
+		if err != nil || referer.Host == "" {
+			return true
+		}
+		origin = referer.Scheme + "://" + referer.Host
+	}
+
+	normalized, ok := normalizeOrigin(origin)
+	if !ok {
+		// Includes "null", sent by sandboxed pages and after redirects
+		return false
+	}
+	if p.origins[normalized] {
+		return true
+	}
+	// Same-origin requests, e.g. from pages served behind the same host
+	return strings.EqualFold(strings.SplitN(normalized, "://", 2)[1], c.Request.Host)
+}
+
+// valid reports whether token was issued for the request's session
+func (p *CSRFProtection) valid(c *gin.Context, token string) bool {
+	encodedRandom, encodedMAC, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+	random, err := base64.RawURLEncoding.DecodeString(encodedRandom)
+	if err != nil || len(random) != csrfRandomBytes {
+		return false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(mac, p.sign(c, random))
+}
+
+// sign returns the HMAC binding random to the request's session cookie
+func (p *CSRFProtection) sign(c *gin.Context, random []byte) []byte {
+	session, _ := c.Cookie(p.cookie)
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte("csrf\x00"))
+	mac.Write([]byte(services.HashSessionToken(session)))
+	mac.Write([]byte{0})
+	mac.Write(random)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testCSRFSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// setupCSRFRouter returns a router with CSRF protection, a token endpoint
+// and a POST endpoint
+func setupCSRFRouter(t *testing.T) *gin.Engine {
+	csrf, err := NewCSRFProtection(testCSRFSecret, "session_id", []string{"http://localhost:3000"})
+	if err != nil {
+		t.Fatalf("Failed to create CSRF protection: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(csrf.Protect())
+	r.GET("/csrf", func(c *gin.Context) {
+		token, err := csrf.Token(c)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, token)
+	})
+	r.POST("/posts", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	return r
+}
+
+// csrfRequest sends a request to path on the API host with the given session cookie and headers,
+// where empty values are left out
+func csrfRequest(r *gin.Engine, method, path, session string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://api.example.com"+path, nil)
+	if session != "" {
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: session})
+	}
+	for name, value := range headers {
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNewCSRFProtection_ShortSecret(t *testing.T) {
+	if _, err := NewCSRFProtection([]byte("short"), "session_id", nil); err == nil {
+		t.Error("Expected an error for a short secret")
+	}
+}
+
+func TestCSRF_Token(t *testing.T) {
+	r := setupCSRFRouter(t)
+	token := csrfRequest(r, http.MethodGet, "/csrf", "alice-session", nil).Body.String()
+	otherToken := csrfRequest(r, http.MethodGet, "/csrf", "bob-session", nil).Body.String()
+	anonymousToken := csrfRequest(r, http.MethodGet, "/csrf", "", nil).Body.String()
+
+	tests := []struct {
+		name    string
+		session string
+		token   string
+		status  int
+	}{
+		{"valid token", "alice-session", token, http.StatusCreated},
+		{"missing token", "alice-session", "", http.StatusForbidden},
+		{"token of another session", "alice-session", otherToken, http.StatusForbidden},
+		{"token from before login", "alice-session", anonymousToken, http.StatusForbidden},
+		{"tampered token", "alice-session", token[:len(token)-2] + "AA", http.StatusForbidden},
+		{"malformed token", "alice-session", "not-a-token", http.StatusForbidden},
+		{"no session cookie", "", "", http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		w := csrfRequest(r, http.MethodPost, "/posts", tt.session, map[string]string{CSRFHeader: tt.token})
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+	}
+
+	if w := csrfRequest(r, http.MethodGet, "/csrf", "alice-session", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected GET to need no token, got status %d", w.Code)
+	}
+}
+
+func TestCSRF_Origin(t *testing.T) {
+	r := setupCSRFRouter(t)
+
+	tests := []struct {
+		origin  string
+		referer string
+		status  int
+	}{
+		{"", "", http.StatusCreated},
+		{"http://localhost:3000", "", http.StatusCreated},
+		{"http://api.example.com", "", http.StatusCreated},
+		{"", "http://localhost:3000/posts/new", http.StatusCreated},
+		{"https://evil.example", "", http.StatusForbidden},
+		{"", "https://evil.example/attack", http.StatusForbidden},
+		{"null", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		// Bearer-authenticated requests are checked for their origin too
+		w := csrfRequest(r, http.MethodPost, "/posts", "", map[string]string{"Origin": tt.origin, "Referer": tt.referer})
+		if w.Code != tt.status {
+			t.Errorf("Origin %q, Referer %q: expected status %d, got %d", tt.origin, tt.referer, tt.status, w.Code)
+		}
+		if w.Code == http.StatusForbidden && !strings.Contains(w.Body.String(), "Cross-origin request blocked") {
+			t.Errorf("Expected a cross-origin error, got %s", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"errors"
+	"user-authentication/models"
+	"user-authentication/repository"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClaimsContextKey is the gin context key holding the *services.AccessClaims
+// of a request authenticated with a JWT
+const ClaimsContextKey = "claims"
+
+// JWTAuthenticator authenticates requests with a short-lived JWT access token
+// in the "Authorization: Bearer" header. Access tokens are renewed with an
+// opaque refresh token, which is stored server-side like a session so that
+// logout can revoke it.
+type JWTAuthenticator struct {
+	tokens  *services.JWTService
+	users   repository.UserRepository
+	refresh *services.SessionService
+	roles   repository.RoleRepository
+}
+
+var (
+	_ Authenticator = (*JWTAuthenticator)(nil)
+	_ Refresher     = (*JWTAuthenticator)(nil)
+)
+
+// NewJWTAuthenticator creates a new JWTAuthenticator. refresh stores the
+// refresh tokens and should be configured with the refresh token lifetime.
+// The user's role names from roles are put into every access token.
+func NewJWTAuthenticator(tokens *services.JWTService, users repository.UserRepository, refresh *services.SessionService, roles repository.RoleRepository) *JWTAuthenticator {
+	return &JWTAuthenticator{tokens: tokens, users: users, refresh: refresh, roles: roles}
+}
+
+// NewRefreshTokenService returns the SessionService that JWTAuthenticator
+// uses for refresh tokens. Refresh tokens are rotated on every use, so their
+// expiry is never extended.
+func NewRefreshTokenService(sessions repository.SessionRepository, users repository.UserRepository, tokens *services.JWTService) *services.SessionService {
+	return services.NewSessionService(sessions, users, &services.SessionConfig{
+		TTL:           tokens.RefreshTTL(),
+		TouchInterval: tokens.RefreshTTL(),
+	})
+}
+
+// Authenticate verifies the bearer token and loads its user. The token's
+// claims are stored under ClaimsContextKey.
+func (a *JWTAuthenticator) Authenticate(c *gin.Context) (*models.User, error) {
+	token, ok := bearerToken(c.GetHeader("Authorization"))
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	claims, err := a.tokens.VerifyAccessToken(token)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return nil, ErrUnauthenticated
+	}
+
+	// VerifyAccessToken already checked that the subject is a user ID
+	userID, _ := claims.UserID()
+	user, err := a.users.GetByID(c.Request.Context(), userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.Set(ClaimsContextKey, claims)
+	return user, nil
+}
+
+// Login issues an access token and a refresh token for user
+func (a *JWTAuthenticator) Login(c *gin.Context, user *models.User) (interface{}, error) {
+	return a.issue(c, user)
+}
+
+// Refresh revokes refreshToken and issues a new access and refresh token
+func (a *JWTAuthenticator) Refresh(c *gin.Context, refreshToken string) (interface{}, error) {
+	ctx := c.Request.Context()
+	user, _, err := a.refresh.Authenticate(ctx, refreshToken)
+	if errors.Is(err, services.ErrInvalidSession) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A refresh token is single use. Authenticate does not consume it, so
+	// only the request whose delete removed the row gets new tokens; a
+	// concurrent reuse of the same token finds it already gone.
+	revoked, err := a.refresh.Revoke(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrUnauthenticated
+	}
+	return a.issue(c, user)
+}
+
+// Logout revokes the refresh token in the request body, if any. Access
+// tokens cannot be revoked and stay valid until they expire.
+func (a *JWTAuthenticator) Logout(c *gin.Context) error {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil
+	}
+	_, err := a.refresh.Revoke(c.Request.Context(), req.RefreshToken)
+	return err
+}
+
+// issue creates a token pair for user
+func (a *JWTAuthenticator) issue(c *gin.Context, user *models.User) (*models.TokenResponse, error) {
+	roles, err := a.roles.ListByUser(c.Request.Context(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	withRoles := *user
+	withRoles.Roles = make([]string, 0, len(roles))
+	for _, role := range roles {
+		withRoles.Roles = append(withRoles.Roles, role.Name)
+	}
+	user = &withRoles
+
+	accessToken, _, err := a.tokens.IssueAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, session, err := a.refresh.Create(c.Request.Context(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		User:             user,
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(a.tokens.AccessTTL().Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int64(session.ExpiresAt.Sub(session.CreatedAt).Seconds()),
+	}, nil
+}
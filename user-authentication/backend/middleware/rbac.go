@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"user-authentication/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionsContextKey is the gin context key holding the permissions of the
+// current user as a map[string]bool
+const PermissionsContextKey = "permissions"
+
+// LoadRoles loads the roles of the user stored by Authenticate. The role
+// names are set on the user and the permissions they grant are stored under
+// PermissionsContextKey. Anonymous requests are left alone.
+func LoadRoles(roles repository.RoleRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		granted, err := roles.ListByUser(c.Request.Context(), user.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user roles"})
+			return
+		}
+
+		names := make([]string, 0, len(granted))
+		permissions := make(map[string]bool)
+		for _, role := range granted {
+			names = append(names, role.Name)
+			for _, permission := range role.Permissions {
+				permissions[permission] = true
+			}
+		}
+
+		// Copy the user so that the authenticator's value is not modified
+		withRoles := *user
+		withRoles.Roles = names
+		c.Set(UserContextKey, &withRoles)
+		c.Set(PermissionsContextKey, permissions)
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the roles of the current user grant
+// permission
+func HasPermission(c *gin.Context, permission string) bool {
+	value, ok := c.Get(PermissionsContextKey)
+	if !ok {
+		return false
+	}
+	permissions, _ := value.(map[string]bool)
+	return permissions[permission]
+}
+
+// RequirePermission rejects requests without permission: anonymous requests
+// with 401 and users whose roles do not grant it with 403. Requests with an
+// API token are rejected unless RequireScope accepted it first. Routes using
+// it must run after LoadRoles.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !apiTokenAllowed(c) {
+			rejectAPIToken(c)
+			return
+		}
+		if HasPermission(c, permission) {
+			c.Next()
+			return
+		}
+
+		if _, ok := CurrentUser(c); !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+	}
+}
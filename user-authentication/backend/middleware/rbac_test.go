@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"user-authentication/models"
+	"user-authentication/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staticRoleRepository grants fixed roles per user ID
+type staticRoleRepository map[int][]*models.Role
+
+func (r staticRoleRepository) List(ctx context.Context) ([]*models.Role, error) {
+	return nil, nil
+}
+
+func (r staticRoleRepository) ListByUser(ctx context.Context, userID int) ([]*models.Role, error) {
+	return r[userID], nil
+}
+
+func (r staticRoleRepository) Grant(ctx context.Context, userID int, role string) error {
+	return repository.ErrRoleNotFound
+}
+
+func (r staticRoleRepository) Revoke(ctx context.Context, userID int, role string) error {
+	return repository.ErrRoleNotFound
+}
+
+func setupRBACRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	roles := staticRoleRepository{
+		1: {{Name: "moderator", Permissions: []string{models.PermissionPostsDeleteAny}}},
+	}
+
+	r := gin.New()
+	// Authenticate the user whose ID is in the X-User-ID header
+	r.Use(func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-User-ID")); err == nil {
+			c.Set(UserContextKey, &models.User{ID: id})
+		}
+	})
+	r.Use(LoadRoles(roles))
+	handler := func(c *gin.Context) {
+		user, _ := CurrentUser(c)
+		if user != nil {
+			c.String(http.StatusOK, strings.Join(user.Roles, ","))
+			return
+		}
+		c.String(http.StatusOK, c.GetString(AdminContextKey))
+	}
+	r.DELETE("/posts", RequireAdminTokenOr(ParseAdminTokens("ops:s3cret"), RequirePermission(models.PermissionPostsDeleteAny)), handler)
+	r.DELETE("/comments", RequirePermission(models.PermissionPostsDeleteAny), handler)
+	return r
+}
+
+func TestRequirePermission(t *testing.T) {
+	r := setupRBACRouter()
+
+	tests := []struct {
+		name   string
+		userID string
+		header string
+		status int
+		body   string
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized, ""},
+		{"user without role", "2", "", http.StatusForbidden, ""},
+		{"moderator", "1", "", http.StatusOK, "moderator"},
+		{"admin token", "", "Bearer s3cret", http.StatusOK, "ops"},
+		{"wrong admin token", "", "Bearer wrong", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/posts", nil)
+		if tt.userID != "" {
+			req.Header.Set("X-User-ID", tt.userID)
+		}
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected body '%s', got '%s'", tt.name, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	if HasPermission(c, models.PermissionRolesManage) {
+		t.Error("Expected no permissions before LoadRoles")
+	}
+
+	c.Set(PermissionsContextKey, map[string]bool{models.PermissionPostsDeleteAny: true})
+	if !HasPermission(c, models.PermissionPostsDeleteAny) {
+		t.Error("Expected granted permission to be allowed")
+	}
+	if HasPermission(c, models.PermissionRolesManage) {
+		t.Error("Expected other permissions to be denied")
+	}
+
+	c.Set(AdminContextKey, "ops")
+	if HasPermission(c, models.PermissionRolesManage) {
+		t.Error("Expected an admin token to grant no permissions")
+	}
+}
+
+func TestRequirePermission_AdminTokenOnlyWhereAccepted(t *testing.T) {
+	r := setupRBACRouter()
+
+	req := httptest.NewRequest(http.MethodDelete, "/comments", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an admin token on a route that does not accept it, got %d", w.Code)
+	}
+}
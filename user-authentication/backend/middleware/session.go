@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"user-authentication/models"
+	"user-authentication/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// UserContextKey is the gin context key holding the *models.User of the
+	// logged-in user
+	UserContextKey = "user"
+	// SessionContextKey is the gin context key holding the current *models.Session
+	SessionContextKey = "session"
+)
+
+// SessionCookie describes the cookie that carries the session token
+type SessionCookie struct {
+	Name     string
+	Path     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// GetDefaultSessionCookie returns the cookie settings from the environment:
+// SESSION_COOKIE_NAME, SESSION_COOKIE_DOMAIN, SESSION_COOKIE_SECURE and
+// SESSION_COOKIE_SAMESITE (lax, strict or none). Cookies are Secure by
+// default in release mode.
+func GetDefaultSessionCookie() SessionCookie {
+	cookie := SessionCookie{
+		Name:     "session_id",
+		Path:     "/",
+		Domain:   os.Getenv("SESSION_COOKIE_DOMAIN"),
+		Secure:   gin.Mode() == gin.ReleaseMode,
+		SameSite: parseSameSite(os.Getenv("SESSION_COOKIE_SAMESITE")),
+	}
+	if name := os.Getenv("SESSION_COOKIE_NAME"); name != "" {
+		cookie.Name = name
+	}
+	if value := os.Getenv("SESSION_COOKIE_SECURE"); value != "" {
+		cookie.Secure = value == "true"
+	}
+	// Browsers reject SameSite=None cookies that are not Secure
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+	return cookie
+}
+
+// parseSameSite maps a SameSite setting to its http constant, defaulting to Lax
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// Read returns the session token sent with the request, if any
+func (sc SessionCookie) Read(c *gin.Context) string {
+	token, err := c.Cookie(sc.Name)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// Set stores token in an HttpOnly cookie that expires at expiresAt
+func (sc SessionCookie) Set(c *gin.Context, token string, expiresAt time.Time) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sc.Name,
+		Value:    token,
+		Path:     sc.Path,
+		Domain:   sc.Domain,
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		Secure:   sc.Secure,
+		HttpOnly: true,
+		SameSite: sc.SameSite,
+	})
+}
+
+// Clear tells the client to delete the session cookie
+func (sc SessionCookie) Clear(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sc.Name,
+		Value:    "",
+		Path:     sc.Path,
+		Domain:   sc.Domain,
+		MaxAge:   -1,
+		Secure:   sc.Secure,
+		HttpOnly: true,
+		SameSite: sc.SameSite,
+	})
+}
+
+// SessionAuthenticator authenticates requests with a server-side session
+// whose token is kept in a cookie
+type SessionAuthenticator struct {
+	sessions *services.SessionService
+	cookie   SessionCookie
+}
+
+var _ Authenticator = (*SessionAuthenticator)(nil)
+
+// NewSessionAuthenticator creates a new SessionAuthenticator
+func NewSessionAuthenticator(sessions *services.SessionService, cookie SessionCookie) *SessionAuthenticator {
+	return &SessionAuthenticator{sessions: sessions, cookie: cookie}
+}
+
+// Authenticate returns the user of the session cookie and stores the session
+// under SessionContextKey. An unknown or expired cookie is cleared.
+func (a *SessionAuthenticator) Authenticate(c *gin.Context) (*models.User, error) {
+	token := a.cookie.Read(c)
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	user, session, err := a.sessions.Authenticate(c.Request.Context(), token)
+	if errors.Is(err, services.ErrInvalidSession) {
+		a.cookie.Clear(c)
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the cookie's lifetime in step with the sliding server-side expiry
+	a.cookie.Set(c, token, session.ExpiresAt)
+	c.Set(SessionContextKey, session)
+	return user, nil
+}
+
+// Login starts a new session for user and sets the session cookie. Any
+// session the client already had is revoked so its ID cannot be reused.
+func (a *SessionAuthenticator) Login(c *gin.Context, user *models.User) (interface{}, error) {
+	ctx := c.Request.Context()
+	if _, err := a.sessions.Revoke(ctx, a.cookie.Read(c)); err != nil {
+		return nil, err
+	}
+
+	token, session, err := a.sessions.Create(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.cookie.Set(c, token, session.ExpiresAt)
+	return user, nil
+}
+
+// Logout revokes the session and clears the cookie
+func (a *SessionAuthenticator) Logout(c *gin.Context) error {
+	if _, err := a.sessions.Revoke(c.Request.Context(), a.cookie.Read(c)); err != nil {
+		return err
+	}
+	a.cookie.Clear(c)
+	return nil
+}
+
+// RequireUser rejects requests that Authenticate did not authenticate, and
+// requests with an API token unless RequireScope accepted it first
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := CurrentUser(c); !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		if !apiTokenAllowed(c) {
+			rejectAPIToken(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects requests whose user has not verified their
+// email address with 403. Routes using it must run after RequireUser.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, ok := CurrentUser(c); !ok || !user.EmailVerified() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser returns the logged-in user stored by Authenticate
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	value, ok := c.Get(UserContextKey)
+	if !ok {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok
+}
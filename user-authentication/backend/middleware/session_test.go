@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseSameSite(t *testing.T) {
+	tests := map[string]http.SameSite{
+		"":       http.SameSiteLaxMode,
+		"lax":    http.SameSiteLaxMode,
+		"Strict": http.SameSiteStrictMode,
+		"none":   http.SameSiteNoneMode,
+		"bogus":  http.SameSiteLaxMode,
+	}
+
+	for value, expected := range tests {
+		if got := parseSameSite(value); got != expected {
+			t.Errorf("parseSameSite(%q): expected %v, got %v", value, expected, got)
+		}
+	}
+}
+
+func TestGetDefaultSessionCookie_SameSiteNoneIsSecure(t *testing.T) {
+	t.Setenv("SESSION_COOKIE_SAMESITE", "none")
+	t.Setenv("SESSION_COOKIE_SECURE", "false")
+
+	if cookie := GetDefaultSessionCookie(); !cookie.Secure {
+		t.Error("Expected SameSite=None cookie to be Secure")
+	}
+}
+
+func TestSessionCookie_Set(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	cookie := SessionCookie{Name: "session_id", Path: "/", Secure: true, SameSite: http.SameSiteStrictMode}
+	cookie.Set(c, "token", time.Now().Add(time.Hour))
+
+	header := w.Header().Get("Set-Cookie")
+	for _, attr := range []string{"session_id=token", "HttpOnly", "Secure", "SameSite=Strict", "Max-Age="} {
+		if !strings.Contains(header, attr) {
+			t.Errorf("Expected Set-Cookie to contain %q, got %q", attr, header)
+		}
+	}
+}
+
+func TestRequireUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/anonymous", RequireUser(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/user", func(c *gin.Context) {
+		c.Set(UserContextKey, &models.User{ID: 1})
+	}, RequireUser(), func(c *gin.Context) {
+		user, _ := CurrentUser(c)
+		c.JSON(http.StatusOK, gin.H{"id": user.ID})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/anonymous", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifiedAt := time.Now()
+
+	r := gin.New()
+	r.GET("/unverified", func(c *gin.Context) {
+		c.Set(UserContextKey, &models.User{ID: 1})
+	}, RequireVerifiedEmail(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/verified", func(c *gin.Context) {
+		c.Set(UserContextKey, &models.User{ID: 1, EmailVerifiedAt: &verifiedAt})
+	}, RequireVerifiedEmail(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unverified", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verified", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
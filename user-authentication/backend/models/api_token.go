@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Scopes of personal API tokens. A token can only be used on the endpoints
+// that accept one of its scopes; sessions and JWT access tokens are not
+// limited by scopes.
+const (
+	// ScopePostsRead allows listing the token owner's own posts
+	ScopePostsRead = "posts:read"
+	// ScopePostsWrite allows creating, editing and deleting posts
+	ScopePostsWrite = "posts:write"
+)
+
+// Scopes lists every scope a token can be given
+var Scopes = []string{
+	ScopePostsRead,
+	ScopePostsWrite,
+}
+
+// APIToken is a personal access token a user created for scripts. The
+// token itself is only shown when it is created; TokenHash is its SHA-256.
+type APIToken struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Name      string `json:"name"`
+	TokenHash string `json:"-"`
+	// Prefix is the start of the token, so that users can tell tokens apart
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// HasScope reports whether the token was given scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPITokenRequest represents the request body for creating a token
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// ExpiresInDays is how long the token is valid; 0 means it does not expire
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// CreateAPITokenResponse is returned when a token is created. Token is not
+// shown again.
+type CreateAPITokenResponse struct {
+	Token    string    `json:"token"`
+	APIToken *APIToken `json:"api_token"`
+}
+
+// IntrospectTokenRequest represents the request body of token introspection
+type IntrospectTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// IntrospectTokenResponse describes a token to another service, following
+// OAuth 2.0 Token Introspection (RFC 7662). Only Active is set for tokens
+// that are unknown, expired or revoked.
+type IntrospectTokenResponse struct {
+	Active   bool     `json:"active"`
+	Scope    string   `json:"scope,omitempty"`
+	Subject  string   `json:"sub,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	// ExpiresAt is a Unix time; tokens without expiry leave it out
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
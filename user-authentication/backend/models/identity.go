@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserIdentity links an account at an external identity provider to a user
+type UserIdentity struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
+	// Provider is the name of the configured OpenID Connect provider
+	Provider string `json:"provider"`
+	// Subject is the provider's stable ID for the account
+	Subject string `json:"subject"`
+	// Email is the address the provider reported when the identity was linked
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
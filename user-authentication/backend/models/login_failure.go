@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Scopes that failed logins are counted in
+const (
+	// LoginFailureScopeAccount counts failures per email address
+	LoginFailureScopeAccount = "account"
+	// LoginFailureScopeIP counts failures per client IP
+	LoginFailureScopeIP = "ip"
+)
+
+// LoginFailure counts recent failed logins for an email address or client IP
+type LoginFailure struct {
+	Scope         string
+	Subject       string
+	Failures      int
+	LastFailureAt time.Time
+}
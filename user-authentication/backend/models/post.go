@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Post represents a bulletin board post written by a user
+type Post struct {
+	ID        int       `json:"id"`
+	AuthorID  int       `json:"author_id"`
+	Author    string    `json:"author"` // the author's username
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreatePostRequest represents the request body for creating a post
+type CreatePostRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// UpdatePostRequest represents the request body for updating a post
+type UpdatePostRequest struct {
+	Content string `json:"content" binding:"required"`
+}
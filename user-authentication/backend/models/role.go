@@ -0,0 +1,33 @@
+package models
+
+// Permissions checked by the API. Roles are granted a set of these in the
+// role_permissions table.
+const (
+	// PermissionPostsUpdateAny allows editing posts written by other users
+	PermissionPostsUpdateAny = "posts:update:any"
+	// PermissionPostsDeleteAny allows deleting posts written by other users
+	PermissionPostsDeleteAny = "posts:delete:any"
+	// PermissionRolesManage allows granting and revoking roles
+	PermissionRolesManage = "roles:manage"
+	// PermissionMigrationsRun allows using the migration API
+	PermissionMigrationsRun = "migrations:run"
+	// PermissionUsersUnlock allows clearing failed login attempts
+	PermissionUsersUnlock = "users:unlock"
+)
+
+// Permissions lists every permission the API checks
+var Permissions = []string{
+	PermissionPostsUpdateAny,
+	PermissionPostsDeleteAny,
+	PermissionRolesManage,
+	PermissionMigrationsRun,
+	PermissionUsersUnlock,
+}
+
+// Role is a named set of permissions that can be granted to users
+type Role struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
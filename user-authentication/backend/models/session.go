@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Session is a server-side login session. ID is the SHA-256 hash of the
+// token stored in the client's cookie, never the token itself.
+type Session struct {
+	ID         string
+	UserID     int
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
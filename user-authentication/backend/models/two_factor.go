@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// TOTP is a user's time-based one-time password secret
+type TOTP struct {
+	UserID int
+	// Secret is the base32 shared secret of the authenticator app
+	Secret string
+	// EnabledAt is nil while the enrollment has not been confirmed
+	EnabledAt *time.Time
+	// LastUsedStep is the time step of the last accepted code
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// TOTPEnrollment is returned when a user starts setting up an authenticator app
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI to show as a QR code
+	URI string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus describes a user's second factor
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorChallenge is the login response for users with two-factor
+// authentication. The token must be sent back with a code to finish logging in.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Token             string `json:"two_factor_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// TwoFactorLoginRequest represents the request body for the second login step
+type TwoFactorLoginRequest struct {
+	Token string `json:"two_factor_token" binding:"required"`
+	// Code is a code from the authenticator app or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest represents a request body confirming an action with a
+// code from the authenticator app or a recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// User represents a registered user
+type User struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+	// EmailVerifiedAt is nil until the user has verified their email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	// Roles holds the names of the user's roles once they have been loaded
+	Roles []string `json:"roles,omitempty"`
+}
+
+// RegisterRequest represents the request body for registering a user
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// EmailVerified reports whether the user has verified their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// ResendVerificationRequest represents the request body for resending the
+// verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// LoginRequest represents the request body for logging in
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
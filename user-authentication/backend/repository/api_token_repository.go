@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-authentication/models"
+)
+
+// ErrAPITokenNotFound is returned when no API token matches the lookup
+var ErrAPITokenNotFound = errors.New("API token not found")
+
+// APITokenRepository abstracts storage of personal API tokens
+type APITokenRepository interface {
+	// Create inserts token and fills in its ID
+	Create(ctx context.Context, token *models.APIToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	ListByUser(ctx context.Context, userID int) ([]*models.APIToken, error)
+	// Touch records that the token was used
+	Touch(ctx context.Context, id int, lastUsedAt time.Time) error
+	// Delete removes the token id of userID. It returns ErrAPITokenNotFound
+	// when userID has no such token.
+	Delete(ctx context.Context, userID, id int) error
+	// DeleteExpired removes tokens that expired before now and returns how many
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"user-authentication/models"
+)
+
+var (
+	// ErrIdentityNotFound is returned when no identity matches the lookup
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrDuplicateIdentity is returned when the provider account is already
+	// linked to a user
+	ErrDuplicateIdentity = errors.New("identity already linked")
+)
+
+// IdentityRepository abstracts storage of external identities
+type IdentityRepository interface {
+	Get(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	ListByUser(ctx context.Context, userID int) ([]*models.UserIdentity, error)
+	// Create inserts identity and fills in its ID. It returns
+	// ErrDuplicateIdentity when the provider account is already linked.
+	Create(ctx context.Context, identity *models.UserIdentity) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-authentication/models"
+)
+
+// ErrLoginFailureNotFound is returned when there are no recorded failures for the lookup
+var ErrLoginFailureNotFound = errors.New("login failure not found")
+
+// LoginFailureRepository abstracts storage of failed login counters
+type LoginFailureRepository interface {
+	Get(ctx context.Context, scope, subject string) (*models.LoginFailure, error)
+	// Increment atomically records a failure at now and returns the updated
+	// counter. A counter whose last failure is before resetBefore starts
+	// again from one.
+	Increment(ctx context.Context, scope, subject string, now, resetBefore time.Time) (*models.LoginFailure, error)
+	// Delete clears the counter of subject
+	Delete(ctx context.Context, scope, subject string) error
+	// DeleteBefore removes counters whose last failure is before before and
+	// returns how many
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-authentication/models"
+)
+
+const apiTokenColumns = "id, user_id, name, token_hash, prefix, scopes, created_at, expires_at, last_used_at"
+
+// MySQLAPITokenRepository stores personal API tokens in the api_tokens table
+type MySQLAPITokenRepository struct {
+	db *sql.DB
+}
+
+var _ APITokenRepository = (*MySQLAPITokenRepository)(nil)
+
+// NewMySQLAPITokenRepository creates a new MySQLAPITokenRepository
+func NewMySQLAPITokenRepository(db *sql.DB) *MySQLAPITokenRepository {
+	return &MySQLAPITokenRepository{db: db}
+}
+
+// Create inserts a new API token
+func (r *MySQLAPITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	query := "INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	result, err := r.db.ExecContext(ctx, query, token.UserID, token.Name, token.TokenHash, token.Prefix, strings.Join(token.Scopes, " "), token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get API token ID: %w", err)
+	}
+	token.ID = int(id)
+	return nil
+}
+
+// GetByHash returns the API token whose SHA-256 is tokenHash
+func (r *MySQLAPITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	query := "SELECT " + apiTokenColumns + " FROM api_tokens WHERE token_hash = ?"
+
+	token, err := scanAPIToken(r.db.QueryRowContext(ctx, query, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	return token, nil
+}
+
+// ListByUser returns the API tokens of a user, oldest first
+func (r *MySQLAPITokenRepository) ListByUser(ctx context.Context, userID int) ([]*models.APIToken, error) {
+	query := "SELECT " + apiTokenColumns + " FROM api_tokens WHERE user_id = ? ORDER BY id"
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*models.APIToken, 0)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Touch sets last_used_at of an API token
+func (r *MySQLAPITokenRepository) Touch(ctx context.Context, id int, lastUsedAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, "UPDATE api_tokens SET last_used_at = ? WHERE id = ?", lastUsedAt, id); err != nil {
+		return fmt.Errorf("failed to touch API token: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an API token of a user
+func (r *MySQLAPITokenRepository) Delete(ctx context.Context, userID, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API token: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes every API token that expired before now
+func (r *MySQLAPITokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE expires_at < ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired API tokens: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// scanAPIToken reads a row selected with apiTokenColumns
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*models.APIToken, error) {
+	var (
+		token      models.APIToken
+		scopes     string
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.Prefix, &scopes, &token.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"user-authentication/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const identityColumns = "id, user_id, provider, subject, email, created_at"
+
+// MySQLIdentityRepository stores external identities in the user_identities table
+type MySQLIdentityRepository struct {
+	db *sql.DB
+}
+
+var _ IdentityRepository = (*MySQLIdentityRepository)(nil)
+
+// NewMySQLIdentityRepository creates a new MySQLIdentityRepository
+func NewMySQLIdentityRepository(db *sql.DB) *MySQLIdentityRepository {
+	return &MySQLIdentityRepository{db: db}
+}
+
+// Get returns the identity of a provider account
+func (r *MySQLIdentityRepository) Get(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	query := "SELECT " + identityColumns + " FROM user_identities WHERE provider = ? AND subject = ?"
+
+	var identity models.UserIdentity
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// ListByUser returns the identities linked to a user
+func (r *MySQLIdentityRepository) ListByUser(ctx context.Context, userID int) ([]*models.UserIdentity, error) {
+	query := "SELECT " + identityColumns + " FROM user_identities WHERE user_id = ? ORDER BY id"
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := make([]*models.UserIdentity, 0)
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, &identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+// Create links a provider account to a user
+func (r *MySQLIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	query := "INSERT INTO user_identities (user_id, provider, subject, email, created_at) VALUES (?, ?, ?, ?, ?)"
+	result, err := r.db.ExecContext(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return ErrDuplicateIdentity
+		}
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get identity ID: %w", err)
+	}
+	identity.ID = int(id)
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/models"
+)
+
+// MySQLLoginFailureRepository stores failed login counters in the login_failures table
+type MySQLLoginFailureRepository struct {
+	db *sql.DB
+}
+
+var _ LoginFailureRepository = (*MySQLLoginFailureRepository)(nil)
+
+// NewMySQLLoginFailureRepository creates a new MySQLLoginFailureRepository
+func NewMySQLLoginFailureRepository(db *sql.DB) *MySQLLoginFailureRepository {
+	return &MySQLLoginFailureRepository{db: db}
+}
+
+// Get returns the failure counter of subject
+func (r *MySQLLoginFailureRepository) Get(ctx context.Context, scope, subject string) (*models.LoginFailure, error) {
+	return r.get(ctx, r.db, scope, subject)
+}
+
+// Increment records a failure with an upsert, so concurrent failures are all counted
+func (r *MySQLLoginFailureRepository) Increment(ctx context.Context, scope, subject string, now, resetBefore time.Time) (*models.LoginFailure, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// MySQL applies the assignments left to right, so failures still sees
+	// the previous last_failure_at
+	query := `INSERT INTO login_failures (scope, subject, failures, last_failure_at) VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(last_failure_at < ?, 1, failures + 1),
+			last_failure_at = ?`
+	if _, err := tx.ExecContext(ctx, query, scope, subject, now, resetBefore, now); err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	failure, err := r.get(ctx, tx, scope, subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit login failure: %w", err)
+	}
+	return failure, nil
+}
+
+// Delete removes the failure counter of subject
+func (r *MySQLLoginFailureRepository) Delete(ctx context.Context, scope, subject string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM login_failures WHERE scope = ? AND subject = ?", scope, subject); err != nil {
+		return fmt.Errorf("failed to delete login failures: %w", err)
+	}
+	return nil
+}
+
+// DeleteBefore removes counters that have not changed since before
+func (r *MySQLLoginFailureRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM login_failures WHERE last_failure_at < ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale login failures: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// queryRower is satisfied by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *MySQLLoginFailureRepository) get(ctx context.Context, q queryRower, scope, subject string) (*models.LoginFailure, error) {
+	query := "SELECT scope, subject, failures, last_failure_at FROM login_failures WHERE scope = ? AND subject = ?"
+
+	var failure models.LoginFailure
+	err := q.QueryRowContext(ctx, query, scope, subject).Scan(&failure.Scope, &failure.Subject, &failure.Failures, &failure.LastFailureAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLoginFailureNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login failures: %w", err)
+	}
+	return &failure, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/models"
+)
+
+// MySQLPasswordResetRepository stores password resets in the password_resets table
+type MySQLPasswordResetRepository struct {
+	db *sql.DB
+}
+
+var _ PasswordResetRepository = (*MySQLPasswordResetRepository)(nil)
+
+// NewMySQLPasswordResetRepository creates a new MySQLPasswordResetRepository
+func NewMySQLPasswordResetRepository(db *sql.DB) *MySQLPasswordResetRepository {
+	return &MySQLPasswordResetRepository{db: db}
+}
+
+// Create inserts a new password reset
+func (r *MySQLPasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	query := "INSERT INTO password_resets (id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)"
+	if _, err := r.db.ExecContext(ctx, query, reset.ID, reset.UserID, reset.CreatedAt, reset.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+	return nil
+}
+
+// Get returns the password reset with the given ID
+func (r *MySQLPasswordResetRepository) Get(ctx context.Context, id string) (*models.PasswordReset, error) {
+	query := "SELECT id, user_id, created_at, expires_at, used_at FROM password_resets WHERE id = ?"
+
+	var (
+		reset  models.PasswordReset
+		usedAt sql.NullTime
+	)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&reset.ID, &reset.UserID, &reset.CreatedAt, &reset.ExpiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password reset: %w", err)
+	}
+	if usedAt.Valid {
+		reset.UsedAt = &usedAt.Time
+	}
+	return &reset, nil
+}
+
+// MarkUsed sets used_at on an unused password reset
+func (r *MySQLPasswordResetRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL", usedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to redeem password reset: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrPasswordResetNotFound
+	}
+	return nil
+}
+
+// DeleteByUser removes every password reset of a user
+func (r *MySQLPasswordResetRepository) DeleteByUser(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete password resets: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes every password reset that expired before now
+func (r *MySQLPasswordResetRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM password_resets WHERE expires_at < ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired password resets: %w", err)
+	}
+	return result.RowsAffected()
+}
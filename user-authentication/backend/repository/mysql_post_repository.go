@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"user-authentication/models"
+)
+
+// postSelect reads posts together with their author's username
+const postSelect = `SELECT p.id, p.author_id, u.username, p.content, p.created_at, p.updated_at
+	FROM posts p JOIN users u ON u.id = p.author_id`
+
+// MySQLPostRepository stores posts in the posts table
+type MySQLPostRepository struct {
+	db *sql.DB
+}
+
+var _ PostRepository = (*MySQLPostRepository)(nil)
+
+// NewMySQLPostRepository creates a new MySQLPostRepository
+func NewMySQLPostRepository(db *sql.DB) *MySQLPostRepository {
+	return &MySQLPostRepository{db: db}
+}
+
+// List returns all posts, newest first
+func (r *MySQLPostRepository) List(ctx context.Context) ([]*models.Post, error) {
+	return r.query(ctx, postSelect+" ORDER BY p.created_at DESC, p.id DESC")
+}
+
+// ListByAuthor returns the posts written by authorID, newest first
+func (r *MySQLPostRepository) ListByAuthor(ctx context.Context, authorID int) ([]*models.Post, error) {
+	return r.query(ctx, postSelect+" WHERE p.author_id = ? ORDER BY p.created_at DESC, p.id DESC", authorID)
+}
+
+// Get returns the post with the given ID
+func (r *MySQLPostRepository) Get(ctx context.Context, id int) (*models.Post, error) {
+	post, err := scanPost(r.db.QueryRowContext(ctx, postSelect+" WHERE p.id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return post, nil
+}
+
+// Create inserts a new post
+func (r *MySQLPostRepository) Create(ctx context.Context, post *models.Post) error {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO posts (author_id, content) VALUES (?, ?)", post.AuthorID, post.Content)
+	if err != nil {
+		return fmt.Errorf("failed to create post: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get post ID: %w", err)
+	}
+
+	created, err := r.Get(ctx, int(id))
+	if err != nil {
+		return err
+	}
+	*post = *created
+	return nil
+}
+
+// Update replaces the content of a post and returns the stored result
+func (r *MySQLPostRepository) Update(ctx context.Context, id int, content string) (*models.Post, error) {
+	// MySQL reports zero affected rows when the content is unchanged, so
+	// existence is decided by re-reading the row
+	if _, err := r.db.ExecContext(ctx, "UPDATE posts SET content = ? WHERE id = ?", content, id); err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete removes a post
+func (r *MySQLPostRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// query runs a postSelect query and scans every row
+func (r *MySQLPostRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Post, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posts: %w", err)
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate posts: %w", err)
+	}
+	return posts, nil
+}
+
+// scanPost reads one postSelect row
+func scanPost(row interface{ Scan(...interface{}) error }) (*models.Post, error) {
+	var post models.Post
+	err := row.Scan(&post.ID, &post.AuthorID, &post.Author, &post.Content, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
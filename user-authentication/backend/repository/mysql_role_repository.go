@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"user-authentication/models"
+)
+
+// roleSelect reads roles together with their permissions, one row per
+// permission. Roles without permissions have a NULL permission.
+const roleSelect = `SELECT r.id, r.name, r.description, rp.permission
+	FROM roles r LEFT JOIN role_permissions rp ON rp.role_id = r.id`
+
+// MySQLRoleRepository stores roles in the roles, role_permissions and
+// user_roles tables
+type MySQLRoleRepository struct {
+	db *sql.DB
+}
+
+var _ RoleRepository = (*MySQLRoleRepository)(nil)
+
+// NewMySQLRoleRepository creates a new MySQLRoleRepository
+func NewMySQLRoleRepository(db *sql.DB) *MySQLRoleRepository {
+	return &MySQLRoleRepository{db: db}
+}
+
+// List returns every role with its permissions
+func (r *MySQLRoleRepository) List(ctx context.Context) ([]*models.Role, error) {
+	return r.query(ctx, roleSelect+" ORDER BY r.name, rp.permission")
+}
+
+// ListByUser returns the roles granted to userID
+func (r *MySQLRoleRepository) ListByUser(ctx context.Context, userID int) ([]*models.Role, error) {
+	return r.query(ctx, roleSelect+" JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = ? ORDER BY r.name, rp.permission", userID)
+}
+
+// Grant gives userID the named role
+func (r *MySQLRoleRepository) Grant(ctx context.Context, userID int, role string) error {
+	roleID, err := r.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT IGNORE INTO user_roles (user_id, role_id) VALUES (?, ?)"
+	if _, err := r.db.ExecContext(ctx, query, userID, roleID); err != nil {
+		return fmt.Errorf("failed to grant role: %w", err)
+	}
+	return nil
+}
+
+// Revoke takes the named role away from userID
+func (r *MySQLRoleRepository) Revoke(ctx context.Context, userID int, role string) error {
+	roleID, err := r.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM user_roles WHERE user_id = ? AND role_id = ?"
+	if _, err := r.db.ExecContext(ctx, query, userID, roleID); err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	return nil
+}
+
+// roleID looks up the ID of the named role
+func (r *MySQLRoleRepository) roleID(ctx context.Context, name string) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM roles WHERE name = ?", name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrRoleNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get role: %w", err)
+	}
+	return id, nil
+}
+
+// query runs a roleSelect query ordered by role and folds the permission
+// rows into their roles
+func (r *MySQLRoleRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Role, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []*models.Role{}
+	for rows.Next() {
+		var (
+			role       models.Role
+			permission sql.NullString
+		)
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].ID != role.ID {
+			role.Permissions = []string{}
+			roles = append(roles, &role)
+		}
+		if permission.Valid {
+			last := roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate roles: %w", err)
+	}
+	return roles, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/models"
+)
+
+// MySQLSessionRepository stores sessions in the sessions table
+type MySQLSessionRepository struct {
+	db *sql.DB
+}
+
+var _ SessionRepository = (*MySQLSessionRepository)(nil)
+
+// NewMySQLSessionRepository creates a new MySQLSessionRepository
+func NewMySQLSessionRepository(db *sql.DB) *MySQLSessionRepository {
+	return &MySQLSessionRepository{db: db}
+}
+
+// Create inserts a new session
+func (r *MySQLSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	query := "INSERT INTO sessions (id, user_id, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?)"
+	_, err := r.db.ExecContext(ctx, query, session.ID, session.UserID, session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// Get returns the session with the given ID
+func (r *MySQLSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	query := "SELECT id, user_id, created_at, last_seen_at, expires_at FROM sessions WHERE id = ?"
+
+	var session models.Session
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID, &session.UserID, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &session, nil
+}
+
+// Touch updates the last activity and expiry of a session
+func (r *MySQLSessionRepository) Touch(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error {
+	query := "UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?"
+	if _, err := r.db.ExecContext(ctx, query, lastSeenAt, expiresAt, id); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a session and reports whether a row was deleted
+func (r *MySQLSessionRepository) Delete(ctx context.Context, id string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete session: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete session: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// DeleteByUser removes every session of a user
+func (r *MySQLSessionRepository) DeleteByUser(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes every session that expired before now
+func (r *MySQLSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
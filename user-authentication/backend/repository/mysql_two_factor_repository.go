@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/models"
+)
+
+// MySQLTwoFactorRepository stores TOTP secrets in the user_totp table and
+// recovery codes in the recovery_codes table
+type MySQLTwoFactorRepository struct {
+	db *sql.DB
+}
+
+var _ TwoFactorRepository = (*MySQLTwoFactorRepository)(nil)
+
+// NewMySQLTwoFactorRepository creates a new MySQLTwoFactorRepository
+func NewMySQLTwoFactorRepository(db *sql.DB) *MySQLTwoFactorRepository {
+	return &MySQLTwoFactorRepository{db: db}
+}
+
+// GetTOTP returns the TOTP secret of a user
+func (r *MySQLTwoFactorRepository) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	query := "SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_totp WHERE user_id = ?"
+
+	var (
+		totp      models.TOTP
+		enabledAt sql.NullTime
+	)
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&totp.UserID, &totp.Secret, &enabledAt, &totp.LastUsedStep, &totp.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTOTPNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+	if enabledAt.Valid {
+		totp.EnabledAt = &enabledAt.Time
+	}
+	return &totp, nil
+}
+
+// SaveTOTP stores a pending TOTP secret
+func (r *MySQLTwoFactorRepository) SaveTOTP(ctx context.Context, totp *models.TOTP) error {
+	query := "REPLACE INTO user_totp (user_id, secret, enabled_at, last_used_step, created_at) VALUES (?, ?, NULL, 0, ?)"
+	if _, err := r.db.ExecContext(ctx, query, totp.UserID, totp.Secret, totp.CreatedAt); err != nil {
+		return fmt.Errorf("failed to save totp: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP sets enabled_at on a pending TOTP secret and stores the
+// recovery codes in the same transaction
+func (r *MySQLTwoFactorRepository) EnableTOTP(ctx context.Context, userID int, enabledAt time.Time, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE user_totp SET enabled_at = ?, last_used_step = ? WHERE user_id = ? AND enabled_at IS NULL"
+	if err := r.updateTOTP(ctx, tx, query, enabledAt, step, userID); err != nil {
+		return err
+	}
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep advances last_used_step
+func (r *MySQLTwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	query := "UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?"
+	return r.updateTOTP(ctx, r.db, query, step, userID, step)
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// updateTOTP runs an update on user_totp and returns ErrTOTPNotFound when it
+// changed nothing
+func (r *MySQLTwoFactorRepository) updateTOTP(ctx context.Context, e execer, query string, args ...interface{}) error {
+	result, err := e.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update totp: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrTOTPNotFound
+	}
+	return nil
+}
+
+// DeleteTOTP removes the TOTP secret and recovery codes of a user
+func (r *MySQLTwoFactorRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete totp: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes deletes the user's recovery codes and inserts new ones
+func (r *MySQLTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and inserts new ones
+// within tx
+func (r *MySQLTwoFactorRepository) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hash); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode sets used_at on an unused recovery code
+func (r *MySQLTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, hash string, usedAt time.Time) error {
+	query := "UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL"
+	result, err := r.db.ExecContext(ctx, query, usedAt, userID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *MySQLTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+	"user-authentication/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTwoFactorTestDB returns an in-memory SQLite database with the two
+// factor tables; the repository's statements are plain enough for SQLite. A
+// CHECK constraint makes inserting the recovery code "fail" fail.
+func newTwoFactorTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE user_totp (
+			user_id INTEGER PRIMARY KEY,
+			secret TEXT NOT NULL,
+			enabled_at DATETIME NULL,
+			last_used_step INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		);
+		CREATE TABLE recovery_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			code_hash TEXT NOT NULL CHECK (code_hash <> 'fail'),
+			used_at DATETIME NULL
+		);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	return db
+}
+
+func TestMySQLTwoFactorRepository_EnableTOTP(t *testing.T) {
+	db := newTwoFactorTestDB(t)
+	repo := NewMySQLTwoFactorRepository(db)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := repo.SaveTOTP(ctx, &models.TOTP{UserID: 1, Secret: "secret", CreatedAt: now}); err != nil {
+		t.Fatalf("SaveTOTP failed: %v", err)
+	}
+
+	// A failing recovery code insert must not leave two-factor enabled
+	if err := repo.EnableTOTP(ctx, 1, now, 10, []string{"a", "fail"}); err == nil {
+		t.Fatal("Expected EnableTOTP to fail")
+	}
+	totp, err := repo.GetTOTP(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetTOTP failed: %v", err)
+	}
+	if totp.EnabledAt != nil || totp.LastUsedStep != 0 {
+		t.Errorf("Expected the secret to stay pending, got %+v", totp)
+	}
+	if count, _ := repo.CountRecoveryCodes(ctx, 1); count != 0 {
+		t.Errorf("Expected no recovery codes after the rollback, got %d", count)
+	}
+
+	if err := repo.EnableTOTP(ctx, 1, now, 10, []string{"a", "b"}); err != nil {
+		t.Fatalf("EnableTOTP failed: %v", err)
+	}
+	totp, err = repo.GetTOTP(ctx, 1)
+	if err != nil || totp.EnabledAt == nil || totp.LastUsedStep != 10 {
+		t.Errorf("Expected the secret to be enabled at step 10, got %+v (err: %v)", totp, err)
+	}
+	if count, _ := repo.CountRecoveryCodes(ctx, 1); count != 2 {
+		t.Errorf("Expected 2 recovery codes, got %d", count)
+	}
+
+	// Enabling twice, e.g. by two concurrent requests, keeps the first codes
+	if err := repo.EnableTOTP(ctx, 1, now, 11, []string{"c"}); !errors.Is(err, ErrTOTPNotFound) {
+		t.Errorf("Expected ErrTOTPNotFound for an enabled secret, got %v", err)
+	}
+	if err := repo.UseRecoveryCode(ctx, 1, "a", now); err != nil {
+		t.Errorf("Expected the first recovery codes to remain, got %v", err)
+	}
+}
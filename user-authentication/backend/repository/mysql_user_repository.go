@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-authentication/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for unique index violations
+const mysqlDuplicateEntry = 1062
+
+const userColumns = "id, username, email, password_hash, email_verified_at, created_at, updated_at"
+
+// MySQLUserRepository stores users in the users table
+type MySQLUserRepository struct {
+	db *sql.DB
+}
+
+var _ UserRepository = (*MySQLUserRepository)(nil)
+
+// NewMySQLUserRepository creates a new MySQLUserRepository
+func NewMySQLUserRepository(db *sql.DB) *MySQLUserRepository {
+	return &MySQLUserRepository{db: db}
+}
+
+// Create inserts a new user
+func (r *MySQLUserRepository) Create(ctx context.Context, user *models.User) error {
+	query := "INSERT INTO users (username, email, password_hash) VALUES (?, ?, ?)"
+	result, err := r.db.ExecContext(ctx, query, user.Username, user.Email, user.PasswordHash)
+	if err != nil {
+		if dupErr := duplicateUserError(err); dupErr != nil {
+			return dupErr
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get user ID: %w", err)
+	}
+
+	created, err := r.GetByID(ctx, int(id))
+	if err != nil {
+		return err
+	}
+	*user = *created
+	return nil
+}
+
+// GetByID returns the user with the given ID
+func (r *MySQLUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return r.getBy(ctx, "id", id)
+}
+
+// GetByEmail returns the user with the given email
+func (r *MySQLUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.getBy(ctx, "email", email)
+}
+
+// GetByUsername returns the user with the given username
+func (r *MySQLUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.getBy(ctx, "username", username)
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *MySQLUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// A new hash always changes the row, so zero rows means no such user
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// MarkEmailVerified sets email_verified_at unless it is already set
+func (r *MySQLUserRepository) MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error {
+	query := "UPDATE users SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL"
+	if _, err := r.db.ExecContext(ctx, query, verifiedAt, id); err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	return nil
+}
+
+// getBy returns the user whose column equals value; column must be a trusted constant
+func (r *MySQLUserRepository) getBy(ctx context.Context, column string, value interface{}) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE " + column + " = ?"
+
+	var user models.User
+	var verifiedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, value).Scan(
+		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &verifiedAt, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	return &user, nil
+}
+
+// duplicateUserError maps a MySQL duplicate entry error on the users table's
+// unique indexes to ErrDuplicateEmail or ErrDuplicateUsername. Other errors
+// yield nil.
+func duplicateUserError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlDuplicateEntry {
+		return nil
+	}
+
+	// The message ends with "for key 'users.email'" on MySQL 8 and
+	// "for key 'email'" on older servers
+	_, key, found := strings.Cut(mysqlErr.Message, "for key ")
+	if !found {
+		return nil
+	}
+	key = strings.Trim(key, "'`")
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+
+	switch key {
+	case "email", "idx_users_email":
+		return ErrDuplicateEmail
+	case "username", "idx_users_username":
+		return ErrDuplicateUsername
+	}
+	return nil
+}
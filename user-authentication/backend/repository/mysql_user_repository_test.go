@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestDuplicateUserError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.email'"}, ErrDuplicateEmail},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'email'"}, ErrDuplicateEmail},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'alice' for key 'users.username'"}, ErrDuplicateUsername},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'alice' for key 'users.idx_users_username'"}, ErrDuplicateUsername},
+		{fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'users.email'"}), ErrDuplicateEmail},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'users.PRIMARY'"}, nil},
+		{&mysql.MySQLError{Number: 1146, Message: "Table 'users' doesn't exist"}, nil},
+		{errors.New("connection refused"), nil},
+	}
+
+	for _, tt := range tests {
+		if got := duplicateUserError(tt.err); got != tt.expected {
+			t.Errorf("duplicateUserError(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-authentication/models"
+)
+
+// ErrPasswordResetNotFound is returned when no unused password reset matches the lookup
+var ErrPasswordResetNotFound = errors.New("password reset not found")
+
+// PasswordResetRepository abstracts storage of password reset tokens
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	Get(ctx context.Context, id string) (*models.PasswordReset, error)
+	// MarkUsed redeems the reset. It returns ErrPasswordResetNotFound when
+	// the reset does not exist or was already used, so that two concurrent
+	// requests cannot both redeem it.
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) error
+	// DeleteByUser removes every reset of userID
+	DeleteByUser(ctx context.Context, userID int) error
+	// DeleteExpired removes resets that expired before now and returns how many
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"user-authentication/models"
+)
+
+// ErrPostNotFound is returned when no post matches the lookup
+var ErrPostNotFound = errors.New("post not found")
+
+// PostRepository abstracts storage of posts
+type PostRepository interface {
+	// List returns all posts, newest first
+	List(ctx context.Context) ([]*models.Post, error)
+	// ListByAuthor returns the posts written by authorID, newest first
+	ListByAuthor(ctx context.Context, authorID int) ([]*models.Post, error)
+	Get(ctx context.Context, id int) (*models.Post, error)
+	// Create stores post and fills in its ID, author name and timestamps
+	Create(ctx context.Context, post *models.Post) error
+	Update(ctx context.Context, id int, content string) (*models.Post, error)
+	Delete(ctx context.Context, id int) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"user-authentication/models"
+)
+
+// ErrRoleNotFound is returned when no role has the given name
+var ErrRoleNotFound = errors.New("role not found")
+
+// RoleRepository abstracts storage of roles and their assignment to users
+type RoleRepository interface {
+	// List returns every role with its permissions, ordered by name
+	List(ctx context.Context) ([]*models.Role, error)
+	// ListByUser returns the roles granted to userID, ordered by name
+	ListByUser(ctx context.Context, userID int) ([]*models.Role, error)
+	// Grant gives userID the named role. Granting a role twice is not an
+	// error. It returns ErrRoleNotFound for an unknown role.
+	Grant(ctx context.Context, userID int, role string) error
+	// Revoke takes the named role away from userID. Revoking a role the user
+	// does not have is not an error. It returns ErrRoleNotFound for an
+	// unknown role.
+	Revoke(ctx context.Context, userID int, role string) error
+}
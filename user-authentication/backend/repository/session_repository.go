@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-authentication/models"
+)
+
+// ErrSessionNotFound is returned when no session matches the lookup
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository abstracts storage of login sessions
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	Get(ctx context.Context, id string) (*models.Session, error)
+	// Touch records activity on the session and moves its expiry
+	Touch(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error
+	// Delete removes the session and reports whether it still existed, so
+	// that of two concurrent deletes of a single-use token only one succeeds
+	Delete(ctx context.Context, id string) (bool, error)
+	// DeleteByUser removes every session of userID, logging them out everywhere
+	DeleteByUser(ctx context.Context, userID int) error
+	// DeleteExpired removes sessions that expired before now and returns how many
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-authentication/models"
+)
+
+var (
+	// ErrTOTPNotFound is returned when the user has no TOTP secret, or when
+	// a time step has already been used
+	ErrTOTPNotFound = errors.New("totp not found")
+	// ErrRecoveryCodeNotFound is returned when no unused recovery code matches
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+)
+
+// TwoFactorRepository abstracts storage of TOTP secrets and recovery codes
+type TwoFactorRepository interface {
+	GetTOTP(ctx context.Context, userID int) (*models.TOTP, error)
+	// SaveTOTP stores a new, not yet enabled secret, replacing any earlier one
+	SaveTOTP(ctx context.Context, totp *models.TOTP) error
+	// EnableTOTP confirms the enrollment, records step as used and replaces
+	// the user's recovery codes with the given hashes, all or nothing. It
+	// returns ErrTOTPNotFound unless a pending secret exists.
+	EnableTOTP(ctx context.Context, userID int, enabledAt time.Time, step int64, recoveryCodeHashes []string) error
+	// UseTOTPStep records step as used. It returns ErrTOTPNotFound unless
+	// step is later than the last used step, so that two concurrent requests
+	// cannot both use the same code.
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	// DeleteTOTP removes the secret and the recovery codes of the user
+	DeleteTOTP(ctx context.Context, userID int) error
+
+	// ReplaceRecoveryCodes replaces all recovery codes of the user with the
+	// given hashes
+	ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error
+	// UseRecoveryCode marks an unused code as used. It returns
+	// ErrRecoveryCodeNotFound if there is no such unused code.
+	UseRecoveryCode(ctx context.Context, userID int, hash string, usedAt time.Time) error
+	// CountRecoveryCodes returns how many unused recovery codes the user has
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-authentication/models"
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches the lookup
+	ErrUserNotFound = errors.New("user not found")
+	// ErrDuplicateEmail is returned when the email is already registered
+	ErrDuplicateEmail = errors.New("email already registered")
+	// ErrDuplicateUsername is returned when the username is already taken
+	ErrDuplicateUsername = errors.New("username already taken")
+)
+
+// UserRepository abstracts storage of users
+type UserRepository interface {
+	// Create inserts user and fills in its ID and timestamps. It returns
+	// ErrDuplicateEmail or ErrDuplicateUsername when a unique index rejects it.
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// UpdatePassword replaces the user's password hash. It returns
+	// ErrUserNotFound when the user does not exist.
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	// MarkEmailVerified records that the user verified their email address
+	// at verifiedAt. It does nothing if the address is already verified.
+	MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error
+}
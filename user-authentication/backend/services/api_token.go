@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// ErrInvalidAPIToken is returned for unknown, expired or revoked API tokens
+var ErrInvalidAPIToken = errors.New("invalid or expired API token")
+
+// APITokenPrefix starts every personal API token, which tells them apart
+// from JWT access tokens in the same Authorization header and lets secret
+// scanners recognize leaked tokens
+const APITokenPrefix = "pat_"
+
+const (
+	// apiTokenPrefixLength is how much of a token is stored and shown to
+	// tell tokens apart
+	apiTokenPrefixLength = len(APITokenPrefix) + 8
+	// apiTokenTouchInterval is the minimum time between updates of a
+	// token's last_used_at, to avoid a database write on every request
+	apiTokenTouchInterval = time.Minute
+)
+
+// IsAPIToken reports whether a bearer token is a personal API token
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// APITokenService creates, validates and revokes personal API tokens
+type APITokenService struct {
+	tokens repository.APITokenRepository
+	users  repository.UserRepository
+	now    func() time.Time
+}
+
+// NewAPITokenService creates a new APITokenService
+func NewAPITokenService(tokens repository.APITokenRepository, users repository.UserRepository) *APITokenService {
+	return &APITokenService{tokens: tokens, users: users, now: time.Now}
+}
+
+// Create issues a token for userID and returns it with its stored record.
+// Only the token's hash is stored, so it cannot be shown again. A ttl of
+// zero creates a token that does not expire.
+func (s *APITokenService) Create(ctx context.Context, userID int, name string, scopes []string, ttl time.Duration) (string, *models.APIToken, error) {
+	random, err := newRandomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := APITokenPrefix + random
+
+	now := s.now()
+	apiToken := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(token),
+		Prefix:    token[:apiTokenPrefixLength],
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		apiToken.ExpiresAt = &expiresAt
+	}
+	if err := s.tokens.Create(ctx, apiToken); err != nil {
+		return "", nil, err
+	}
+	return token, apiToken, nil
+}
+
+// List returns the tokens of userID
+func (s *APITokenService) List(ctx context.Context, userID int) ([]*models.APIToken, error) {
+	return s.tokens.ListByUser(ctx, userID)
+}
+
+// Revoke deletes the token id of userID. It returns
+// repository.ErrAPITokenNotFound when userID has no such token.
+func (s *APITokenService) Revoke(ctx context.Context, userID, id int) error {
+	return s.tokens.Delete(ctx, userID, id)
+}
+
+// Authenticate returns the user and stored record of token and records that
+// it was used. It returns ErrInvalidAPIToken when the token is not a live
+// API token.
+func (s *APITokenService) Authenticate(ctx context.Context, token string) (*models.User, *models.APIToken, error) {
+	if !IsAPIToken(token) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	apiToken, err := s.tokens.GetByHash(ctx, hashToken(token))
+	if errors.Is(err, repository.ErrAPITokenNotFound) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now()
+	if apiToken.ExpiresAt != nil && !now.Before(*apiToken.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	user, err := s.users.GetByID(ctx, apiToken.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.tokens.Touch(ctx, apiToken.ID, now); err != nil {
+			return nil, nil, err
+		}
+		apiToken.LastUsedAt = &now
+	}
+
+	return user, apiToken, nil
+}
+
+// PurgeExpired deletes tokens that have expired and returns how many
+func (s *APITokenService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.tokens.DeleteExpired(ctx, s.now())
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// memoryAPITokenRepository is an in-memory APITokenRepository
+type memoryAPITokenRepository struct {
+	tokens  map[int]*models.APIToken
+	touches int
+}
+
+func (r *memoryAPITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	token.ID = len(r.tokens) + 1
+	stored := *token
+	r.tokens[token.ID] = &stored
+	return nil
+}
+
+func (r *memoryAPITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrAPITokenNotFound
+}
+
+func (r *memoryAPITokenRepository) ListByUser(ctx context.Context, userID int) ([]*models.APIToken, error) {
+	tokens := make([]*models.APIToken, 0)
+	for id := 1; id <= len(r.tokens); id++ {
+		if token, ok := r.tokens[id]; ok && token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *memoryAPITokenRepository) Touch(ctx context.Context, id int, lastUsedAt time.Time) error {
+	if token, ok := r.tokens[id]; ok {
+		token.LastUsedAt = &lastUsedAt
+		r.touches++
+	}
+	return nil
+}
+
+func (r *memoryAPITokenRepository) Delete(ctx context.Context, userID, id int) error {
+	if token, ok := r.tokens[id]; !ok || token.UserID != userID {
+		return repository.ErrAPITokenNotFound
+	}
+	delete(r.tokens, id)
+	return nil
+}
+
+func (r *memoryAPITokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	for id, token := range r.tokens {
+		if token.ExpiresAt != nil && token.ExpiresAt.Before(now) {
+			delete(r.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func newTestAPITokenService() (*APITokenService, *memoryAPITokenRepository, *time.Time) {
+	tokens := &memoryAPITokenRepository{tokens: make(map[int]*models.APIToken)}
+	users := &singleUserRepository{user: &models.User{ID: 1, Username: "alice", Email: "alice@example.com"}}
+	service := NewAPITokenService(tokens, users)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, tokens, &now
+}
+
+func TestAPITokenService_CreateAuthenticate(t *testing.T) {
+	service, tokens, now := newTestAPITokenService()
+	ctx := context.Background()
+
+	token, apiToken, err := service.Create(ctx, 1, "deploy script", []string{models.ScopePostsWrite}, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(token, APITokenPrefix) || !strings.HasPrefix(token, apiToken.Prefix) {
+		t.Errorf("Expected a %s token starting with %q, got %q", APITokenPrefix, apiToken.Prefix, token)
+	}
+	if apiToken.ExpiresAt != nil {
+		t.Errorf("Expected no expiry, got %v", apiToken.ExpiresAt)
+	}
+	if stored := tokens.tokens[apiToken.ID]; stored.TokenHash == token || strings.Contains(stored.TokenHash, token) {
+		t.Error("Token must be stored by hash, not by the raw token")
+	}
+
+	user, found, err := service.Authenticate(ctx, token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if user.ID != 1 || !found.HasScope(models.ScopePostsWrite) || found.HasScope(models.ScopePostsRead) {
+		t.Errorf("Expected alice's posts:write token, got user %d with %v", user.ID, found.Scopes)
+	}
+	if found.LastUsedAt == nil || !found.LastUsedAt.Equal(*now) {
+		t.Errorf("Expected last_used_at %v, got %v", *now, found.LastUsedAt)
+	}
+
+	// Using the token again right away does not write to the database
+	if _, _, err := service.Authenticate(ctx, token); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if tokens.touches != 1 {
+		t.Errorf("Expected 1 touch, got %d", tokens.touches)
+	}
+
+	for _, bad := range []string{"", "pat_unknown", strings.TrimPrefix(token, APITokenPrefix)} {
+		if _, _, err := service.Authenticate(ctx, bad); !errors.Is(err, ErrInvalidAPIToken) {
+			t.Errorf("Expected ErrInvalidAPIToken for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestAPITokenService_Expiry(t *testing.T) {
+	service, tokens, now := newTestAPITokenService()
+	ctx := context.Background()
+
+	token, apiToken, err := service.Create(ctx, 1, "ci", []string{models.ScopePostsRead}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if apiToken.ExpiresAt == nil || !apiToken.ExpiresAt.Equal(now.Add(24*time.Hour)) {
+		t.Errorf("Expected expiry in 24h, got %v", apiToken.ExpiresAt)
+	}
+
+	*now = now.Add(24 * time.Hour)
+	if _, _, err := service.Authenticate(ctx, token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("Expected ErrInvalidAPIToken for an expired token, got %v", err)
+	}
+
+	*now = now.Add(time.Second)
+	deleted, err := service.PurgeExpired(ctx)
+	if err != nil || deleted != 1 || len(tokens.tokens) != 0 {
+		t.Errorf("Expected the expired token to be purged, got %d deleted (%v)", deleted, err)
+	}
+}
+
+func TestAPITokenService_Revoke(t *testing.T) {
+	service, _, _ := newTestAPITokenService()
+	ctx := context.Background()
+
+	token, apiToken, err := service.Create(ctx, 1, "ci", []string{models.ScopePostsRead}, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := service.Revoke(ctx, 2, apiToken.ID); !errors.Is(err, repository.ErrAPITokenNotFound) {
+		t.Errorf("Expected another user's revoke to fail with ErrAPITokenNotFound, got %v", err)
+	}
+	if err := service.Revoke(ctx, 1, apiToken.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, _, err := service.Authenticate(ctx, token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("Expected ErrInvalidAPIToken for a revoked token, got %v", err)
+	}
+}
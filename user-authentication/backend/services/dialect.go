@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes the SQL differences between databases that the
+// migration manager has to care about: the DDL for its bookkeeping table and
+// the bind parameter style. Migrations themselves are plain SQL and are
+// responsible for their own portability.
+type Dialect interface {
+	// Name returns the database/sql driver name the dialect is meant for
+	Name() string
+	// CreateMigrationTableSQL returns the DDL for the migrations table
+	CreateMigrationTableSQL() string
+	// Placeholder returns the bind parameter for the n-th argument (1-based)
+	Placeholder(n int) string
+}
+
+// MySQLDialect is the dialect for github.com/go-sql-driver/mysql
+type MySQLDialect struct{}
+
+// Name returns the driver name
+func (MySQLDialect) Name() string { return "mysql" }
+
+// CreateMigrationTableSQL returns the MySQL DDL for the migrations table
+func (MySQLDialect) CreateMigrationTableSQL() string {
+	return `
+		CREATE TABLE IF NOT EXISTS migrations (
+			version INT PRIMARY KEY,
+			description VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL DEFAULT '',
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`
+}
+
+// Placeholder returns "?"
+func (MySQLDialect) Placeholder(n int) string { return "?" }
+
+// SQLiteDialect is the dialect for github.com/mattn/go-sqlite3
+type SQLiteDialect struct{}
+
+// Name returns the driver name
+func (SQLiteDialect) Name() string { return "sqlite3" }
+
+// CreateMigrationTableSQL returns the SQLite DDL for the migrations table
+func (SQLiteDialect) CreateMigrationTableSQL() string {
+	return `
+		CREATE TABLE IF NOT EXISTS migrations (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			checksum TEXT NOT NULL DEFAULT '',
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+}
+
+// Placeholder returns "?"
+func (SQLiteDialect) Placeholder(n int) string { return "?" }
+
+// PostgresDialect is the dialect for PostgreSQL drivers such as lib/pq and pgx
+type PostgresDialect struct{}
+
+// Name returns the driver name
+func (PostgresDialect) Name() string { return "postgres" }
+
+// CreateMigrationTableSQL returns the PostgreSQL DDL for the migrations table
+func (PostgresDialect) CreateMigrationTableSQL() string {
+	return `
+		CREATE TABLE IF NOT EXISTS migrations (
+			version INTEGER PRIMARY KEY,
+			description VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL DEFAULT '',
+			applied_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+}
+
+// Placeholder returns "$n"
+func (PostgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// DialectFor returns the dialect for a database/sql driver name
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case "mysql":
+		return MySQLDialect{}, nil
+	case "sqlite3", "sqlite":
+		return SQLiteDialect{}, nil
+	case "postgres", "pgx":
+		return PostgresDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+// rebind rewrites the "?" placeholders in query into the dialect's style.
+// Queries passed to it must not contain "?" inside string literals.
+func rebind(d Dialect, query string) string {
+	if d.Placeholder(1) == "?" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(d.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
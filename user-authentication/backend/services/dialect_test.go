@@ -0,0 +1,121 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDialectFor(t *testing.T) {
+	tests := map[string]string{
+		"mysql":    "mysql",
+		"sqlite3":  "sqlite3",
+		"sqlite":   "sqlite3",
+		"postgres": "postgres",
+		"pgx":      "postgres",
+	}
+
+	for driver, expected := range tests {
+		dialect, err := DialectFor(driver)
+		if err != nil {
+			t.Errorf("DialectFor(%q) returned error: %v", driver, err)
+			continue
+		}
+		if dialect.Name() != expected {
+			t.Errorf("Expected dialect '%s' for driver '%s', got '%s'", expected, driver, dialect.Name())
+		}
+	}
+
+	if _, err := DialectFor("oracle"); err == nil {
+		t.Error("Expected error for unsupported driver")
+	}
+}
+
+func TestMySQLDialect(t *testing.T) {
+	d := MySQLDialect{}
+
+	if !strings.Contains(d.CreateMigrationTableSQL(), "ENGINE=InnoDB") {
+		t.Error("Expected MySQL DDL to use InnoDB")
+	}
+
+	query := "DELETE FROM migrations WHERE version = ?"
+	if rebind(d, query) != query {
+		t.Errorf("Expected MySQL query to be unchanged, got '%s'", rebind(d, query))
+	}
+}
+
+func TestSQLiteDialect(t *testing.T) {
+	d := SQLiteDialect{}
+
+	if strings.Contains(d.CreateMigrationTableSQL(), "ENGINE") {
+		t.Error("SQLite DDL must not contain MySQL table options")
+	}
+
+	query := "INSERT INTO migrations (version, description, applied_at) VALUES (?, ?, ?)"
+	if rebind(d, query) != query {
+		t.Errorf("Expected SQLite query to be unchanged, got '%s'", rebind(d, query))
+	}
+}
+
+func TestPostgresDialect(t *testing.T) {
+	d := PostgresDialect{}
+
+	ddl := d.CreateMigrationTableSQL()
+	if strings.Contains(ddl, "ENGINE") {
+		t.Error("PostgreSQL DDL must not contain MySQL table options")
+	}
+	if !strings.Contains(ddl, "TIMESTAMPTZ") {
+		t.Error("Expected PostgreSQL DDL to use TIMESTAMPTZ")
+	}
+
+	query := "INSERT INTO migrations (version, description, applied_at) VALUES (?, ?, ?)"
+	expected := "INSERT INTO migrations (version, description, applied_at) VALUES ($1, $2, $3)"
+	if got := rebind(d, query); got != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, got)
+	}
+}
+
+func TestMigrationManager_SQLite(t *testing.T) {
+	db := newSQLiteDB(t)
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Create widgets table",
+		Up: func(exec Executor) error {
+			_, err := exec.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY)")
+			return err
+		},
+		Down: func(exec Executor) error {
+			_, err := exec.Exec("DROP TABLE widgets")
+			return err
+		},
+	})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	// Initializing twice must be harmless
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("Second InitializeMigrationTable failed: %v", err)
+	}
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	applied, err := manager.IsMigrationApplied(1)
+	if err != nil || !applied {
+		t.Errorf("Expected migration 1 to be applied, got %v (err: %v)", applied, err)
+	}
+
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+
+	status, err := manager.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status) != 1 || status[0].Applied {
+		t.Errorf("Expected migration 1 to be rolled back, got %+v", status)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// ErrInvalidVerificationToken is returned for malformed, badly signed or
+// expired email verification tokens, and for tokens issued for an address
+// the user no longer has
+var ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+
+// What an unverified email address blocks, set with EMAIL_VERIFICATION_REQUIRED
+const (
+	// VerificationRequiredNone lets unverified users do everything
+	VerificationRequiredNone = "none"
+	// VerificationRequiredPosts lets unverified users log in but not write posts
+	VerificationRequiredPosts = "posts"
+	// VerificationRequiredLogin keeps unverified users from logging in at all
+	VerificationRequiredLogin = "login"
+)
+
+// EmailVerificationConfig controls email verification
+type EmailVerificationConfig struct {
+	// Secret signs the tokens. Changing it invalidates every link sent.
+	Secret []byte
+	// TTL is how long a verification link can be used
+	TTL time.Duration
+	// URL is the page the link points at. The token is added as the "token"
+	// query parameter.
+	URL string
+	// Required is VerificationRequiredNone, VerificationRequiredPosts or
+	// VerificationRequiredLogin
+	Required string
+}
+
+// LoadEmailVerificationConfig reads the email verification configuration
+// from the environment: EMAIL_VERIFICATION_SECRET, EMAIL_VERIFICATION_TTL,
+// EMAIL_VERIFICATION_URL and EMAIL_VERIFICATION_REQUIRED. Secret is nil when
+// EMAIL_VERIFICATION_SECRET is not set.
+func LoadEmailVerificationConfig() (*EmailVerificationConfig, error) {
+	config := &EmailVerificationConfig{
+		TTL:      getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		URL:      getEnv("EMAIL_VERIFICATION_URL", "http://localhost:8080/api/auth/verify-email"),
+		Required: getEnv("EMAIL_VERIFICATION_REQUIRED", VerificationRequiredNone),
+	}
+
+	switch config.Required {
+	case VerificationRequiredNone, VerificationRequiredPosts, VerificationRequiredLogin:
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_VERIFICATION_REQUIRED %q (use none, posts or login)", config.Required)
+	}
+
+	if secret := os.Getenv("EMAIL_VERIFICATION_SECRET"); secret != "" {
+		// Anything shorter than the hash output can be brute-forced offline
+		if len(secret) < sha256.Size {
+			return nil, fmt.Errorf("EMAIL_VERIFICATION_SECRET must be at least %d bytes", sha256.Size)
+		}
+		config.Secret = []byte(secret)
+	}
+	return config, nil
+}
+
+// EmailVerificationService sends signed verification links and verifies them.
+// Tokens are not stored: they carry the user ID and expiry and are signed
+// together with the email address, so a link stops working when the user's
+// address changes.
+type EmailVerificationService struct {
+	users  repository.UserRepository
+	mailer Mailer
+	config EmailVerificationConfig
+	now    func() time.Time
+}
+
+// NewEmailVerificationService creates a new EmailVerificationService
+func NewEmailVerificationService(users repository.UserRepository, mailer Mailer, config *EmailVerificationConfig) (*EmailVerificationService, error) {
+	if len(config.Secret) == 0 {
+		return nil, errors.New("email verification secret is required")
+	}
+	return &EmailVerificationService{users: users, mailer: mailer, config: *config, now: time.Now}, nil
+}
+
+// Required returns what an unverified email address blocks
+func (s *EmailVerificationService) Required() string {
+	return s.config.Required
+}
+
+// Send emails a verification link to user. It does nothing if the address is
+// already verified.
+func (s *EmailVerificationService) Send(ctx context.Context, user *models.User) error {
+	if user.EmailVerified() {
+		return nil
+	}
+
+	link, err := s.link(s.sign(user, s.now().Add(s.config.TTL)))
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, &Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email address. It expires in %s.\n\n%s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.Username, s.config.TTL, link),
+	})
+}
+
+// Resend emails a new verification link to the user with email. Unknown and
+// already verified addresses are silently ignored so that the endpoint does
+// not reveal who is registered.
+func (s *EmailVerificationService) Resend(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, user)
+}
+
+// Verify marks the email address of the token's user as verified and returns
+// the user. Verifying an already verified address again succeeds.
+func (s *EmailVerificationService) Verify(ctx context.Context, token string) (*models.User, error) {
+	userID, expiresAt, ok := parseUserToken(token)
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+	now := s.now()
+	if !now.Before(expiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(token), []byte(s.sign(user, expiresAt))) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if user.EmailVerified() {
+		return user, nil
+	}
+
+	if err := s.users.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+	verified := *user
+	verified.EmailVerifiedAt = &now
+	return &verified, nil
+}
+
+// sign returns the verification token for user. The signature covers the
+// email address without putting it in the link.
+func (s *EmailVerificationService) sign(user *models.User, expiresAt time.Time) string {
+	return signUserToken(s.config.Secret, "email-verification", user.ID, expiresAt, user.Email)
+}
+
+// link returns the verification URL for token
+func (s *EmailVerificationService) link(token string) (string, error) {
+	u, err := url.Parse(s.config.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid EMAIL_VERIFICATION_URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
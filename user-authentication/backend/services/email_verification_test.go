@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/models"
+)
+
+// newTestEmailVerificationService returns an EmailVerificationService for
+// alice with a controllable clock
+func newTestEmailVerificationService(t *testing.T) (*EmailVerificationService, *singleUserRepository, *captureMailer, *time.Time) {
+	t.Helper()
+	users := &singleUserRepository{user: &models.User{ID: 1, Username: "alice", Email: "alice@example.com"}}
+	mailer := &captureMailer{}
+	service, err := NewEmailVerificationService(users, mailer, &EmailVerificationConfig{
+		Secret: []byte(strings.Repeat("s", 32)),
+		TTL:    time.Hour,
+		URL:    "http://localhost:8080/api/auth/verify-email",
+	})
+	if err != nil {
+		t.Fatalf("NewEmailVerificationService failed: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, users, mailer, &now
+}
+
+func TestLoadEmailVerificationConfig(t *testing.T) {
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "")
+	t.Setenv("EMAIL_VERIFICATION_REQUIRED", "")
+
+	config, err := LoadEmailVerificationConfig()
+	if err != nil {
+		t.Fatalf("LoadEmailVerificationConfig failed: %v", err)
+	}
+	if config.Secret != nil || config.Required != VerificationRequiredNone {
+		t.Errorf("Expected no secret and nothing required, got %+v", config)
+	}
+
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "too-short")
+	if _, err := LoadEmailVerificationConfig(); err == nil {
+		t.Error("Expected error for a short secret")
+	}
+
+	t.Setenv("EMAIL_VERIFICATION_SECRET", strings.Repeat("s", 32))
+	t.Setenv("EMAIL_VERIFICATION_REQUIRED", "always")
+	if _, err := LoadEmailVerificationConfig(); err == nil {
+		t.Error("Expected error for an unknown EMAIL_VERIFICATION_REQUIRED")
+	}
+}
+
+func TestEmailVerificationService_Verify(t *testing.T) {
+	service, users, mailer, now := newTestEmailVerificationService(t)
+	ctx := context.Background()
+
+	if err := service.Send(ctx, users.user); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "alice@example.com" {
+		t.Fatalf("Expected 1 message to alice@example.com, got %+v", mailer.messages)
+	}
+	token := linkToken(t, mailer.messages[0])
+
+	if _, err := service.Verify(ctx, token[:len(token)-1]+"x"); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("Expected ErrInvalidVerificationToken for a tampered token, got %v", err)
+	}
+
+	user, err := service.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !user.EmailVerified() || !users.user.EmailVerifiedAt.Equal(*now) {
+		t.Errorf("Expected the email to be verified at %v, got %v", *now, users.user.EmailVerifiedAt)
+	}
+
+	// Opening the link twice is harmless
+	if _, err := service.Verify(ctx, token); err != nil {
+		t.Errorf("Expected verifying twice to succeed, got %v", err)
+	}
+
+	// Verified users get no more emails
+	if err := service.Resend(ctx, "alice@example.com"); err != nil || len(mailer.messages) != 1 {
+		t.Errorf("Expected no email for a verified address, got %d (err: %v)", len(mailer.messages), err)
+	}
+}
+
+func TestEmailVerificationService_ExpiredToken(t *testing.T) {
+	service, users, mailer, now := newTestEmailVerificationService(t)
+	ctx := context.Background()
+
+	if err := service.Resend(ctx, "Alice@Example.com"); err != nil {
+		t.Fatalf("Resend failed: %v", err)
+	}
+	token := linkToken(t, mailer.messages[0])
+
+	*now = now.Add(time.Hour)
+	if _, err := service.Verify(ctx, token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("Expected ErrInvalidVerificationToken for an expired token, got %v", err)
+	}
+	if users.user.EmailVerified() {
+		t.Error("Expected the email to stay unverified")
+	}
+}
+
+func TestEmailVerificationService_EmailChanged(t *testing.T) {
+	service, users, mailer, _ := newTestEmailVerificationService(t)
+	ctx := context.Background()
+
+	if err := service.Send(ctx, users.user); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	token := linkToken(t, mailer.messages[0])
+
+	users.user.Email = "alice@example.org"
+	if _, err := service.Verify(ctx, token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("Expected ErrInvalidVerificationToken after the email changed, got %v", err)
+	}
+
+	if err := service.Resend(ctx, "nobody@example.com"); err != nil || len(mailer.messages) != 1 {
+		t.Errorf("Expected no email for an unknown address, got %d (err: %v)", len(mailer.messages), err)
+	}
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"user-authentication/models"
+)
+
+// Supported JWT signing algorithms
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// ErrInvalidToken is returned for malformed, badly signed, expired or
+// otherwise unacceptable tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTKey is one signing key, identified in token headers by its ID ("kid").
+// Keys loaded from a public key can verify tokens but not sign them.
+type JWTKey struct {
+	ID string
+
+	secret  []byte           // HS256
+	private crypto.Signer    // RS256 and EdDSA
+	public  crypto.PublicKey // RS256 and EdDSA
+}
+
+// CanSign reports whether the key holds the secret or private key needed to
+// sign tokens
+func (k JWTKey) CanSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+// JWTConfig configures issuing and verifying access tokens
+type JWTConfig struct {
+	Algorithm string
+	// Keys are all keys that tokens may be signed with. Keeping the previous
+	// key here after rotating lets tokens signed with it stay valid until
+	// they expire.
+	Keys []JWTKey
+	// SigningKeyID selects the key new tokens are signed with; empty means
+	// the first key
+	SigningKeyID string
+	Issuer       string
+	Audience     string
+	AccessTTL    time.Duration
+	RefreshTTL   time.Duration
+}
+
+// LoadJWTConfig reads the JWT configuration from the environment:
+// JWT_ALGORITHM (HS256, RS256 or EdDSA), JWT_KEYS, JWT_SIGNING_KEY_ID,
+// JWT_ISSUER, JWT_AUDIENCE, JWT_ACCESS_TTL and JWT_REFRESH_TTL. See
+// ParseJWTKeys for the JWT_KEYS format.
+func LoadJWTConfig() (*JWTConfig, error) {
+	config := &JWTConfig{
+		Algorithm:    getEnv("JWT_ALGORITHM", JWTAlgorithmHS256),
+		SigningKeyID: os.Getenv("JWT_SIGNING_KEY_ID"),
+		Issuer:       getEnv("JWT_ISSUER", "user-authentication"),
+		Audience:     os.Getenv("JWT_AUDIENCE"),
+		AccessTTL:    getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute),
+		RefreshTTL:   getEnvDuration("JWT_REFRESH_TTL", 30*24*time.Hour),
+	}
+
+	keys, err := ParseJWTKeys(config.Algorithm, os.Getenv("JWT_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	config.Keys = keys
+	return config, nil
+}
+
+// ParseJWTKeys parses a comma-separated list of kid:key pairs. For HS256 the
+// key is the shared secret. For RS256 and EdDSA it is a PEM private or public
+// key, given inline or as the path of a PEM file.
+//
+//	JWT_KEYS="2024-06:<new secret>,2024-01:<old secret>"
+//	JWT_KEYS="2024-06:/etc/jwt/2024-06.pem"
+func ParseJWTKeys(algorithm, value string) ([]JWTKey, error) {
+	var keys []JWTKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, material, found := strings.Cut(entry, ":")
+		if !found || id == "" || material == "" {
+			return nil, fmt.Errorf("JWT key %q must have the form kid:key", entry)
+		}
+
+		key, err := ParseJWTKey(algorithm, id, material)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParseJWTKey builds the key called id for algorithm from material (see
+// ParseJWTKeys)
+func ParseJWTKey(algorithm, id, material string) (JWTKey, error) {
+	switch algorithm {
+	case JWTAlgorithmHS256:
+		// Anything shorter than the hash output can be brute-forced offline
+		if len(material) < sha256.Size {
+			return JWTKey{}, fmt.Errorf("HS256 key %q must be at least %d bytes", id, sha256.Size)
+		}
+		return JWTKey{ID: id, secret: []byte(material)}, nil
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+	default:
+		return JWTKey{}, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+
+	pemBytes := []byte(material)
+	if !strings.HasPrefix(strings.TrimSpace(material), "-----BEGIN") {
+		data, err := os.ReadFile(material)
+		if err != nil {
+			return JWTKey{}, fmt.Errorf("failed to read JWT key %q: %w", id, err)
+		}
+		pemBytes = data
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return JWTKey{}, fmt.Errorf("JWT key %q is not PEM encoded", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return JWTKey{}, fmt.Errorf("JWT key %q has unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("failed to parse JWT key %q: %w", id, err)
+	}
+
+	key := JWTKey{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.private, key.public = k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.public = k
+	case ed25519.PrivateKey:
+		key.private, key.public = k, k.Public()
+	case ed25519.PublicKey:
+		key.public = k
+	default:
+		return JWTKey{}, fmt.Errorf("JWT key %q has unsupported type %T", id, parsed)
+	}
+
+	_, isRSA := key.public.(*rsa.PublicKey)
+	_, isEd25519 := key.public.(ed25519.PublicKey)
+	if (algorithm == JWTAlgorithmRS256 && !isRSA) || (algorithm == JWTAlgorithmEdDSA && !isEd25519) {
+		return JWTKey{}, fmt.Errorf("JWT key %q cannot be used with %s", id, algorithm)
+	}
+	return key, nil
+}
+
+// AccessClaims are the claims carried by an access token
+type AccessClaims struct {
+	ID       string `json:"jti"`
+	Issuer   string `json:"iss"`
+	Subject  string `json:"sub"` // the user ID
+	Audience string `json:"aud,omitempty"`
+	Username string `json:"username"`
+	// Roles lets services that only verify tokens, such as the Lambda API,
+	// make authorization decisions. It is a snapshot taken at issue time.
+	Roles     []string `json:"roles,omitempty"`
+	Type      string   `json:"typ"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// UserID returns the user ID in the subject claim
+func (c *AccessClaims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// accessTokenType marks access tokens so that other JWTs signed with the
+// same key cannot be used in their place
+const accessTokenType = "access"
+
+// jwtHeader is the JOSE header of a token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// JWTService issues and verifies access tokens
+type JWTService struct {
+	config     JWTConfig
+	keys       map[string]JWTKey
+	signingKey *JWTKey
+	now        func() time.Time
+}
+
+// NewJWTService validates config and creates a JWTService. A service whose
+// keys are all public keys can only verify tokens.
+func NewJWTService(config *JWTConfig) (*JWTService, error) {
+	switch config.Algorithm {
+	case JWTAlgorithmHS256, JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", config.Algorithm)
+	}
+	if len(config.Keys) == 0 {
+		return nil, errors.New("at least one JWT key is required")
+	}
+
+	s := &JWTService{config: *config, keys: make(map[string]JWTKey), now: time.Now}
+	for i, key := range config.Keys {
+		if _, exists := s.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate JWT key ID %q", key.ID)
+		}
+		s.keys[key.ID] = key
+		if (config.SigningKeyID == "" && i == 0) || key.ID == config.SigningKeyID {
+			s.signingKey = &s.config.Keys[i]
+		}
+	}
+	if config.SigningKeyID != "" && s.signingKey == nil {
+		return nil, fmt.Errorf("JWT signing key %q is not in JWT_KEYS", config.SigningKeyID)
+	}
+	return s, nil
+}
+
+// CanSign reports whether the service has a key to issue tokens with
+func (s *JWTService) CanSign() bool {
+	return s.signingKey != nil && s.signingKey.CanSign()
+}
+
+// AccessTTL returns how long access tokens are valid
+func (s *JWTService) AccessTTL() time.Duration {
+	return s.config.AccessTTL
+}
+
+// RefreshTTL returns how long refresh tokens are valid
+func (s *JWTService) RefreshTTL() time.Duration {
+	return s.config.RefreshTTL
+}
+
+// IssueAccessToken returns a signed access token for user and its expiry
+func (s *JWTService) IssueAccessToken(user *models.User) (string, time.Time, error) {
+	if !s.CanSign() {
+		return "", time.Time{}, errors.New("no JWT signing key configured")
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	now := s.now()
+	expiresAt := now.Add(s.config.AccessTTL)
+	claims := AccessClaims{
+		ID:        base64.RawURLEncoding.EncodeToString(jti),
+		Issuer:    s.config.Issuer,
+		Subject:   strconv.Itoa(user.ID),
+		Audience:  s.config.Audience,
+		Username:  user.Username,
+		Roles:     user.Roles,
+		Type:      accessTokenType,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	token, err := s.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// VerifyAccessToken checks the token's signature and claims and returns the
+// claims. Every failure is reported as ErrInvalidToken.
+func (s *JWTService) VerifyAccessToken(token string) (*AccessClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	// The algorithm is fixed by configuration, never chosen by the token
+	if header.Algorithm != s.config.Algorithm {
+		return nil, fmt.Errorf("%w: unexpected algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+	key, ok := s.keys[header.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, header.KeyID)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if !s.verifySignature(key, parts[0]+"."+parts[1], signature) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims AccessClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	switch {
+	case claims.Type != accessTokenType:
+		return nil, fmt.Errorf("%w: not an access token", ErrInvalidToken)
+	case claims.Issuer != s.config.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	case claims.Audience != s.config.Audience:
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	case s.now().Unix() >= claims.ExpiresAt:
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+// sign encodes claims and signs them with the signing key
+func (s *JWTService) sign(claims AccessClaims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: s.config.Algorithm, Type: "JWT", KeyID: s.signingKey.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch s.config.Algorithm {
+	case JWTAlgorithmHS256:
+		mac := hmac.New(sha256.New, s.signingKey.secret)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case JWTAlgorithmRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = s.signingKey.private.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case JWTAlgorithmEdDSA:
+		signature, err = s.signingKey.private.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verifySignature checks signature over signingInput with key
+func (s *JWTService) verifySignature(key JWTKey, signingInput string, signature []byte) bool {
+	switch s.config.Algorithm {
+	case JWTAlgorithmHS256:
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case JWTAlgorithmRS256:
+		public, ok := key.public.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) == nil
+	case JWTAlgorithmEdDSA:
+		public, ok := key.public.(ed25519.PublicKey)
+		return ok && ed25519.Verify(public, []byte(signingInput), signature)
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url JSON token segment into v
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
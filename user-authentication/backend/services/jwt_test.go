@@ -0,0 +1,251 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/models"
+)
+
+var testUser = &models.User{ID: 42, Username: "alice"}
+
+func hsKey(t *testing.T, id string) JWTKey {
+	t.Helper()
+	key, err := ParseJWTKey(JWTAlgorithmHS256, id, strings.Repeat(id, 32))
+	if err != nil {
+		t.Fatalf("ParseJWTKey failed: %v", err)
+	}
+	return key
+}
+
+func newTestJWTService(t *testing.T, config JWTConfig) *JWTService {
+	t.Helper()
+	if config.Issuer == "" {
+		config.Issuer = "test"
+	}
+	if config.AccessTTL == 0 {
+		config.AccessTTL = time.Minute
+	}
+	service, err := NewJWTService(&config)
+	if err != nil {
+		t.Fatalf("NewJWTService failed: %v", err)
+	}
+	return service
+}
+
+// pemEncode returns key as an inline PEM block
+func pemEncode(t *testing.T, blockType string, der []byte, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+func TestJWTService_HS256(t *testing.T) {
+	service := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{hsKey(t, "a")}})
+
+	token, expiresAt, err := service.IssueAccessToken(testUser)
+	if err != nil {
+		t.Fatalf("IssueAccessToken failed: %v", err)
+	}
+	if time.Until(expiresAt) > time.Minute {
+		t.Errorf("Expected expiry within the access TTL, got %v", expiresAt)
+	}
+
+	claims, err := service.VerifyAccessToken(token)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken failed: %v", err)
+	}
+	if id, _ := claims.UserID(); id != 42 || claims.Username != "alice" || claims.Issuer != "test" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+}
+
+func TestJWTService_RolesClaim(t *testing.T) {
+	service := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{hsKey(t, "a")}})
+
+	token, _, err := service.IssueAccessToken(&models.User{ID: 7, Username: "bob", Roles: []string{"admin", "moderator"}})
+	if err != nil {
+		t.Fatalf("IssueAccessToken failed: %v", err)
+	}
+	claims, err := service.VerifyAccessToken(token)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken failed: %v", err)
+	}
+	if strings.Join(claims.Roles, ",") != "admin,moderator" {
+		t.Errorf("Expected roles claim 'admin,moderator', got %v", claims.Roles)
+	}
+
+	// Users without roles get no roles claim at all
+	token, _, _ = service.IssueAccessToken(testUser)
+	payload, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if strings.Contains(string(payload), "roles") {
+		t.Errorf("Expected no roles claim, got %s", payload)
+	}
+}
+
+func TestJWTService_RS256AndEdDSA(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	rsaPrivate := pemEncode(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil)
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPublic := pemEncode(t, "PUBLIC KEY", rsaPublicDER, err)
+	edPrivateDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	edPrivate := pemEncode(t, "PRIVATE KEY", edPrivateDER, err)
+	edPublicDER, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	edPublic := pemEncode(t, "PUBLIC KEY", edPublicDER, err)
+
+	// Keys can also be read from files
+	edPrivatePath := filepath.Join(t.TempDir(), "ed25519.pem")
+	if err := os.WriteFile(edPrivatePath, []byte(edPrivate), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		algorithm string
+		private   string
+		public    string
+	}{
+		{JWTAlgorithmRS256, rsaPrivate, rsaPublic},
+		{JWTAlgorithmEdDSA, edPrivatePath, edPublic},
+	}
+
+	for _, tt := range tests {
+		privateKey, err := ParseJWTKey(tt.algorithm, "k1", tt.private)
+		if err != nil {
+			t.Fatalf("%s: ParseJWTKey(private) failed: %v", tt.algorithm, err)
+		}
+		publicKey, err := ParseJWTKey(tt.algorithm, "k1", tt.public)
+		if err != nil {
+			t.Fatalf("%s: ParseJWTKey(public) failed: %v", tt.algorithm, err)
+		}
+
+		issuer := newTestJWTService(t, JWTConfig{Algorithm: tt.algorithm, Keys: []JWTKey{privateKey}})
+		verifier := newTestJWTService(t, JWTConfig{Algorithm: tt.algorithm, Keys: []JWTKey{publicKey}})
+
+		token, _, err := issuer.IssueAccessToken(testUser)
+		if err != nil {
+			t.Fatalf("%s: IssueAccessToken failed: %v", tt.algorithm, err)
+		}
+		if _, err := verifier.VerifyAccessToken(token); err != nil {
+			t.Errorf("%s: expected the public key to verify the token, got %v", tt.algorithm, err)
+		}
+
+		if verifier.CanSign() {
+			t.Errorf("%s: a public key must not be able to sign", tt.algorithm)
+		}
+		if _, _, err := verifier.IssueAccessToken(testUser); err == nil {
+			t.Errorf("%s: expected issuing with a public key to fail", tt.algorithm)
+		}
+	}
+
+	if _, err := ParseJWTKey(JWTAlgorithmEdDSA, "k1", rsaPublic); err == nil {
+		t.Error("Expected an RSA key to be rejected for EdDSA")
+	}
+}
+
+func TestJWTService_KeyRotation(t *testing.T) {
+	oldKey, newKey := hsKey(t, "old"), hsKey(t, "new")
+
+	before := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{oldKey}})
+	oldToken, _, _ := before.IssueAccessToken(testUser)
+
+	// After rotating, new tokens use the new key and old tokens still verify
+	after := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{oldKey, newKey}, SigningKeyID: "new"})
+	newToken, _, _ := after.IssueAccessToken(testUser)
+
+	var header jwtHeader
+	decodeSegment(strings.Split(newToken, ".")[0], &header)
+	if header.KeyID != "new" {
+		t.Errorf("Expected new tokens to be signed with kid 'new', got '%s'", header.KeyID)
+	}
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := after.VerifyAccessToken(token); err != nil {
+			t.Errorf("Expected %s token to verify, got %v", name, err)
+		}
+	}
+
+	// Once the old key is removed, its tokens are rejected
+	retired := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{newKey}})
+	if _, err := retired.VerifyAccessToken(oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for a retired key, got %v", err)
+	}
+
+	if _, err := NewJWTService(&JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{newKey}, SigningKeyID: "missing"}); err == nil {
+		t.Error("Expected an unknown signing key ID to be rejected")
+	}
+}
+
+func TestJWTService_RejectsInvalidTokens(t *testing.T) {
+	service := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{hsKey(t, "a")}, Audience: "board"})
+	token, _, _ := service.IssueAccessToken(testUser)
+	parts := strings.Split(token, ".")
+
+	otherIssuer := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{hsKey(t, "a")}, Issuer: "other", Audience: "board"})
+	otherIssuerToken, _, _ := otherIssuer.IssueAccessToken(testUser)
+
+	otherAudience := newTestJWTService(t, JWTConfig{Algorithm: JWTAlgorithmHS256, Keys: []JWTKey{hsKey(t, "a")}, Audience: "admin"})
+	otherAudienceToken, _, _ := otherAudience.IssueAccessToken(testUser)
+
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT","kid":"a"}`))
+
+	tests := map[string]string{
+		"malformed":      "not-a-token",
+		"tampered":       parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1","typ":"access","iss":"test","aud":"board","exp":9999999999}`)) + "." + parts[2],
+		"alg none":       noneHeader + "." + parts[1] + ".",
+		"wrong issuer":   otherIssuerToken,
+		"wrong audience": otherAudienceToken,
+	}
+	for name, token := range tests {
+		if _, err := service.VerifyAccessToken(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+
+	// Expired
+	service.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := service.VerifyAccessToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for an expired token, got %v", err)
+	}
+}
+
+func TestParseJWTKeys(t *testing.T) {
+	keys, err := ParseJWTKeys(JWTAlgorithmHS256, "k2:"+strings.Repeat("b", 32)+", k1:"+strings.Repeat("a", 32))
+	if err != nil {
+		t.Fatalf("ParseJWTKeys failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "k2" || keys[1].ID != "k1" {
+		t.Errorf("Unexpected keys: %+v", keys)
+	}
+
+	if _, err := ParseJWTKeys(JWTAlgorithmHS256, strings.Repeat("a", 32)); err == nil {
+		t.Error("Expected a key without kid to be rejected")
+	}
+	if _, err := ParseJWTKeys(JWTAlgorithmHS256, "k1:short"); err == nil {
+		t.Error("Expected a short HS256 secret to be rejected")
+	}
+	if _, err := ParseJWTKey("HS512", "k1", strings.Repeat("a", 64)); err == nil {
+		t.Error("Expected an unsupported algorithm to be rejected")
+	}
+	if _, err := ParseJWTKey(JWTAlgorithmRS256, "k1", filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("Expected a missing key file to be rejected")
+	}
+}
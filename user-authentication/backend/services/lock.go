@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultLockTimeout is how long Up and Down wait for another runner's lock
+const DefaultLockTimeout = 30 * time.Second
+
+// lockPollInterval is how often lock implementations without a blocking
+// primitive retry
+const lockPollInterval = 100 * time.Millisecond
+
+// lockKey identifies the migration lock for PostgreSQL advisory locks
+const lockKey = 72947501
+
+// ErrLockTimeout is returned when the migration lock could not be acquired
+// within the manager's lock timeout
+var ErrLockTimeout = errors.New("timed out waiting for migration lock")
+
+// Locker is implemented by dialects that can stop two processes from running
+// migrations against the same database at the same time. The returned
+// release function must be called once the migrations are done.
+type Locker interface {
+	AcquireLock(ctx context.Context, db *sql.DB, timeout time.Duration) (release func() error, err error)
+}
+
+// SetLockTimeout sets how long Up, Down and the other migrating methods wait
+// for the migration lock. Zero or less disables waiting: the lock is tried
+// once.
+func (m *MigrationManager) SetLockTimeout(timeout time.Duration) {
+	m.lockTimeout = timeout
+}
+
+// withLock runs fn while holding the migration lock, if the dialect has one
+func (m *MigrationManager) withLock(fn func() error) error {
+	locker, ok := m.dialect.(Locker)
+	if !ok {
+		return fn()
+	}
+
+	release, err := locker.AcquireLock(context.Background(), m.db, m.lockTimeout)
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn()
+	if err := release(); err != nil && fnErr == nil {
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+	return fnErr
+}
+
+// pollLock calls try until it reports success, fails, or timeout elapses
+func pollLock(ctx context.Context, timeout time.Duration, try func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := try()
+		if err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if ok {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%w after %s: another migration runner holds the lock", ErrLockTimeout, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// AcquireLock takes a named MySQL lock with GET_LOCK. Named locks belong to
+// a connection, so one is reserved until release; MySQL also frees the lock
+// if the process dies.
+func (MySQLDialect) AcquireLock(ctx context.Context, db *sql.DB, timeout time.Duration) (func() error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve connection for migration lock: %w", err)
+	}
+
+	// Lock names are server-wide, so include the database name
+	const name = "CONCAT('migrations:', DATABASE())"
+
+	seconds := int(timeout.Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK("+name+", ?)", seconds).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("%w after %s: another migration runner holds the lock", ErrLockTimeout, timeout)
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "DO RELEASE_LOCK("+name+")")
+		return err
+	}, nil
+}
+
+// AcquireLock takes a PostgreSQL session-level advisory lock. Like MySQL's
+// named locks it is tied to a reserved connection.
+func (PostgresDialect) AcquireLock(ctx context.Context, db *sql.DB, timeout time.Duration) (func() error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve connection for migration lock: %w", err)
+	}
+
+	err = pollLock(ctx, timeout, func() (bool, error) {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&acquired)
+		return acquired, err
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+		return err
+	}, nil
+}
+
+// AcquireLock inserts the single row of the migration_lock table. SQLite has
+// no advisory locks, so unlike MySQL and PostgreSQL the lock survives a
+// crashed runner; delete the row by hand once no runner is active.
+func (SQLiteDialect) AcquireLock(ctx context.Context, db *sql.DB, timeout time.Duration) (func() error, error) {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS migration_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			locked_at DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration_lock table: %w", err)
+	}
+
+	err = pollLock(ctx, timeout, func() (bool, error) {
+		result, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO migration_lock (id, locked_at) VALUES (1, ?)", time.Now().UTC())
+		if err != nil {
+			return false, err
+		}
+		inserted, err := result.RowsAffected()
+		return inserted == 1, err
+	})
+	if err != nil {
+		if errors.Is(err, ErrLockTimeout) {
+			return nil, fmt.Errorf("%w (if no runner is active, clear it with DELETE FROM migration_lock)", err)
+		}
+		return nil, err
+	}
+
+	return func() error {
+		_, err := db.ExecContext(context.Background(), "DELETE FROM migration_lock WHERE id = 1")
+		return err
+	}, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMigrationManager_LockBlocksConcurrentRunner(t *testing.T) {
+	manager, db := newCountingManager(t, 1)
+	manager.SetLockTimeout(300 * time.Millisecond)
+
+	// Another runner holds the lock
+	release, err := SQLiteDialect{}.AcquireLock(context.Background(), db, 0)
+	if err != nil {
+		t.Fatalf("AcquireLock failed: %v", err)
+	}
+
+	start := time.Now()
+	err = manager.Up()
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Expected ErrLockTimeout, got %v", err)
+	}
+	if time.Since(start) < 300*time.Millisecond {
+		t.Error("Expected Up to wait for the lock timeout")
+	}
+	if len(appliedVersions(t, manager)) != 0 {
+		t.Error("Expected no migrations to run without the lock")
+	}
+
+	if err := release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed after the lock was released: %v", err)
+	}
+	if len(appliedVersions(t, manager)) != 1 {
+		t.Error("Expected migration 1 to be applied")
+	}
+
+	// Up must release the lock when it is done
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM migration_lock").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected lock row to be removed, got %d (err: %v)", count, err)
+	}
+}
+
+func TestMigrationManager_LockReleasedOnFailure(t *testing.T) {
+	db := newSQLiteDB(t)
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Broken migration",
+		Up:          func(exec Executor) error { return errors.New("boom") },
+		Down:        func(exec Executor) error { return nil },
+	})
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	if err := manager.Up(); err == nil {
+		t.Fatal("Expected Up to fail")
+	}
+
+	release, err := SQLiteDialect{}.AcquireLock(context.Background(), db, 0)
+	if err != nil {
+		t.Fatalf("Expected lock to be free after a failed Up, got %v", err)
+	}
+	release()
+}
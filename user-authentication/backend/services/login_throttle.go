@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// LoginThrottleConfig controls how failed logins slow down further attempts
+type LoginThrottleConfig struct {
+	// MaxFailures is how many failures lock an email address for Lockout.
+	// Before that, every failure doubles the wait starting at BackoffBase, up
+	// to BackoffMax. 0 disables throttling per email address.
+	MaxFailures int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// IPMaxFailures is how many failures lock a client IP for Lockout. It is
+	// higher than MaxFailures because many users can share an IP. 0 disables
+	// throttling per IP.
+	IPMaxFailures int
+	Lockout       time.Duration
+	// Window is how long failures are remembered. A counter starts over once
+	// there has been no failure for Window (or Lockout, if that is longer).
+	Window time.Duration
+}
+
+// GetDefaultLoginThrottleConfig returns the login throttling configuration
+// from the environment: LOGIN_MAX_FAILURES, LOGIN_BACKOFF_BASE,
+// LOGIN_BACKOFF_MAX, LOGIN_IP_MAX_FAILURES, LOGIN_LOCKOUT_DURATION and
+// LOGIN_FAILURE_WINDOW
+func GetDefaultLoginThrottleConfig() *LoginThrottleConfig {
+	return &LoginThrottleConfig{
+		MaxFailures:   getEnvInt("LOGIN_MAX_FAILURES", 5),
+		BackoffBase:   getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+		BackoffMax:    getEnvDuration("LOGIN_BACKOFF_MAX", time.Minute),
+		IPMaxFailures: getEnvInt("LOGIN_IP_MAX_FAILURES", 50),
+		Lockout:       getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		Window:        getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+}
+
+// LoginThrottle counts failed logins per email address and per client IP and
+// tells how long a new attempt has to wait
+type LoginThrottle struct {
+	failures repository.LoginFailureRepository
+	config   LoginThrottleConfig
+	now      func() time.Time
+}
+
+// NewLoginThrottle creates a new LoginThrottle
+func NewLoginThrottle(failures repository.LoginFailureRepository, config *LoginThrottleConfig) *LoginThrottle {
+	return &LoginThrottle{failures: failures, config: *config, now: time.Now}
+}
+
+// Check returns how long a login for email from ip has to wait. Zero means
+// the attempt may proceed.
+func (t *LoginThrottle) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	now := t.now()
+	var wait time.Duration
+
+	for scope, subject := range t.subjects(email, ip) {
+		failure, err := t.failures.Get(ctx, scope, subject)
+		if errors.Is(err, repository.ErrLoginFailureNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if failure.LastFailureAt.Before(t.resetBefore(now)) {
+			continue
+		}
+
+		if remaining := failure.LastFailureAt.Add(t.delay(scope, failure.Failures)).Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// RecordFailure counts a failed login for email from ip
+func (t *LoginThrottle) RecordFailure(ctx context.Context, email, ip string) error {
+	now := t.now()
+	for scope, subject := range t.subjects(email, ip) {
+		if _, err := t.failures.Increment(ctx, scope, subject, now, t.resetBefore(now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the failures of email. The IP's failures are kept, so
+// that logging in to one account does not reset an attack on others.
+func (t *LoginThrottle) RecordSuccess(ctx context.Context, email string) error {
+	return t.failures.Delete(ctx, models.LoginFailureScopeAccount, normalizeEmail(email))
+}
+
+// UnlockAccount clears the failures of email
+func (t *LoginThrottle) UnlockAccount(ctx context.Context, email string) error {
+	return t.failures.Delete(ctx, models.LoginFailureScopeAccount, normalizeEmail(email))
+}
+
+// UnlockIP clears the failures of ip
+func (t *LoginThrottle) UnlockIP(ctx context.Context, ip string) error {
+	return t.failures.Delete(ctx, models.LoginFailureScopeIP, normalizeIP(ip))
+}
+
+// PurgeStale deletes counters that no longer slow anything down and returns how many
+func (t *LoginThrottle) PurgeStale(ctx context.Context) (int64, error) {
+	return t.failures.DeleteBefore(ctx, t.resetBefore(t.now()))
+}
+
+// subjects returns the counters a login for email from ip is checked against
+func (t *LoginThrottle) subjects(email, ip string) map[string]string {
+	subjects := make(map[string]string, 2)
+	if t.config.MaxFailures > 0 {
+		subjects[models.LoginFailureScopeAccount] = normalizeEmail(email)
+	}
+	if t.config.IPMaxFailures > 0 && ip != "" {
+		subjects[models.LoginFailureScopeIP] = normalizeIP(ip)
+	}
+	return subjects
+}
+
+// delay returns how long after the last of failures a new attempt has to wait
+func (t *LoginThrottle) delay(scope string, failures int) time.Duration {
+	if scope == models.LoginFailureScopeIP {
+		if failures >= t.config.IPMaxFailures {
+			return t.config.Lockout
+		}
+		return 0
+	}
+
+	if failures >= t.config.MaxFailures {
+		return t.config.Lockout
+	}
+	delay := t.config.BackoffBase
+	for i := 1; i < failures && delay < t.config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > t.config.BackoffMax {
+		delay = t.config.BackoffMax
+	}
+	return delay
+}
+
+// resetBefore returns the time before which failures are forgotten. A lockout
+// always runs to its end.
+func (t *LoginThrottle) resetBefore(now time.Time) time.Time {
+	memory := t.config.Window
+	if t.config.Lockout > memory {
+		memory = t.config.Lockout
+	}
+	return now.Add(-memory)
+}
+
+// normalizeIP returns ip in canonical form, so that "::1" and "0:0::1" are
+// counted together
+func normalizeIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// normalizeEmail returns email the way it is stored in users.email
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// memoryLoginFailureRepository is an in-memory LoginFailureRepository for tests
+type memoryLoginFailureRepository struct {
+	failures map[string]*models.LoginFailure
+}
+
+func (r *memoryLoginFailureRepository) Get(ctx context.Context, scope, subject string) (*models.LoginFailure, error) {
+	if stored, ok := r.failures[scope+":"+subject]; ok {
+		failure := *stored
+		return &failure, nil
+	}
+	return nil, repository.ErrLoginFailureNotFound
+}
+
+func (r *memoryLoginFailureRepository) Increment(ctx context.Context, scope, subject string, now, resetBefore time.Time) (*models.LoginFailure, error) {
+	key := scope + ":" + subject
+	stored, ok := r.failures[key]
+	if !ok || stored.LastFailureAt.Before(resetBefore) {
+		stored = &models.LoginFailure{Scope: scope, Subject: subject}
+		r.failures[key] = stored
+	}
+	stored.Failures++
+	stored.LastFailureAt = now
+	failure := *stored
+	return &failure, nil
+}
+
+func (r *memoryLoginFailureRepository) Delete(ctx context.Context, scope, subject string) error {
+	delete(r.failures, scope+":"+subject)
+	return nil
+}
+
+func (r *memoryLoginFailureRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for key, failure := range r.failures {
+		if failure.LastFailureAt.Before(before) {
+			delete(r.failures, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// newTestLoginThrottle returns a LoginThrottle with a controllable clock
+func newTestLoginThrottle(config LoginThrottleConfig) (*LoginThrottle, *memoryLoginFailureRepository, *time.Time) {
+	repo := &memoryLoginFailureRepository{failures: make(map[string]*models.LoginFailure)}
+	throttle := NewLoginThrottle(repo, &config)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+	return throttle, repo, &now
+}
+
+func TestLoginThrottle_BackoffAndLockout(t *testing.T) {
+	throttle, _, now := newTestLoginThrottle(LoginThrottleConfig{
+		MaxFailures: 4,
+		BackoffBase: time.Second,
+		BackoffMax:  3 * time.Second,
+		Lockout:     time.Hour,
+		Window:      time.Hour,
+	})
+	ctx := context.Background()
+
+	if wait, err := throttle.Check(ctx, "alice@example.com", "192.0.2.1"); err != nil || wait != 0 {
+		t.Fatalf("Expected no wait before any failure, got %v (err: %v)", wait, err)
+	}
+
+	// 1s, 2s, then capped at 3s, then locked out
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, time.Hour} {
+		if err := throttle.RecordFailure(ctx, "alice@example.com", "192.0.2.1"); err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+		wait, err := throttle.Check(ctx, "Alice@Example.com", "192.0.2.2")
+		if err != nil || wait != expected {
+			t.Errorf("After failure %d expected wait %v, got %v (err: %v)", i+1, expected, wait, err)
+		}
+	}
+
+	*now = now.Add(30 * time.Minute)
+	if wait, _ := throttle.Check(ctx, "alice@example.com", ""); wait != 30*time.Minute {
+		t.Errorf("Expected 30m left of the lockout, got %v", wait)
+	}
+	if wait, _ := throttle.Check(ctx, "bob@example.com", ""); wait != 0 {
+		t.Errorf("Expected other accounts not to wait, got %v", wait)
+	}
+
+	if err := throttle.UnlockAccount(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("UnlockAccount failed: %v", err)
+	}
+	if wait, _ := throttle.Check(ctx, "alice@example.com", ""); wait != 0 {
+		t.Errorf("Expected no wait after unlocking, got %v", wait)
+	}
+}
+
+func TestLoginThrottle_SuccessClearsAccountOnly(t *testing.T) {
+	throttle, repo, _ := newTestLoginThrottle(LoginThrottleConfig{
+		MaxFailures:   5,
+		BackoffBase:   time.Second,
+		BackoffMax:    time.Minute,
+		IPMaxFailures: 2,
+		Lockout:       time.Hour,
+		Window:        time.Hour,
+	})
+	ctx := context.Background()
+
+	throttle.RecordFailure(ctx, "alice@example.com", "192.0.2.1")
+	throttle.RecordFailure(ctx, "bob@example.com", "192.0.2.1")
+
+	if wait, _ := throttle.Check(ctx, "carol@example.com", "192.0.2.1"); wait != time.Hour {
+		t.Errorf("Expected the IP to be locked out, got %v", wait)
+	}
+	if wait, _ := throttle.Check(ctx, "carol@example.com", "192.0.2.2"); wait != 0 {
+		t.Errorf("Expected other IPs not to wait, got %v", wait)
+	}
+
+	if err := throttle.RecordSuccess(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("RecordSuccess failed: %v", err)
+	}
+	if _, ok := repo.failures["account:alice@example.com"]; ok {
+		t.Error("Expected a successful login to clear the account's failures")
+	}
+	if _, ok := repo.failures["ip:192.0.2.1"]; !ok {
+		t.Error("Expected a successful login to keep the IP's failures")
+	}
+
+	if err := throttle.UnlockIP(ctx, "192.0.2.1"); err != nil {
+		t.Fatalf("UnlockIP failed: %v", err)
+	}
+	if wait, _ := throttle.Check(ctx, "carol@example.com", "192.0.2.1"); wait != 0 {
+		t.Errorf("Expected no wait after unlocking the IP, got %v", wait)
+	}
+}
+
+func TestLoginThrottle_FailuresAreForgotten(t *testing.T) {
+	throttle, _, now := newTestLoginThrottle(LoginThrottleConfig{
+		MaxFailures: 2,
+		BackoffBase: time.Second,
+		BackoffMax:  time.Minute,
+		Lockout:     2 * time.Hour,
+		Window:      time.Hour,
+	})
+	ctx := context.Background()
+
+	throttle.RecordFailure(ctx, "alice@example.com", "")
+	throttle.RecordFailure(ctx, "alice@example.com", "")
+
+	// The lockout outlasts the window
+	*now = now.Add(90 * time.Minute)
+	if wait, _ := throttle.Check(ctx, "alice@example.com", ""); wait != 30*time.Minute {
+		t.Errorf("Expected the lockout to run to its end, got %v", wait)
+	}
+	if deleted, _ := throttle.PurgeStale(ctx); deleted != 0 {
+		t.Errorf("Expected an active lockout not to be purged, got %d", deleted)
+	}
+
+	*now = now.Add(time.Hour)
+	if wait, _ := throttle.Check(ctx, "alice@example.com", ""); wait != 0 {
+		t.Errorf("Expected no wait after the lockout, got %v", wait)
+	}
+	throttle.RecordFailure(ctx, "alice@example.com", "")
+	if wait, _ := throttle.Check(ctx, "alice@example.com", ""); wait != time.Second {
+		t.Errorf("Expected the count to start over, got %v", wait)
+	}
+}
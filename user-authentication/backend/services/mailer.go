@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Mail drivers accepted in MAIL_DRIVER
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverFile = "file"
+	MailDriverLog  = "log"
+)
+
+// MailConfig selects and configures the Mailer
+type MailConfig struct {
+	// Driver is smtp, file or log
+	Driver string
+	// From is the sender address of every message
+	From string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Dir is where the file driver writes messages
+	Dir string
+}
+
+// GetDefaultMailConfig returns the mail configuration from the environment
+func GetDefaultMailConfig() *MailConfig {
+	return &MailConfig{
+		Driver:       getEnv("MAIL_DRIVER", MailDriverLog),
+		From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		Dir:          getEnv("MAIL_DIR", "mail"),
+	}
+}
+
+// NewMailer creates the Mailer selected by config.Driver
+func NewMailer(config *MailConfig) (Mailer, error) {
+	switch config.Driver {
+	case MailDriverSMTP:
+		if config.SMTPHost == "" {
+			return nil, errors.New("SMTP_HOST is required when MAIL_DRIVER=smtp")
+		}
+		mailer := &SMTPMailer{
+			Addr: net.JoinHostPort(config.SMTPHost, strconv.Itoa(config.SMTPPort)),
+			From: config.From,
+		}
+		if config.SMTPUsername != "" {
+			mailer.Auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
+		}
+		return mailer, nil
+	case MailDriverFile:
+		return &FileMailer{Dir: config.Dir, From: config.From}, nil
+	case MailDriverLog:
+		return &LogMailer{Logger: log.Default()}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q (use smtp, file or log)", config.Driver)
+	}
+}
+
+// SMTPMailer sends messages through an SMTP server. net/smtp upgrades the
+// connection with STARTTLS when the server offers it, and refuses to send
+// credentials over an unencrypted connection to a remote host.
+type SMTPMailer struct {
+	// Addr is the server's host:port
+	Addr string
+	// Auth is nil for servers that do not require authentication
+	Auth smtp.Auth
+	From string
+}
+
+// Send delivers msg. The context is not used because net/smtp does not
+// support cancellation.
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := formatMessage(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// FileMailer writes every message to its own .eml file in Dir, for local
+// development and tests
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes msg to a new file
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := formatMessage(m.From, msg, now)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	if err := os.WriteFile(filepath.Join(m.Dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
+
+// LogMailer writes messages to a logger instead of sending them. Messages
+// contain secrets such as reset links, so it is for local development only.
+type LogMailer struct {
+	Logger *log.Logger
+}
+
+// Send logs msg
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	m.Logger.Printf("[MAIL] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// formatMessage renders msg as an RFC 5322 message
+func formatMessage(from string, msg *Message, date time.Time) ([]byte, error) {
+	// A line break in a header would let the value inject more headers
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.New("email headers must not contain line breaks")
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMailer(t *testing.T) {
+	if _, err := NewMailer(&MailConfig{Driver: MailDriverSMTP}); err == nil {
+		t.Error("Expected error for smtp driver without host")
+	}
+	if _, err := NewMailer(&MailConfig{Driver: "carrier-pigeon"}); err == nil {
+		t.Error("Expected error for unknown driver")
+	}
+
+	mailer, err := NewMailer(&MailConfig{Driver: MailDriverSMTP, SMTPHost: "smtp.example.com", SMTPPort: 2525})
+	if err != nil {
+		t.Fatalf("NewMailer failed: %v", err)
+	}
+	if smtpMailer, ok := mailer.(*SMTPMailer); !ok || smtpMailer.Addr != "smtp.example.com:2525" || smtpMailer.Auth != nil {
+		t.Errorf("Expected unauthenticated SMTPMailer for smtp.example.com:2525, got %+v", mailer)
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	date := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	data, err := formatMessage("no-reply@example.com", &Message{
+		To:      "alice@example.com",
+		Subject: "Passwort zurücksetzen",
+		Body:    "line one\nline two",
+	}, date)
+	if err != nil {
+		t.Fatalf("formatMessage failed: %v", err)
+	}
+
+	message := string(data)
+	for _, expected := range []string{
+		"From: no-reply@example.com\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected message to contain %q, got %q", expected, message)
+		}
+	}
+
+	if _, err := formatMessage("no-reply@example.com", &Message{
+		To:      "alice@example.com\r\nBcc: mallory@example.com",
+		Subject: "Hi",
+	}, date); err == nil {
+		t.Error("Expected error for a header containing a line break")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := &FileMailer{Dir: dir, From: "no-reply@example.com"}
+
+	if err := mailer.Send(context.Background(), &Message{To: "alice@example.com", Subject: "Hi", Body: "Hello"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 message file, got %v (err: %v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), "To: alice@example.com") {
+		t.Errorf("Expected the message to be written, got %q", data)
+	}
+}
@@ -1,35 +1,92 @@
 package services
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
+	"strings"
 	"time"
 )
 
-// Migration represents a database migration
+// Executor is the subset of *sql.DB and *sql.Tx that migrations use
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Migration represents a database migration.
+//
+// Up and Down receive the transaction that also records the migration in the
+// migrations table, so a failure leaves neither schema changes nor a record
+// behind. Note that MySQL commits implicitly on most DDL statements, so there
+// only the statements after the last DDL are rolled back.
+//
+// Set NoTransaction for statements that cannot run inside a transaction
+// (e.g. PostgreSQL's CREATE INDEX CONCURRENTLY). Up and Down then receive the
+// *sql.DB and the migration is recorded only after they succeed.
+//
+// Checksum identifies the migration's contents; it is stored when the
+// migration is applied so that later edits can be detected (see Status).
+// SQL migrations get one automatically, Go migrations can use Checksum() on
+// their SQL. Migrations without a checksum are not checked for drift.
 type Migration struct {
-	Version     int
-	Description string
-	Up          func(*sql.DB) error
-	Down        func(*sql.DB) error
+	Version       int
+	Description   string
+	Up            func(Executor) error
+	Down          func(Executor) error
+	NoTransaction bool
+	Checksum      string
+}
+
+// ErrMigrationDrift is returned by Validate when applied migrations no longer
+// match the registered ones
+var ErrMigrationDrift = errors.New("migration drift detected")
+
+// Checksum returns the hex SHA-256 of the given migration sources
+func Checksum(sources ...string) string {
+	h := sha256.New()
+	for _, source := range sources {
+		h.Write([]byte(source))
+		// Separate the parts so that moving text between them changes the sum
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // MigrationManager manages database migrations
 type MigrationManager struct {
-	db         *sql.DB
-	migrations []Migration
+	db          *sql.DB
+	dialect     Dialect
+	migrations  []Migration
+	lockTimeout time.Duration
 }
 
-// NewMigrationManager creates a new migration manager
+// NewMigrationManager creates a new migration manager for MySQL
 func NewMigrationManager(db *sql.DB) *MigrationManager {
+	return NewMigrationManagerWithDialect(db, MySQLDialect{})
+}
+
+// NewMigrationManagerWithDialect creates a new migration manager that uses
+// dialect for its bookkeeping table and queries
+func NewMigrationManagerWithDialect(db *sql.DB, dialect Dialect) *MigrationManager {
 	return &MigrationManager{
-		db:         db,
-		migrations: make([]Migration, 0),
+		db:          db,
+		dialect:     dialect,
+		migrations:  make([]Migration, 0),
+		lockTimeout: DefaultLockTimeout,
 	}
 }
 
+// Dialect returns the dialect the manager was created with
+func (m *MigrationManager) Dialect() Dialect {
+	return m.dialect
+}
+
 // AddMigration adds a migration to the manager
 func (m *MigrationManager) AddMigration(migration Migration) {
 	m.migrations = append(m.migrations, migration)
@@ -42,19 +99,19 @@ func (m *MigrationManager) GetMigrations() []Migration {
 
 // InitializeMigrationTable creates the migrations table if it doesn't exist
 func (m *MigrationManager) InitializeMigrationTable() error {
-	query := `
-		CREATE TABLE IF NOT EXISTS migrations (
-			version INT PRIMARY KEY,
-			description VARCHAR(255) NOT NULL,
-			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
-	`
-
-	_, err := m.db.Exec(query)
+	_, err := m.db.Exec(m.dialect.CreateMigrationTableSQL())
 	if err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
+	// Tables created before checksums were introduced lack the column
+	if _, err := m.db.Exec("SELECT checksum FROM migrations WHERE 1 = 0"); err != nil {
+		_, err = m.db.Exec("ALTER TABLE migrations ADD COLUMN checksum VARCHAR(64) NOT NULL DEFAULT ''")
+		if err != nil {
+			return fmt.Errorf("failed to add checksum column to migrations table: %w", err)
+		}
+	}
+
 	log.Println("Migration table initialized successfully")
 	return nil
 }
@@ -84,35 +141,41 @@ func (m *MigrationManager) GetAppliedMigrations() ([]int, error) {
 func (m *MigrationManager) IsMigrationApplied(version int) (bool, error) {
 	query := "SELECT COUNT(*) FROM migrations WHERE version = ?"
 	var count int
-	err := m.db.QueryRow(query, version).Scan(&count)
+	err := m.db.QueryRow(rebind(m.dialect, query), version).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check migration status: %w", err)
 	}
 	return count > 0, nil
 }
 
-// RecordMigration records a migration as applied
-func (m *MigrationManager) RecordMigration(version int, description string) error {
-	query := "INSERT INTO migrations (version, description, applied_at) VALUES (?, ?, ?)"
-	_, err := m.db.Exec(query, version, description, time.Now())
+// RecordMigration records a migration as applied using exec, which is
+// normally the migration's transaction
+func (m *MigrationManager) RecordMigration(exec Executor, version int, description, checksum string) error {
+	query := "INSERT INTO migrations (version, description, checksum, applied_at) VALUES (?, ?, ?, ?)"
+	_, err := exec.Exec(rebind(m.dialect, query), version, description, checksum, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
 	return nil
 }
 
-// RemoveMigrationRecord removes a migration record
-func (m *MigrationManager) RemoveMigrationRecord(version int) error {
+// RemoveMigrationRecord removes a migration record using exec, which is
+// normally the rollback's transaction
+func (m *MigrationManager) RemoveMigrationRecord(exec Executor, version int) error {
 	query := "DELETE FROM migrations WHERE version = ?"
-	_, err := m.db.Exec(query, version)
+	_, err := exec.Exec(rebind(m.dialect, query), version)
 	if err != nil {
 		return fmt.Errorf("failed to remove migration record: %w", err)
 	}
 	return nil
 }
 
-// Up runs all pending migrations
+// Up runs all pending migrations while holding the migration lock
 func (m *MigrationManager) Up() error {
+	return m.withLock(m.up)
+}
+
+func (m *MigrationManager) up() error {
 	// Sort migrations by version
 	sort.Slice(m.migrations, func(i, j int) bool {
 		return m.migrations[i].Version < m.migrations[j].Version
@@ -136,27 +199,8 @@ func (m *MigrationManager) Up() error {
 
 		log.Printf("Applying migration %d: %s", migration.Version, migration.Description)
 
-		// Start transaction
-		tx, err := m.db.Begin()
-		if err != nil {
-			return fmt.Errorf("failed to start transaction for migration %d: %w", migration.Version, err)
-		}
-
-		// Run the migration
-		if err := migration.Up(m.db); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
-		}
-
-		// Record the migration
-		if err := m.RecordMigration(migration.Version, migration.Description); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
-		}
-
-		// Commit transaction
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+		if err := m.applyMigration(migration); err != nil {
+			return err
 		}
 
 		log.Printf("Successfully applied migration %d: %s", migration.Version, migration.Description)
@@ -165,8 +209,12 @@ func (m *MigrationManager) Up() error {
 	return nil
 }
 
-// Down rolls back the last applied migration
+// Down rolls back the last applied migration while holding the migration lock
 func (m *MigrationManager) Down() error {
+	return m.withLock(m.down)
+}
+
+func (m *MigrationManager) down() error {
 	appliedMigrations, err := m.GetAppliedMigrations()
 	if err != nil {
 		return fmt.Errorf("failed to get applied migrations: %w", err)
@@ -195,51 +243,316 @@ func (m *MigrationManager) Down() error {
 
 	log.Printf("Rolling back migration %d: %s", migrationToRollback.Version, migrationToRollback.Description)
 
-	// Start transaction
-	tx, err := m.db.Begin()
+	if err := m.rollbackMigration(*migrationToRollback); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully rolled back migration %d: %s", migrationToRollback.Version, migrationToRollback.Description)
+	return nil
+}
+
+// PlanUpTo returns the pending migrations with a version up to and including
+// version, in the order UpTo would apply them
+func (m *MigrationManager) PlanUpTo(version int) ([]Migration, error) {
+	if err := m.checkTargetVersion(version); err != nil {
+		return nil, err
+	}
+
+	appliedMigrations, err := m.GetAppliedMigrations()
 	if err != nil {
-		return fmt.Errorf("failed to start transaction for rollback %d: %w", lastVersion, err)
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 
-	// Run the rollback
-	if err := migrationToRollback.Down(m.db); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to rollback migration %d: %w", lastVersion, err)
+	appliedMap := make(map[int]bool)
+	for _, v := range appliedMigrations {
+		appliedMap[v] = true
+	}
+
+	var plan []Migration
+	for _, migration := range m.sortedMigrations() {
+		if migration.Version <= version && !appliedMap[migration.Version] {
+			plan = append(plan, migration)
+		}
+	}
+	return plan, nil
+}
+
+// PlanDownTo returns the applied migrations with a version above version, in
+// the order DownTo would roll them back (newest first)
+func (m *MigrationManager) PlanDownTo(version int) ([]Migration, error) {
+	if err := m.checkTargetVersion(version); err != nil {
+		return nil, err
+	}
+
+	appliedMigrations, err := m.GetAppliedMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	registered := make(map[int]Migration)
+	for _, migration := range m.migrations {
+		registered[migration.Version] = migration
+	}
+
+	var plan []Migration
+	for i := len(appliedMigrations) - 1; i >= 0; i-- {
+		v := appliedMigrations[i]
+		if v <= version {
+			break
+		}
+		migration, ok := registered[v]
+		if !ok {
+			return nil, fmt.Errorf("migration %d not found in registered migrations", v)
+		}
+		plan = append(plan, migration)
+	}
+	return plan, nil
+}
+
+// PlanRedo returns the migration Redo would roll back and re-apply, or nil
+// when nothing is applied
+func (m *MigrationManager) PlanRedo() (*Migration, error) {
+	appliedMigrations, err := m.GetAppliedMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	if len(appliedMigrations) == 0 {
+		return nil, nil
+	}
+
+	lastVersion := appliedMigrations[len(appliedMigrations)-1]
+	for _, migration := range m.migrations {
+		if migration.Version == lastVersion {
+			return &migration, nil
+		}
+	}
+	return nil, fmt.Errorf("migration %d not found in registered migrations", lastVersion)
+}
+
+// UpTo applies pending migrations up to and including version
+func (m *MigrationManager) UpTo(version int) error {
+	return m.withLock(func() error { return m.upTo(version) })
+}
+
+func (m *MigrationManager) upTo(version int) error {
+	plan, err := m.PlanUpTo(version)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range plan {
+		log.Printf("Applying migration %d: %s", migration.Version, migration.Description)
+
+		if err := m.applyMigration(migration); err != nil {
+			return err
+		}
+
+		log.Printf("Successfully applied migration %d: %s", migration.Version, migration.Description)
+	}
+
+	return nil
+}
+
+// DownTo rolls back applied migrations, newest first, until version is the
+// latest applied one. DownTo(0) rolls back everything.
+func (m *MigrationManager) DownTo(version int) error {
+	return m.withLock(func() error { return m.downTo(version) })
+}
+
+func (m *MigrationManager) downTo(version int) error {
+	plan, err := m.PlanDownTo(version)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range plan {
+		log.Printf("Rolling back migration %d: %s", migration.Version, migration.Description)
+
+		if err := m.rollbackMigration(migration); err != nil {
+			return err
+		}
+
+		log.Printf("Successfully rolled back migration %d: %s", migration.Version, migration.Description)
+	}
+
+	return nil
+}
+
+// Redo rolls back the last applied migration and applies it again
+func (m *MigrationManager) Redo() error {
+	return m.withLock(m.redo)
+}
+
+func (m *MigrationManager) redo() error {
+	migration, err := m.PlanRedo()
+	if err != nil {
+		return err
+	}
+
+	if migration == nil {
+		log.Println("No migrations to redo")
+		return nil
+	}
+
+	log.Printf("Redoing migration %d: %s", migration.Version, migration.Description)
+
+	if err := m.rollbackMigration(*migration); err != nil {
+		return err
+	}
+	if err := m.applyMigration(*migration); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully redid migration %d: %s", migration.Version, migration.Description)
+	return nil
+}
+
+// Reset rolls back every applied migration
+func (m *MigrationManager) Reset() error {
+	return m.DownTo(0)
+}
+
+// checkTargetVersion accepts 0 (no migrations) and registered versions
+func (m *MigrationManager) checkTargetVersion(version int) error {
+	if version == 0 {
+		return nil
+	}
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("migration %d not found in registered migrations", version)
+}
+
+// sortedMigrations returns the registered migrations ordered by version
+func (m *MigrationManager) sortedMigrations() []Migration {
+	migrations := append([]Migration{}, m.migrations...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// applyMigration runs migration.Up and records it in the same transaction
+func (m *MigrationManager) applyMigration(migration Migration) error {
+	return m.inTransaction(migration, func(exec Executor) error {
+		// Run the migration
+		if err := migration.Up(exec); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+		}
+
+		// Record the migration
+		if err := m.RecordMigration(exec, migration.Version, migration.Description, migration.Checksum); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		return nil
+	})
+}
+
+// rollbackMigration runs migration.Down and removes its record in the same transaction
+func (m *MigrationManager) rollbackMigration(migration Migration) error {
+	return m.inTransaction(migration, func(exec Executor) error {
+		// Run the rollback
+		if err := migration.Down(exec); err != nil {
+			return fmt.Errorf("failed to rollback migration %d: %w", migration.Version, err)
+		}
+
+		// Remove the migration record
+		if err := m.RemoveMigrationRecord(exec, migration.Version); err != nil {
+			return fmt.Errorf("failed to remove migration record %d: %w", migration.Version, err)
+		}
+		return nil
+	})
+}
+
+// inTransaction calls fn with a transaction that is committed if fn succeeds
+// and rolled back otherwise. Migrations marked NoTransaction get the *sql.DB.
+func (m *MigrationManager) inTransaction(migration Migration, fn func(Executor) error) error {
+	if migration.NoTransaction {
+		return fn(m.db)
 	}
 
-	// Remove the migration record
-	if err := m.RemoveMigrationRecord(lastVersion); err != nil {
+	// Start transaction
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction for migration %d: %w", migration.Version, err)
+	}
+
+	if err := fn(tx); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to remove migration record %d: %w", lastVersion, err)
+		return err
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit rollback %d: %w", lastVersion, err)
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
 	}
-
-	log.Printf("Successfully rolled back migration %d: %s", migrationToRollback.Version, migrationToRollback.Description)
 	return nil
 }
 
-// Status returns the current migration status
-func (m *MigrationManager) Status() ([]MigrationStatus, error) {
-	appliedMigrations, err := m.GetAppliedMigrations()
+// appliedMigration is a row of the migrations table
+type appliedMigration struct {
+	Version     int
+	Description string
+	Checksum    string
+}
+
+// getAppliedRecords returns the rows of the migrations table keyed by version
+func (m *MigrationManager) getAppliedRecords() (map[int]appliedMigration, error) {
+	rows, err := m.db.Query("SELECT version, description, checksum FROM migrations")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
 	}
+	defer rows.Close()
 
-	appliedMap := make(map[int]bool)
-	for _, version := range appliedMigrations {
-		appliedMap[version] = true
+	records := make(map[int]appliedMigration)
+	for rows.Next() {
+		var record appliedMigration
+		if err := rows.Scan(&record.Version, &record.Description, &record.Checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		records[record.Version] = record
 	}
 
+	return records, rows.Err()
+}
+
+// Status returns the current migration status.
+//
+// An applied migration is flagged Modified when its stored checksum differs
+// from the registered one, and applied versions that are not registered at
+// all are included with Unknown set. Both count as drift.
+func (m *MigrationManager) Status() ([]MigrationStatus, error) {
+	applied, err := m.getAppliedRecords()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	registered := make(map[int]bool)
 	var status []MigrationStatus
 	for _, migration := range m.migrations {
+		registered[migration.Version] = true
+
+		record, ok := applied[migration.Version]
 		status = append(status, MigrationStatus{
 			Version:     migration.Version,
 			Description: migration.Description,
-			Applied:     appliedMap[migration.Version],
+			Applied:     ok,
+			Modified:    ok && record.Checksum != "" && migration.Checksum != "" && record.Checksum != migration.Checksum,
+		})
+	}
+
+	for version, record := range applied {
+		if registered[version] {
+			continue
+		}
+		status = append(status, MigrationStatus{
+			Version:     version,
+			Description: record.Description,
+			Applied:     true,
+			Unknown:     true,
 		})
 	}
 
@@ -251,9 +564,35 @@ func (m *MigrationManager) Status() ([]MigrationStatus, error) {
 	return status, nil
 }
 
+// Validate returns an error wrapping ErrMigrationDrift when Status reports
+// modified or unknown migrations
+func (m *MigrationManager) Validate() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, s := range status {
+		if s.Modified {
+			problems = append(problems, fmt.Sprintf("migration %d (%s) was modified after it was applied", s.Version, s.Description))
+		}
+		if s.Unknown {
+			problems = append(problems, fmt.Sprintf("migration %d (%s) is applied but not registered", s.Version, s.Description))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrMigrationDrift, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 // MigrationStatus represents the status of a migration
 type MigrationStatus struct {
 	Version     int    `json:"version"`
 	Description string `json:"description"`
 	Applied     bool   `json:"applied"`
+	Modified    bool   `json:"modified"`
+	Unknown     bool   `json:"unknown"`
 }
\ No newline at end of file
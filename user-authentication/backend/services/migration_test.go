@@ -2,19 +2,48 @@ package services
 
 import (
 	"database/sql"
+	"errors"
+	"strconv"
 	"testing"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
 )
 
+// newSQLiteDB opens an in-memory SQLite database. The pool is limited to one
+// connection, so anything that runs outside the migration's transaction
+// would deadlock instead of silently passing.
+func newSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func sqliteTableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	return count > 0
+}
+
 func TestMigrationManager_AddMigration(t *testing.T) {
 	manager := NewMigrationManager(nil)
 
 	migration := Migration{
 		Version:     1,
 		Description: "Test migration",
-		Up:          func(db *sql.DB) error { return nil },
-		Down:        func(db *sql.DB) error { return nil },
+		Up:          func(exec Executor) error { return nil },
+		Down:        func(exec Executor) error { return nil },
 	}
 
 	manager.AddMigration(migration)
@@ -66,4 +95,303 @@ func TestNewMigrationManager(t *testing.T) {
 	if len(manager.migrations) != 0 {
 		t.Errorf("Expected empty migrations slice, got %d migrations", len(manager.migrations))
 	}
-}
\ No newline at end of file
+
+	if manager.Dialect().Name() != "mysql" {
+		t.Errorf("Expected default dialect 'mysql', got '%s'", manager.Dialect().Name())
+	}
+}
+
+func TestMigrationManager_UpRollsBackFailedMigration(t *testing.T) {
+	db := newSQLiteDB(t)
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Broken migration",
+		Up: func(exec Executor) error {
+			if _, err := exec.Exec("CREATE TABLE half_done (id INTEGER)"); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+		Down: func(exec Executor) error { return nil },
+	})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	if err := manager.Up(); err == nil {
+		t.Fatal("Expected Up to fail")
+	}
+
+	if sqliteTableExists(t, db, "half_done") {
+		t.Error("Expected partial schema changes to be rolled back")
+	}
+
+	applied, err := manager.IsMigrationApplied(1)
+	if err != nil || applied {
+		t.Errorf("Expected migration 1 not to be recorded, got %v (err: %v)", applied, err)
+	}
+}
+
+func TestMigrationManager_DownRollsBackFailedRollback(t *testing.T) {
+	db := newSQLiteDB(t)
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Create widgets table",
+		Up: func(exec Executor) error {
+			_, err := exec.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY)")
+			return err
+		},
+		Down: func(exec Executor) error {
+			if _, err := exec.Exec("DROP TABLE widgets"); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+	})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	if err := manager.Down(); err == nil {
+		t.Fatal("Expected Down to fail")
+	}
+
+	if !sqliteTableExists(t, db, "widgets") {
+		t.Error("Expected dropped table to be restored by the rollback")
+	}
+
+	applied, err := manager.IsMigrationApplied(1)
+	if err != nil || !applied {
+		t.Errorf("Expected migration 1 to stay recorded, got %v (err: %v)", applied, err)
+	}
+}
+
+func TestMigrationManager_NoTransaction(t *testing.T) {
+	db := newSQLiteDB(t)
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+
+	var got Executor
+	manager.AddMigration(Migration{
+		Version:     1,
+		Description: "Non-transactional migration",
+		Up: func(exec Executor) error {
+			got = exec
+			_, err := exec.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY)")
+			return err
+		},
+		Down:          func(exec Executor) error { return nil },
+		NoTransaction: true,
+	})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	if _, ok := got.(*sql.DB); !ok {
+		t.Errorf("Expected NoTransaction migration to receive *sql.DB, got %T", got)
+	}
+
+	applied, err := manager.IsMigrationApplied(1)
+	if err != nil || !applied {
+		t.Errorf("Expected migration 1 to be recorded, got %v (err: %v)", applied, err)
+	}
+}
+func TestMigrationManager_DetectsDrift(t *testing.T) {
+	db := newSQLiteDB(t)
+	noop := func(exec Executor) error { return nil }
+
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	manager.AddMigration(Migration{Version: 1, Description: "First", Up: noop, Down: noop, Checksum: Checksum("v1")})
+	manager.AddMigration(Migration{Version: 2, Description: "Second", Up: noop, Down: noop, Checksum: Checksum("v2")})
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := manager.Validate(); err != nil {
+		t.Errorf("Expected no drift right after Up, got %v", err)
+	}
+
+	// Migration 1 is edited and migration 2 disappears from the code base
+	edited := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	edited.AddMigration(Migration{Version: 1, Description: "First", Up: noop, Down: noop, Checksum: Checksum("v1 edited")})
+
+	status, err := edited.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status) != 2 {
+		t.Fatalf("Expected 2 statuses, got %d", len(status))
+	}
+	if !status[0].Modified {
+		t.Error("Expected migration 1 to be flagged as modified")
+	}
+	if !status[1].Unknown || !status[1].Applied || status[1].Description != "Second" {
+		t.Errorf("Expected migration 2 to be flagged as unknown, got %+v", status[1])
+	}
+
+	if err := edited.Validate(); !errors.Is(err, ErrMigrationDrift) {
+		t.Errorf("Expected ErrMigrationDrift, got %v", err)
+	}
+}
+
+func TestMigrationManager_UpgradesTableWithoutChecksum(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	// Table layout from before checksums were recorded
+	_, err := db.Exec("CREATE TABLE migrations (version INTEGER PRIMARY KEY, description TEXT NOT NULL, applied_at DATETIME)")
+	if err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO migrations (version, description) VALUES (1, 'First')"); err != nil {
+		t.Fatalf("Failed to insert legacy record: %v", err)
+	}
+
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	noop := func(exec Executor) error { return nil }
+	manager.AddMigration(Migration{Version: 1, Description: "First", Up: noop, Down: noop, Checksum: Checksum("v1")})
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+
+	// Records without a checksum cannot be compared and are not drift
+	if err := manager.Validate(); err != nil {
+		t.Errorf("Expected no drift for legacy records, got %v", err)
+	}
+}
+
+// newCountingManager registers migrations 1..n that each create table tN
+func newCountingManager(t *testing.T, n int) (*MigrationManager, *sql.DB) {
+	t.Helper()
+
+	db := newSQLiteDB(t)
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+	for i := 1; i <= n; i++ {
+		table := "t" + strconv.Itoa(i)
+		manager.AddMigration(Migration{
+			Version:     i,
+			Description: "Create " + table,
+			Up: func(exec Executor) error {
+				_, err := exec.Exec("CREATE TABLE " + table + " (id INTEGER)")
+				return err
+			},
+			Down: func(exec Executor) error {
+				_, err := exec.Exec("DROP TABLE " + table)
+				return err
+			},
+		})
+	}
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	return manager, db
+}
+
+func appliedVersions(t *testing.T, manager *MigrationManager) []int {
+	t.Helper()
+
+	versions, err := manager.GetAppliedMigrations()
+	if err != nil {
+		t.Fatalf("GetAppliedMigrations failed: %v", err)
+	}
+	return versions
+}
+
+func TestMigrationManager_UpToDownTo(t *testing.T) {
+	manager, db := newCountingManager(t, 4)
+
+	plan, err := manager.PlanUpTo(2)
+	if err != nil {
+		t.Fatalf("PlanUpTo failed: %v", err)
+	}
+	if len(plan) != 2 || plan[0].Version != 1 || plan[1].Version != 2 {
+		t.Errorf("Expected plan [1 2], got %+v", plan)
+	}
+	if len(appliedVersions(t, manager)) != 0 {
+		t.Error("Planning must not apply migrations")
+	}
+
+	if err := manager.UpTo(2); err != nil {
+		t.Fatalf("UpTo failed: %v", err)
+	}
+	if got := appliedVersions(t, manager); len(got) != 2 {
+		t.Errorf("Expected versions [1 2] applied, got %v", got)
+	}
+	if sqliteTableExists(t, db, "t3") {
+		t.Error("Expected migration 3 not to run")
+	}
+
+	if err := manager.UpTo(4); err != nil {
+		t.Fatalf("UpTo failed: %v", err)
+	}
+
+	plan, err = manager.PlanDownTo(1)
+	if err != nil {
+		t.Fatalf("PlanDownTo failed: %v", err)
+	}
+	if len(plan) != 3 || plan[0].Version != 4 || plan[2].Version != 2 {
+		t.Errorf("Expected plan [4 3 2], got %+v", plan)
+	}
+
+	if err := manager.DownTo(1); err != nil {
+		t.Fatalf("DownTo failed: %v", err)
+	}
+	if got := appliedVersions(t, manager); len(got) != 1 || got[0] != 1 {
+		t.Errorf("Expected only version 1 applied, got %v", got)
+	}
+
+	if err := manager.UpTo(7); err == nil {
+		t.Error("Expected error for unknown target version")
+	}
+}
+
+func TestMigrationManager_RedoReset(t *testing.T) {
+	manager, db := newCountingManager(t, 3)
+
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// Redo must run Down and Up of the last migration
+	if _, err := db.Exec("INSERT INTO t3 (id) VALUES (1)"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := manager.Redo(); err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM t3").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected t3 to be recreated empty, got %d rows (err: %v)", count, err)
+	}
+	if got := appliedVersions(t, manager); len(got) != 3 {
+		t.Errorf("Expected all migrations applied after Redo, got %v", got)
+	}
+
+	if err := manager.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if got := appliedVersions(t, manager); len(got) != 0 {
+		t.Errorf("Expected no migrations applied after Reset, got %v", got)
+	}
+	if sqliteTableExists(t, db, "t1") {
+		t.Error("Expected t1 to be dropped by Reset")
+	}
+
+	migration, err := manager.PlanRedo()
+	if err != nil || migration != nil {
+		t.Errorf("Expected nothing to redo, got %+v (err: %v)", migration, err)
+	}
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+var (
+	// ErrUnknownOIDCProvider is returned for provider names that are not configured
+	ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+	// ErrInvalidOIDCState is returned when the callback does not belong to a
+	// login started in the same browser, or the login took too long
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrOIDCNoEmail is returned when a new user would be created but the
+	// provider did not return a usable email address
+	ErrOIDCNoEmail = errors.New("identity provider did not return a usable email address")
+	// ErrOIDCEmailTaken is returned when a new provider account has the email
+	// address of an existing user. The user has to log in and link it.
+	ErrOIDCEmailTaken = errors.New("email address is registered to an existing user")
+	// ErrIdentityLinkedToOtherUser is returned when a logged in user tries to
+	// link a provider account that belongs to someone else
+	ErrIdentityLinkedToOtherUser = errors.New("identity is linked to another user")
+)
+
+// oidcProviderNamePattern keeps provider names usable in URLs, environment
+// variable names and user_identities.provider (VARCHAR(50))
+var oidcProviderNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// invalidUsernameChars matches what usernames may not contain
+var invalidUsernameChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// OIDCConfig controls login with external OpenID Connect providers
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+	// StateSecret signs the cookie that carries the state, nonce and PKCE
+	// verifier from the start of a login to the callback
+	StateSecret []byte
+	// StateTTL is how long the user has to log in at the provider
+	StateTTL time.Duration
+}
+
+// LoadOIDCConfig reads the OpenID Connect configuration from the
+// environment. OIDC_PROVIDERS lists the provider names; each NAME is
+// configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_SCOPES. The callback URL is
+// OIDC_REDIRECT_BASE_URL/<name>/callback. StateSecret is nil when
+// OIDC_STATE_SECRET is not set.
+func LoadOIDCConfig() (*OIDCConfig, error) {
+	config := &OIDCConfig{StateTTL: getEnvDuration("OIDC_STATE_TTL", 10*time.Minute)}
+	redirectBase := strings.TrimSuffix(getEnv("OIDC_REDIRECT_BASE_URL", "http://localhost:8080/api/auth/oidc"), "/")
+
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !oidcProviderNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("OIDC provider name %q must be lowercase letters, digits and underscores", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate OIDC provider %q", name)
+		}
+		seen[name] = true
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  redirectBase + "/" + name + "/callback",
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		config.Providers = append(config.Providers, provider)
+	}
+
+	if secret := os.Getenv("OIDC_STATE_SECRET"); secret != "" {
+		if len(secret) < sha256.Size {
+			return nil, fmt.Errorf("OIDC_STATE_SECRET must be at least %d bytes", sha256.Size)
+		}
+		config.StateSecret = []byte(secret)
+	}
+	return config, nil
+}
+
+// OIDCLogin is a login started with an identity provider
+type OIDCLogin struct {
+	// URL is the provider's authorization URL to redirect the browser to
+	URL string
+	// State must be kept in the browser, e.g. in a cookie, and passed to
+	// Complete with the callback
+	State string
+}
+
+// oidcState is what the browser keeps between the start of a login and the
+// callback
+type oidcState struct {
+	Provider  string `json:"provider"`
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// OIDCService logs users in with external OpenID Connect providers using the
+// authorization code flow with PKCE. Provider accounts are linked to users
+// through user_identities; a provider account is only ever linked to an
+// existing user by that user while logged in, never by matching email
+// addresses.
+type OIDCService struct {
+	providers  map[string]*OIDCProvider
+	identities repository.IdentityRepository
+	users      repository.UserRepository
+	config     OIDCConfig
+	now        func() time.Time
+}
+
+// NewOIDCService creates a new OIDCService for the configured providers
+func NewOIDCService(identities repository.IdentityRepository, users repository.UserRepository, config *OIDCConfig) (*OIDCService, error) {
+	if len(config.StateSecret) == 0 {
+		return nil, errors.New("OIDC state secret is required")
+	}
+
+	providers := make(map[string]*OIDCProvider, len(config.Providers))
+	for _, provider := range config.Providers {
+		providers[provider.Name] = NewOIDCProvider(provider)
+	}
+	return &OIDCService{providers: providers, identities: identities, users: users, config: *config, now: time.Now}, nil
+}
+
+// Providers returns the names of the configured providers
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Identities returns the provider accounts linked to a user
+func (s *OIDCService) Identities(ctx context.Context, userID int) ([]*models.UserIdentity, error) {
+	return s.identities.ListByUser(ctx, userID)
+}
+
+// StateTTL returns how long a started login stays valid
+func (s *OIDCService) StateTTL() time.Duration {
+	return s.config.StateTTL
+}
+
+// Begin starts a login with provider
+func (s *OIDCService) Begin(ctx context.Context, provider string) (*OIDCLogin, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	state := oidcState{Provider: provider, ExpiresAt: s.now().Add(s.config.StateTTL).Unix()}
+	for _, value := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		token, err := newRandomToken()
+		if err != nil {
+			return nil, err
+		}
+		*value = token
+	}
+
+	url, err := p.AuthorizationURL(ctx, state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.seal(state)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLogin{URL: url, State: sealed}, nil
+}
+
+// Complete finishes a login from the provider's callback and returns the
+// user, and whether the user was created by this login. sealedState is the
+// OIDCLogin.State kept by the browser; state and code are the callback's
+// query parameters. If current is not nil, the provider account is linked
+// to that logged in user.
+func (s *OIDCService) Complete(ctx context.Context, provider, sealedState, state, code string, current *models.User) (*models.User, bool, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, false, ErrUnknownOIDCProvider
+	}
+
+	saved, ok := s.open(sealedState)
+	if !ok || saved.Provider != provider || !s.now().Before(time.Unix(saved.ExpiresAt, 0)) ||
+		state == "" || subtle.ConstantTimeCompare([]byte(saved.State), []byte(state)) != 1 {
+		return nil, false, ErrInvalidOIDCState
+	}
+
+	claims, err := p.Exchange(ctx, code, saved.Verifier, saved.Nonce)
+	if err != nil {
+		return nil, false, err
+	}
+
+	identity, err := s.identities.Get(ctx, provider, claims.Subject)
+	switch {
+	case err == nil:
+		if current != nil && current.ID != identity.UserID {
+			return nil, false, ErrIdentityLinkedToOtherUser
+		}
+		user, err := s.users.GetByID(ctx, identity.UserID)
+		return user, false, err
+	case !errors.Is(err, repository.ErrIdentityNotFound):
+		return nil, false, err
+	case current != nil:
+		if err := s.link(ctx, current.ID, provider, claims); err != nil {
+			return nil, false, err
+		}
+		user, err := s.users.GetByID(ctx, current.ID)
+		return user, false, err
+	}
+
+	return s.register(ctx, provider, claims)
+}
+
+// register creates a user for a provider account that is not linked yet
+func (s *OIDCService) register(ctx context.Context, provider string, claims *IDTokenClaims) (*models.User, bool, error) {
+	email := normalizeEmail(claims.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email || len(email) > 100 {
+		return nil, false, ErrOIDCNoEmail
+	}
+
+	_, err := s.users.GetByEmail(ctx, email)
+	if err == nil {
+		return nil, false, ErrOIDCEmailTaken
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, false, err
+	}
+
+	// The user has no password; they can set one with a password reset
+	user := &models.User{Email: email}
+	base := oidcUsername(claims)
+	for attempt := 0; ; attempt++ {
+		user.Username = base
+		if attempt > 0 {
+			suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+			if err != nil {
+				return nil, false, err
+			}
+			user.Username = fmt.Sprintf("%s_%04d", truncate(base, 45), suffix.Int64())
+		}
+
+		err = s.users.Create(ctx, user)
+		if !errors.Is(err, repository.ErrDuplicateUsername) || attempt == 4 {
+			break
+		}
+	}
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		// Another callback for the same account may have won the race
+		if identity, getErr := s.identities.Get(ctx, provider, claims.Subject); getErr == nil {
+			user, err := s.users.GetByID(ctx, identity.UserID)
+			return user, false, err
+		}
+		return nil, false, ErrOIDCEmailTaken
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if claims.EmailVerified {
+		now := s.now()
+		if err := s.users.MarkEmailVerified(ctx, user.ID, now); err != nil {
+			return nil, false, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := s.link(ctx, user.ID, provider, claims); err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// link stores the identity of a provider account for userID
+func (s *OIDCService) link(ctx context.Context, userID int, provider string, claims *IDTokenClaims) error {
+	err := s.identities.Create(ctx, &models.UserIdentity{
+		UserID:    userID,
+		Provider:  provider,
+		Subject:   claims.Subject,
+		Email:     truncate(normalizeEmail(claims.Email), 100),
+		CreatedAt: s.now(),
+	})
+	if errors.Is(err, repository.ErrDuplicateIdentity) {
+		return ErrIdentityLinkedToOtherUser
+	}
+	return err
+}
+
+// seal encodes and signs state for the browser
+func (s *OIDCService) seal(state oidcState) (string, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.stateSignature(encoded), nil
+}
+
+// open verifies and decodes a sealed state
+func (s *OIDCService) open(sealed string) (*oidcState, bool) {
+	encoded, signature, found := strings.Cut(sealed, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(s.stateSignature(encoded))) {
+		return nil, false
+	}
+
+	var state oidcState
+	if err := decodeSegment(encoded, &state); err != nil {
+		return nil, false
+	}
+	return &state, true
+}
+
+// stateSignature returns the signature of an encoded state
+func (s *OIDCService) stateSignature(encoded string) string {
+	mac := hmac.New(sha256.New, s.config.StateSecret)
+	mac.Write([]byte("oidc-state\x00" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// oidcUsername derives a valid username from the provider's claims
+func oidcUsername(claims *IDTokenClaims) string {
+	candidate := claims.PreferredUsername
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	username := strings.Trim(invalidUsernameChars.ReplaceAllString(candidate, "_"), "_")
+	if len(username) < 3 {
+		username = "user_" + username
+	}
+	return truncate(username, 50)
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
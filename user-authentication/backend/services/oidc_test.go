@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/models"
+	"user-authentication/oidctest"
+	"user-authentication/repository"
+)
+
+// memoryUserRepository is an in-memory UserRepository for tests that create users
+type memoryUserRepository struct {
+	users map[int]*models.User
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	for _, u := range r.users {
+		if u.Email == user.Email {
+			return repository.ErrDuplicateEmail
+		}
+		if u.Username == user.Username {
+			return repository.ErrDuplicateUsername
+		}
+	}
+	user.ID = len(r.users) + 1
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	if user, ok := r.users[id]; ok {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *memoryUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Username == username {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	user.PasswordHash = passwordHash
+	return nil
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error {
+	if user, ok := r.users[id]; ok && user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	return nil
+}
+
+// memoryIdentityRepository is an in-memory IdentityRepository for tests
+type memoryIdentityRepository struct {
+	identities []*models.UserIdentity
+}
+
+func (r *memoryIdentityRepository) Get(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrIdentityNotFound
+}
+
+func (r *memoryIdentityRepository) ListByUser(ctx context.Context, userID int) ([]*models.UserIdentity, error) {
+	var identities []*models.UserIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *memoryIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	if _, err := r.Get(ctx, identity.Provider, identity.Subject); err == nil {
+		return repository.ErrDuplicateIdentity
+	}
+	identity.ID = len(r.identities) + 1
+	stored := *identity
+	r.identities = append(r.identities, &stored)
+	return nil
+}
+
+// oidcFixture is an OIDCService with a fake provider named "mock"
+type oidcFixture struct {
+	service    *OIDCService
+	provider   *oidctest.Provider
+	users      *memoryUserRepository
+	identities *memoryIdentityRepository
+	now        *time.Time
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	provider, server, err := oidctest.NewServer("client", "client-secret", oidctest.User{
+		Subject:           "mock-alice",
+		Email:             "Alice@Example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice.smith",
+	})
+	if err != nil {
+		t.Fatalf("Failed to start fake provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	f := &oidcFixture{
+		provider:   provider,
+		users:      &memoryUserRepository{users: make(map[int]*models.User)},
+		identities: &memoryIdentityRepository{},
+	}
+	f.service, err = NewOIDCService(f.identities, f.users, &OIDCConfig{
+		Providers: []OIDCProviderConfig{{
+			Name:         "mock",
+			Issuer:       provider.Issuer,
+			ClientID:     "client",
+			ClientSecret: "client-secret",
+			RedirectURL:  "http://localhost:8080/api/auth/oidc/mock/callback",
+			Scopes:       []string{"openid", "email", "profile"},
+		}},
+		StateSecret: []byte(strings.Repeat("s", 32)),
+		StateTTL:    10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCService failed: %v", err)
+	}
+
+	now := time.Now()
+	f.now = &now
+	f.service.now = func() time.Time { return now }
+	return f
+}
+
+// begin starts a login and returns it with the state and code of the
+// provider's callback
+func (f *oidcFixture) begin(t *testing.T) (*OIDCLogin, string, string) {
+	t.Helper()
+	login, err := f.service.Begin(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	callback, err := f.provider.Approve(login.URL)
+	if err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	parsed, err := url.Parse(callback)
+	if err != nil {
+		t.Fatalf("Invalid callback URL %q: %v", callback, err)
+	}
+	return login, parsed.Query().Get("state"), parsed.Query().Get("code")
+}
+
+// login runs a whole login as current
+func (f *oidcFixture) login(t *testing.T, current *models.User) (*models.User, bool, error) {
+	t.Helper()
+	login, state, code := f.begin(t)
+	return f.service.Complete(context.Background(), "mock", login.State, state, code, current)
+}
+
+func TestLoadOIDCConfig(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS", "google, mock")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "google-client")
+	t.Setenv("OIDC_MOCK_ISSUER", "http://localhost:9000")
+	t.Setenv("OIDC_MOCK_CLIENT_ID", "local-client")
+	t.Setenv("OIDC_MOCK_SCOPES", "openid email")
+	t.Setenv("OIDC_REDIRECT_BASE_URL", "https://example.com/api/auth/oidc/")
+	t.Setenv("OIDC_STATE_SECRET", "")
+
+	config, err := LoadOIDCConfig()
+	if err != nil {
+		t.Fatalf("LoadOIDCConfig failed: %v", err)
+	}
+	if len(config.Providers) != 2 || config.StateSecret != nil {
+		t.Fatalf("Expected 2 providers and no secret, got %+v", config)
+	}
+	google := config.Providers[0]
+	if google.Name != "google" || google.RedirectURL != "https://example.com/api/auth/oidc/google/callback" {
+		t.Errorf("Unexpected google provider %+v", google)
+	}
+	if strings.Join(google.Scopes, " ") != "openid email profile" {
+		t.Errorf("Expected default scopes, got %v", google.Scopes)
+	}
+	if strings.Join(config.Providers[1].Scopes, " ") != "openid email" {
+		t.Errorf("Expected configured scopes, got %v", config.Providers[1].Scopes)
+	}
+
+	t.Setenv("OIDC_MOCK_CLIENT_ID", "")
+	if _, err := LoadOIDCConfig(); err == nil {
+		t.Error("Expected error for a provider without client ID")
+	}
+
+	t.Setenv("OIDC_PROVIDERS", "Bad-Name")
+	if _, err := LoadOIDCConfig(); err == nil {
+		t.Error("Expected error for an invalid provider name")
+	}
+}
+
+func TestOIDCService_RegisterAndLogin(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	if _, err := f.service.Begin(context.Background(), "unknown"); !errors.Is(err, ErrUnknownOIDCProvider) {
+		t.Errorf("Expected ErrUnknownOIDCProvider, got %v", err)
+	}
+
+	login, _, _ := f.begin(t)
+	for _, param := range []string{"code_challenge_method=S256", "code_challenge=", "nonce=", "state="} {
+		if !strings.Contains(login.URL, param) {
+			t.Errorf("Expected authorization URL to contain %s, got %s", param, login.URL)
+		}
+	}
+
+	user, created, err := f.login(t, nil)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if !created || user.Email != "alice@example.com" || user.Username != "alice_smith" {
+		t.Errorf("Expected new user alice_smith with a normalized email, got %+v (created %v)", user, created)
+	}
+	if !user.EmailVerified() {
+		t.Error("Expected the provider's verified email to be marked verified")
+	}
+	if user.PasswordHash != "" {
+		t.Error("Expected a user without password")
+	}
+
+	again, created, err := f.login(t, nil)
+	if err != nil || created || again.ID != user.ID {
+		t.Errorf("Expected the same user on the next login, got %+v (created %v, err %v)", again, created, err)
+	}
+	if len(f.identities.identities) != 1 {
+		t.Errorf("Expected 1 identity, got %d", len(f.identities.identities))
+	}
+}
+
+func TestOIDCService_InvalidState(t *testing.T) {
+	f := newOIDCFixture(t)
+	ctx := context.Background()
+
+	login, state, code := f.begin(t)
+	tests := map[string]struct{ provider, sealed, state string }{
+		"wrong state":    {"mock", login.State, "other"},
+		"empty state":    {"mock", login.State, ""},
+		"tampered seal":  {"mock", login.State + "x", state},
+		"missing cookie": {"mock", "", state},
+	}
+	for name, tt := range tests {
+		if _, _, err := f.service.Complete(ctx, tt.provider, tt.sealed, tt.state, code, nil); !errors.Is(err, ErrInvalidOIDCState) {
+			t.Errorf("%s: expected ErrInvalidOIDCState, got %v", name, err)
+		}
+	}
+
+	*f.now = f.now.Add(10 * time.Minute)
+	if _, _, err := f.service.Complete(ctx, "mock", login.State, state, code, nil); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("Expected ErrInvalidOIDCState after expiry, got %v", err)
+	}
+	*f.now = f.now.Add(-10 * time.Minute)
+
+	if _, _, err := f.service.Complete(ctx, "mock", login.State, state, code, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	// The provider accepts a code only once
+	if _, _, err := f.service.Complete(ctx, "mock", login.State, state, code, nil); err == nil {
+		t.Error("Expected a used code to be rejected")
+	}
+}
+
+func TestOIDCService_Linking(t *testing.T) {
+	f := newOIDCFixture(t)
+	existing := &models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+	if err := f.users.Create(context.Background(), existing); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// An email match alone never links accounts
+	if _, _, err := f.login(t, nil); !errors.Is(err, ErrOIDCEmailTaken) {
+		t.Errorf("Expected ErrOIDCEmailTaken, got %v", err)
+	}
+
+	user, created, err := f.login(t, existing)
+	if err != nil || created || user.ID != existing.ID {
+		t.Fatalf("Expected the identity to be linked to alice, got %+v (created %v, err %v)", user, created, err)
+	}
+
+	user, _, err = f.login(t, nil)
+	if err != nil || user.ID != existing.ID {
+		t.Errorf("Expected login as alice after linking, got %+v (err %v)", user, err)
+	}
+
+	other := &models.User{Username: "bob", Email: "bob@example.com"}
+	if err := f.users.Create(context.Background(), other); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, _, err := f.login(t, other); !errors.Is(err, ErrIdentityLinkedToOtherUser) {
+		t.Errorf("Expected ErrIdentityLinkedToOtherUser, got %v", err)
+	}
+}
+
+func TestOIDCService_NoEmail(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.provider.SetUser(oidctest.User{Subject: "no-email"})
+
+	if _, _, err := f.login(t, nil); !errors.Is(err, ErrOIDCNoEmail) {
+		t.Errorf("Expected ErrOIDCNoEmail, got %v", err)
+	}
+}
+
+func TestOIDCProvider_VerifyIDToken(t *testing.T) {
+	f := newOIDCFixture(t)
+	provider := f.service.providers["mock"]
+	ctx := context.Background()
+	user := oidctest.User{Subject: "mock-alice", Email: "alice@example.com"}
+
+	valid := f.provider.Claims(user, "nonce")
+	token, err := f.provider.SignIDToken(valid)
+	if err != nil {
+		t.Fatalf("SignIDToken failed: %v", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, token, "nonce")
+	if err != nil || claims.Subject != "mock-alice" {
+		t.Fatalf("Expected valid token for mock-alice, got %+v (err %v)", claims, err)
+	}
+
+	if _, err := provider.VerifyIDToken(ctx, token, "other-nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Expected ErrInvalidIDToken for a wrong nonce, got %v", err)
+	}
+	if _, err := provider.VerifyIDToken(ctx, token[:len(token)-4]+"AAAA", "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Expected ErrInvalidIDToken for a bad signature, got %v", err)
+	}
+
+	tests := map[string]func(claims map[string]interface{}){
+		"wrong issuer":   func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" },
+		"wrong audience": func(c map[string]interface{}) { c["aud"] = "other-client" },
+		"expired":        func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() },
+		"future iat":     func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+		"no subject":     func(c map[string]interface{}) { c["sub"] = "" },
+		"azp mismatch": func(c map[string]interface{}) {
+			c["aud"] = []string{"client", "other-client"}
+			c["azp"] = "other-client"
+		},
+	}
+	for name, modify := range tests {
+		claims := f.provider.Claims(user, "nonce")
+		modify(claims)
+		token, err := f.provider.SignIDToken(claims)
+		if err != nil {
+			t.Fatalf("SignIDToken failed: %v", err)
+		}
+		if _, err := provider.VerifyIDToken(ctx, token, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("%s: expected ErrInvalidIDToken, got %v", name, err)
+		}
+	}
+
+	// Several audiences are fine when this client is the authorized party
+	multi := f.provider.Claims(user, "nonce")
+	multi["aud"] = []string{"client", "other-client"}
+	multi["azp"] = "client"
+	token, _ = f.provider.SignIDToken(multi)
+	if _, err := provider.VerifyIDToken(ctx, token, "nonce"); err != nil {
+		t.Errorf("Expected token with several audiences to be accepted, got %v", err)
+	}
+
+	// An unsigned token is never accepted
+	parts := strings.Split(token, ".")
+	unsigned := "eyJhbGciOiJub25lIn0." + parts[1] + "."
+	if _, err := provider.VerifyIDToken(ctx, unsigned, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Expected ErrInvalidIDToken for alg none, got %v", err)
+	}
+}
+
+func TestOIDCUsername(t *testing.T) {
+	tests := []struct {
+		claims   IDTokenClaims
+		expected string
+	}{
+		{IDTokenClaims{PreferredUsername: "alice"}, "alice"},
+		{IDTokenClaims{PreferredUsername: "Alice Smith!"}, "Alice_Smith"},
+		{IDTokenClaims{Email: "bob.jones@example.com"}, "bob_jones"},
+		{IDTokenClaims{Email: "x@example.com"}, "user_x"},
+		{IDTokenClaims{PreferredUsername: strings.Repeat("a", 60)}, strings.Repeat("a", 50)},
+	}
+
+	for _, tt := range tests {
+		if got := oidcUsername(&tt.claims); got != tt.expected {
+			t.Errorf("Expected username '%s' for %+v, got '%s'", tt.expected, tt.claims, got)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownHashFormat is returned when a stored hash was not produced by a
+// supported algorithm
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHasher hashes passwords for storage in users.password_hash
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+}
+
+// PasswordConfig selects and tunes the password hashing algorithm
+type PasswordConfig struct {
+	Algorithm     string // "bcrypt" or "argon2id"
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32 // KiB
+	Argon2Threads uint8
+}
+
+// GetDefaultPasswordConfig returns the password configuration from the
+// environment: PASSWORD_HASH_ALGORITHM, BCRYPT_COST, ARGON2_TIME,
+// ARGON2_MEMORY_KIB and ARGON2_THREADS
+func GetDefaultPasswordConfig() *PasswordConfig {
+	return &PasswordConfig{
+		Algorithm:     getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+		BcryptCost:    getEnvInt("BCRYPT_COST", bcrypt.DefaultCost),
+		Argon2Time:    uint32(getEnvInt("ARGON2_TIME", 3)),
+		Argon2Memory:  uint32(getEnvInt("ARGON2_MEMORY_KIB", 64*1024)),
+		Argon2Threads: uint8(getEnvInt("ARGON2_THREADS", 2)),
+	}
+}
+
+// NewPasswordHasher returns the hasher selected by config
+func NewPasswordHasher(config *PasswordConfig) (PasswordHasher, error) {
+	switch config.Algorithm {
+	case "bcrypt":
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, config.BcryptCost)
+		}
+		return &BcryptHasher{Cost: config.BcryptCost}, nil
+	case "argon2id":
+		if config.Argon2Time == 0 || config.Argon2Memory == 0 || config.Argon2Threads == 0 {
+			return nil, errors.New("argon2id time, memory and threads must be positive")
+		}
+		return &Argon2idHasher{Time: config.Argon2Time, Memory: config.Argon2Memory, Threads: config.Argon2Threads}, nil
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", config.Algorithm)
+	}
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash returns the bcrypt hash of password. bcrypt only looks at the first
+// 72 bytes, so longer passwords are rejected instead of silently truncated.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Argon2idHasher hashes passwords with argon2id and encodes them in the PHC
+// string format: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Hash returns the encoded argon2id hash of password
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// VerifyPassword reports whether password matches hash. It accepts hashes from
+// either supported algorithm, so changing PASSWORD_HASH_ALGORITHM does not
+// lock out existing users.
+func VerifyPassword(hash, password string) (bool, error) {
+	switch {
+	case hash == "":
+		// Users created by logging in with an identity provider have no password
+		return false, nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	default:
+		return false, ErrUnknownHashFormat
+	}
+}
+
+func verifyArgon2id(encoded, password string) (bool, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrUnknownHashFormat
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, ErrUnknownHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrUnknownHashFormat
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, ErrUnknownHashFormat
+	}
+
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
\ No newline at end of file
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// ErrInvalidResetToken is returned for unknown, expired or already used password reset tokens
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// PasswordResetConfig controls password reset tokens
+type PasswordResetConfig struct {
+	// TTL is how long an emailed token can be used
+	TTL time.Duration
+	// URL is the frontend page that asks for the new password. The token is
+	// added as the "token" query parameter.
+	URL string
+}
+
+// GetDefaultPasswordResetConfig returns the password reset configuration
+// from the environment: PASSWORD_RESET_TTL and PASSWORD_RESET_URL
+func GetDefaultPasswordResetConfig() *PasswordResetConfig {
+	return &PasswordResetConfig{
+		TTL: getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		URL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+	}
+}
+
+// PasswordResetService emails single-use password reset links and redeems them
+type PasswordResetService struct {
+	resets   repository.PasswordResetRepository
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	hasher   PasswordHasher
+	mailer   Mailer
+	config   PasswordResetConfig
+	now      func() time.Time
+}
+
+// NewPasswordResetService creates a new PasswordResetService. sessions is used
+// to log the user out everywhere once the password has been changed.
+func NewPasswordResetService(resets repository.PasswordResetRepository, users repository.UserRepository, sessions repository.SessionRepository, hasher PasswordHasher, mailer Mailer, config *PasswordResetConfig) *PasswordResetService {
+	return &PasswordResetService{
+		resets:   resets,
+		users:    users,
+		sessions: sessions,
+		hasher:   hasher,
+		mailer:   mailer,
+		config:   *config,
+		now:      time.Now,
+	}
+}
+
+// RequestReset emails a reset link to the user with email. Unknown emails are
+// silently ignored so that the endpoint does not reveal who is registered.
+// Earlier links of the user stop working.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	if err := s.resets.DeleteByUser(ctx, user.ID); err != nil {
+		return err
+	}
+	now := s.now()
+	reset := &models.PasswordReset{
+		ID:        hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.TTL),
+	}
+	if err := s.resets.Create(ctx, reset); err != nil {
+		return err
+	}
+
+	link, err := s.link(token)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, &Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new password. It can be used once and expires in %s.\n\n%s\n\nIf you did not ask to reset your password, you can ignore this email.\n",
+			user.Username, s.config.TTL, link),
+	})
+}
+
+// Reset sets a new password for the user that token was sent to and logs
+// them out everywhere. It returns ErrInvalidResetToken when the token is
+// unknown, expired or already used. The password must already be validated.
+func (s *PasswordResetService) Reset(ctx context.Context, token, password string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+
+	reset, err := s.resets.Get(ctx, hashToken(token))
+	if errors.Is(err, repository.ErrPasswordResetNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	// Hash before redeeming so that a hashing failure does not burn the token
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.resets.MarkUsed(ctx, reset.ID, now)
+	if errors.Is(err, repository.ErrPasswordResetNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.users.UpdatePassword(ctx, reset.UserID, hash)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// Whoever knew the old password must not stay logged in
+	return s.sessions.DeleteByUser(ctx, reset.UserID)
+}
+
+// PurgeExpired deletes expired reset tokens and returns how many
+func (s *PasswordResetService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.resets.DeleteExpired(ctx, s.now())
+}
+
+// link returns the reset page URL for token
+func (s *PasswordResetService) link(token string) (string, error) {
+	u, err := url.Parse(s.config.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid PASSWORD_RESET_URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
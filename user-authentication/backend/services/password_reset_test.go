@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryPasswordResetRepository is an in-memory PasswordResetRepository for tests
+type memoryPasswordResetRepository struct {
+	resets map[string]*models.PasswordReset
+}
+
+func (r *memoryPasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	stored := *reset
+	r.resets[reset.ID] = &stored
+	return nil
+}
+
+func (r *memoryPasswordResetRepository) Get(ctx context.Context, id string) (*models.PasswordReset, error) {
+	if stored, ok := r.resets[id]; ok {
+		reset := *stored
+		return &reset, nil
+	}
+	return nil, repository.ErrPasswordResetNotFound
+}
+
+func (r *memoryPasswordResetRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	stored, ok := r.resets[id]
+	if !ok || stored.UsedAt != nil {
+		return repository.ErrPasswordResetNotFound
+	}
+	stored.UsedAt = &usedAt
+	return nil
+}
+
+func (r *memoryPasswordResetRepository) DeleteByUser(ctx context.Context, userID int) error {
+	for id, reset := range r.resets {
+		if reset.UserID == userID {
+			delete(r.resets, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryPasswordResetRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	for id, reset := range r.resets {
+		if reset.ExpiresAt.Before(now) {
+			delete(r.resets, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// captureMailer records sent messages
+type captureMailer struct {
+	messages []*Message
+}
+
+func (m *captureMailer) Send(ctx context.Context, msg *Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// linkToken extracts the token query parameter from the link in msg
+func linkToken(t *testing.T, msg *Message) string {
+	t.Helper()
+	start := strings.Index(msg.Body, "http://")
+	if start < 0 {
+		t.Fatalf("Expected a link in the message, got %q", msg.Body)
+	}
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	if err != nil {
+		t.Fatalf("Invalid link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+type passwordResetFixture struct {
+	service  *PasswordResetService
+	resets   *memoryPasswordResetRepository
+	sessions *memorySessionRepository
+	users    *singleUserRepository
+	mailer   *captureMailer
+	now      *time.Time
+}
+
+func newPasswordResetFixture(t *testing.T) *passwordResetFixture {
+	t.Helper()
+	hasher, err := NewPasswordHasher(&PasswordConfig{Algorithm: "bcrypt", BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher failed: %v", err)
+	}
+
+	f := &passwordResetFixture{
+		resets:   &memoryPasswordResetRepository{resets: make(map[string]*models.PasswordReset)},
+		sessions: &memorySessionRepository{sessions: make(map[string]*models.Session)},
+		users:    &singleUserRepository{user: &models.User{ID: 1, Username: "alice", Email: "alice@example.com", PasswordHash: "old"}},
+		mailer:   &captureMailer{},
+	}
+	f.service = NewPasswordResetService(f.resets, f.users, f.sessions, hasher, f.mailer, &PasswordResetConfig{
+		TTL: time.Hour,
+		URL: "http://localhost:3000/reset-password",
+	})
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	f.now = &now
+	f.service.now = func() time.Time { return *f.now }
+	return f
+}
+
+func TestPasswordResetService_Reset(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	ctx := context.Background()
+	f.sessions.sessions["existing"] = &models.Session{ID: "existing", UserID: 1, ExpiresAt: f.now.Add(time.Hour)}
+
+	if err := f.service.RequestReset(ctx, " Alice@Example.com "); err != nil {
+		t.Fatalf("RequestReset failed: %v", err)
+	}
+	if len(f.mailer.messages) != 1 || f.mailer.messages[0].To != "alice@example.com" {
+		t.Fatalf("Expected 1 message to alice@example.com, got %+v", f.mailer.messages)
+	}
+	token := linkToken(t, f.mailer.messages[0])
+	if _, ok := f.resets.resets[token]; ok {
+		t.Error("Expected the stored ID to be a hash, not the token")
+	}
+
+	if err := f.service.Reset(ctx, token, "new password 123"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if ok, _ := VerifyPassword(f.users.user.PasswordHash, "new password 123"); !ok {
+		t.Error("Expected the password to be changed")
+	}
+	if len(f.sessions.sessions) != 0 {
+		t.Errorf("Expected all sessions to be revoked, got %d", len(f.sessions.sessions))
+	}
+
+	// The token is single use
+	if err := f.service.Reset(ctx, token, "another password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("Expected ErrInvalidResetToken for a used token, got %v", err)
+	}
+}
+
+func TestPasswordResetService_ExpiredToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	ctx := context.Background()
+
+	if err := f.service.RequestReset(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("RequestReset failed: %v", err)
+	}
+	token := linkToken(t, f.mailer.messages[0])
+
+	*f.now = f.now.Add(time.Hour)
+	if err := f.service.Reset(ctx, token, "new password 123"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("Expected ErrInvalidResetToken for an expired token, got %v", err)
+	}
+	if f.users.user.PasswordHash != "old" {
+		t.Error("Expected the password to be unchanged")
+	}
+
+	deleted, err := f.service.PurgeExpired(ctx)
+	if err != nil || deleted != 0 {
+		t.Errorf("Expected nothing purged at the expiry instant, got %d (err: %v)", deleted, err)
+	}
+	*f.now = f.now.Add(time.Second)
+	if deleted, _ := f.service.PurgeExpired(ctx); deleted != 1 {
+		t.Errorf("Expected 1 purged token, got %d", deleted)
+	}
+}
+
+func TestPasswordResetService_NewRequestReplacesOldToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := f.service.RequestReset(ctx, "alice@example.com"); err != nil {
+			t.Fatalf("RequestReset failed: %v", err)
+		}
+	}
+	first := linkToken(t, f.mailer.messages[0])
+	second := linkToken(t, f.mailer.messages[1])
+
+	if err := f.service.Reset(ctx, first, "new password 123"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("Expected the first token to be invalidated, got %v", err)
+	}
+	if err := f.service.Reset(ctx, second, "new password 123"); err != nil {
+		t.Errorf("Expected the latest token to work, got %v", err)
+	}
+}
+
+func TestPasswordResetService_UnknownEmail(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if err := f.service.RequestReset(context.Background(), "nobody@example.com"); err != nil {
+		t.Errorf("Expected no error for an unknown email, got %v", err)
+	}
+	if len(f.mailer.messages) != 0 || len(f.resets.resets) != 0 {
+		t.Error("Expected no token and no email for an unknown email")
+	}
+
+	if err := f.service.Reset(context.Background(), "bogus", "new password 123"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("Expected ErrInvalidResetToken for an unknown token, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGetDefaultPasswordConfig(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "")
+	t.Setenv("BCRYPT_COST", "")
+
+	config := GetDefaultPasswordConfig()
+	if config.Algorithm != "bcrypt" {
+		t.Errorf("Expected algorithm 'bcrypt', got '%s'", config.Algorithm)
+	}
+	if config.BcryptCost != bcrypt.DefaultCost {
+		t.Errorf("Expected bcrypt cost %d, got %d", bcrypt.DefaultCost, config.BcryptCost)
+	}
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "argon2id")
+	t.Setenv("BCRYPT_COST", "12")
+	config = GetDefaultPasswordConfig()
+	if config.Algorithm != "argon2id" || config.BcryptCost != 12 {
+		t.Errorf("Expected env overrides, got %+v", config)
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	if _, err := NewPasswordHasher(&PasswordConfig{Algorithm: "bcrypt", BcryptCost: 2}); err == nil {
+		t.Error("Expected error for bcrypt cost below the minimum")
+	}
+	if _, err := NewPasswordHasher(&PasswordConfig{Algorithm: "md5"}); err == nil {
+		t.Error("Expected error for unsupported algorithm")
+	}
+}
+
+func TestPasswordHashers(t *testing.T) {
+	configs := []*PasswordConfig{
+		{Algorithm: "bcrypt", BcryptCost: bcrypt.MinCost},
+		{Algorithm: "argon2id", Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1},
+	}
+
+	for _, config := range configs {
+		hasher, err := NewPasswordHasher(config)
+		if err != nil {
+			t.Fatalf("%s: NewPasswordHasher failed: %v", config.Algorithm, err)
+		}
+
+		hash, err := hasher.Hash("correct horse battery staple")
+		if err != nil {
+			t.Fatalf("%s: Hash failed: %v", config.Algorithm, err)
+		}
+		if strings.Contains(hash, "correct horse") {
+			t.Errorf("%s: hash contains the password", config.Algorithm)
+		}
+
+		ok, err := VerifyPassword(hash, "correct horse battery staple")
+		if err != nil || !ok {
+			t.Errorf("%s: expected password to verify, got %v (err: %v)", config.Algorithm, ok, err)
+		}
+
+		ok, err = VerifyPassword(hash, "wrong password")
+		if err != nil || ok {
+			t.Errorf("%s: expected wrong password to fail, got %v (err: %v)", config.Algorithm, ok, err)
+		}
+	}
+
+	if _, err := VerifyPassword("plaintext", "plaintext"); err != ErrUnknownHashFormat {
+		t.Errorf("Expected ErrUnknownHashFormat, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// ErrInvalidSession is returned for unknown, expired or revoked session tokens
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// tokenBytes is the amount of randomness in session and other opaque tokens
+const tokenBytes = 32
+
+// SessionConfig controls how long sessions live
+type SessionConfig struct {
+	// TTL is the idle timeout; every request pushes the expiry TTL into the future
+	TTL time.Duration
+	// MaxLifetime caps how long a session can be kept alive by activity; zero means no cap
+	MaxLifetime time.Duration
+	// TouchInterval is the minimum time between expiry updates, to avoid a
+	// database write on every request
+	TouchInterval time.Duration
+}
+
+// GetDefaultSessionConfig returns the session configuration from the
+// environment: SESSION_TTL, SESSION_MAX_LIFETIME and SESSION_TOUCH_INTERVAL
+func GetDefaultSessionConfig() *SessionConfig {
+	return &SessionConfig{
+		TTL:           getEnvDuration("SESSION_TTL", 24*time.Hour),
+		MaxLifetime:   getEnvDuration("SESSION_MAX_LIFETIME", 30*24*time.Hour),
+		TouchInterval: getEnvDuration("SESSION_TOUCH_INTERVAL", time.Minute),
+	}
+}
+
+// SessionService creates, validates and revokes server-side login sessions
+type SessionService struct {
+	sessions repository.SessionRepository
+	users    repository.UserRepository
+	config   SessionConfig
+	now      func() time.Time
+}
+
+// NewSessionService creates a new SessionService
+func NewSessionService(sessions repository.SessionRepository, users repository.UserRepository, config *SessionConfig) *SessionService {
+	return &SessionService{sessions: sessions, users: users, config: *config, now: time.Now}
+}
+
+// Create starts a session for userID and returns the token to hand to the
+// client. Only the token's hash is stored.
+func (s *SessionService) Create(ctx context.Context, userID int) (string, *models.Session, error) {
+	token, err := newRandomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	now := s.now()
+	session := &models.Session{
+		ID:         HashSessionToken(token),
+		UserID:     userID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  s.expiry(now, now),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// Authenticate returns the user and session for token and slides the
+// session's expiry forward. It returns ErrInvalidSession when the token does
+// not belong to a live session.
+func (s *SessionService) Authenticate(ctx context.Context, token string) (*models.User, *models.Session, error) {
+	if token == "" {
+		return nil, nil, ErrInvalidSession
+	}
+
+	session, err := s.sessions.Get(ctx, HashSessionToken(token))
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now()
+	if !now.Before(session.ExpiresAt) {
+		if _, err := s.sessions.Delete(ctx, session.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidSession
+	}
+
+	user, err := s.users.GetByID(ctx, session.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if now.Sub(session.LastSeenAt) >= s.config.TouchInterval {
+		expiresAt := s.expiry(session.CreatedAt, now)
+		if err := s.sessions.Touch(ctx, session.ID, now, expiresAt); err != nil {
+			return nil, nil, err
+		}
+		session.LastSeenAt = now
+		session.ExpiresAt = expiresAt
+	}
+
+	return user, session, nil
+}
+
+// Revoke ends the session for token and reports whether it was still live.
+// Unknown tokens are ignored.
+func (s *SessionService) Revoke(ctx context.Context, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	return s.sessions.Delete(ctx, HashSessionToken(token))
+}
+
+// PurgeExpired deletes sessions that have expired and returns how many
+func (s *SessionService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.sessions.DeleteExpired(ctx, s.now())
+}
+
+// expiry returns when a session created at createdAt and last used at now
+// expires: TTL after now, but no later than MaxLifetime after createdAt
+func (s *SessionService) expiry(createdAt, now time.Time) time.Time {
+	expiresAt := now.Add(s.config.TTL)
+	if s.config.MaxLifetime > 0 {
+		if limit := createdAt.Add(s.config.MaxLifetime); expiresAt.After(limit) {
+			expiresAt = limit
+		}
+	}
+	return expiresAt
+}
+
+// HashSessionToken returns the session ID stored for token
+func HashSessionToken(token string) string {
+	return hashToken(token)
+}
+
+// newRandomToken returns an unguessable URL-safe token
+func newRandomToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the SHA-256 of an opaque token, which is what gets
+// stored so that a leaked table cannot be used to present the token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
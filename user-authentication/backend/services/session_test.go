@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// memorySessionRepository is an in-memory SessionRepository for tests
+type memorySessionRepository struct {
+	sessions map[string]*models.Session
+	touches  int
+}
+
+func (r *memorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *memorySessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	if s, ok := r.sessions[id]; ok {
+		session := *s
+		return &session, nil
+	}
+	return nil, repository.ErrSessionNotFound
+}
+
+func (r *memorySessionRepository) Touch(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error {
+	r.touches++
+	if s, ok := r.sessions[id]; ok {
+		s.LastSeenAt = lastSeenAt
+		s.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) Delete(ctx context.Context, id string) (bool, error) {
+	_, ok := r.sessions[id]
+	delete(r.sessions, id)
+	return ok, nil
+}
+
+func (r *memorySessionRepository) DeleteByUser(ctx context.Context, userID int) error {
+	for id, s := range r.sessions {
+		if s.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	for id, s := range r.sessions {
+		if s.ExpiresAt.Before(now) {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// singleUserRepository knows exactly one user
+type singleUserRepository struct {
+	user *models.User
+}
+
+func (r *singleUserRepository) Create(ctx context.Context, user *models.User) error {
+	return errors.New("not supported")
+}
+
+func (r *singleUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	if r.user != nil && r.user.ID == id {
+		return r.user, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *singleUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if r.user != nil && r.user.Email == email {
+		return r.user, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *singleUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *singleUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	if r.user == nil || r.user.ID != id {
+		return repository.ErrUserNotFound
+	}
+	r.user.PasswordHash = passwordHash
+	return nil
+}
+
+func (r *singleUserRepository) MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error {
+	if r.user != nil && r.user.ID == id && r.user.EmailVerifiedAt == nil {
+		r.user.EmailVerifiedAt = &verifiedAt
+	}
+	return nil
+}
+
+// newTestSessionService returns a SessionService with a controllable clock
+func newTestSessionService(config SessionConfig) (*SessionService, *memorySessionRepository, *time.Time) {
+	repo := &memorySessionRepository{sessions: make(map[string]*models.Session)}
+	users := &singleUserRepository{user: &models.User{ID: 1, Username: "alice"}}
+	service := NewSessionService(repo, users, &config)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, repo, &now
+}
+
+func TestSessionService_CreateAndAuthenticate(t *testing.T) {
+	service, repo, _ := newTestSessionService(SessionConfig{TTL: time.Hour})
+	ctx := context.Background()
+
+	token, session, err := service.Create(ctx, 1)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if session.ID != HashSessionToken(token) || session.ID == token {
+		t.Error("Expected the session ID to be the hash of the token")
+	}
+	if _, ok := repo.sessions[session.ID]; !ok {
+		t.Error("Expected the session to be stored")
+	}
+
+	user, _, err := service.Authenticate(ctx, token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("Expected user alice, got %s", user.Username)
+	}
+
+	if _, _, err := service.Authenticate(ctx, "bogus"); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession for unknown token, got %v", err)
+	}
+}
+
+func TestSessionService_SlidingExpiry(t *testing.T) {
+	service, repo, now := newTestSessionService(SessionConfig{TTL: time.Hour, TouchInterval: time.Minute})
+	ctx := context.Background()
+
+	token, _, _ := service.Create(ctx, 1)
+
+	// Activity within the touch interval does not write to the database
+	*now = now.Add(30 * time.Second)
+	if _, _, err := service.Authenticate(ctx, token); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if repo.touches != 0 {
+		t.Errorf("Expected no expiry update, got %d", repo.touches)
+	}
+
+	// Activity after 50 minutes keeps the session alive past the original expiry
+	*now = now.Add(50 * time.Minute)
+	_, session, err := service.Authenticate(ctx, token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if expected := now.Add(time.Hour); !session.ExpiresAt.Equal(expected) {
+		t.Errorf("Expected expiry %v, got %v", expected, session.ExpiresAt)
+	}
+
+	*now = now.Add(59 * time.Minute)
+	if _, _, err := service.Authenticate(ctx, token); err != nil {
+		t.Errorf("Expected session to still be valid, got %v", err)
+	}
+
+	// Idle for longer than the TTL
+	*now = now.Add(2 * time.Hour)
+	if _, _, err := service.Authenticate(ctx, token); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession after the TTL, got %v", err)
+	}
+	if len(repo.sessions) != 0 {
+		t.Error("Expected the expired session to be deleted")
+	}
+}
+
+func TestSessionService_MaxLifetime(t *testing.T) {
+	service, _, now := newTestSessionService(SessionConfig{TTL: time.Hour, MaxLifetime: 90 * time.Minute})
+	ctx := context.Background()
+
+	token, session, _ := service.Create(ctx, 1)
+	limit := session.CreatedAt.Add(90 * time.Minute)
+
+	*now = now.Add(50 * time.Minute)
+	_, session, err := service.Authenticate(ctx, token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if !session.ExpiresAt.Equal(limit) {
+		t.Errorf("Expected expiry capped at %v, got %v", limit, session.ExpiresAt)
+	}
+
+	*now = limit
+	if _, _, err := service.Authenticate(ctx, token); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession after the max lifetime, got %v", err)
+	}
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+	service, repo, _ := newTestSessionService(SessionConfig{TTL: time.Hour})
+	ctx := context.Background()
+
+	token, _, _ := service.Create(ctx, 1)
+	if revoked, err := service.Revoke(ctx, token); err != nil || !revoked {
+		t.Fatalf("Expected Revoke to delete the session, got %v (err: %v)", revoked, err)
+	}
+	if len(repo.sessions) != 0 {
+		t.Error("Expected the session to be deleted")
+	}
+	if _, _, err := service.Authenticate(ctx, token); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession after revoke, got %v", err)
+	}
+
+	// Revoking again finds nothing to delete
+	if revoked, err := service.Revoke(ctx, token); err != nil || revoked {
+		t.Errorf("Expected a second Revoke to report nothing deleted, got %v (err: %v)", revoked, err)
+	}
+}
+
+func TestSessionService_DeletedUser(t *testing.T) {
+	service, _, _ := newTestSessionService(SessionConfig{TTL: time.Hour})
+	ctx := context.Background()
+
+	token, _, _ := service.Create(ctx, 2)
+	if _, _, err := service.Authenticate(ctx, token); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession for a missing user, got %v", err)
+	}
+}
+
+func TestSessionService_PurgeExpired(t *testing.T) {
+	service, repo, now := newTestSessionService(SessionConfig{TTL: time.Hour})
+	ctx := context.Background()
+
+	service.Create(ctx, 1)
+	*now = now.Add(2 * time.Hour)
+	service.Create(ctx, 1)
+
+	deleted, err := service.PurgeExpired(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpired failed: %v", err)
+	}
+	if deleted != 1 || len(repo.sessions) != 1 {
+		t.Errorf("Expected 1 session purged and 1 left, got %d purged and %d left", deleted, len(repo.sessions))
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sqlMigrationFile matches migration file names such as 002_create_sessions_table.up.sql
+var sqlMigrationFile = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+// noTransactionDirective marks a SQL migration as NoTransaction when it
+// appears as a comment line in its up file
+const noTransactionDirective = "-- migrate:no-transaction"
+
+// LoadSQLMigrations reads NNN_name.up.sql / NNN_name.down.sql pairs from the
+// root of fsys and returns them as migrations sorted by version. The
+// description is derived from the name, so 002_create_sessions_table becomes
+// "Create sessions table".
+//
+// Files are split into statements on semicolons and executed one at a time,
+// which keeps them independent of driver options such as MySQL's
+// multiStatements. Every version needs both an up and a down file.
+func LoadSQLMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	type sqlPair struct {
+		name string
+		up   string
+		down string
+	}
+	pairs := make(map[int]*sqlPair)
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := sqlMigrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q: expected NNN_name.up.sql or NNN_name.down.sql", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", entry.Name(), err)
+		}
+
+		pair, ok := pairs[version]
+		if !ok {
+			pair = &sqlPair{name: match[2]}
+			pairs[version] = pair
+		}
+		if pair.name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, pair.name, match[2])
+		}
+
+		if match[3] == "up" {
+			pair.up = string(content)
+		} else {
+			pair.down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for version, pair := range pairs {
+		if pair.up == "" {
+			return nil, fmt.Errorf("migration %d (%s) is missing its .up.sql file", version, pair.name)
+		}
+		if pair.down == "" {
+			return nil, fmt.Errorf("migration %d (%s) is missing its .down.sql file", version, pair.name)
+		}
+
+		migrations = append(migrations, Migration{
+			Version:       version,
+			Description:   describeMigration(pair.name),
+			Up:            execSQL(pair.up),
+			Down:          execSQL(pair.down),
+			NoTransaction: hasNoTransactionDirective(pair.up),
+			Checksum:      Checksum(pair.up, pair.down),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// AddMigrationsFromFS loads the SQL migrations in fsys (for example an
+// embed.FS) and registers them. Together with the migrations that are
+// already registered, versions must be unique and run from 1 without gaps;
+// otherwise nothing is registered and an error is returned.
+func (m *MigrationManager) AddMigrationsFromFS(fsys fs.FS) error {
+	loaded, err := LoadSQLMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	combined := append(append([]Migration{}, m.migrations...), loaded...)
+	if err := validateVersions(combined); err != nil {
+		return err
+	}
+
+	m.migrations = combined
+	return nil
+}
+
+// AddMigrationsFromDir is AddMigrationsFromFS for a directory on disk
+func (m *MigrationManager) AddMigrationsFromDir(dir string) error {
+	return m.AddMigrationsFromFS(os.DirFS(dir))
+}
+
+// validateVersions checks that versions are unique and contiguous from 1
+func validateVersions(migrations []Migration) error {
+	versions := make([]int, 0, len(migrations))
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	sort.Ints(versions)
+
+	for i, version := range versions {
+		if i > 0 && version == versions[i-1] {
+			return fmt.Errorf("duplicate migration version %d", version)
+		}
+		if version != i+1 {
+			return fmt.Errorf("migration versions have a gap: expected version %d, got %d", i+1, version)
+		}
+	}
+	return nil
+}
+
+// describeMigration turns a file name like create_users_table into "Create users table"
+func describeMigration(name string) string {
+	description := strings.ReplaceAll(name, "_", " ")
+	if description == "" {
+		return description
+	}
+	return strings.ToUpper(description[:1]) + description[1:]
+}
+
+func hasNoTransactionDirective(query string) bool {
+	for _, line := range strings.Split(query, "\n") {
+		if strings.TrimSpace(line) == noTransactionDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// execSQL returns a migration function that runs each statement in query
+func execSQL(query string) func(Executor) error {
+	statements := splitStatements(query)
+	return func(exec Executor) error {
+		for _, statement := range statements {
+			if _, err := exec.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// splitStatements splits query on semicolons that are not inside quotes or
+// comments. Comment-only and empty statements are dropped.
+func splitStatements(query string) []string {
+	var statements []string
+	var current strings.Builder
+	hasCode := false
+
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if hasCode && statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			// Line comment: copy through the end of the line
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			current.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 2
+			} else {
+				end += 2
+			}
+			current.WriteString(query[i : i+2+end])
+			i += 2 + end - 1
+		case c == '\'' || c == '"' || c == '`':
+			// Quoted string or identifier; a doubled quote is an escaped quote
+			j := i + 1
+			for j < len(query) {
+				if query[j] == '\\' && c != '`' {
+					j += 2
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(query) {
+				j = len(query) - 1
+			}
+			current.WriteString(query[i : j+1])
+			hasCode = true
+			i = j
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+			if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				hasCode = true
+			}
+		}
+	}
+	flush()
+
+	return statements
+}
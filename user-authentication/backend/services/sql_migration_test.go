@@ -0,0 +1,147 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadSQLMigrations(t *testing.T) {
+	db := newSQLiteDB(t)
+	manager := NewMigrationManagerWithDialect(db, SQLiteDialect{})
+
+	if err := manager.AddMigrationsFromDir("testdata/sql_migrations"); err != nil {
+		t.Fatalf("AddMigrationsFromDir failed: %v", err)
+	}
+
+	migrations := manager.GetMigrations()
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Description != "Create widgets table" {
+		t.Errorf("Unexpected first migration: %d %s", migrations[0].Version, migrations[0].Description)
+	}
+
+	if err := manager.InitializeMigrationTable(); err != nil {
+		t.Fatalf("InitializeMigrationTable failed: %v", err)
+	}
+	if err := manager.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var name string
+	if err := db.QueryRow("INSERT INTO widgets (color) VALUES ('red') RETURNING name").Scan(&name); err != nil {
+		t.Fatalf("Expected widgets table with color column: %v", err)
+	}
+	if name != "a;b" {
+		t.Errorf("Expected default name 'a;b', got '%s'", name)
+	}
+
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if err := manager.Down(); err != nil {
+		t.Fatalf("Second Down failed: %v", err)
+	}
+	if sqliteTableExists(t, db, "widgets") {
+		t.Error("Expected widgets table to be dropped")
+	}
+}
+
+func TestAddMigrationsFromFS_AfterGoMigrations(t *testing.T) {
+	manager := NewMigrationManager(nil)
+	manager.AddMigration(Migration{Version: 1, Description: "Go migration"})
+
+	fsys := fstest.MapFS{
+		"002_create_sessions_table.up.sql":   {Data: []byte("CREATE TABLE sessions (id INT);")},
+		"002_create_sessions_table.down.sql": {Data: []byte("DROP TABLE sessions;")},
+		"README.md":                          {Data: []byte("not a migration")},
+	}
+
+	if err := manager.AddMigrationsFromFS(fsys); err != nil {
+		t.Fatalf("AddMigrationsFromFS failed: %v", err)
+	}
+	if len(manager.GetMigrations()) != 2 {
+		t.Errorf("Expected 2 migrations, got %d", len(manager.GetMigrations()))
+	}
+}
+
+func TestAddMigrationsFromFS_Rejects(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"gap": {
+			"001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"001_a.down.sql": {Data: []byte("SELECT 1;")},
+			"003_c.up.sql":   {Data: []byte("SELECT 1;")},
+			"003_c.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"duplicate version": {
+			"001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"001_a.down.sql": {Data: []byte("SELECT 1;")},
+			"1_b.up.sql":     {Data: []byte("SELECT 1;")},
+			"1_b.down.sql":   {Data: []byte("SELECT 1;")},
+		},
+		"missing down": {
+			"001_a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"bad name": {
+			"001-a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+
+	for name, fsys := range tests {
+		manager := NewMigrationManager(nil)
+		if err := manager.AddMigrationsFromFS(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if len(manager.GetMigrations()) != 0 {
+			t.Errorf("%s: expected nothing to be registered", name)
+		}
+	}
+
+	// A SQL file may not reuse the version of a Go migration
+	manager := NewMigrationManager(nil)
+	manager.AddMigration(Migration{Version: 1, Description: "Go migration"})
+	err := manager.AddMigrationsFromFS(fstest.MapFS{
+		"001_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"001_a.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("Expected duplicate version error, got %v", err)
+	}
+}
+
+func TestLoadSQLMigrations_NoTransaction(t *testing.T) {
+	migrations, err := LoadSQLMigrations(fstest.MapFS{
+		"001_a.up.sql":   {Data: []byte("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx ON t (c);")},
+		"001_a.down.sql": {Data: []byte("DROP INDEX idx;")},
+	})
+	if err != nil {
+		t.Fatalf("LoadSQLMigrations failed: %v", err)
+	}
+	if !migrations[0].NoTransaction {
+		t.Error("Expected migration to be marked NoTransaction")
+	}
+	if migrations[0].Checksum != Checksum("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx ON t (c);", "DROP INDEX idx;") {
+		t.Errorf("Unexpected checksum %s", migrations[0].Checksum)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	query := `
+		-- comment; with a semicolon
+		CREATE TABLE t (s VARCHAR(10) DEFAULT 'x;''y');
+		/* block; comment */
+		INSERT INTO t VALUES ("a;b");;
+	`
+
+	statements := splitStatements(query)
+	if len(statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d: %q", len(statements), statements)
+	}
+	if !strings.HasSuffix(statements[0], `DEFAULT 'x;''y')`) {
+		t.Errorf("Unexpected first statement: %q", statements[0])
+	}
+	if !strings.HasSuffix(statements[1], `VALUES ("a;b")`) {
+		t.Errorf("Unexpected second statement: %q", statements[1])
+	}
+}
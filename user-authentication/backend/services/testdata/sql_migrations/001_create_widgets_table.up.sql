@@ -0,0 +1,7 @@
+-- Widgets are the example table used by the SQL migration tests
+CREATE TABLE widgets (
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(50) NOT NULL DEFAULT 'a;b'
+);
+
+CREATE INDEX idx_widgets_name ON widgets (name);
@@ -0,0 +1 @@
+ALTER TABLE widgets ADD COLUMN color VARCHAR(20);
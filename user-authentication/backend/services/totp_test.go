@@ -0,0 +1,115 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 test key of RFC 6238, base32 encoded
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTP_RFC6238Vectors(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	tests := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range tests {
+		step := totpStep(time.Unix(unix, 0))
+		if got := hotp([]byte("12345678901234567890"), step); got != expected {
+			t.Errorf("Expected code %s at %d, got %s", expected, unix, got)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := totpStep(now)
+
+	matched, ok := matchTOTP(rfc6238Secret, "081804", now, 0)
+	if !ok || matched != step {
+		t.Errorf("Expected code to match step %d, got %d (%v)", step, matched, ok)
+	}
+
+	// The previous and next step are accepted for clock drift
+	if _, ok := matchTOTP(rfc6238Secret, "081804", now.Add(totpPeriod), 0); !ok {
+		t.Error("Expected code of the previous step to match")
+	}
+	if _, ok := matchTOTP(rfc6238Secret, "081804", now.Add(-totpPeriod), 0); !ok {
+		t.Error("Expected code of the next step to match")
+	}
+	if _, ok := matchTOTP(rfc6238Secret, "081804", now.Add(2*totpPeriod), 0); ok {
+		t.Error("Expected code two steps old to be rejected")
+	}
+
+	// A used step cannot be used again
+	if _, ok := matchTOTP(rfc6238Secret, "081804", now, step); ok {
+		t.Error("Expected code of a used step to be rejected")
+	}
+
+	// Lowercase secrets are accepted
+	if _, ok := matchTOTP(strings.ToLower(rfc6238Secret), "081804", now, 0); !ok {
+		t.Error("Expected lowercase secret to work")
+	}
+
+	if _, ok := matchTOTP(rfc6238Secret, "000000", now, 0); ok {
+		t.Error("Expected wrong code to be rejected")
+	}
+	if _, ok := matchTOTP("not base32!", "081804", now, 0); ok {
+		t.Error("Expected invalid secret to be rejected")
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("newTOTPSecret failed: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("Expected base32 secret, got %q: %v", secret, err)
+	}
+	if len(key) != totpSecretBytes {
+		t.Errorf("Expected %d byte secret, got %d", totpSecretBytes, len(key))
+	}
+
+	other, _ := newTOTPSecret()
+	if other == secret {
+		t.Error("Expected secrets to be random")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("My App", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("Expected valid URI, got %q: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("Expected otpauth://totp URI, got %q", uri)
+	}
+	if parsed.Path != "/My App:alice@example.com" {
+		t.Errorf("Expected label 'My App:alice@example.com', got '%s'", parsed.Path)
+	}
+
+	query := parsed.Query()
+	expected := map[string]string{
+		"secret":    "JBSWY3DPEHPK3PXP",
+		"issuer":    "My App",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for key, value := range expected {
+		if query.Get(key) != value {
+			t.Errorf("Expected %s '%s', got '%s'", key, value, query.Get(key))
+		}
+	}
+}
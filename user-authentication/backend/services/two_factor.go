@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for wrong, expired or already used
+	// authenticator and recovery codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorNotEnabled is returned when the user has no confirmed second factor
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorAlreadyEnabled is returned when setting up a second factor
+	// for a user who already has one
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnrolled is returned when enabling two-factor
+	// authentication before a secret has been generated
+	ErrTwoFactorNotEnrolled = errors.New("two-factor setup has not been started")
+	// ErrInvalidTwoFactorChallenge is returned for malformed, badly signed or
+	// expired login challenges
+	ErrInvalidTwoFactorChallenge = errors.New("invalid or expired two-factor challenge")
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time
+const recoveryCodeCount = 10
+
+// TwoFactorConfig controls two-factor authentication
+type TwoFactorConfig struct {
+	// Issuer is the name authenticator apps show next to the account
+	Issuer string
+	// Secret signs the login challenges that connect the password step with
+	// the code step
+	Secret []byte
+	// ChallengeTTL is how long the user has to enter the code after the password
+	ChallengeTTL time.Duration
+}
+
+// LoadTwoFactorConfig reads the two-factor configuration from the
+// environment: TOTP_ISSUER, TWO_FACTOR_SECRET and TWO_FACTOR_CHALLENGE_TTL.
+// Secret is nil when TWO_FACTOR_SECRET is not set.
+func LoadTwoFactorConfig() (*TwoFactorConfig, error) {
+	config := &TwoFactorConfig{
+		Issuer:       getEnv("TOTP_ISSUER", "user-authentication"),
+		ChallengeTTL: getEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+	}
+
+	if secret := os.Getenv("TWO_FACTOR_SECRET"); secret != "" {
+		if len(secret) < sha256.Size {
+			return nil, fmt.Errorf("TWO_FACTOR_SECRET must be at least %d bytes", sha256.Size)
+		}
+		config.Secret = []byte(secret)
+	}
+	return config, nil
+}
+
+// TwoFactorService manages TOTP authenticator apps and recovery codes, and
+// the challenge that makes a login with two-factor authentication a two-step
+// process. Recovery codes are stored hashed and can be used once each.
+type TwoFactorService struct {
+	store  repository.TwoFactorRepository
+	users  repository.UserRepository
+	config TwoFactorConfig
+	now    func() time.Time
+}
+
+// NewTwoFactorService creates a new TwoFactorService
+func NewTwoFactorService(store repository.TwoFactorRepository, users repository.UserRepository, config *TwoFactorConfig) (*TwoFactorService, error) {
+	if len(config.Secret) == 0 {
+		return nil, errors.New("two-factor secret is required")
+	}
+	return &TwoFactorService{store: store, users: users, config: *config, now: time.Now}, nil
+}
+
+// Enabled reports whether the user has confirmed a second factor
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int) (bool, error) {
+	totp, err := s.store.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.EnabledAt != nil, nil
+}
+
+// Status returns whether two-factor authentication is enabled for the user
+// and how many recovery codes are left
+func (s *TwoFactorService) Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil || !enabled {
+		return &models.TwoFactorStatus{}, err
+	}
+
+	remaining, err := s.store.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorStatus{Enabled: true, RecoveryCodesRemaining: remaining}, nil
+}
+
+// BeginEnrollment generates a new TOTP secret for user. It does not take
+// effect until Enable is called with a code from the authenticator app.
+// Starting over replaces a secret that has not been enabled yet.
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, user *models.User) (*models.TOTPEnrollment, error) {
+	enabled, err := s.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveTOTP(ctx, &models.TOTP{UserID: user.ID, Secret: secret, CreatedAt: s.now()}); err != nil {
+		return nil, err
+	}
+	return &models.TOTPEnrollment{Secret: secret, URI: totpURI(s.config.Issuer, user.Email, secret)}, nil
+}
+
+// Enable confirms the enrollment with a code from the authenticator app and
+// returns the user's recovery codes. They are shown only this once.
+func (s *TwoFactorService) Enable(ctx context.Context, userID int, code string) ([]string, error) {
+	totp, err := s.store.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	now := s.now()
+	step, ok := matchTOTP(totp.Secret, normalizeCode(code), now, totp.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	// Enabling and storing the recovery codes happen together, so a failure
+	// cannot leave two-factor enabled without codes the user has seen
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableTOTP(ctx, userID, now, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable removes the user's second factor after checking code
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.store.DeleteTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user after
+// checking code, and returns the new ones
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// Verify checks a code from the authenticator app or a recovery code. Both
+// work only once.
+func (s *TwoFactorService) Verify(ctx context.Context, userID int, code string) error {
+	totp, err := s.store.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if totp.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		err := s.store.UseRecoveryCode(ctx, userID, hashToken(code), s.now())
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	step, ok := matchTOTP(totp.Secret, code, s.now(), totp.LastUsedStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	// Another request may have used the same code in the meantime
+	err = s.store.UseTOTPStep(ctx, userID, step)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// IssueChallenge returns the challenge for a user who has entered the right
+// password. The token is signed together with the password hash, so changing
+// the password invalidates it.
+func (s *TwoFactorService) IssueChallenge(user *models.User) *models.TwoFactorChallenge {
+	return &models.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		Token:             s.sign(user, s.now().Add(s.config.ChallengeTTL)),
+		ExpiresIn:         int64(s.config.ChallengeTTL.Seconds()),
+	}
+}
+
+// VerifyChallenge returns the user of a challenge token. It does not check
+// the code; call Verify for that.
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, token string) (*models.User, error) {
+	userID, expiresAt, ok := parseUserToken(token)
+	if !ok || !s.now().Before(expiresAt) {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(token), []byte(s.sign(user, expiresAt))) {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	return user, nil
+}
+
+// sign returns the challenge token for user
+func (s *TwoFactorService) sign(user *models.User, expiresAt time.Time) string {
+	return signUserToken(s.config.Secret, "two-factor", user.ID, expiresAt, user.PasswordHash)
+}
+
+// replaceRecoveryCodes generates new recovery codes for the user and stores
+// their hashes
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// newRecoveryCode returns a random code such as "k3qzp-7m2xa" (50 bits)
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeCode removes what users tend to type around a code: spaces,
+// dashes and upper case
+func normalizeCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// isTOTPCode reports whether code looks like a code from an authenticator
+// app rather than a recovery code
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"user-authentication/models"
+	"user-authentication/repository"
+)
+
+// memoryTwoFactorRepository is an in-memory TwoFactorRepository for tests
+type memoryTwoFactorRepository struct {
+	totps map[int]*models.TOTP
+	// codes maps user IDs to recovery code hashes and whether they are used
+	codes map[int]map[string]bool
+}
+
+func newMemoryTwoFactorRepository() *memoryTwoFactorRepository {
+	return &memoryTwoFactorRepository{totps: make(map[int]*models.TOTP), codes: make(map[int]map[string]bool)}
+}
+
+func (r *memoryTwoFactorRepository) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	totp, ok := r.totps[userID]
+	if !ok {
+		return nil, repository.ErrTOTPNotFound
+	}
+	copied := *totp
+	return &copied, nil
+}
+
+func (r *memoryTwoFactorRepository) SaveTOTP(ctx context.Context, totp *models.TOTP) error {
+	copied := *totp
+	r.totps[totp.UserID] = &copied
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) EnableTOTP(ctx context.Context, userID int, enabledAt time.Time, step int64, recoveryCodeHashes []string) error {
+	totp, ok := r.totps[userID]
+	if !ok || totp.EnabledAt != nil {
+		return repository.ErrTOTPNotFound
+	}
+	totp.EnabledAt = &enabledAt
+	totp.LastUsedStep = step
+	return r.ReplaceRecoveryCodes(ctx, userID, recoveryCodeHashes)
+}
+
+func (r *memoryTwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	totp, ok := r.totps[userID]
+	if !ok || totp.LastUsedStep >= step {
+		return repository.ErrTOTPNotFound
+	}
+	totp.LastUsedStep = step
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	delete(r.totps, userID)
+	delete(r.codes, userID)
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	r.codes[userID] = make(map[string]bool)
+	for _, hash := range hashes {
+		r.codes[userID][hash] = false
+	}
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, hash string, usedAt time.Time) error {
+	used, ok := r.codes[userID][hash]
+	if !ok || used {
+		return repository.ErrRecoveryCodeNotFound
+	}
+	r.codes[userID][hash] = true
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	count := 0
+	for _, used := range r.codes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// newTestTwoFactorService returns a TwoFactorService for alice with a
+// controllable clock
+func newTestTwoFactorService(t *testing.T) (*TwoFactorService, *singleUserRepository, *memoryTwoFactorRepository, *time.Time) {
+	t.Helper()
+	users := &singleUserRepository{user: &models.User{ID: 1, Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}}
+	store := newMemoryTwoFactorRepository()
+	service, err := NewTwoFactorService(store, users, &TwoFactorConfig{
+		Issuer:       "Test",
+		Secret:       []byte(strings.Repeat("s", 32)),
+		ChallengeTTL: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewTwoFactorService failed: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, users, store, &now
+}
+
+// currentCode returns the authenticator app code for secret at now
+func currentCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("Invalid secret %q: %v", secret, err)
+	}
+	return hotp(key, totpStep(now))
+}
+
+// enableTwoFactor enrolls alice and returns her secret and recovery codes
+func enableTwoFactor(t *testing.T, service *TwoFactorService, user *models.User, now *time.Time) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	enrollment, err := service.BeginEnrollment(ctx, user)
+	if err != nil {
+		t.Fatalf("BeginEnrollment failed: %v", err)
+	}
+	codes, err := service.Enable(ctx, user.ID, currentCode(t, enrollment.Secret, *now))
+	if err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+	return enrollment.Secret, codes
+}
+
+func TestLoadTwoFactorConfig(t *testing.T) {
+	t.Setenv("TWO_FACTOR_SECRET", "")
+	t.Setenv("TOTP_ISSUER", "")
+
+	config, err := LoadTwoFactorConfig()
+	if err != nil {
+		t.Fatalf("LoadTwoFactorConfig failed: %v", err)
+	}
+	if config.Secret != nil || config.Issuer != "user-authentication" {
+		t.Errorf("Expected no secret and the default issuer, got %+v", config)
+	}
+
+	t.Setenv("TWO_FACTOR_SECRET", "too-short")
+	if _, err := LoadTwoFactorConfig(); err == nil {
+		t.Error("Expected error for a short secret")
+	}
+}
+
+func TestTwoFactorService_Enrollment(t *testing.T) {
+	service, users, store, now := newTestTwoFactorService(t)
+	ctx := context.Background()
+
+	if _, err := service.Enable(ctx, 1, "123456"); !errors.Is(err, ErrTwoFactorNotEnrolled) {
+		t.Errorf("Expected ErrTwoFactorNotEnrolled before setup, got %v", err)
+	}
+
+	enrollment, err := service.BeginEnrollment(ctx, users.user)
+	if err != nil {
+		t.Fatalf("BeginEnrollment failed: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/Test:alice@example.com?") {
+		t.Errorf("Expected otpauth URI for alice, got '%s'", enrollment.URI)
+	}
+
+	// A pending enrollment does not enable anything
+	status, err := service.Status(ctx, 1)
+	if err != nil || status.Enabled {
+		t.Errorf("Expected two-factor to be disabled before confirmation, got %+v (err: %v)", status, err)
+	}
+
+	if _, err := service.Enable(ctx, 1, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected ErrInvalidTwoFactorCode, got %v", err)
+	}
+
+	codes, err := service.Enable(ctx, 1, currentCode(t, enrollment.Secret, *now))
+	if err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+	for _, code := range codes {
+		// Only hashes are stored
+		if _, ok := store.codes[1][code]; ok {
+			t.Errorf("Expected recovery code %s to be stored hashed", code)
+		}
+	}
+
+	status, err = service.Status(ctx, 1)
+	if err != nil || !status.Enabled || status.RecoveryCodesRemaining != recoveryCodeCount {
+		t.Errorf("Expected two-factor enabled with %d codes, got %+v (err: %v)", recoveryCodeCount, status, err)
+	}
+
+	if _, err := service.BeginEnrollment(ctx, users.user); !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+		t.Errorf("Expected ErrTwoFactorAlreadyEnabled, got %v", err)
+	}
+}
+
+func TestTwoFactorService_VerifyTOTP(t *testing.T) {
+	service, users, _, now := newTestTwoFactorService(t)
+	ctx := context.Background()
+
+	if err := service.Verify(ctx, 1, "123456"); !errors.Is(err, ErrTwoFactorNotEnabled) {
+		t.Errorf("Expected ErrTwoFactorNotEnabled, got %v", err)
+	}
+
+	secret, _ := enableTwoFactor(t, service, users.user, now)
+
+	// The code used to enable two-factor cannot be used again
+	if err := service.Verify(ctx, 1, currentCode(t, secret, *now)); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected the enrollment code to be used up, got %v", err)
+	}
+
+	*now = now.Add(totpPeriod)
+	code := currentCode(t, secret, *now)
+	if err := service.Verify(ctx, 1, code[:3]+" "+code[3:]); err != nil {
+		t.Errorf("Expected code with a space to be accepted, got %v", err)
+	}
+	if err := service.Verify(ctx, 1, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a code to work only once, got %v", err)
+	}
+}
+
+func TestTwoFactorService_VerifyRecoveryCode(t *testing.T) {
+	service, users, _, now := newTestTwoFactorService(t)
+	ctx := context.Background()
+	_, codes := enableTwoFactor(t, service, users.user, now)
+
+	if err := service.Verify(ctx, 1, strings.ToUpper(codes[0])); err != nil {
+		t.Errorf("Expected recovery code to be accepted, got %v", err)
+	}
+	if err := service.Verify(ctx, 1, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a recovery code to work only once, got %v", err)
+	}
+	if err := service.Verify(ctx, 1, "aaaaa-aaaaa"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected unknown recovery code to be rejected, got %v", err)
+	}
+
+	status, _ := service.Status(ctx, 1)
+	if status.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Errorf("Expected %d recovery codes left, got %d", recoveryCodeCount-1, status.RecoveryCodesRemaining)
+	}
+
+	newCodes, err := service.RegenerateRecoveryCodes(ctx, 1, codes[1])
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes failed: %v", err)
+	}
+	if err := service.Verify(ctx, 1, codes[2]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected old recovery codes to be replaced, got %v", err)
+	}
+	if err := service.Verify(ctx, 1, newCodes[0]); err != nil {
+		t.Errorf("Expected new recovery code to be accepted, got %v", err)
+	}
+}
+
+func TestTwoFactorService_Disable(t *testing.T) {
+	service, users, store, now := newTestTwoFactorService(t)
+	ctx := context.Background()
+	_, codes := enableTwoFactor(t, service, users.user, now)
+
+	if err := service.Disable(ctx, 1, "aaaaa-aaaaa"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected ErrInvalidTwoFactorCode, got %v", err)
+	}
+	if err := service.Disable(ctx, 1, codes[0]); err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+
+	enabled, err := service.Enabled(ctx, 1)
+	if err != nil || enabled {
+		t.Errorf("Expected two-factor to be disabled, got %v (err: %v)", enabled, err)
+	}
+	if len(store.codes[1]) != 0 {
+		t.Errorf("Expected recovery codes to be deleted, got %d", len(store.codes[1]))
+	}
+}
+
+func TestTwoFactorService_Challenge(t *testing.T) {
+	service, users, _, now := newTestTwoFactorService(t)
+	ctx := context.Background()
+
+	challenge := service.IssueChallenge(users.user)
+	if !challenge.TwoFactorRequired || challenge.ExpiresIn != 300 {
+		t.Errorf("Expected challenge valid for 300 seconds, got %+v", challenge)
+	}
+
+	user, err := service.VerifyChallenge(ctx, challenge.Token)
+	if err != nil || user.ID != 1 {
+		t.Fatalf("Expected challenge for user 1, got %v (err: %v)", user, err)
+	}
+
+	if _, err := service.VerifyChallenge(ctx, challenge.Token+"x"); !errors.Is(err, ErrInvalidTwoFactorChallenge) {
+		t.Errorf("Expected ErrInvalidTwoFactorChallenge for a tampered token, got %v", err)
+	}
+	if _, err := service.VerifyChallenge(ctx, "garbage"); !errors.Is(err, ErrInvalidTwoFactorChallenge) {
+		t.Errorf("Expected ErrInvalidTwoFactorChallenge for garbage, got %v", err)
+	}
+
+	// Changing the password invalidates the challenge
+	users.user.PasswordHash = "new-hash"
+	if _, err := service.VerifyChallenge(ctx, challenge.Token); !errors.Is(err, ErrInvalidTwoFactorChallenge) {
+		t.Errorf("Expected ErrInvalidTwoFactorChallenge after a password change, got %v", err)
+	}
+
+	challenge = service.IssueChallenge(users.user)
+	*now = now.Add(5 * time.Minute)
+	if _, err := service.VerifyChallenge(ctx, challenge.Token); !errors.Is(err, ErrInvalidTwoFactorChallenge) {
+		t.Errorf("Expected ErrInvalidTwoFactorChallenge after expiry, got %v", err)
+	}
+}
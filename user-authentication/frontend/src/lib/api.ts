@@ -1,6 +1,6 @@
 // API client configuration and utilities
 
-import axios, { AxiosInstance, AxiosError } from 'axios';
+import axios, { AxiosInstance, AxiosError, InternalAxiosRequestConfig } from 'axios';
 import { toast } from 'react-hot-toast';
 
 // Create axios instance with default configuration
@@ -13,10 +13,32 @@ const api: AxiosInstance = axios.create({
   },
 });
 
+// CSRF token for requests that change data. It is bound to the session,
+// so it is fetched again when the server rejects it, e.g. after login.
+const CSRF_ENDPOINT = '/api/auth/csrf';
+const SAFE_METHODS = ['get', 'head', 'options'];
+let csrfToken: Promise<string> | null = null;
+
+const getCsrfToken = (): Promise<string> => {
+  if (!csrfToken) {
+    csrfToken = api
+      .get<{ csrf_token: string }>(CSRF_ENDPOINT)
+      .then((response) => response.data.csrf_token)
+      .catch((error) => {
+        csrfToken = null;
+        throw error;
+      });
+  }
+  return csrfToken;
+};
+
 // Request interceptor
 api.interceptors.request.use(
-  (config) => {
-    // Add any request modifications here
+  async (config) => {
+    // Send the CSRF token with requests that change data
+    if (!SAFE_METHODS.includes((config.method || 'get').toLowerCase())) {
+      config.headers.set('X-CSRF-Token', await getCsrfToken());
+    }
     return config;
   },
   (error) => {
@@ -29,7 +51,15 @@ api.interceptors.response.use(
   (response) => {
     return response;
   },
-  (error: AxiosError) => {
+  async (error: AxiosError<{ error?: string }>) => {
+    // Retry once with a new CSRF token when the session has changed
+    const config = error.config as (InternalAxiosRequestConfig & { csrfRetried?: boolean }) | undefined;
+    if (error.response?.status === 403 && error.response.data?.error === 'Invalid CSRF token' && config && !config.csrfRetried) {
+      config.csrfRetried = true;
+      csrfToken = null;
+      return api(config);
+    }
+
     // Handle common errors
     if (error.response?.status === 401) {
       // Unauthorized - redirect to login
@@ -64,6 +94,7 @@ export const endpoints = {
     register: '/api/auth/register',
     logout: '/api/auth/logout',
     me: '/api/auth/me',
+    csrf: CSRF_ENDPOINT,
   },
   
   // Posts